@@ -215,6 +215,16 @@ resource_policies:
       org_list:
       role_list:
         - admin
+#  # Rule can also be a policy expression of OUTOF(threshold, ...), AND(...) and OR(...), which can be nested.
+#  # The principals are org ids with an optional role, such as {org1_id}:ADMIN, "*" means any organization.
+#  # A weight such as 2*{org1_id} counts the organization multiple times in OUTOF. org_list should be empty,
+#  # role_list applies to the principals without role.
+#  - resource_name: CHAIN_CONFIG-CONSENSUS_EXT_UPDATE
+#    policy:
+#      rule: "AND(OUTOF(3, 2*{org1_id}, {org2_id}, {org3_id}), {org4_id}:ADMIN)"
+#      org_list:
+#      role_list:
+#        - admin
 
 # The disabled native contract list
 # Disable the system contract by specifying the system contract name
@@ -169,6 +169,32 @@ rpc:
     # -1: unlimited, by default is 10000.
     token_bucket_size: -1
 
+  # Rate limit by the signer of requests, each member has separate token buckets of query, invoke
+  # and subscribe, the quotas of the members can be adjusted per org.
+  identity_ratelimit:
+    # Identity rate limit switch. Default is false.
+    enabled: false
+
+    # The quotas of each member of the orgs not listed below.
+    # token_per_second 0 or -1: unlimited; token_bucket_size 0: equal to token_per_second.
+    default:
+      query:
+        token_per_second: 1000
+        token_bucket_size: 1000
+      invoke:
+        token_per_second: 100
+        token_bucket_size: 100
+      subscribe:
+        token_per_second: 10
+        token_bucket_size: 10
+
+    # The quotas of each member of the orgs, token_per_second 0 uses the default, -1 is unlimited.
+    orgs:
+      # - org_id: {org_id}
+      #   invoke:
+      #     token_per_second: 500
+      #     token_bucket_size: 500
+
   # Rate limit settings for subscriber
   subscriber:
     ratelimit:
@@ -186,10 +212,32 @@ rpc:
     # RPC TLS public key file path
     cert_file:      ../config/{org_path}/certs/{rpc_cert_path}.crt
 
-  # RPC blacklisted ip addresses
+  # RPC blacklist, it can be replaced at runtime by the admin of the org with
+  # "cmc client blacklist set", without restarting the node. The blacklist set at runtime
+  # is not written back to this file, so update it here as well to keep it after restart.
   blacklist:
+    # Blacklisted ip addresses or CIDR ranges
     addresses:
       # - "127.0.0.1"
+      # - "10.10.0.0/16"
+    # Allowed ip addresses or CIDR ranges, if it is not empty, all other addresses are rejected
+    allow_addresses:
+      # - "192.168.1.0/24"
+    # Blacklisted cert hashes in hex, computed with the hash type of the chain
+    cert_hashes:
+      # - "7d7a3f5e..."
+    # Blacklisted org ids
+    orgs:
+      # - "wx-org5.chainmaker.org"
+
+  # HTTP/JSON gateway of the RPC service, which serves POST /v1/sendrequest, POST /v1/subscribe
+  # (Server-Sent Events) and GET /v1/getversion behind the blacklist, ratelimit and tls above.
+  gateway:
+    # Gateway switch, default is false.
+    enabled: false
+
+    # Gateway port, 0 means sharing the RPC port, which is only supported if tls mode is disable.
+    port: 0
 
 # Monitor related settings
 monitor:
@@ -220,10 +268,94 @@ consensus:
     # Min time unit in rate election and heartbeat.
     ticker: 1
 
+# Block sync related settings
+sync:
+  # The time in seconds a peer is excluded from the block requests after it served a block failing to verify
+  # or timed out too many times. Default is 60.
+  peer_ban_duration: 60
+  # The max size in bytes of the blocks packed in one sync response, at least one block is packed. Default is 4MiB.
+  max_batch_bytes: 4194304
+  # Whether to compress the sync responses with gzip. Default is false.
+  compress_response: false
+  # The max size in bytes of a sync response from peers after decompressing, the larger responses are dropped.
+  # It must not be less than max_batch_bytes and the block size of the peers. Default is 64MiB.
+  max_decompressed_bytes: 67108864
+
 # Scheduler related settings
 scheduler:
   # whether log the txRWSet map in debug mode
   rwset_log: false
+  # Conflict-aware schedule of the txs in a proposing block, the txs predicted to access the same hot keys
+  # are executed serially instead of being executed again and again on read/write conflicts. A tx may declare
+  # its keys by the parameters __READ_KEYS__ and __WRITE_KEYS__, JSON arrays of "<contract name>#<key>".
+  conflict_aware:
+    # Conflict-aware schedule switch. Default is false.
+    enabled: false
+    # A written key is hot if it is predicted to be accessed by this number of txs or more in a block. Default is 2.
+    hot_key_threshold: 2
+    # A tx conflicted more times is executed serially after the others. Default is 3.
+    max_conflict_retries: 3
+    # Max size of the goroutine pool, 0 means the pool capacity of the store. The pool is sized by the
+    # parallel txs of a block and shrinks when the last block conflicted a lot.
+    max_pool_size: 0
+
+# Event dispatcher of the subscriptions, the events of a chain are delivered to the subscribers in order
+subscriber:
+  # Size of the event queue of a chain. The committing of blocks never waits for it, when it is full
+  # the subscriptions of the event are closed with an error. Default is 1024.
+  queue_size: 1024
+  # Size of the event buffer of a subscription, except for the unbounded policy. Default is 128.
+  buffer_size: 128
+  # What to do when the buffer of a subscription is full, default is unbounded.
+  # unbounded: buffer the events without limit
+  # drop: drop the events for the subscription
+  # disconnect: close the subscription with an error
+  # block: wait for the subscription at most block_timeout, which holds back the other subscriptions of the chain,
+  #        and then close it with an error
+  slow_consumer_policy: unbounded
+  # Milliseconds waiting for a slow subscription with the block policy. Default is 1000.
+  block_timeout: 1000
+  # Push the notifications of blocks, txs and contract events to webhooks or message queues.
+  # The notifications are spooled on disk and retried until delivered, those failing after max_retries
+  # are appended to dead_letter.log in the spool directory of the sink. The blocks committed while a sink
+  # is disconnected or stopped are read from the store.
+  event_sinks:
+    # Directory of the spooled notifications, <spool_dir>/<chain_id>/<sink_name>
+    spool_dir: ../data/{org_id}/event_sinks
+    sinks:
+#      - name: webhook1
+#        # webhook or queue
+#        type: webhook
+#        # Chains of the notifications, all chains if it is empty
+#        chain_ids: [chain1]
+#        # block, tx or contract_event, all types if it is empty
+#        events: [block, tx, contract_event]
+#        # Only the txs and contract events of the contracts, all contracts if it is empty
+#        contract_names: []
+#        # Only the contract events of the topics, all topics if it is empty
+#        topics: []
+#        # -1 means retrying forever. Default is 10.
+#        max_retries: 10
+#        # Interval before the first retry in milliseconds, doubled for each retry up to one minute
+#        retry_interval: 1000
+#        # The block events wait while so many notifications are waiting to be delivered. Default is 10000.
+#        max_pending: 10000
+#        webhook:
+#          url: http://127.0.0.1:8080/events
+#          # The requests are signed in X-ChainMaker-Signature by hex(HMAC-SHA256(secret, timestamp + "." + body)),
+#          # timestamp is in X-ChainMaker-Timestamp
+#          secret: changeme
+#          # Timeout of a request in milliseconds
+#          timeout: 5000
+#      - name: queue1
+#        type: queue
+#        events: [contract_event]
+#        queue:
+#          # The name registered by sink.RegisterQueueProvider
+#          provider: kafka
+#          topic: chainmaker_events
+#          options:
+#            brokers: 127.0.0.1:9092
 
 # Storage config settings
 # Contains blockDb, stateDb, historyDb, resultDb, contractEventDb
@@ -20,12 +20,17 @@ import (
 	"time"
 
 	"chainmaker.org/chainmaker-go/blockchain"
+	"chainmaker.org/chainmaker-go/core/common/scheduler"
 	"chainmaker.org/chainmaker-go/module/monitor"
 	"chainmaker.org/chainmaker-go/rpcserver"
+	"chainmaker.org/chainmaker-go/subscriber"
+	"chainmaker.org/chainmaker-go/subscriber/sink"
+	"chainmaker.org/chainmaker-go/sync"
 	"chainmaker.org/chainmaker/localconf/v2"
 	"chainmaker.org/chainmaker/logger/v2"
 	"code.cloudfoundry.org/bytefmt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var log = logger.GetLogger(logger.MODULE_CLI)
@@ -37,7 +42,7 @@ func StartCMD() *cobra.Command {
 		Long:  "Startup ChainMaker",
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			initLocalConfig(cmd)
-			mainStart()
+			mainStart(cmd)
 			fmt.Println("ChainMaker exit")
 			return nil
 		},
@@ -46,14 +51,33 @@ func StartCMD() *cobra.Command {
 	return startCmd
 }
 
-func mainStart() {
+func mainStart(cmd *cobra.Command) {
 	if localconf.ChainMakerConfig.DebugConfig.IsTraceMemoryUsage {
 		traceMemoryUsage()
 	}
 
+	// the sections of chainmaker.yml which are not a part of localconf are read once for all the modules
+	v, err := readConfigFile(cmd)
+	if err != nil {
+		log.Errorf("read config file failed, %s", err.Error())
+		return
+	}
+	if err = loadSchedulerConfig(v); err != nil {
+		log.Errorf("load scheduler config failed, %s", err.Error())
+		return
+	}
+	if err = loadSubscriberConfig(v); err != nil {
+		log.Errorf("load subscriber config failed, %s", err.Error())
+		return
+	}
+	if err = loadSyncConfig(v); err != nil {
+		log.Errorf("load sync config failed, %s", err.Error())
+		return
+	}
+
 	// init chainmaker server
 	chainMakerServer := blockchain.NewChainMakerServer()
-	if err := chainMakerServer.Init(); err != nil {
+	if err = chainMakerServer.Init(); err != nil {
 		log.Errorf("chainmaker server init failed, %s", err.Error())
 		return
 	}
@@ -64,9 +88,13 @@ func mainStart() {
 		log.Errorf("rpc server init failed, %s", err.Error())
 		return
 	}
+	if err = loadRpcServerConfig(v, rpcServer); err != nil {
+		log.Errorf("load rpc server config failed, %s", err.Error())
+		return
+	}
 
 	// init monitor server
-	monitorServer := monitor.NewMonitorServer()
+	monitorServer := monitor.NewMonitorServer(chainMakerServer)
 
 	//// p2p callback to validate
 	//txpool.RegisterCallback(rpcServer.Gateway().Invoke)
@@ -110,6 +138,85 @@ func mainStart() {
 
 }
 
+// loadRpcServerConfig reads the sections of rpc in chainmaker.yml which are not a part of localconf
+func loadRpcServerConfig(v *viper.Viper, rpcServer *rpcserver.RPCServer) error {
+	gatewayConfig := rpcserver.GatewayConfig{}
+	if err := v.UnmarshalKey("rpc.gateway", &gatewayConfig); err != nil {
+		return fmt.Errorf("invalid rpc.gateway, %s", err)
+	}
+	identityRateLimitConfig := &rpcserver.IdentityRateLimitConfig{}
+	if err := v.UnmarshalKey("rpc.identity_ratelimit", identityRateLimitConfig); err != nil {
+		return fmt.Errorf("invalid rpc.identity_ratelimit, %s", err)
+	}
+
+	blackListConfig := &rpcserver.BlackListConfig{}
+	if err := v.UnmarshalKey("rpc.blacklist", blackListConfig); err != nil {
+		return fmt.Errorf("invalid rpc.blacklist, %s", err)
+	}
+
+	rpcServer.SetGatewayConfig(gatewayConfig)
+	rpcServer.SetIdentityRateLimitConfig(identityRateLimitConfig)
+	if err := rpcServer.SetBlackListConfig(blackListConfig); err != nil {
+		return fmt.Errorf("invalid rpc.blacklist, %s", err)
+	}
+	return nil
+}
+
+// loadSchedulerConfig reads scheduler.conflict_aware in chainmaker.yml, which is not a part of localconf
+func loadSchedulerConfig(v *viper.Viper) error {
+	config := &scheduler.ConflictAwareConfig{}
+	if err := v.UnmarshalKey("scheduler.conflict_aware", config); err != nil {
+		return fmt.Errorf("invalid scheduler.conflict_aware, %s", err)
+	}
+	scheduler.SetConflictAwareConfig(config)
+	return nil
+}
+
+// loadSubscriberConfig reads subscriber in chainmaker.yml, which is not a part of localconf
+func loadSubscriberConfig(v *viper.Viper) error {
+	config := &subscriber.Config{}
+	if err := v.UnmarshalKey("subscriber", config); err != nil {
+		return fmt.Errorf("invalid subscriber, %s", err)
+	}
+	if err := subscriber.SetConfig(config); err != nil {
+		return fmt.Errorf("invalid subscriber, %s", err)
+	}
+	sinkConfig := &sink.Config{}
+	if err := v.UnmarshalKey("subscriber.event_sinks", sinkConfig); err != nil {
+		return fmt.Errorf("invalid subscriber.event_sinks, %s", err)
+	}
+	if err := sink.SetConfig(sinkConfig); err != nil {
+		return fmt.Errorf("invalid subscriber.event_sinks, %s", err)
+	}
+	return nil
+}
+
+// loadSyncConfig reads the settings of sync in chainmaker.yml which are not a part of localconf
+func loadSyncConfig(v *viper.Viper) error {
+	config := &sync.ExtConfig{}
+	if err := v.UnmarshalKey("sync", config); err != nil {
+		return fmt.Errorf("invalid sync, %s", err)
+	}
+	if err := sync.SetExtConfig(config); err != nil {
+		return fmt.Errorf("invalid sync, %s", err)
+	}
+	return nil
+}
+
+// readConfigFile reads the config file loaded by localconf, the flags of cmd are bound as localconf does,
+// so the sections which are not a part of localconf see the same overrides
+func readConfigFile(cmd *cobra.Command) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(localconf.ConfigFilepath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	if err := v.BindPFlags(cmd.Flags()); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
 func handleExitSignal(exitC chan<- error) {
 
 	signalChan := make(chan os.Signal, 1)
@@ -7,9 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"chainmaker.org/chainmaker-go/consensus"
 	"chainmaker.org/chainmaker-go/txpool"
 	"chainmaker.org/chainmaker-go/vm"
 	"chainmaker.org/chainmaker/localconf/v2"
+	consensusPb "chainmaker.org/chainmaker/pb-go/v2/consensus"
 	"chainmaker.org/chainmaker/protocol/v2"
 	batch "chainmaker.org/chainmaker/txpool-batch/v2"
 	single "chainmaker.org/chainmaker/txpool-single/v2"
@@ -21,6 +23,16 @@ import (
 )
 
 func init() {
+	// consensus
+	// an in-house engine is added here, or replaces a built-in one, with consensus.RegisterConsensusProvider,
+	// together with a core engine provider of the same consensus type registered with
+	// provider.RegisterCoreEngineProvider.
+	consensus.RegisterConsensusProvider(consensusPb.ConsensusType_TBFT, consensus.TBFTProvider{})
+	consensus.RegisterConsensusProvider(consensusPb.ConsensusType_DPOS, consensus.TBFTProvider{})
+	consensus.RegisterConsensusProvider(consensusPb.ConsensusType_SOLO, consensus.SOLOProvider{})
+	consensus.RegisterConsensusProvider(consensusPb.ConsensusType_RAFT, consensus.RAFTProvider{})
+	consensus.RegisterConsensusProvider(consensusPb.ConsensusType_HOTSTUFF, consensus.HotStuffProvider{})
+
 	// txPool
 	txpool.RegisterTxPoolProvider(single.TxPoolType, single.NewTxPoolImpl)
 	txpool.RegisterTxPoolProvider(batch.TxPoolType, batch.NewBatchTxPool)
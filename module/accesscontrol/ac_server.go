@@ -576,6 +576,9 @@ func (acs *accessControlService) checkResourcePolicyRule(resourcePolicy *config.
 		acs.log.Debugf("delete policy configuration of %s", resourcePolicy.ResourceName)
 		return true
 	default:
+		if isPolicyExpression(resourcePolicy.Policy.Rule) {
+			return acs.checkResourcePolicyRuleExpressionCase(resourcePolicy.Policy)
+		}
 		return acs.checkResourcePolicyRuleDefaultCase(resourcePolicy.Policy)
 	}
 }
@@ -649,6 +652,27 @@ func (acs *accessControlService) checkResourcePolicyRuleDefaultCase(policy *pbac
 	}
 }
 
+func (acs *accessControlService) checkResourcePolicyRuleExpressionCase(policy *pbac.Policy) bool {
+	if len(policy.OrgList) > 0 {
+		acs.log.Errorf("bad configuration: organization list should be empty for the policy expression [%s], "+
+			"the organizations are listed in the rule", policy.Rule)
+		return false
+	}
+	expr, err := parsePolicyExpression(policy.Rule)
+	if err != nil {
+		acs.log.Errorf("bad configuration: %s", err.Error())
+		return false
+	}
+	for _, orgId := range expr.orgIds() {
+		if _, ok := acs.orgList.Load(orgId); !ok {
+			acs.log.Errorf("bad configuration: policy expression [%s] contains unknown organization [%s]",
+				policy.Rule, orgId)
+			return false
+		}
+	}
+	return true
+}
+
 func (acs *accessControlService) lookUpMemberInCache(memberInfo string) (*memberCached, bool) {
 	ret, ok := acs.memberCache.Get(memberInfo)
 	if ok {
@@ -827,6 +851,9 @@ func (acs *accessControlService) verifyPrincipalPolicy(principal, refinedPrincip
 	case protocol.RuleAll:
 		return acs.verifyPrincipalPolicyRuleAllCase(p, endorsements)
 	default:
+		if p.expr != nil {
+			return acs.verifyPrincipalPolicyRuleExpressionCase(p, endorsements)
+		}
 		return acs.verifyPrincipalPolicyRuleDefaultCase(p, endorsements)
 	}
 }
@@ -978,6 +1005,27 @@ func (acs *accessControlService) verifyPrincipalPolicyRuleDefaultCase(p *policy,
 	}
 }
 
+func (acs *accessControlService) verifyPrincipalPolicyRuleExpressionCase(p *policy,
+	endorsements []*common.EndorsementEntry) (bool, error) {
+	_, roleList := buildOrgListRoleListOfPolicyForVerifyPrincipal(p)
+	signers := make([]*policySigner, 0, len(endorsements))
+	for _, endorsement := range endorsements {
+		signer := &policySigner{orgId: endorsement.Signer.OrgId}
+		if member := acs.getMemberFromCache(endorsement.Signer); member != nil {
+			signer.role = member.GetRole()
+		} else {
+			acs.log.Debugf("authentication warning: the member is not in member cache, memberInfo[%s]",
+				string(endorsement.Signer.MemberInfo))
+		}
+		signers = append(signers, signer)
+	}
+	if p.expr.satisfied(signers, roleList) {
+		return true, nil
+	}
+	return false, fmt.Errorf("%s: policy expression [%s] is not satisfied", notEnoughParticipantsSupportError,
+		p.GetRule())
+}
+
 func (acs *accessControlService) countValidEndorsements(orgList map[string]bool, roleList map[protocol.Role]bool,
 	endorsements []*common.EndorsementEntry) int {
 	refinedEndorsements := acs.getValidEndorsements(orgList, roleList, endorsements)
@@ -13,6 +13,7 @@ import (
 	logger2 "chainmaker.org/chainmaker/logger/v2"
 	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
 	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/config"
 	"chainmaker.org/chainmaker/protocol/v2"
 	"github.com/stretchr/testify/require"
 )
@@ -250,6 +251,51 @@ func TestVerifyAllPrincipal(t *testing.T) {
 
 }
 
+func TestVerifyExpressionPrincipal(t *testing.T) {
+	orgMemberMap := testInitFunc(t)
+	resourcePolicy := &config.ResourcePolicy{
+		ResourceName: "TEST_CONTRACT-EXPRESSION",
+		Policy:       &pbac.Policy{Rule: "AND(OUTOF(3, 2*org1, org2, org3), org4:ADMIN)"},
+	}
+	require.Equal(t, true, test1CertACProvider.ValidateResourcePolicy(resourcePolicy))
+	test1CertACProvider.(*certACProvider).acService.resourceNamePolicyMap.Store(resourcePolicy.ResourceName,
+		newPolicyFromPb(resourcePolicy.Policy))
+
+	endorsements := map[string]*common.EndorsementEntry{}
+	for _, orgId := range []string{testOrg1, testOrg2, testOrg3} {
+		endorsement, err := testCreateEndorsementEntry(orgMemberMap[orgId], protocol.RoleClient, testHashType, testMsg)
+		require.Nil(t, err)
+		endorsements[orgId] = endorsement
+	}
+	org4Admin, err := testCreateEndorsementEntry(orgMemberMap[testOrg4], protocol.RoleAdmin, testHashType, testMsg)
+	require.Nil(t, err)
+	org4Client, err := testCreateEndorsementEntry(orgMemberMap[testOrg4], protocol.RoleClient, testHashType, testMsg)
+	require.Nil(t, err)
+
+	ok, err := testVerifyPrincipal(test1CertACProvider, resourcePolicy.ResourceName,
+		[]*common.EndorsementEntry{endorsements[testOrg1], endorsements[testOrg3], org4Admin})
+	require.Nil(t, err)
+	require.Equal(t, true, ok)
+
+	//the weight of org2 and org3 is not enough
+	ok, err = testVerifyPrincipal(test1CertACProvider, resourcePolicy.ResourceName,
+		[]*common.EndorsementEntry{endorsements[testOrg2], endorsements[testOrg3], org4Admin})
+	require.NotNil(t, err)
+	require.Equal(t, false, ok)
+
+	//org4 requires an admin
+	ok, err = testVerifyPrincipal(test1CertACProvider, resourcePolicy.ResourceName,
+		[]*common.EndorsementEntry{endorsements[testOrg1], endorsements[testOrg2], org4Client})
+	require.NotNil(t, err)
+	require.Equal(t, false, ok)
+
+	//unknown organization
+	resourcePolicy.Policy.Rule = "OUTOF(1, org1, org5)"
+	require.Equal(t, false, test1CertACProvider.ValidateResourcePolicy(resourcePolicy))
+	resourcePolicy.Policy = &pbac.Policy{Rule: "OR(org1, org2)", OrgList: []string{testOrg1}}
+	require.Equal(t, false, test1CertACProvider.ValidateResourcePolicy(resourcePolicy))
+}
+
 func TestVerifyTrustMemberPrincipal(t *testing.T) {
 	orgMemberMap := testInitFunc(t)
 	//read
@@ -18,6 +18,8 @@ type policy struct {
 	rule     protocol.Rule
 	orgList  []string
 	roleList []protocol.Role
+
+	expr *policyExpr // parsed from the rule if it is a policy expression
 }
 
 func (p *policy) GetRule() protocol.Rule {
@@ -66,5 +68,13 @@ func newPolicyFromPb(input *pbac.Policy) *policy {
 		p.roleList = append(p.roleList, protocol.Role(role))
 	}
 
+	if isPolicyExpression(input.Rule) {
+		var err error
+		// the rule is checked by ValidateResourcePolicy, a bad one which slips through forbids the resource
+		if p.expr, err = parsePolicyExpression(input.Rule); err != nil {
+			return newPolicy(protocol.RuleForbidden, nil, nil)
+		}
+	}
+
 	return p
 }
@@ -0,0 +1,317 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package accesscontrol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"chainmaker.org/chainmaker/protocol/v2"
+)
+
+// Keywords of the policy expressions. A rule containing "(" is a policy expression, such as
+//
+//	OUTOF(3, 2*org1, org2, org3)                          org1 counts double, 3 of the weights are required
+//	AND(OUTOF(2, org1, org2, org3), org4:ADMIN)           2 of org1, org2 and org3, and an admin of org4
+//	OR(AND(org1:ADMIN, org2:ADMIN), *:CONSENSUS)
+//
+// A principal is an organization, optionally followed by ":" and the role required, "*" means any organization.
+// The roles of the policy are required if the role of a principal is absent, and any role if the role list is empty.
+// A principal is met by a valid endorsement signed by a member of the organization with the role, and an
+// endorsement meets one principal at most.
+// The weights, "<weight>*" before a principal or an expression, are allowed only in OUTOF, the default is 1.
+const (
+	PolicyExprOutOf = "OUTOF"
+	PolicyExprAnd   = "AND"
+	PolicyExprOr    = "OR"
+
+	policyExprWildcardOrg = "*"
+	policyExprMaxDepth    = 8
+)
+
+var policyExprRoles = map[protocol.Role]bool{
+	protocol.RoleAdmin:         true,
+	protocol.RoleClient:        true,
+	protocol.RoleLight:         true,
+	protocol.RoleConsensusNode: true,
+	protocol.RoleCommonNode:    true,
+}
+
+// policyExpr a node of a policy expression, which is an operator or a principal
+type policyExpr struct {
+	op        string // OUTOF, AND or OR, empty for a principal
+	threshold int    // the sum of the weights of the children met required by OUTOF
+	children  []*policyExpr
+	weight    int
+
+	orgId string
+	role  protocol.Role // empty means the roles of the policy
+}
+
+// policySigner the organization and role of a valid endorsement
+type policySigner struct {
+	orgId string
+	role  protocol.Role
+}
+
+func isPolicyExpression(rule string) bool {
+	return strings.Contains(rule, "(")
+}
+
+// parsePolicyExpression parses and checks the rule, the blank characters are ignored
+func parsePolicyExpression(rule string) (*policyExpr, error) {
+	parser := &policyExprParser{input: strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, rule)}
+	expr, err := parser.parseTerm(0, false)
+	if err != nil {
+		return nil, fmt.Errorf("bad policy expression [%s]: %s", rule, err.Error())
+	}
+	if parser.pos != len(parser.input) {
+		return nil, fmt.Errorf("bad policy expression [%s]: unexpected [%s]", rule, parser.input[parser.pos:])
+	}
+	if expr.op == "" {
+		return nil, fmt.Errorf("bad policy expression [%s]: should be OUTOF, AND or OR", rule)
+	}
+	return expr, nil
+}
+
+type policyExprParser struct {
+	input string
+	pos   int
+}
+
+// parseTerm parses a principal or an expression with an optional weight
+func (p *policyExprParser) parseTerm(depth int, weighted bool) (*policyExpr, error) {
+	if depth > policyExprMaxDepth {
+		return nil, fmt.Errorf("nested deeper than %d", policyExprMaxDepth)
+	}
+	word := p.nextWord()
+	weight := 1
+	if i := strings.Index(word, "*"); i > 0 {
+		w, err := strconv.Atoi(word[:i])
+		if err == nil {
+			if w <= 0 {
+				return nil, fmt.Errorf("weight of [%s] should be positive", word)
+			}
+			if !weighted {
+				return nil, fmt.Errorf("weight of [%s] is allowed only in %s", word, PolicyExprOutOf)
+			}
+			weight, word = w, word[i+1:]
+		}
+	}
+	if p.peek() != '(' {
+		expr, err := parsePolicyPrincipal(word)
+		if err != nil {
+			return nil, err
+		}
+		expr.weight = weight
+		return expr, nil
+	}
+
+	expr := &policyExpr{op: word, weight: weight}
+	switch word {
+	case PolicyExprAnd, PolicyExprOr:
+	case PolicyExprOutOf:
+		p.pos++
+		threshold, err := strconv.Atoi(p.nextWord())
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("threshold of %s should be a positive integer", PolicyExprOutOf)
+		}
+		expr.threshold = threshold
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("%s requires principals", PolicyExprOutOf)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator [%s]", word)
+	}
+	p.pos++
+
+	principals := map[string]bool{}
+	totalWeight := 0
+	for {
+		child, err := p.parseTerm(depth+1, expr.op == PolicyExprOutOf)
+		if err != nil {
+			return nil, err
+		}
+		if child.op == "" {
+			key := child.orgId + ":" + string(child.role)
+			if principals[key] {
+				return nil, fmt.Errorf("duplicated principal [%s] in %s", key, expr.op)
+			}
+			principals[key] = true
+		}
+		expr.children = append(expr.children, child)
+		totalWeight += child.weight
+
+		c := p.peek()
+		p.pos++
+		if c == ')' {
+			break
+		}
+		if c != ',' {
+			return nil, fmt.Errorf("%s is not closed", expr.op)
+		}
+	}
+	if expr.threshold > totalWeight {
+		return nil, fmt.Errorf("threshold %d of %s is greater than the total weight %d", expr.threshold,
+			PolicyExprOutOf, totalWeight)
+	}
+	return expr, nil
+}
+
+// nextWord returns the characters before the next delimiter
+func (p *policyExprParser) nextWord() string {
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("(),", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *policyExprParser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func parsePolicyPrincipal(word string) (*policyExpr, error) {
+	expr := &policyExpr{orgId: word}
+	if i := strings.LastIndex(word, ":"); i >= 0 {
+		expr.orgId, expr.role = word[:i], protocol.Role(strings.ToUpper(word[i+1:]))
+		if !policyExprRoles[expr.role] {
+			return nil, fmt.Errorf("unknown role [%s] of principal [%s]", word[i+1:], word)
+		}
+	}
+	if expr.orgId == "" {
+		return nil, fmt.Errorf("empty organization in principal [%s]", word)
+	}
+	return expr, nil
+}
+
+// orgIds returns the organizations in the expression except the wildcard
+func (e *policyExpr) orgIds() []string {
+	if e.op == "" {
+		if e.orgId == policyExprWildcardOrg {
+			return nil
+		}
+		return []string{e.orgId}
+	}
+	var orgIds []string
+	for _, child := range e.children {
+		orgIds = append(orgIds, child.orgIds()...)
+	}
+	return orgIds
+}
+
+// satisfied returns whether the signers meet the expression, roles are those of the policy. An endorsement
+// meets one principal at most, so the principals are assigned to distinct signers by backtracking.
+func (e *policyExpr) satisfied(signers []*policySigner, roles map[protocol.Role]bool) bool {
+	// the signers of the same organization and role are interchangeable, they are counted together
+	var groups []*policySignerGroup
+	indexes := make(map[policySigner]int)
+	for _, signer := range signers {
+		i, ok := indexes[*signer]
+		if !ok {
+			i = len(groups)
+			indexes[*signer] = i
+			groups = append(groups, &policySignerGroup{signer: signer})
+		}
+		groups[i].count++
+	}
+	return e.assign(groups, roles, func() bool {
+		return true
+	})
+}
+
+// policySignerGroup the signers of the same organization and role, count is the number of the unassigned ones
+type policySignerGroup struct {
+	signer *policySigner
+	count  int
+}
+
+// assign assigns the unassigned signers to the principals of the expression, and then calls next for the rest
+// of the expressions. It returns true if next does, the assignment is undone otherwise.
+func (e *policyExpr) assign(groups []*policySignerGroup, roles map[protocol.Role]bool, next func() bool) bool {
+	switch e.op {
+	case "":
+		for _, group := range groups {
+			if group.count == 0 || !e.matches(group.signer, roles) {
+				continue
+			}
+			group.count--
+			ok := next()
+			group.count++
+			if ok {
+				return true
+			}
+		}
+		return false
+	case PolicyExprAnd:
+		return e.assignChildren(0, groups, roles, next)
+	case PolicyExprOr:
+		for _, child := range e.children {
+			if child.assign(groups, roles, next) {
+				return true
+			}
+		}
+		return false
+	default:
+		remaining := 0
+		for _, child := range e.children {
+			remaining += child.weight
+		}
+		return e.assignOutOf(0, 0, remaining, groups, roles, next)
+	}
+}
+
+// assignChildren assigns the signers to all the children from i
+func (e *policyExpr) assignChildren(i int, groups []*policySignerGroup, roles map[protocol.Role]bool,
+	next func() bool) bool {
+	if i == len(e.children) {
+		return next()
+	}
+	return e.children[i].assign(groups, roles, func() bool {
+		return e.assignChildren(i+1, groups, roles, next)
+	})
+}
+
+// assignOutOf assigns the signers to the children from i until the weight of the children met reaches
+// the threshold, remaining is the total weight of the children from i
+func (e *policyExpr) assignOutOf(i, weight, remaining int, groups []*policySignerGroup,
+	roles map[protocol.Role]bool, next func() bool) bool {
+	if weight >= e.threshold {
+		return next()
+	}
+	if i == len(e.children) || weight+remaining < e.threshold {
+		return false
+	}
+	child := e.children[i]
+	if child.assign(groups, roles, func() bool {
+		return e.assignOutOf(i+1, weight+child.weight, remaining-child.weight, groups, roles, next)
+	}) {
+		return true
+	}
+	return e.assignOutOf(i+1, weight, remaining-child.weight, groups, roles, next)
+}
+
+// matches returns whether the signer meets the principal
+func (e *policyExpr) matches(signer *policySigner, roles map[protocol.Role]bool) bool {
+	if e.orgId != policyExprWildcardOrg && e.orgId != signer.orgId {
+		return false
+	}
+	if e.role != "" {
+		return signer.role == e.role
+	}
+	return len(roles) == 0 || roles[signer.role]
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package accesscontrol
+
+import (
+	"testing"
+
+	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicyExpression(t *testing.T) {
+	validRules := []string{
+		"OUTOF(3, 2*org1, org2, org3)",
+		"AND(OUTOF(2, org1, org2), OUTOF(1, org3:admin))",
+		"OR(AND(wx-org1.chainmaker.org:ADMIN, wx-org2.chainmaker.org:ADMIN), *:CONSENSUS)",
+		"OUTOF(2, 2*AND(org1, org2), org3)",
+		"OUTOF(1, org1:ADMIN, org1:CLIENT)",
+	}
+	for _, rule := range validRules {
+		_, err := parsePolicyExpression(rule)
+		require.Nil(t, err, rule)
+	}
+
+	invalidRules := []string{
+		"org1",
+		"AND(org1, org2",
+		"AND(org1, org2))",
+		"AND()",
+		"AND(2*org1, org2)",
+		"2*OUTOF(1, org1)",
+		"OUTOF(0, org1)",
+		"OUTOF(3, org1, org2)",
+		"OUTOF(2)",
+		"OUTOF(1, 0*org1)",
+		"OUTOF(1, org1, org1)",
+		"NOT(org1)",
+		"OR(org1:OWNER)",
+		"OR(:ADMIN)",
+		"OR(OR(OR(OR(OR(OR(OR(OR(OR(OR(org1))))))))))",
+	}
+	for _, rule := range invalidRules {
+		_, err := parsePolicyExpression(rule)
+		require.NotNil(t, err, rule)
+	}
+
+	expr, err := parsePolicyExpression("AND(OUTOF(2, org1, org2), OR(org3:ADMIN, *:CONSENSUS))")
+	require.Nil(t, err)
+	require.Equal(t, []string{"org1", "org2", "org3"}, expr.orgIds())
+}
+
+func TestPolicyExpressionSatisfied(t *testing.T) {
+	signer := func(orgId string, role protocol.Role) *policySigner {
+		return &policySigner{orgId: orgId, role: role}
+	}
+	tests := []struct {
+		rule     string
+		roles    map[protocol.Role]bool
+		signers  []*policySigner
+		expected bool
+	}{
+		{"OUTOF(3, 2*org1, org2, org3)", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org2", protocol.RoleClient)}, true},
+		{"OUTOF(3, 2*org1, org2, org3)", nil,
+			[]*policySigner{signer("org2", protocol.RoleAdmin), signer("org3", protocol.RoleClient)}, false},
+		// a member is counted once for its organization
+		{"OUTOF(2, org1, org2)", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org1", protocol.RoleClient)}, false},
+		{"AND(OUTOF(2, org1, org2, org3), OUTOF(1, org4))", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org3", protocol.RoleAdmin),
+				signer("org4", protocol.RoleAdmin)}, true},
+		{"AND(OUTOF(2, org1, org2, org3), OUTOF(1, org4))", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org3", protocol.RoleAdmin)}, false},
+		{"OR(org1:ADMIN, *:CONSENSUS)", nil,
+			[]*policySigner{signer("org1", protocol.RoleClient), signer("org5", protocol.RoleConsensusNode)}, true},
+		{"OR(org1:ADMIN, *:CONSENSUS)", nil,
+			[]*policySigner{signer("org1", protocol.RoleClient)}, false},
+		// the roles of the policy apply to the principals without role
+		{"OUTOF(1, org1, org2)", map[protocol.Role]bool{protocol.RoleAdmin: true},
+			[]*policySigner{signer("org1", protocol.RoleClient)}, false},
+		{"OUTOF(1, org1, org2:CLIENT)", map[protocol.Role]bool{protocol.RoleAdmin: true},
+			[]*policySigner{signer("org2", protocol.RoleClient)}, true},
+		// an endorsement meets one principal at most
+		{"AND(org1:ADMIN, *:ADMIN)", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin)}, false},
+		{"AND(org1:ADMIN, *:ADMIN)", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org1", protocol.RoleAdmin)}, true},
+		{"OUTOF(2, org1, *)", nil,
+			[]*policySigner{signer("org1", protocol.RoleClient)}, false},
+		{"OR(AND(org1, org2), AND(org1:ADMIN, *))", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org3", protocol.RoleClient)}, true},
+		// the signers are assigned again if the first choice fails the later principals
+		{"AND(*:ADMIN, org1:ADMIN)", nil,
+			[]*policySigner{signer("org1", protocol.RoleAdmin), signer("org2", protocol.RoleAdmin)}, true},
+		{"AND(OUTOF(1, org1, org2), org1)", nil,
+			[]*policySigner{signer("org1", protocol.RoleClient), signer("org2", protocol.RoleClient)}, true},
+		{"AND(OUTOF(2, 2*org1, org2, org3), org1)", nil,
+			[]*policySigner{signer("org1", protocol.RoleClient), signer("org2", protocol.RoleClient),
+				signer("org3", protocol.RoleClient)}, true},
+		{"AND(OUTOF(2, org1, org2), OUTOF(2, org1, org2))", nil,
+			[]*policySigner{signer("org1", protocol.RoleClient), signer("org2", protocol.RoleClient)}, false},
+	}
+	for _, test := range tests {
+		expr, err := parsePolicyExpression(test.rule)
+		require.Nil(t, err, test.rule)
+		require.Equal(t, test.expected, expr.satisfied(test.signers, test.roles), test.rule)
+	}
+}
+
+func TestNewPolicyFromPbBadExpression(t *testing.T) {
+	p := newPolicyFromPb(&pbac.Policy{Rule: "OUTOF(2, org1)"})
+	require.Equal(t, protocol.RuleForbidden, p.GetRule())
+	require.Nil(t, p.expr)
+
+	p = newPolicyFromPb(&pbac.Policy{Rule: "OUTOF(1, org1)"})
+	require.NotNil(t, p.expr)
+}
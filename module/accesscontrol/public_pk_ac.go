@@ -126,7 +126,7 @@ func newPkACProvider(chainConfig *config.ChainConfig,
 		exceptionalPolicyMap:  &sync.Map{},
 	}
 
-	pkAcProvider.createDefaultResourcePolicy()
+	pkAcProvider.initResourcePolicy(chainConfig.ResourcePolicies)
 
 	err := pkAcProvider.initAdminMembers(chainConfig.TrustRoots)
 	if err != nil {
@@ -234,6 +234,7 @@ func (p *pkACProvider) Watch(chainConfig *config.ChainConfig) error {
 	if err != nil {
 		return fmt.Errorf("new public AC provider failed: %s", err.Error())
 	}
+	p.initResourcePolicy(chainConfig.ResourcePolicies)
 	p.memberCache.Clear()
 	return nil
 }
@@ -254,6 +255,26 @@ func (p *pkACProvider) NewMember(pbMember *pbac.Member) (protocol.Member, error)
 	return member, nil
 }
 
+// initResourcePolicy applies the policy expressions of the chain config over the default policies, it runs
+// again on each chain config update. The other rules are not supported in public mode, they are rejected by
+// ValidateResourcePolicy on the chain config update, and ignored with a warning if they are in the genesis.
+func (p *pkACProvider) initResourcePolicy(resourcePolicies []*config.ResourcePolicy) {
+	p.createDefaultResourcePolicy()
+	for _, resourcePolicy := range resourcePolicies {
+		if resourcePolicy.Policy == nil {
+			continue
+		}
+		if !isPolicyExpression(resourcePolicy.Policy.Rule) {
+			p.log.Warnf("the policy [%s] of the resource %s is ignored, only the policy expressions are "+
+				"supported in public mode", resourcePolicy.Policy.Rule, resourcePolicy.ResourceName)
+			continue
+		}
+		if p.ValidateResourcePolicy(resourcePolicy) {
+			p.resourceNamePolicyMap.Store(resourcePolicy.ResourceName, newPolicyFromPb(resourcePolicy.Policy))
+		}
+	}
+}
+
 func (p *pkACProvider) createDefaultResourcePolicy() {
 
 	p.resourceNamePolicyMap.Store(protocol.ResourceNameConsensusNode, pubPolicyConsensus)
@@ -383,6 +404,9 @@ func (p *pkACProvider) verifyPrincipalPolicy(principal,
 	case protocol.RuleMajority:
 		return p.verifyRuleMajorityCase(pol, endorsements)
 	default:
+		if pol.expr != nil {
+			return p.verifyRuleExpressionCase(pol, endorsements)
+		}
 		return false, fmt.Errorf("public authentication fail: [%s] is not supported", rule)
 	}
 }
@@ -424,6 +448,23 @@ func (p *pkACProvider) verifyRuleMajorityCase(pol *policy, endorsements []*commo
 		notEnoughParticipantsSupportError, int(float64(p.adminNum)/2.0+1), numOfValid)
 }
 
+func (p *pkACProvider) verifyRuleExpressionCase(pol *policy, endorsements []*common.EndorsementEntry) (bool, error) {
+	roleList := p.buildRoleListForVerifyPrincipal(pol)
+	signers := make([]*policySigner, 0, len(endorsements))
+	for _, endorsement := range endorsements {
+		signer := &policySigner{orgId: endorsement.Signer.OrgId}
+		if member := p.getMemberFromCache(endorsement.Signer); member != nil {
+			signer.role = member.GetRole()
+		}
+		signers = append(signers, signer)
+	}
+	if pol.expr.satisfied(signers, roleList) {
+		return true, nil
+	}
+	return false, fmt.Errorf("%s: policy expression [%s] is not satisfied", notEnoughParticipantsSupportError,
+		pol.rule)
+}
+
 func (p *pkACProvider) buildRoleListForVerifyPrincipal(pol *policy) map[protocol.Role]bool {
 	roleListRaw := pol.GetRoleList()
 	roleList := map[protocol.Role]bool{}
@@ -534,7 +575,32 @@ func (p *pkACProvider) GetHashAlg() string {
 }
 
 // ValidateResourcePolicy checks whether the given resource principal is valid
+// The members have no organization in public mode, so only the wildcard organization is allowed in the policy
+// expressions.
 func (p *pkACProvider) ValidateResourcePolicy(resourcePolicy *config.ResourcePolicy) bool {
+	if resourcePolicy.Policy == nil {
+		return true
+	}
+	if !isPolicyExpression(resourcePolicy.Policy.Rule) {
+		p.log.Errorf("bad configuration: the policy [%s] of the resource %s is not supported in public mode, "+
+			"use a policy expression", resourcePolicy.Policy.Rule, resourcePolicy.ResourceName)
+		return false
+	}
+	if _, ok := restrainedResourceList[resourcePolicy.ResourceName]; ok {
+		p.log.Errorf("bad configuration: should not modify the access policy of the resource: %s",
+			resourcePolicy.ResourceName)
+		return false
+	}
+	expr, err := parsePolicyExpression(resourcePolicy.Policy.Rule)
+	if err != nil {
+		p.log.Errorf("bad configuration: %s", err.Error())
+		return false
+	}
+	if orgIds := expr.orgIds(); len(orgIds) > 0 || len(resourcePolicy.Policy.OrgList) > 0 {
+		p.log.Errorf("bad configuration: policy expression [%s] should use the wildcard organization [%s] "+
+			"in public mode", resourcePolicy.Policy.Rule, policyExprWildcardOrg)
+		return false
+	}
 	return true
 }
 
@@ -12,6 +12,7 @@ import (
 
 	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
 	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/config"
 	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
 	"chainmaker.org/chainmaker/protocol/v2"
 	"github.com/stretchr/testify/require"
@@ -167,3 +168,15 @@ func testPublicPKGetValidEndorsements(provider protocol.AccessControlProvider,
 	}
 	return provider.(*pkACProvider).GetValidEndorsements(principal)
 }
+
+func TestPublicPKValidateResourcePolicy(t *testing.T) {
+	testInitPublicPKFunc(t)
+	resourcePolicy := func(rule string) *config.ResourcePolicy {
+		return &config.ResourcePolicy{ResourceName: "TEST_RESOURCE", Policy: &pbac.Policy{Rule: rule}}
+	}
+	require.True(t, test1PublicPKACProvider.ValidateResourcePolicy(resourcePolicy("OUTOF(2, *:ADMIN, *:CONSENSUS)")))
+	// the rules other than the policy expressions are not supported in public mode
+	require.False(t, test1PublicPKACProvider.ValidateResourcePolicy(resourcePolicy(string(protocol.RuleMajority))))
+	require.False(t, test1PublicPKACProvider.ValidateResourcePolicy(resourcePolicy("OUTOF(1, org1)")))
+	require.False(t, test1PublicPKACProvider.ValidateResourcePolicy(resourcePolicy("OUTOF(2, *)")))
+}
@@ -9,6 +9,9 @@ SPDX-License-Identifier: Apache-2.0
 package blockchain
 
 import (
+	"fmt"
+	"sync"
+
 	"chainmaker.org/chainmaker-go/subscriber"
 	"chainmaker.org/chainmaker/common/v2/msgbus"
 	"chainmaker.org/chainmaker/logger/v2"
@@ -87,6 +90,9 @@ type Blockchain struct {
 
 	eventSubscriber *subscriber.EventSubscriber
 
+	// initModules and startModules are guarded by moduleLock, they are read by the health probes
+	// without the lifecycle lock of the server
+	moduleLock   sync.RWMutex
 	initModules  map[string]struct{}
 	startModules map[string]struct{}
 }
@@ -115,3 +121,32 @@ func (bc *Blockchain) getConsensusType() consensus.ConsensusType {
 func (bc *Blockchain) GetAccessControl() protocol.AccessControlProvider {
 	return bc.ac
 }
+
+// evidenceProvider is implemented by the consensus engines which collect misbehavior evidences.
+type evidenceProvider interface {
+	GetEvidences() ([]byte, error)
+}
+
+// GetConsensusEvidences get the json encoded misbehavior evidences collected by the consensus engine.
+func (bc *Blockchain) GetConsensusEvidences() ([]byte, error) {
+	provider, ok := bc.consensus.(evidenceProvider)
+	if !ok {
+		return nil, fmt.Errorf("consensus %s does not collect evidences", bc.getConsensusType())
+	}
+	return provider.GetEvidences()
+}
+
+// leadershipTransferer is implemented by the consensus engines which support transferring the leadership.
+type leadershipTransferer interface {
+	TransferLeadership(nodeId string) (string, error)
+}
+
+// TransferConsensusLeadership transfers the leadership of the consensus to nodeId and returns the new leader,
+// if nodeId is empty, the consensus engine chooses the transferee.
+func (bc *Blockchain) TransferConsensusLeadership(nodeId string) (string, error) {
+	transferer, ok := bc.consensus.(leadershipTransferer)
+	if !ok {
+		return "", fmt.Errorf("consensus %s does not support transferring leadership", bc.getConsensusType())
+	}
+	return transferer.TransferLeadership(nodeId)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blockchain
+
+import (
+	"sort"
+
+	"chainmaker.org/chainmaker/localconf/v2"
+)
+
+const (
+	// ConsensusRoleValidator the node takes part in the consensus of the chain
+	ConsensusRoleValidator = "VALIDATOR"
+	// ConsensusRoleSync the node only synchronizes the blocks of the chain
+	ConsensusRoleSync = "SYNC"
+)
+
+// the modules which have to be started to serve the chain
+var startableModules = []string{
+	moduleNameNetService,
+	moduleNameVM,
+	moduleNameTxPool,
+	moduleNameCore,
+	moduleNameConsensus,
+	moduleNameSync,
+}
+
+// peerHeightProvider is implemented by the sync service which knows the heights of the peers
+type peerHeightProvider interface {
+	GetMaxPeerHeight() (height uint64, known bool)
+}
+
+// ChainHealth is the health state of a chain in the node
+type ChainHealth struct {
+	ChainId       string          `json:"chain_id"`
+	Status        string          `json:"status"`
+	Modules       map[string]bool `json:"modules"` // Whether each initialized module is started
+	Height        uint64          `json:"height"`
+	PeerHeight    uint64          `json:"peer_height"` // The highest height of the peers, 0 if unknown
+	SyncLag       uint64          `json:"sync_lag"`
+	ConsensusRole string          `json:"consensus_role"`
+	PeerCount     int             `json:"peer_count"`
+
+	status          ChainStatus
+	peerHeightKnown bool // Whether the sync service has received the status of a peer
+}
+
+// IsHealthy the chain is not failed to start
+func (h *ChainHealth) IsHealthy() bool {
+	return h.status != ChainStatusFailed
+}
+
+// IsReady the chain is running with all the modules started, and it has caught up with the peers.
+// The chain connected to peers is not ready until the height of the peers is known.
+func (h *ChainHealth) IsReady(maxSyncLag uint64) bool {
+	if h.status != ChainStatusRunning || h.SyncLag > maxSyncLag {
+		return false
+	}
+	if h.PeerCount > 0 && !h.peerHeightKnown {
+		return false
+	}
+	for _, started := range h.Modules {
+		if !started {
+			return false
+		}
+	}
+	return true
+}
+
+// health collects the health state of the chain, the modules are reported only if the chain is started
+func (bc *Blockchain) health(status ChainStatus) *ChainHealth {
+	h := &ChainHealth{
+		ChainId:       bc.chainId,
+		Status:        status.String(),
+		Modules:       make(map[string]bool),
+		ConsensusRole: ConsensusRoleSync,
+		status:        status,
+	}
+	if status == ChainStatusInitialized {
+		return h
+	}
+
+	for _, moduleName := range startableModules {
+		if bc.isModuleInit(moduleName) {
+			h.Modules[moduleName] = bc.isModuleStartUp(moduleName)
+		}
+	}
+	if bc.isModuleInit(moduleNameConsensus) {
+		h.ConsensusRole = ConsensusRoleValidator
+	}
+	if bc.ledgerCache != nil {
+		if height, err := bc.ledgerCache.CurrentHeight(); err == nil {
+			h.Height = height
+		}
+	}
+	if provider, ok := bc.syncServer.(peerHeightProvider); ok {
+		h.PeerHeight, h.peerHeightKnown = provider.GetMaxPeerHeight()
+		if h.PeerHeight > h.Height {
+			h.SyncLag = h.PeerHeight - h.Height
+		}
+	}
+	if bc.netService != nil {
+		if nodes, err := bc.netService.GetChainNodesInfoProvider().GetChainNodesInfo(); err == nil {
+			for _, node := range nodes {
+				if node.NodeUid != localconf.ChainMakerConfig.NodeConfig.NodeId {
+					h.PeerCount++
+				}
+			}
+		}
+	}
+	return h
+}
+
+// GetAllChainHealth get the health state of all the chains, sorted by chain id. It does not wait for
+// the lifecycle operations, so the probes are not blocked by a slow Resume.
+func (server *ChainMakerServer) GetAllChainHealth() []*ChainHealth {
+	healths := make([]*ChainHealth, 0)
+	server.blockchains.Range(func(key, value interface{}) bool {
+		chainId, _ := key.(string)
+		chain, _ := value.(*Blockchain)
+		status, err := server.GetBlockchainStatus(chainId)
+		if err != nil {
+			return true
+		}
+		healths = append(healths, chain.health(status))
+		return true
+	})
+	sort.Slice(healths, func(i, j int) bool {
+		return healths[i].ChainId < healths[j].ChainId
+	})
+	return healths
+}
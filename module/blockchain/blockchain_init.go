@@ -25,6 +25,7 @@ import (
 	"chainmaker.org/chainmaker-go/net"
 	"chainmaker.org/chainmaker-go/snapshot"
 	"chainmaker.org/chainmaker-go/subscriber"
+	"chainmaker.org/chainmaker-go/subscriber/sink"
 	blockSync "chainmaker.org/chainmaker-go/sync"
 	"chainmaker.org/chainmaker-go/txpool"
 	"chainmaker.org/chainmaker/chainconf/v2"
@@ -135,8 +136,7 @@ func (bc *Blockchain) initExtModules(extModules []map[string]func() error) (err
 }
 
 func (bc *Blockchain) initNetService() (err error) {
-	_, ok := bc.initModules[moduleNameNetService]
-	if ok {
+	if bc.isModuleInit(moduleNameNetService) {
 		bc.log.Infof("net service module existed, ignore.")
 		return
 	}
@@ -146,13 +146,12 @@ func (bc *Blockchain) initNetService() (err error) {
 		bc.log.Errorf("new net service failed, %s", err)
 		return
 	}
-	bc.initModules[moduleNameNetService] = struct{}{}
+	bc.setModuleInit(moduleNameNetService)
 	return
 }
 
 func (bc *Blockchain) initStore() (err error) {
-	_, ok := bc.initModules[moduleNameStore]
-	if ok {
+	if bc.isModuleInit(moduleNameStore) {
 		bc.log.Infof("store module existed, ignore.")
 		return
 	}
@@ -186,13 +185,17 @@ func (bc *Blockchain) initStore() (err error) {
 		bc.log.Errorf("new store failed, %s", err.Error())
 		return err
 	}
-	bc.initModules[moduleNameStore] = struct{}{}
+	// the sinks read the blocks they missed from the store, and stop with the subscriber
+	if _, err = sink.StartSinks(bc.chainId, bc.eventSubscriber, bc.store); err != nil {
+		bc.log.Errorf("start event sinks failed, %s", err.Error())
+		return err
+	}
+	bc.setModuleInit(moduleNameStore)
 	return
 }
 
 func (bc *Blockchain) initChainConf() (err error) {
-	_, ok := bc.initModules[moduleNameChainConf]
-	if ok {
+	if bc.isModuleInit(moduleNameChainConf) {
 		bc.log.Infof("chain config module existed, ignore.")
 		return
 	}
@@ -239,7 +242,7 @@ func (bc *Blockchain) initChainConf() (err error) {
 		bc.log.Errorf("load node list of chain config failed, %s", err)
 		return err
 	}
-	bc.initModules[moduleNameChainConf] = struct{}{}
+	bc.setModuleInit(moduleNameChainConf)
 
 	// register myself as config watcher
 	bc.chainConf.AddWatch(bc)
@@ -250,8 +253,7 @@ func (bc *Blockchain) initChainConf() (err error) {
 }
 
 func (bc *Blockchain) initCache() (err error) {
-	_, ok := bc.initModules[moduleNameLedger]
-	if ok {
+	if bc.isModuleInit(moduleNameLedger) {
 		bc.log.Infof("ledger module existed, ignore.")
 		return
 	}
@@ -322,13 +324,12 @@ func (bc *Blockchain) initCache() (err error) {
 	bc.ledgerCache.SetLastCommittedBlock(bc.lastBlock)
 	bc.proposalCache = cache.NewProposalCache(bc.chainConf, bc.ledgerCache)
 	bc.log.Debugf("go last block: %+v", bc.lastBlock)
-	bc.initModules[moduleNameLedger] = struct{}{}
+	bc.setModuleInit(moduleNameLedger)
 	return nil
 }
 
 func (bc *Blockchain) initAC() (err error) {
-	_, ok := bc.initModules[moduleNameAccessControl]
-	if ok {
+	if bc.isModuleInit(moduleNameAccessControl) {
 		bc.log.Infof("access control module existed, ignore.")
 		return
 	}
@@ -383,13 +384,12 @@ func (bc *Blockchain) initAC() (err error) {
 		return
 	}
 
-	bc.initModules[moduleNameAccessControl] = struct{}{}
+	bc.setModuleInit(moduleNameAccessControl)
 	return
 }
 
 func (bc *Blockchain) initTxPool() (err error) {
-	_, ok := bc.initModules[moduleNameTxPool]
-	if ok {
+	if bc.isModuleInit(moduleNameTxPool) {
 		bc.log.Infof("tx pool module existed, ignore.")
 		return
 	}
@@ -425,13 +425,12 @@ func (bc *Blockchain) initTxPool() (err error) {
 	}
 
 	bc.txPool = currentTxPool
-	bc.initModules[moduleNameTxPool] = struct{}{}
+	bc.setModuleInit(moduleNameTxPool)
 	return nil
 }
 
 func (bc *Blockchain) initVM() (err error) {
-	_, ok := bc.initModules[moduleNameVM]
-	if ok {
+	if bc.isModuleInit(moduleNameVM) {
 		bc.log.Infof("vm module existed, ignore.")
 		return
 	}
@@ -541,7 +540,7 @@ func (bc *Blockchain) initVM() (err error) {
 			bc.chainConf,
 		)
 	}
-	bc.initModules[moduleNameVM] = struct{}{}
+	bc.setModuleInit(moduleNameVM)
 	return
 }
 
@@ -552,8 +551,7 @@ func (s *soloChainNodesInfoProvider) GetChainNodesInfo() ([]*protocol.ChainNodeI
 }
 
 func (bc *Blockchain) initCore() (err error) {
-	_, ok := bc.initModules[moduleNameCore]
-	if ok {
+	if bc.isModuleInit(moduleNameCore) {
 		bc.log.Infof("core engine module existed, ignore.")
 		return
 	}
@@ -588,7 +586,7 @@ func (bc *Blockchain) initCore() (err error) {
 		bc.log.Errorf("new core engine failed, %s", err.Error())
 		return err
 	}
-	bc.initModules[moduleNameCore] = struct{}{}
+	bc.setModuleInit(moduleNameCore)
 	return
 }
 
@@ -609,11 +607,10 @@ func (bc *Blockchain) initConsensus() (err error) {
 	}
 	if !isConsensusNode {
 		// this node is not a consensus node
-		delete(bc.initModules, moduleNameConsensus)
+		bc.removeModuleInit(moduleNameConsensus)
 		return nil
 	}
-	_, ok := bc.initModules[moduleNameConsensus]
-	if ok {
+	if bc.isModuleInit(moduleNameConsensus) {
 		bc.log.Infof("consensus module existed, ignore.")
 		return
 	}
@@ -640,13 +637,12 @@ func (bc *Blockchain) initConsensus() (err error) {
 		bc.log.Errorf("new consensus engine failed, %s", err)
 		return err
 	}
-	bc.initModules[moduleNameConsensus] = struct{}{}
+	bc.setModuleInit(moduleNameConsensus)
 	return
 }
 
 func (bc *Blockchain) initSync() (err error) {
-	_, ok := bc.initModules[moduleNameSync]
-	if ok {
+	if bc.isModuleInit(moduleNameSync) {
 		bc.log.Infof("sync module existed, ignore.")
 		return
 	}
@@ -660,22 +656,35 @@ func (bc *Blockchain) initSync() (err error) {
 		bc.coreEngine.GetBlockVerifier(),
 		bc.coreEngine.GetBlockCommitter(),
 	)
-	bc.initModules[moduleNameSync] = struct{}{}
+	bc.setModuleInit(moduleNameSync)
 	return
 }
 
 func (bc *Blockchain) initSubscriber() error {
-	_, ok := bc.initModules[moduleNameSubscriber]
-	if ok {
+	if bc.isModuleInit(moduleNameSubscriber) {
 		bc.log.Infof("subscriber module existed, ignore.")
 		return nil
 	}
-	bc.eventSubscriber = subscriber.NewSubscriber(bc.msgBus)
-	bc.initModules[moduleNameSubscriber] = struct{}{}
+	bc.eventSubscriber = subscriber.NewSubscriber(bc.chainId, bc.msgBus)
+	bc.setModuleInit(moduleNameSubscriber)
 	return nil
 }
 
 func (bc *Blockchain) isModuleInit(moduleName string) bool {
+	bc.moduleLock.RLock()
+	defer bc.moduleLock.RUnlock()
 	_, ok := bc.initModules[moduleName]
 	return ok
 }
+
+func (bc *Blockchain) setModuleInit(moduleName string) {
+	bc.moduleLock.Lock()
+	defer bc.moduleLock.Unlock()
+	bc.initModules[moduleName] = struct{}{}
+}
+
+func (bc *Blockchain) removeModuleInit(moduleName string) {
+	bc.moduleLock.Lock()
+	defer bc.moduleLock.Unlock()
+	delete(bc.initModules, moduleName)
+}
@@ -60,7 +60,7 @@ func (bc *Blockchain) startNetService() error {
 		bc.log.Errorf("start net service failed, %s", err.Error())
 		return err
 	}
-	bc.startModules[moduleNameNetService] = struct{}{}
+	bc.setModuleStartUp(moduleNameNetService)
 	return nil
 }
 
@@ -73,14 +73,14 @@ func (bc *Blockchain) startConsensus() error {
 		bc.log.Errorf("start consensus failed, %s", err.Error())
 		return err
 	}
-	bc.startModules[moduleNameConsensus] = struct{}{}
+	bc.setModuleStartUp(moduleNameConsensus)
 	return nil
 }
 
 func (bc *Blockchain) startCoreEngine() error {
 	// start core engine
 	bc.coreEngine.Start()
-	bc.startModules[moduleNameCore] = struct{}{}
+	bc.setModuleStartUp(moduleNameCore)
 	return nil
 }
 
@@ -90,7 +90,7 @@ func (bc *Blockchain) startSyncService() error {
 		bc.log.Errorf("start sync server failed, %s", err.Error())
 		return err
 	}
-	bc.startModules[moduleNameSync] = struct{}{}
+	bc.setModuleStartUp(moduleNameSync)
 	return nil
 }
 
@@ -101,7 +101,7 @@ func (bc *Blockchain) startTxPool() error {
 		bc.log.Errorf("start tx pool failed, %s", err)
 		return err
 	}
-	bc.startModules[moduleNameTxPool] = struct{}{}
+	bc.setModuleStartUp(moduleNameTxPool)
 	return nil
 }
 
@@ -111,11 +111,45 @@ func (bc *Blockchain) startVM() error {
 		bc.log.Errorf("start vm failed, %s", err)
 		return err
 	}
-	bc.startModules[moduleNameVM] = struct{}{}
+	bc.setModuleStartUp(moduleNameVM)
 	return nil
 }
 
 func (bc *Blockchain) isModuleStartUp(moduleName string) bool {
+	bc.moduleLock.RLock()
+	defer bc.moduleLock.RUnlock()
 	_, res := bc.startModules[moduleName]
 	return res
 }
+
+func (bc *Blockchain) setModuleStartUp(moduleName string) {
+	bc.moduleLock.Lock()
+	defer bc.moduleLock.Unlock()
+	bc.startModules[moduleName] = struct{}{}
+}
+
+func (bc *Blockchain) removeModuleStartUp(moduleName string) {
+	bc.moduleLock.Lock()
+	defer bc.moduleLock.Unlock()
+	delete(bc.startModules, moduleName)
+}
+
+// startedModules the names of the modules started
+func (bc *Blockchain) startedModules() []string {
+	bc.moduleLock.RLock()
+	defer bc.moduleLock.RUnlock()
+	names := make([]string, 0, len(bc.startModules))
+	for moduleName := range bc.startModules {
+		names = append(names, moduleName)
+	}
+	return names
+}
+
+// Resume re-creates and starts the modules stopped by Pause or Stop.
+func (bc *Blockchain) Resume() error {
+	bc.resetStoppedModules()
+	if err := bc.Init(); err != nil {
+		return err
+	}
+	return bc.Start()
+}
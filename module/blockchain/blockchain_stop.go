@@ -81,9 +81,8 @@ func (bc *Blockchain) StopOnRequirements() {
 		moduleNameVM:         5,
 	}
 	closeFlagArray := [6]string{}
-	for moduleName := range bc.startModules {
-		_, ok := bc.initModules[moduleName]
-		if ok {
+	for _, moduleName := range bc.startedModules() {
+		if bc.isModuleInit(moduleName) {
 			continue
 		}
 		seq, canStop := sequence[moduleName]
@@ -113,7 +112,7 @@ func (bc *Blockchain) stopNetService() error {
 		bc.log.Errorf("stop net service failed, %s", err.Error())
 		return err
 	}
-	delete(bc.startModules, moduleNameNetService)
+	bc.removeModuleStartUp(moduleNameNetService)
 	return nil
 }
 
@@ -123,21 +122,21 @@ func (bc *Blockchain) stopConsensus() error {
 		bc.log.Errorf("stop consensus failed, %s", err.Error())
 		return err
 	}
-	delete(bc.startModules, moduleNameConsensus)
+	bc.removeModuleStartUp(moduleNameConsensus)
 	return nil
 }
 
 func (bc *Blockchain) stopCoreEngine() error {
 	// stop core engine
 	bc.coreEngine.Stop()
-	delete(bc.startModules, moduleNameCore)
+	bc.removeModuleStartUp(moduleNameCore)
 	return nil
 }
 
 func (bc *Blockchain) stopSyncService() error {
 	// stop sync
 	bc.syncServer.Stop()
-	delete(bc.startModules, moduleNameSync)
+	bc.removeModuleStartUp(moduleNameSync)
 	return nil
 }
 
@@ -149,7 +148,7 @@ func (bc *Blockchain) stopTxPool() error {
 
 		return err
 	}
-	delete(bc.startModules, moduleNameTxPool)
+	bc.removeModuleStartUp(moduleNameTxPool)
 	return nil
 }
 
@@ -160,6 +159,34 @@ func (bc *Blockchain) stopVM() error {
 		bc.log.Errorf("stop vm failed, %s", err)
 		return err
 	}
-	delete(bc.startModules, moduleNameVM)
+	bc.removeModuleStartUp(moduleNameVM)
 	return nil
 }
+
+// Pause stops the modules which produce and synchronize blocks, the chain keeps serving
+// the queries and accepting transactions. The stopped modules are re-created by Resume.
+func (bc *Blockchain) Pause() {
+	if bc.isModuleStartUp(moduleNameSync) {
+		if err := bc.stopSyncService(); err != nil {
+			bc.log.Errorf("stop module[%s] failed, %s", moduleNameSync, err)
+		}
+	}
+	if bc.isModuleStartUp(moduleNameConsensus) {
+		if err := bc.stopConsensus(); err != nil {
+			bc.log.Errorf("stop module[%s] failed, %s", moduleNameConsensus, err)
+		}
+	}
+	bc.resetStoppedModules()
+}
+
+// resetStoppedModules marks the stopped modules as not initialized, so that they are
+// re-created by the next Init, the stopped instances can not be started again.
+// The net service is kept, it is a view of the net shared by all the chains.
+func (bc *Blockchain) resetStoppedModules() {
+	for _, moduleName := range []string{moduleNameVM, moduleNameTxPool, moduleNameCore, moduleNameConsensus,
+		moduleNameSync} {
+		if !bc.isModuleStartUp(moduleName) {
+			bc.removeModuleInit(moduleName)
+		}
+	}
+}
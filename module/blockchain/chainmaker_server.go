@@ -11,7 +11,6 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -39,6 +38,11 @@ type ChainMakerServer struct {
 	// blockchains known by this node
 	blockchains sync.Map // map[string]*Blockchain
 
+	// lifecycle status of the blockchains, absent means initialized
+	chainStatuses sync.Map // map[string]ChainStatus
+	// serializes the runtime lifecycle operations of the blockchains
+	lifecycleLock sync.Mutex
+
 	readyC chan struct{}
 }
 
@@ -195,7 +199,7 @@ func (server *ChainMakerServer) newBlockchainTaskListener() {
 				continue
 			}
 			newBlockchain, _ := server.blockchains.Load(newChainId)
-			go startBlockchain(newBlockchain.(*Blockchain))
+			go server.startBlockchain(newBlockchain.(*Blockchain))
 
 		}
 	}
@@ -221,14 +225,6 @@ func (server *ChainMakerServer) initBlockchain(chainId, genesis string) error {
 	return nil
 }
 
-func startBlockchain(chain *Blockchain) {
-	if err := chain.Start(); err != nil {
-		log.Errorf("[Core] start blockchain[%s] failed, %s", chain.chainId, err.Error())
-		os.Exit(-1)
-	}
-	log.Infof("[Core] start blockchain[%s] success", chain.chainId)
-}
-
 // Start ChainMakerServer.
 func (server *ChainMakerServer) Start() error {
 	// 1) start Net
@@ -241,7 +237,7 @@ func (server *ChainMakerServer) Start() error {
 	// 2) start blockchains
 	server.blockchains.Range(func(_, value interface{}) bool {
 		chain, _ := value.(*Blockchain)
-		go startBlockchain(chain)
+		go server.startBlockchain(chain)
 		return true
 	})
 
@@ -277,6 +273,10 @@ func (server *ChainMakerServer) Stop() {
 // AddTx add a transaction.
 func (server *ChainMakerServer) AddTx(chainId string, tx *common.Transaction, source protocol.TxSource) error {
 	if blockchain, ok := server.blockchains.Load(chainId); ok {
+		if status, _ := server.GetBlockchainStatus(chainId); status == ChainStatusStopped ||
+			status == ChainStatusFailed {
+			return fmt.Errorf("blockchain[%s] is %s", chainId, status)
+		}
 		return blockchain.(*Blockchain).txPool.AddTx(tx, source)
 	}
 	return fmt.Errorf(chainIdNotFoundErrorTemplate, chainId)
@@ -0,0 +1,179 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blockchain
+
+import (
+	"fmt"
+)
+
+// ChainStatus is the lifecycle status of a chain in ChainMakerServer
+type ChainStatus int32
+
+const (
+	// ChainStatusInitialized the chain is initialized but not started yet
+	ChainStatusInitialized ChainStatus = iota
+	// ChainStatusRunning all the modules of the chain are started
+	ChainStatusRunning
+	// ChainStatusPaused the consensus and sync of the chain are stopped, queries and transactions are still served
+	ChainStatusPaused
+	// ChainStatusStopped all the modules of the chain are stopped, only the data in store can be queried
+	ChainStatusStopped
+	// ChainStatusFailed the chain failed to start, the started modules are stopped
+	ChainStatusFailed
+)
+
+var chainStatusNames = map[ChainStatus]string{
+	ChainStatusInitialized: "INITIALIZED",
+	ChainStatusRunning:     "RUNNING",
+	ChainStatusPaused:      "PAUSED",
+	ChainStatusStopped:     "STOPPED",
+	ChainStatusFailed:      "FAILED",
+}
+
+func (s ChainStatus) String() string {
+	if name, ok := chainStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int32(s))
+}
+
+// startBlockchain starts the chain, a chain failed to start is stopped and marked as failed,
+// the other chains of the node are not affected.
+func (server *ChainMakerServer) startBlockchain(chain *Blockchain) {
+	if err := chain.Start(); err != nil {
+		log.Errorf("[Core] start blockchain[%s] failed, %s", chain.chainId, err.Error())
+		chain.Stop()
+		server.chainStatuses.Store(chain.chainId, ChainStatusFailed)
+		return
+	}
+	server.chainStatuses.Store(chain.chainId, ChainStatusRunning)
+	log.Infof("[Core] start blockchain[%s] success", chain.chainId)
+}
+
+// GetBlockchainStatus get the lifecycle status of chain which id is the given.
+func (server *ChainMakerServer) GetBlockchainStatus(chainId string) (ChainStatus, error) {
+	if _, ok := server.blockchains.Load(chainId); !ok {
+		return 0, fmt.Errorf(chainIdNotFoundErrorTemplate, chainId)
+	}
+	if status, ok := server.chainStatuses.Load(chainId); ok {
+		return status.(ChainStatus), nil
+	}
+	return ChainStatusInitialized, nil
+}
+
+// GetAllBlockchainStatus get the lifecycle status of all the chains.
+func (server *ChainMakerServer) GetAllBlockchainStatus() map[string]ChainStatus {
+	statuses := make(map[string]ChainStatus)
+	server.blockchains.Range(func(key, _ interface{}) bool {
+		chainId, _ := key.(string)
+		statuses[chainId], _ = server.GetBlockchainStatus(chainId)
+		return true
+	})
+	return statuses
+}
+
+// PauseBlockchain stops the consensus and sync of chain which id is the given,
+// the chain keeps serving the queries and accepting the transactions.
+func (server *ChainMakerServer) PauseBlockchain(chainId string) error {
+	server.lifecycleLock.Lock()
+	defer server.lifecycleLock.Unlock()
+	chain, status, err := server.getBlockchainWithStatus(chainId)
+	if err != nil {
+		return err
+	}
+	if status != ChainStatusRunning {
+		return fmt.Errorf("blockchain[%s] is %s, only running blockchain can be paused", chainId, status)
+	}
+	chain.Pause()
+	server.chainStatuses.Store(chainId, ChainStatusPaused)
+	log.Infof("[Core] pause blockchain[%s] success", chainId)
+	return nil
+}
+
+// StopBlockchain stops all the modules of chain which id is the given,
+// the chain can be started again by ResumeBlockchain.
+func (server *ChainMakerServer) StopBlockchain(chainId string) error {
+	server.lifecycleLock.Lock()
+	defer server.lifecycleLock.Unlock()
+	chain, status, err := server.getBlockchainWithStatus(chainId)
+	if err != nil {
+		return err
+	}
+	if status == ChainStatusInitialized || status == ChainStatusStopped {
+		return fmt.Errorf("blockchain[%s] is %s, only started blockchain can be stopped", chainId, status)
+	}
+	chain.Stop()
+	server.chainStatuses.Store(chainId, ChainStatusStopped)
+	log.Infof("[Core] stop blockchain[%s] success", chainId)
+	return nil
+}
+
+// ResumeBlockchain starts the paused, stopped or failed chain which id is the given.
+func (server *ChainMakerServer) ResumeBlockchain(chainId string) error {
+	server.lifecycleLock.Lock()
+	defer server.lifecycleLock.Unlock()
+	chain, status, err := server.getBlockchainWithStatus(chainId)
+	if err != nil {
+		return err
+	}
+	if status == ChainStatusRunning || status == ChainStatusInitialized {
+		return fmt.Errorf("blockchain[%s] is %s, only paused, stopped or failed blockchain can be resumed",
+			chainId, status)
+	}
+	if err = chain.Resume(); err != nil {
+		log.Errorf("[Core] resume blockchain[%s] failed, %s", chainId, err.Error())
+		chain.Stop()
+		server.chainStatuses.Store(chainId, ChainStatusFailed)
+		return err
+	}
+	server.chainStatuses.Store(chainId, ChainStatusRunning)
+	log.Infof("[Core] resume blockchain[%s] success", chainId)
+	return nil
+}
+
+// RemoveBlockchain stops chain which id is the given and removes it from the node.
+// The chain is added again when the node restarts unless it is removed from the config file.
+func (server *ChainMakerServer) RemoveBlockchain(chainId string) error {
+	server.lifecycleLock.Lock()
+	defer server.lifecycleLock.Unlock()
+	chain, status, err := server.getBlockchainWithStatus(chainId)
+	if err != nil {
+		return err
+	}
+	if status == ChainStatusInitialized {
+		return fmt.Errorf("blockchain[%s] is %s, it can be removed after started", chainId, status)
+	}
+	if status != ChainStatusStopped {
+		chain.Stop()
+	}
+	server.blockchains.Delete(chainId)
+	server.chainStatuses.Delete(chainId)
+	// the subscribers quit before the store they read is closed, the sinks stop with them
+	if chain.msgBus != nil {
+		chain.msgBus.Close()
+	}
+	if chain.store != nil {
+		if err = chain.store.Close(); err != nil {
+			log.Warnf("[Core] close store of blockchain[%s] failed, %s", chainId, err.Error())
+		}
+	}
+	log.Infof("[Core] remove blockchain[%s] success", chainId)
+	return nil
+}
+
+func (server *ChainMakerServer) getBlockchainWithStatus(chainId string) (*Blockchain, ChainStatus, error) {
+	chain, err := server.GetBlockchain(chainId)
+	if err != nil {
+		return nil, 0, err
+	}
+	status, err := server.GetBlockchainStatus(chainId)
+	if err != nil {
+		return nil, 0, err
+	}
+	return chain, status, nil
+}
@@ -10,6 +10,10 @@ package blockchain
 import (
 	"testing"
 	"time"
+
+	"chainmaker.org/chainmaker/common/v2/msgbus"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitAndStart(t *testing.T) {
@@ -18,3 +22,75 @@ func TestInitAndStart(t *testing.T) {
 	timer := time.NewTimer(5 * time.Second)
 	<-timer.C
 }
+
+func TestChainLifecycle(t *testing.T) {
+	server := ChainMakerServer{}
+	server.blockchains.Store("chain1", NewBlockchain("", "chain1", msgbus.NewMessageBus(), nil))
+
+	status, err := server.GetBlockchainStatus("chain1")
+	require.Nil(t, err)
+	require.Equal(t, ChainStatusInitialized, status)
+	_, err = server.GetBlockchainStatus("chain2")
+	require.NotNil(t, err)
+
+	// the chain not started can not be paused, stopped or removed
+	require.NotNil(t, server.PauseBlockchain("chain1"))
+	require.NotNil(t, server.StopBlockchain("chain1"))
+	require.NotNil(t, server.RemoveBlockchain("chain1"))
+
+	server.chainStatuses.Store("chain1", ChainStatusRunning)
+	require.Nil(t, server.PauseBlockchain("chain1"))
+	require.NotNil(t, server.PauseBlockchain("chain1"))
+	require.Nil(t, server.StopBlockchain("chain1"))
+	require.NotNil(t, server.StopBlockchain("chain1"))
+	require.Equal(t, map[string]ChainStatus{"chain1": ChainStatusStopped}, server.GetAllBlockchainStatus())
+
+	// the transactions are rejected by the stopped chain
+	require.NotNil(t, server.AddTx("chain1", nil, protocol.RPC))
+
+	require.Nil(t, server.RemoveBlockchain("chain1"))
+	_, err = server.GetBlockchain("chain1")
+	require.NotNil(t, err)
+	require.Len(t, server.GetAllBlockchainStatus(), 0)
+}
+
+func TestGetAllChainHealth(t *testing.T) {
+	server := ChainMakerServer{}
+	server.blockchains.Store("chain2", NewBlockchain("", "chain2", msgbus.NewMessageBus(), nil))
+	chain1 := NewBlockchain("", "chain1", msgbus.NewMessageBus(), nil)
+	server.blockchains.Store("chain1", chain1)
+
+	healths := server.GetAllChainHealth()
+	require.Len(t, healths, 2)
+	require.Equal(t, "chain1", healths[0].ChainId)
+	require.Equal(t, ChainStatusInitialized.String(), healths[0].Status)
+	require.True(t, healths[0].IsHealthy())
+	require.False(t, healths[0].IsReady(0))
+
+	// the module initialized but not started
+	chain1.setModuleInit(moduleNameTxPool)
+	server.chainStatuses.Store("chain1", ChainStatusRunning)
+	health := server.GetAllChainHealth()[0]
+	require.Equal(t, map[string]bool{moduleNameTxPool: false}, health.Modules)
+	require.Equal(t, ConsensusRoleSync, health.ConsensusRole)
+	require.False(t, health.IsReady(0))
+
+	chain1.setModuleStartUp(moduleNameTxPool)
+	require.True(t, server.GetAllChainHealth()[0].IsReady(0))
+
+	server.chainStatuses.Store("chain1", ChainStatusFailed)
+	require.False(t, server.GetAllChainHealth()[0].IsHealthy())
+}
+
+func TestChainHealthReadyWithPeers(t *testing.T) {
+	health := &ChainHealth{status: ChainStatusRunning, Height: 10, PeerCount: 2}
+	// the height of the peers is unknown right after the node starts
+	require.False(t, health.IsReady(0))
+
+	health.peerHeightKnown, health.PeerHeight, health.SyncLag = true, 12, 2
+	require.False(t, health.IsReady(1))
+	require.True(t, health.IsReady(2))
+
+	// the chain without peers does not wait for them
+	require.True(t, (&ChainHealth{status: ChainStatusRunning}).IsReady(0))
+}
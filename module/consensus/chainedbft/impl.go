@@ -214,6 +214,9 @@ func (cbi *ConsensusChainedBftImpl) startConsensus() {
 
 //Stop stop consensus
 func (cbi *ConsensusChainedBftImpl) Stop() error {
+	cbi.msgbus.UnRegister(msgbus.ProposedBlock, cbi)
+	cbi.msgbus.UnRegister(msgbus.RecvConsensusMsg, cbi)
+	cbi.msgbus.UnRegister(msgbus.BlockInfo, cbi)
 	close(cbi.quitProtocolCh)
 	close(cbi.quitSyncCh)
 	close(cbi.quitCh)
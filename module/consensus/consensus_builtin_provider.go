@@ -0,0 +1,93 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consensus
+
+import (
+	"fmt"
+
+	"chainmaker.org/chainmaker-go/consensus/chainedbft"
+	"chainmaker.org/chainmaker-go/consensus/dpos"
+	"chainmaker.org/chainmaker-go/consensus/raft"
+	"chainmaker.org/chainmaker-go/consensus/solo"
+	"chainmaker.org/chainmaker-go/consensus/tbft"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
+	"chainmaker.org/chainmaker/protocol/v2"
+)
+
+// TBFTProvider provides the TBFT engine, it is registered for DPoS too
+type TBFTProvider struct{}
+
+func (TBFTProvider) NewConsensusEngine(config *ConsensusImplConfig) (protocol.ConsensusEngine, error) {
+	return tbft.New(tbft.ConsensusTBFTImplConfig{
+		ChainID:     config.ChainId,
+		Id:          config.NodeId,
+		Signer:      config.Signer,
+		Ac:          config.Ac,
+		DbHandle:    config.DbHandle,
+		LedgerCache: config.LedgerCache,
+		Store:       config.Store,
+		ChainConf:   config.ChainConf,
+		NetService:  config.NetService,
+		MsgBus:      config.MsgBus,
+		Dpos:        dpos.NewDPoSImpl(config.ChainConf, config.Store),
+	})
+}
+
+func (TBFTProvider) VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+	store protocol.BlockchainStore, block *commonpb.Block, ledger protocol.LedgerCache) error {
+	return tbft.VerifyBlockSignatures(chainConf, ac, block, store)
+}
+
+// SOLOProvider provides the SOLO engine
+type SOLOProvider struct{}
+
+func (SOLOProvider) NewConsensusEngine(config *ConsensusImplConfig) (protocol.ConsensusEngine, error) {
+	return solo.New(config.ChainId, config.NodeId, config.Signer, config.MsgBus, config.ChainConf)
+}
+
+func (SOLOProvider) VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+	store protocol.BlockchainStore, block *commonpb.Block, ledger protocol.LedgerCache) error {
+	// blocks of SOLO are not signed by consensus
+	return fmt.Errorf("error consensusType: %s", consensuspb.ConsensusType_SOLO)
+}
+
+// RAFTProvider provides the RAFT engine
+type RAFTProvider struct{}
+
+func (RAFTProvider) NewConsensusEngine(config *ConsensusImplConfig) (protocol.ConsensusEngine, error) {
+	return raft.New(raft.ConsensusRaftImplConfig{
+		ChainID:        config.ChainId,
+		NodeId:         config.NodeId,
+		Singer:         config.Signer,
+		Ac:             config.Ac,
+		LedgerCache:    config.LedgerCache,
+		BlockVerifier:  config.BlockVerifier,
+		BlockCommitter: config.BlockCommitter,
+		ChainConf:      config.ChainConf,
+		MsgBus:         config.MsgBus,
+	})
+}
+
+func (RAFTProvider) VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+	store protocol.BlockchainStore, block *commonpb.Block, ledger protocol.LedgerCache) error {
+	return raft.VerifyBlockSignatures(chainConf, ac, block)
+}
+
+// HotStuffProvider provides the chained HotStuff engine
+type HotStuffProvider struct{}
+
+func (HotStuffProvider) NewConsensusEngine(config *ConsensusImplConfig) (protocol.ConsensusEngine, error) {
+	return chainedbft.New(config.ChainId, config.NodeId, config.Signer, config.Ac, config.LedgerCache,
+		config.ProposalCache, config.BlockVerifier, config.BlockCommitter, config.NetService,
+		config.Store, config.MsgBus, config.ChainConf, config.HotStuffHelper)
+}
+
+func (HotStuffProvider) VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+	store protocol.BlockchainStore, block *commonpb.Block, ledger protocol.LedgerCache) error {
+	return chainedbft.VerifyBlockSignatures(chainConf, ac, store, block, ledger)
+}
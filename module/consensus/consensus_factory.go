@@ -9,16 +9,9 @@ package consensus
 import (
 	"fmt"
 
-	"chainmaker.org/chainmaker-go/consensus/dpos"
-
-	"chainmaker.org/chainmaker-go/consensus/chainedbft"
-
 	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
 	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
 
-	"chainmaker.org/chainmaker-go/consensus/raft"
-	"chainmaker.org/chainmaker-go/consensus/solo"
-	"chainmaker.org/chainmaker-go/consensus/tbft"
 	"chainmaker.org/chainmaker/common/v2/msgbus"
 	"chainmaker.org/chainmaker/protocol/v2"
 )
@@ -27,7 +20,8 @@ type Factory struct {
 }
 
 // NewConsensusEngine new the consensus engine.
-// consensusType specfies the consensus engine type.
+// consensusType specfies the consensus engine type, the engine is created
+// by the provider registered with RegisterConsensusProvider.
 // msgBus is used for send and receive messages.
 func (f Factory) NewConsensusEngine(
 	consensusType consensuspb.ConsensusType,
@@ -47,44 +41,27 @@ func (f Factory) NewConsensusEngine(
 	store protocol.BlockchainStore,
 	helper protocol.HotStuffHelper,
 ) (protocol.ConsensusEngine, error) {
-	switch consensusType {
-	case consensuspb.ConsensusType_TBFT, consensuspb.ConsensusType_DPOS:
-		config := tbft.ConsensusTBFTImplConfig{
-			ChainID:     chainID,
-			Id:          id,
-			Signer:      signer,
-			Ac:          ac,
-			DbHandle:    dbHandle,
-			LedgerCache: ledgerCache,
-			ChainConf:   chainConf,
-			NetService:  netService,
-			MsgBus:      msgBus,
-			Dpos:        dpos.NewDPoSImpl(chainConf, store),
-		}
-
-		return tbft.New(config)
-	case consensuspb.ConsensusType_SOLO:
-		return solo.New(chainID, id, signer, msgBus, chainConf)
-	case consensuspb.ConsensusType_RAFT:
-		config := raft.ConsensusRaftImplConfig{
-			ChainID:        chainID,
-			NodeId:         id,
-			Singer:         signer,
-			Ac:             ac,
-			LedgerCache:    ledgerCache,
-			BlockVerifier:  blockVerifier,
-			BlockCommitter: blockCommitter,
-			ChainConf:      chainConf,
-			MsgBus:         msgBus,
-		}
-		return raft.New(config)
-	case consensuspb.ConsensusType_HOTSTUFF:
-		return chainedbft.New(chainID, id, signer, ac, ledgerCache,
-			proposalCache, blockVerifier, blockCommitter, netService,
-			store, msgBus, chainConf, helper)
-	default:
+	provider := GetConsensusProvider(consensusType)
+	if provider == nil {
+		return nil, fmt.Errorf("error consensusType: %s", consensusType)
 	}
-	return nil, fmt.Errorf("error consensusType: %s", consensusType)
+	return provider.NewConsensusEngine(&ConsensusImplConfig{
+		ChainId:        chainID,
+		NodeId:         id,
+		NodeList:       nodeList,
+		Signer:         signer,
+		Ac:             ac,
+		DbHandle:       dbHandle,
+		LedgerCache:    ledgerCache,
+		ProposalCache:  proposalCache,
+		BlockVerifier:  blockVerifier,
+		BlockCommitter: blockCommitter,
+		NetService:     netService,
+		MsgBus:         msgBus,
+		ChainConf:      chainConf,
+		Store:          store,
+		HotStuffHelper: helper,
+	})
 }
 
 // VerifyBlockSignatures verifies whether the signatures in block
@@ -99,16 +76,9 @@ func VerifyBlockSignatures(
 	ledger protocol.LedgerCache,
 ) error {
 	consensusType := chainConf.ChainConfig().Consensus.Type
-	switch consensusType {
-	case consensuspb.ConsensusType_TBFT, consensuspb.ConsensusType_DPOS:
-		return tbft.VerifyBlockSignatures(chainConf, ac, block, store)
-	case consensuspb.ConsensusType_RAFT:
-		return raft.VerifyBlockSignatures(block)
-	case consensuspb.ConsensusType_HOTSTUFF:
-		return chainedbft.VerifyBlockSignatures(chainConf, ac, store, block, ledger)
-	case consensuspb.ConsensusType_SOLO:
-		fallthrough
-	default:
+	provider := GetConsensusProvider(consensusType)
+	if provider == nil {
+		return fmt.Errorf("error consensusType: %s", consensusType)
 	}
-	return fmt.Errorf("error consensusType: %s", consensusType)
+	return provider.VerifyBlockSignatures(chainConf, ac, store, block, ledger)
 }
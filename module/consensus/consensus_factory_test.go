@@ -17,6 +17,7 @@ import (
 	"chainmaker.org/chainmaker-go/consensus/tbft"
 	"chainmaker.org/chainmaker/common/v2/msgbus"
 	"chainmaker.org/chainmaker/localconf/v2"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
 	configpb "chainmaker.org/chainmaker/pb-go/v2/config"
 	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
 	"chainmaker.org/chainmaker/protocol/v2"
@@ -39,6 +40,7 @@ var (
 func TestNewConsensusEngine(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
+	RegisterConsensusProvider(consensuspb.ConsensusType_TBFT, TBFTProvider{})
 
 	prePath := localconf.ChainMakerConfig.GetStorePath()
 	defer func() {
@@ -134,3 +136,53 @@ func TestNewConsensusEngine(t *testing.T) {
 		})
 	}
 }
+
+type mockConsensusProvider struct {
+	engine protocol.ConsensusEngine
+}
+
+func (p mockConsensusProvider) NewConsensusEngine(config *ConsensusImplConfig) (protocol.ConsensusEngine, error) {
+	return p.engine, nil
+}
+
+func (p mockConsensusProvider) VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+	store protocol.BlockchainStore, block *commonpb.Block, ledger protocol.LedgerCache) error {
+	return nil
+}
+
+func TestRegisterConsensusProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	customType := consensuspb.ConsensusType(100)
+	engine := mock.NewMockConsensusEngine(ctrl)
+	RegisterConsensusProvider(customType, mockConsensusProvider{engine: engine})
+
+	var factory Factory
+	got, err := factory.NewConsensusEngine(customType, chainID, id, nodeList,
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil || got != engine {
+		t.Errorf("NewConsensusEngine() = %v, %v, want registered engine", got, err)
+	}
+
+	chainConf := mock.NewMockChainConf(ctrl)
+	chainConf.EXPECT().ChainConfig().AnyTimes().Return(&configpb.ChainConfig{
+		Consensus: &configpb.ConsensusConfig{Type: customType},
+	})
+	if err = VerifyBlockSignatures(chainConf, nil, nil, nil, nil); err != nil {
+		t.Errorf("VerifyBlockSignatures() error = %v", err)
+	}
+
+	if _, err = factory.NewConsensusEngine(consensuspb.ConsensusType(101), chainID, id, nodeList,
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); err == nil {
+		t.Errorf("NewConsensusEngine() with unregistered type should fail")
+	}
+
+	// a registered provider, as the built-in ones, can be overridden
+	override := mock.NewMockConsensusEngine(ctrl)
+	RegisterConsensusProvider(customType, mockConsensusProvider{engine: override})
+	if got, _ = factory.NewConsensusEngine(customType, chainID, id, nodeList,
+		nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); got != override {
+		t.Errorf("NewConsensusEngine() = %v, want the overriding engine", got)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consensus
+
+import (
+	"chainmaker.org/chainmaker/common/v2/msgbus"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
+	"chainmaker.org/chainmaker/protocol/v2"
+)
+
+// ConsensusImplConfig contains the modules a consensus engine may depend on
+type ConsensusImplConfig struct {
+	ChainId        string
+	NodeId         string
+	NodeList       []string
+	Signer         protocol.SigningMember
+	Ac             protocol.AccessControlProvider
+	DbHandle       protocol.DBHandle
+	LedgerCache    protocol.LedgerCache
+	ProposalCache  protocol.ProposalCache
+	BlockVerifier  protocol.BlockVerifier
+	BlockCommitter protocol.BlockCommitter
+	NetService     protocol.NetService
+	MsgBus         msgbus.MessageBus
+	ChainConf      protocol.ChainConf
+	Store          protocol.BlockchainStore
+	HotStuffHelper protocol.HotStuffHelper
+}
+
+// Provider creates the consensus engine of one consensus type,
+// and verifies the signatures of the blocks produced by that engine.
+type Provider interface {
+	// NewConsensusEngine new the consensus engine with config
+	NewConsensusEngine(config *ConsensusImplConfig) (protocol.ConsensusEngine, error)
+
+	// VerifyBlockSignatures verifies whether the signatures in block
+	// is qualified with the consensus algorithm
+	VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+		store protocol.BlockchainStore, block *commonpb.Block, ledger protocol.LedgerCache) error
+}
+
+var consensusProviders = make(map[consensuspb.ConsensusType]Provider)
+
+// RegisterConsensusProvider add the provider of consensusType to the registry.
+// The provider registered later replaces the former one of the same type,
+// so that a built-in engine can be overridden.
+func RegisterConsensusProvider(consensusType consensuspb.ConsensusType, provider Provider) {
+	consensusProviders[consensusType] = provider
+}
+
+// GetConsensusProvider return the provider of consensusType, nil if not registered
+func GetConsensusProvider(consensusType consensuspb.ConsensusType) Provider {
+	provider, ok := consensusProviders[consensusType]
+	if !ok {
+		return nil
+	}
+	return provider
+}
@@ -8,6 +8,7 @@ package dpos
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math/big"
 
@@ -46,8 +47,10 @@ func (impl *DPoSImpl) getNodeIDsFromValidators(epoch *syscontract.Epoch) ([]stri
 	return nodeIDs, nil
 }
 
-// getAllCandidateInfo get all candidates from ledger
-func (impl *DPoSImpl) getAllCandidateInfo() ([]*dpospb.CandidateInfo, error) {
+// getAllCandidateInfo get all candidates from ledger, the validators in updated
+// replace the ones in ledger, which are rewarded or slashed in the proposing block
+func (impl *DPoSImpl) getAllCandidateInfo(
+	updated map[string]*syscontract.Validator) ([]*dpospb.CandidateInfo, error) {
 	prefix := dposmgr.ToValidatorPrefix()
 	iterRange := util.BytesPrefix(prefix)
 	iter, err := impl.stateDB.SelectObject(
@@ -84,6 +87,10 @@ func (impl *DPoSImpl) getAllCandidateInfo() ([]*dpospb.CandidateInfo, error) {
 			impl.log.Errorf("unmarshal validator failed, reason: %s", err)
 			return nil, err
 		}
+		if updatedVal, ok := updated[val.ValidatorAddress]; ok {
+			vals = append(vals, updatedVal)
+			continue
+		}
 		vals = append(vals, &val)
 	}
 	if len(vals) == 0 {
@@ -137,6 +144,23 @@ func (impl *DPoSImpl) createEpochRwSet(epoch *syscontract.Epoch) (*commonpb.TxRW
 	return rw, nil
 }
 
+// appendEpochStakesRwSet snapshots the stakes of the validators of epoch, so that their voting powers
+// stay the same in the epoch whatever the delegations in it
+func (impl *DPoSImpl) appendEpochStakesRwSet(rwSet *commonpb.TxRWSet, epoch *syscontract.Epoch,
+	stakes []string) error {
+	bz, err := json.Marshal(stakes)
+	if err != nil {
+		impl.log.Errorf("marshal epoch stakes failed, reason: %s", err)
+		return err
+	}
+	rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{
+		ContractName: syscontract.SystemContract_DPOS_STAKE.String(),
+		Key:          toEpochStakesKey(epoch.EpochId),
+		Value:        bz,
+	})
+	return nil
+}
+
 func (impl *DPoSImpl) createValidatorsRwSet(epoch *syscontract.Epoch) (*commonpb.TxRWSet, error) {
 
 	nodeIDs, err := impl.getNodeIDsFromValidators(epoch)
@@ -176,14 +200,6 @@ func (impl *DPoSImpl) createValidatorsRwSet(epoch *syscontract.Epoch) (*commonpb
 	return rw, nil
 }
 
-//func (impl *DPoSImpl) createRewardRwSet(rewardAmount big.Int) (*commonpb.TxRWSet, error) {
-//	return nil, nil
-//}
-
-//func (impl *DPoSImpl) createSlashRwSet(slashAmount big.Int) (*commonpb.TxRWSet, error) {
-//	return nil, nil
-//}
-
 func (impl *DPoSImpl) completeUnbounding(epoch *syscontract.Epoch,
 	block *common.Block, blockTxRwSet map[string]*common.TxRWSet) (*commonpb.TxRWSet, error) {
 	undelegations, err := impl.getUnboundingEntries(epoch)
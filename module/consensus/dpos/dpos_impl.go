@@ -34,19 +34,44 @@ func NewDPoSImpl(chainConf protocol.ChainConf, blockChainStore protocol.Blockcha
 	return &DPoSImpl{stateDB: blockChainStore, log: log, chainConf: chainConf}
 }
 
+// CreateDPoSRWSet add the DPoS rwSet to the consensus args of the proposed block. The round and the evidences
+// recorded in the consensus args by the consensus engine are kept.
 func (impl *DPoSImpl) CreateDPoSRWSet(preBlkHash []byte, proposedBlock *consensus.ProposalBlock) error {
-	consensusRwSets, err := impl.createDPoSRWSet(preBlkHash, proposedBlock)
+	if !impl.isDPoSConsensus() {
+		return nil
+	}
+	argBytes, err := impl.createConsensusArgs(preBlkHash, proposedBlock)
 	if err != nil {
 		return err
 	}
-	if consensusRwSets != nil {
-		err = impl.addConsensusArgsToBlock(consensusRwSets, proposedBlock.Block)
+	proposedBlock.Block.Header.ConsensusArgs = argBytes
+	return nil
+}
+
+// createConsensusArgs create the consensus args of the block, which is nil if there is nothing to write
+func (impl *DPoSImpl) createConsensusArgs(preBlkHash []byte, proposedBlock *consensus.ProposalBlock) ([]byte, error) {
+	engineArgs, err := GetConsensusArgs(proposedBlock.Block)
+	if err != nil {
+		impl.log.Errorf("unmarshal BlockHeaderConsensusArgs failed, reason: %s", err)
+		return nil, err
+	}
+	rwSet, err := impl.createDPoSRWSet(preBlkHash, proposedBlock, engineArgs.Round)
+	if err != nil {
+		return nil, err
+	}
+	evidenceWrites := getDoubleSignEvidenceWrites(engineArgs)
+	if rwSet == nil {
+		if len(evidenceWrites) == 0 {
+			return nil, nil
+		}
+		rwSet = &common.TxRWSet{TxId: ModuleName}
 	}
-	return err
+	rwSet.TxWrites = append(rwSet.TxWrites, evidenceWrites...)
+	return impl.marshalConsensusArgs(rwSet, engineArgs.Round)
 }
 
 func (impl *DPoSImpl) createDPoSRWSet(
-	preBlkHash []byte, proposedBlock *consensus.ProposalBlock) (*common.TxRWSet, error) {
+	preBlkHash []byte, proposedBlock *consensus.ProposalBlock, round int64) (*common.TxRWSet, error) {
 	impl.log.Debugf("begin createDPoS rwSet, blockInfo: %d:%x ",
 		proposedBlock.Block.Header.BlockHeight, proposedBlock.Block.Header.BlockHash)
 	// 1. judge consensus: DPoS
@@ -64,17 +89,34 @@ func (impl *DPoSImpl) createDPoSRWSet(
 	if err != nil {
 		return nil, err
 	}
+	conf, err := impl.getEconomicsConfig()
+	if err != nil {
+		return nil, err
+	}
+	// 3. count the proposals of the block
+	stats, err := impl.countProposals(epoch, blockHeight, round, preBlkHash, conf)
+	if err != nil {
+		impl.log.Errorf("count proposals error, reason: %s", err)
+		return nil, err
+	}
 	if epoch.NextEpochCreateHeight != blockHeight {
-		return nil, nil
+		return impl.createEpochStatsRwSet(stats)
 	}
-	// 3. create unbounding rwset
+	// 4. create unbounding rwset
 	unboundingRwSet, err := impl.completeUnbounding(epoch, block, blockTxRwSet)
 	if err != nil {
 		impl.log.Errorf("create complete unbonding error, reason: %s", err)
 		return nil, err
 	}
-	// 4. create newEpoch
-	newEpoch, err := impl.createNewEpoch(blockHeight, epoch, preBlkHash)
+	// 5. create rewards and slashing rwset of the ending epoch
+	economicsRwSet, updatedVals, err := impl.createEconomicsRwSet(epoch, conf, stats, block, blockTxRwSet,
+		unboundingRwSet)
+	if err != nil {
+		impl.log.Errorf("create rewards and slashing rwSet error, reason: %s", err)
+		return nil, err
+	}
+	// 6. create newEpoch
+	newEpoch, stakes, err := impl.createNewEpoch(blockHeight, epoch, preBlkHash, updatedVals)
 	if err != nil {
 		impl.log.Errorf("create new epoch error, reason: %s", err)
 		return nil, err
@@ -84,12 +126,19 @@ func (impl *DPoSImpl) createDPoSRWSet(
 		impl.log.Errorf("create epoch rwSet error, reason: %s", err)
 		return nil, err
 	}
+	if conf.election.StakeWeighted {
+		if err = impl.appendEpochStakesRwSet(epochRwSet, newEpoch, stakes); err != nil {
+			impl.log.Errorf("create epoch stakes rwSet error, reason: %s", err)
+			return nil, err
+		}
+	}
 	validatorsRwSet, err := impl.createValidatorsRwSet(newEpoch)
 	if err != nil {
 		impl.log.Errorf("create validators rwSet error, reason: %s", err)
 		return nil, err
 	}
-	// 5. Aggregate read-write set
+	// 7. Aggregate read-write set
+	unboundingRwSet.TxWrites = append(unboundingRwSet.TxWrites, economicsRwSet.TxWrites...)
 	unboundingRwSet.TxWrites = append(unboundingRwSet.TxWrites, epochRwSet.TxWrites...)
 	unboundingRwSet.TxWrites = append(unboundingRwSet.TxWrites, validatorsRwSet.TxWrites...)
 	impl.log.Debugf("end createDPoS rwSet: %v ", unboundingRwSet)
@@ -100,37 +149,37 @@ func (impl *DPoSImpl) isDPoSConsensus() bool {
 	return impl.chainConf.ChainConfig().Consensus.Type == consensus.ConsensusType_DPOS
 }
 
-func (impl *DPoSImpl) createNewEpoch(
-	proposalHeight uint64, oldEpoch *syscontract.Epoch, seed []byte) (*syscontract.Epoch, error) {
+// createNewEpoch returns the new epoch and the stakes of its validators in order
+func (impl *DPoSImpl) createNewEpoch(proposalHeight uint64, oldEpoch *syscontract.Epoch, seed []byte,
+	updatedVals map[string]*syscontract.Validator) (*syscontract.Epoch, []string, error) {
 	impl.log.Debugf("begin create new epoch in blockHeight: %d", proposalHeight)
 	// 1. get property: epochBlockNum
-	epochBlockNumBz, err := impl.stateDB.ReadObject(
-		syscontract.SystemContract_DPOS_STAKE.String(), []byte(dposmgr.KeyEpochBlockNumber))
+	epochBlockNum, err := impl.getEpochBlockNum()
 	if err != nil {
-		impl.log.Errorf("load epochBlockNum from db failed, reason: %s", err)
-		return nil, err
+		return nil, nil, err
 	}
-	epochBlockNum := binary.BigEndian.Uint64(epochBlockNumBz)
 	impl.log.Debugf("epoch blockNum: %d", epochBlockNum)
 
 	// 2. get all candidates
-	candidates, err := impl.getAllCandidateInfo()
+	candidates, err := impl.getAllCandidateInfo(updatedVals)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if len(candidates) == 0 {
 		impl.log.Errorf("not found candidates from contract")
-		return nil, fmt.Errorf("not found candidates from contract")
+		return nil, nil, fmt.Errorf("not found candidates from contract")
 	}
 
 	// 3. select validators from candidates
 	validators, err := impl.selectValidators(candidates, seed)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	proposer := make([]string, 0, len(validators))
+	stakes := make([]string, 0, len(validators))
 	for _, val := range validators {
 		proposer = append(proposer, val.PeerId)
+		stakes = append(stakes, val.Weight)
 	}
 
 	// 4. create NewEpoch
@@ -140,7 +189,7 @@ func (impl *DPoSImpl) createNewEpoch(
 		ProposerVector:        proposer,
 	}
 	impl.log.Debugf("new epoch: %s", newEpoch.String())
-	return newEpoch, nil
+	return newEpoch, stakes, nil
 }
 
 func (impl *DPoSImpl) selectValidators(candidates []*dpos.CandidateInfo, seed []byte) ([]*dpos.CandidateInfo, error) {
@@ -160,25 +209,22 @@ func (impl *DPoSImpl) selectValidators(candidates []*dpos.CandidateInfo, seed []
 	return vals, nil
 }
 
-func (impl *DPoSImpl) addConsensusArgsToBlock(rwSet *common.TxRWSet, block *common.Block) error {
-	impl.log.Debugf("begin add consensus args to block ")
-	if !impl.isDPoSConsensus() {
-		return nil
-	}
+func (impl *DPoSImpl) marshalConsensusArgs(rwSet *common.TxRWSet, round int64) ([]byte, error) {
 	consensusArgs := &consensus.BlockHeaderConsensusArgs{
 		ConsensusType: int64(consensus.ConsensusType_DPOS),
+		Round:         round,
 		ConsensusData: rwSet,
 	}
 	argBytes, err := proto.Marshal(consensusArgs)
 	if err != nil {
 		impl.log.Errorf("marshal BlockHeaderConsensusArgs failed, reason: %s", err)
-		return err
+		return nil, err
 	}
-	block.Header.ConsensusArgs = argBytes
-	impl.log.Debugf("end add consensus args ")
-	return nil
+	return argBytes, nil
 }
 
+// VerifyConsensusArgs verifies the DPoS rwSet in the consensus args of the block, the evidences in it are
+// verified by the consensus engine
 func (impl *DPoSImpl) VerifyConsensusArgs(block *common.Block, blockTxRwSet map[string]*common.TxRWSet) (err error) {
 	impl.log.Debugf(
 		"begin VerifyConsensusArgs, blockHeight: %d, blockHash: %x",
@@ -187,24 +233,12 @@ func (impl *DPoSImpl) VerifyConsensusArgs(block *common.Block, blockTxRwSet map[
 		return nil
 	}
 
-	localConsensus, err := impl.createDPoSRWSet(
+	localBz, err := impl.createConsensusArgs(
 		block.Header.PreBlockHash, &consensus.ProposalBlock{Block: block, TxsRwSet: blockTxRwSet})
 	if err != nil {
 		impl.log.Errorf("get DPoS txRwSets failed, reason: %s", err)
 		return err
 	}
-
-	var localBz []byte
-	if localConsensus != nil {
-		localBz, err = proto.Marshal(&consensus.BlockHeaderConsensusArgs{
-			ConsensusType: int64(consensus.ConsensusType_DPOS),
-			ConsensusData: localConsensus,
-		})
-		if err != nil {
-			impl.log.Errorf("marshal BlockHeaderConsensusArgs failed, reason: %s", err)
-			return err
-		}
-	}
 	if bytes.Equal(block.Header.ConsensusArgs, localBz) {
 		impl.log.Debugf("end VerifyConsensusArgs")
 		return nil
@@ -213,6 +247,10 @@ func (impl *DPoSImpl) VerifyConsensusArgs(block *common.Block, blockTxRwSet map[
 	if err = proto.Unmarshal(block.Header.ConsensusArgs, consensusArgs); err != nil {
 		return fmt.Errorf("unmarshal dpos consensusArgs from blockHeader failed,reason: %s ", err)
 	}
+	localConsensus := &consensus.BlockHeaderConsensusArgs{}
+	if err = proto.Unmarshal(localBz, localConsensus); err != nil {
+		return fmt.Errorf("unmarshal local dpos consensusArgs failed,reason: %s ", err)
+	}
 	return fmt.Errorf("consensus args verify mismatch, blockConsensus: %v, "+
 		"localConsensus: %v by seed: %x", consensusArgs, localConsensus, block.Header.PreBlockHash)
 }
@@ -12,6 +12,7 @@ import (
 
 	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
 	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
+	"github.com/gogo/protobuf/proto"
 
 	"github.com/stretchr/testify/require"
 )
@@ -30,16 +31,49 @@ func TestDPoSImpl_CreateDPoSRWSet(t *testing.T) {
 	defer fn()
 
 	proposedBlk := &consensuspb.ProposalBlock{Block: &commonpb.Block{Header: &commonpb.BlockHeader{BlockHeight: 99}}}
-	rwSet, err := impl.createDPoSRWSet(nil, proposedBlk)
+	rwSet, err := impl.createDPoSRWSet(nil, proposedBlk, 0)
 	require.NoError(t, err)
 	require.Nil(t, rwSet)
 
 	proposedBlk.Block.Header.BlockHeight = 100
-	rwSet, err = impl.createDPoSRWSet(nil, proposedBlk)
+	rwSet, err = impl.createDPoSRWSet(nil, proposedBlk, 0)
 	require.EqualError(t, err, "not found candidates from contract")
 	require.Nil(t, rwSet)
 }
 
+func TestDPoSImpl_KeepEngineConsensusArgs(t *testing.T) {
+	impl, fn := initTestImpl(t)
+	defer fn()
+
+	// the round and evidences recorded by the consensus engine are kept in the consensus args
+	evidenceWrites := DoubleSignEvidenceWrites(99, "node1/98/0/PREVOTE", []byte(`{"node_id":"node1"}`))
+	engineArgs, err := proto.Marshal(&consensuspb.BlockHeaderConsensusArgs{
+		ConsensusType: int64(consensuspb.ConsensusType_DPOS),
+		Round:         2,
+		ConsensusData: &commonpb.TxRWSet{TxWrites: evidenceWrites},
+	})
+	require.NoError(t, err)
+	block := &commonpb.Block{Header: &commonpb.BlockHeader{BlockHeight: 99, ConsensusArgs: engineArgs}}
+	require.NoError(t, impl.CreateDPoSRWSet(nil, &consensuspb.ProposalBlock{Block: block}))
+
+	args, err := GetConsensusArgs(block)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, args.Round)
+	require.Equal(t, [][]byte{[]byte(`{"node_id":"node1"}`)}, GetDoubleSignEvidences(args))
+	require.NoError(t, impl.VerifyConsensusArgs(block, nil))
+
+	// the writes other than the evidences are verified by DPoS
+	block.Header.ConsensusArgs, err = proto.Marshal(&consensuspb.BlockHeaderConsensusArgs{
+		ConsensusType: int64(consensuspb.ConsensusType_DPOS),
+		Round:         2,
+		ConsensusData: &commonpb.TxRWSet{TxId: ModuleName, TxWrites: append([]*commonpb.TxWrite{{
+			ContractName: DoubleSignEvidenceContract + "_", Key: []byte("k"), Value: []byte("v"),
+		}}, evidenceWrites...)},
+	})
+	require.NoError(t, err)
+	require.Error(t, impl.VerifyConsensusArgs(block, nil))
+}
+
 func TestDPoSImpl_CreateNewEpoch(t *testing.T) {
 
 }
@@ -0,0 +1,660 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dpos
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/vm-native/v2/dposmgr"
+	"github.com/gogo/protobuf/proto"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// The keys of consensus ext config to enable the DPoS economics. Rewards and slashing are
+// disabled by default, so the chains created before keep producing the same epoch rwSets.
+const (
+	// RewardPoolAddr the address which pays the epoch rewards and receives the slashed tokens
+	RewardPoolAddr = "DPOS_reward_pool_addr"
+	// EpochReward the tokens rewarded to the validators at the end of each epoch
+	EpochReward = "DPOS_epoch_reward"
+	// RewardCommissionRate the percent of a validator's reward paid to the validator address,
+	// the rest is added to the bonded tokens of the validator and shared by its delegators
+	RewardCommissionRate = "DPOS_reward_commission_rate"
+	// MissedProposalThreshold the number of missed proposals in an epoch that jails a validator, 0 is disabled
+	MissedProposalThreshold = "DPOS_missed_proposal_threshold"
+	// MissedProposalSlashRate the percent of bonded tokens slashed for missing proposals
+	MissedProposalSlashRate = "DPOS_missed_proposal_slash_rate"
+	// DoubleSignSlashRate the percent of bonded tokens slashed for double signing
+	DoubleSignSlashRate = "DPOS_double_sign_slash_rate"
+	// JailEpochs the number of epochs a slashed validator stays jailed
+	JailEpochs = "DPOS_jail_epochs"
+
+	defaultJailEpochs    = uint64(1)
+	keyJailReleasePrefix = "DPOS_JAIL_RELEASE_"
+	// keyEpochStats the proposals counted in the current epoch, it is updated by the consensus args of each block
+	keyEpochStats = "DPOS_EPOCH_STATS"
+	// keyEpochStakesPrefix the stakes of the validators of an epoch snapshotted when it is created,
+	// they are only written with the DPOS_STAKE validator weights
+	keyEpochStakesPrefix = "DPOS_EPOCH_STAKES_"
+)
+
+// DoubleSignEvidence the fields of an equivocation evidence which DPoS slashes on,
+// the evidence has been verified by the consensus engine before the block is committed
+type DoubleSignEvidence struct {
+	NodeId string `json:"node_id"`
+	Height uint64 `json:"height"`
+	Round  int32  `json:"round"`
+}
+
+// economicsConfig the rewards and slashing parameters parsed from consensus ext config
+type economicsConfig struct {
+	rewardPoolAddr          string
+	epochReward             *big.Int
+	commissionRate          uint64
+	missedProposalThreshold uint64
+	missedProposalSlashRate uint64
+	doubleSignSlashRate     uint64
+	jailEpochs              uint64
+	blocksPerProposer       uint64
+	election                *election.Config
+}
+
+// enabled whether the epoch statistics are needed, double sign evidences
+// are always handled as they only exist when the consensus engine records them
+func (conf *economicsConfig) enabled() bool {
+	return (conf.rewardPoolAddr != "" && conf.epochReward.Sign() > 0) || conf.missedProposalThreshold > 0
+}
+
+// validatorEntry a validator of the stake contract with its state key
+type validatorEntry struct {
+	key []byte
+	val *syscontract.Validator
+}
+
+// epochStats the proposals and misbehaviours of the validators in an epoch, keyed by validator address,
+// the proposals are kept in the state under keyEpochStats
+type epochStats struct {
+	Proposed    map[string]uint64 `json:"proposed"`
+	Missed      map[string]uint64 `json:"missed"`
+	doubleSigns map[string]struct{}
+	// evidenceKeys the state keys of the evidences slashed on, which are removed at the end of the epoch
+	evidenceKeys [][]byte
+}
+
+func (impl *DPoSImpl) getEconomicsConfig() (*economicsConfig, error) {
+	conf := &economicsConfig{
+		epochReward:       big.NewInt(0),
+		jailEpochs:        defaultJailEpochs,
+		blocksPerProposer: 1,
+	}
+	parseRate := func(key, value string) (uint64, error) {
+		rate, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || rate > 100 {
+			return 0, fmt.Errorf("invalid %s: %s, should be a percent in [0, 100]", key, value)
+		}
+		return rate, nil
+	}
+
+	var err error
+	for _, kv := range impl.chainConf.ChainConfig().Consensus.ExtConfig {
+		value := string(kv.Value)
+		switch kv.Key {
+		case RewardPoolAddr:
+			conf.rewardPoolAddr = value
+		case EpochReward:
+			reward, ok := big.NewInt(0).SetString(value, 10)
+			if !ok || reward.Sign() < 0 {
+				return nil, fmt.Errorf("invalid %s: %s", EpochReward, value)
+			}
+			conf.epochReward = reward
+		case RewardCommissionRate:
+			conf.commissionRate, err = parseRate(kv.Key, value)
+		case MissedProposalSlashRate:
+			conf.missedProposalSlashRate, err = parseRate(kv.Key, value)
+		case DoubleSignSlashRate:
+			conf.doubleSignSlashRate, err = parseRate(kv.Key, value)
+		case MissedProposalThreshold:
+			if conf.missedProposalThreshold, err = strconv.ParseUint(value, 10, 64); err != nil {
+				err = fmt.Errorf("invalid %s: %s", MissedProposalThreshold, value)
+			}
+		case JailEpochs:
+			if conf.jailEpochs, err = strconv.ParseUint(value, 10, 64); err != nil || conf.jailEpochs == 0 {
+				err = fmt.Errorf("invalid %s: %s", JailEpochs, value)
+			}
+		case protocol.TBFT_blocks_per_proposer:
+			if conf.blocksPerProposer, err = strconv.ParseUint(value, 10, 64); err != nil ||
+				conf.blocksPerProposer == 0 {
+				err = fmt.Errorf("invalid %s: %s", protocol.TBFT_blocks_per_proposer, value)
+			}
+		}
+		if err != nil {
+			impl.log.Errorf("parse dpos economics config failed, reason: %s", err)
+			return nil, err
+		}
+	}
+	if conf.election, err = election.ParseConfig(impl.chainConf.ChainConfig().Consensus.ExtConfig); err != nil {
+		impl.log.Errorf("parse dpos economics config failed, reason: %s", err)
+		return nil, err
+	}
+	return conf, nil
+}
+
+// createEconomicsRwSet create the rwSet which rewards the validators of the ending epoch, slashes and jails
+// the misbehaving ones and releases the validators whose jail term ends. The balances written by pending
+// are taken as the latest balances. The validators updated are returned for the election of the next epoch.
+func (impl *DPoSImpl) createEconomicsRwSet(epoch *syscontract.Epoch, conf *economicsConfig, stats *epochStats,
+	block *commonpb.Block, blockTxRwSet map[string]*commonpb.TxRWSet, pending *commonpb.TxRWSet) (
+	*commonpb.TxRWSet, map[string]*syscontract.Validator, error) {
+
+	if err := impl.collectDoubleSigns(epoch, stats); err != nil {
+		return nil, nil, err
+	}
+	rwSet := &commonpb.TxRWSet{TxId: ModuleName}
+	// the proposals of the next epoch are counted from scratch
+	if conf.enabled() {
+		rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{
+			ContractName: syscontract.SystemContract_DPOS_STAKE.String(),
+			Key:          []byte(keyEpochStats),
+			Value:        nil,
+		})
+	}
+	for _, key := range stats.evidenceKeys {
+		rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{
+			ContractName: DoubleSignEvidenceContract,
+			Key:          key,
+			Value:        nil,
+		})
+	}
+	if !conf.enabled() && len(stats.doubleSigns) == 0 && !impl.hasJailedByDPoS() {
+		return rwSet, nil, nil
+	}
+
+	entries, err := impl.getAllValidators(block, blockTxRwSet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the writes of economics are appended to a copy of pending, which keeps the latest balances
+	pending = &commonpb.TxRWSet{TxWrites: append([]*commonpb.TxWrite{}, pending.TxWrites...)}
+	slashRwSet, slashed, err := impl.createSlashRwSet(epoch, conf, stats, entries, block, blockTxRwSet, pending)
+	if err != nil {
+		return nil, nil, err
+	}
+	rwSet.TxWrites = append(rwSet.TxWrites, slashRwSet.TxWrites...)
+
+	rewardRwSet, err := impl.createRewardRwSet(conf, stats, entries, slashed, block, blockTxRwSet, pending)
+	if err != nil {
+		return nil, nil, err
+	}
+	rwSet.TxWrites = append(rwSet.TxWrites, rewardRwSet.TxWrites...)
+
+	releaseRwSet, err := impl.createJailReleaseRwSet(epoch, entries, slashed, block, blockTxRwSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	rwSet.TxWrites = append(rwSet.TxWrites, releaseRwSet.TxWrites...)
+
+	updated := make(map[string]*syscontract.Validator, len(entries))
+	for _, addr := range sortedValidatorAddrs(entries) {
+		entry := entries[addr]
+		bz, err := proto.Marshal(entry.val)
+		if err != nil {
+			impl.log.Errorf("marshal validator failed, reason: %s", err)
+			return nil, nil, err
+		}
+		if !impl.validatorChanged(entry, bz, block, blockTxRwSet) {
+			continue
+		}
+		rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{
+			ContractName: syscontract.SystemContract_DPOS_STAKE.String(),
+			Key:          entry.key,
+			Value:        bz,
+		})
+		updated[addr] = entry.val
+	}
+	if len(rwSet.TxWrites) > 0 {
+		impl.log.Debugf("economics rwSet: %s", rwSet.String())
+	}
+	return rwSet, updated, nil
+}
+
+// countProposals add the proposals of the block to the proposals counted in the epoch. The block is proposed in
+// the round recorded in its consensus args, and the proposers of the earlier rounds at the same height, elected
+// in the same way as the TBFT validator set, have missed their proposals.
+func (impl *DPoSImpl) countProposals(epoch *syscontract.Epoch, height uint64, round int64, preBlkHash []byte,
+	conf *economicsConfig) (*epochStats, error) {
+
+	stats := &epochStats{
+		Proposed:    make(map[string]uint64),
+		Missed:      make(map[string]uint64),
+		doubleSigns: make(map[string]struct{}),
+	}
+	if !conf.enabled() || len(epoch.ProposerVector) == 0 {
+		return stats, nil
+	}
+	if round < 0 || round > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid round %d in consensus args of block %d", round, height)
+	}
+	bz, err := impl.stateDB.ReadObject(syscontract.SystemContract_DPOS_STAKE.String(), []byte(keyEpochStats))
+	if err != nil {
+		impl.log.Errorf("load epoch stats from db failed, reason: %s", err)
+		return nil, err
+	}
+	if len(bz) > 0 {
+		if err = json.Unmarshal(bz, stats); err != nil {
+			impl.log.Errorf("unmarshal epoch stats failed, reason: %s", err)
+			return nil, err
+		}
+	}
+
+	nodeIDs, err := GetNodeIDsFromValidators(impl.stateDB, epoch.ProposerVector)
+	if err != nil {
+		return nil, err
+	}
+	nodeToValidator := make(map[string]string, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		nodeToValidator[nodeID] = epoch.ProposerVector[i]
+	}
+	proposerElection, validators, err := impl.getProposerElection(epoch, nodeIDs, conf)
+	if err != nil {
+		return nil, err
+	}
+	for r := int32(0); r <= int32(round); r++ {
+		proposer := proposerElection.Proposer(validators, height, r, conf.blocksPerProposer, preBlkHash)
+		if r == int32(round) {
+			stats.Proposed[nodeToValidator[proposer]]++
+		} else {
+			stats.Missed[nodeToValidator[proposer]]++
+		}
+	}
+	return stats, nil
+}
+
+// createEpochStatsRwSet write the proposals counted in the epoch, nil if the economics are disabled
+func (impl *DPoSImpl) createEpochStatsRwSet(stats *epochStats) (*commonpb.TxRWSet, error) {
+	if len(stats.Proposed) == 0 && len(stats.Missed) == 0 {
+		return nil, nil
+	}
+	bz, err := json.Marshal(stats)
+	if err != nil {
+		impl.log.Errorf("marshal epoch stats failed, reason: %s", err)
+		return nil, err
+	}
+	return &commonpb.TxRWSet{
+		TxId: ModuleName,
+		TxWrites: []*commonpb.TxWrite{{
+			ContractName: syscontract.SystemContract_DPOS_STAKE.String(),
+			Key:          []byte(keyEpochStats),
+			Value:        bz,
+		}},
+	}, nil
+}
+
+// collectDoubleSigns collect the validators of the epoch which double signed from the evidences committed
+// and not slashed on yet. The evidences committed by the block ending the epoch are handled in the next epoch.
+func (impl *DPoSImpl) collectDoubleSigns(epoch *syscontract.Epoch, stats *epochStats) error {
+	iterRange := util.BytesPrefix([]byte(keyEvidenceHeightPrefix))
+	iter, err := impl.stateDB.SelectObject(DoubleSignEvidenceContract, iterRange.Start, iterRange.Limit)
+	if err != nil {
+		impl.log.Errorf("read double sign evidences failed, reason: %s", err)
+		return err
+	}
+	defer iter.Release()
+
+	var evidences []*DoubleSignEvidence
+	for iter.Next() {
+		kv, err := iter.Value()
+		if err != nil {
+			impl.log.Errorf("iterator read error: %s", err)
+			return err
+		}
+		evidence := &DoubleSignEvidence{}
+		if err = json.Unmarshal(kv.Value, evidence); err != nil {
+			impl.log.Errorf("unmarshal double sign evidence %s failed, reason: %s", kv.Key, err)
+			return err
+		}
+		evidences = append(evidences, evidence)
+		stats.evidenceKeys = append(stats.evidenceKeys, kv.Key)
+	}
+	if len(evidences) == 0 || len(epoch.ProposerVector) == 0 {
+		return nil
+	}
+
+	nodeIDs, err := GetNodeIDsFromValidators(impl.stateDB, epoch.ProposerVector)
+	if err != nil {
+		return err
+	}
+	nodeToValidator := make(map[string]string, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		nodeToValidator[nodeID] = epoch.ProposerVector[i]
+	}
+	for _, evidence := range evidences {
+		if addr, ok := nodeToValidator[evidence.NodeId]; ok {
+			stats.doubleSigns[addr] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// getProposerElection returns the proposer election and the validators with voting powers of the epoch,
+// which are the same as the TBFT validator set. The stakes are those snapshotted when the epoch was created.
+func (impl *DPoSImpl) getProposerElection(epoch *syscontract.Epoch, nodeIDs []string, conf *economicsConfig) (
+	election.Election, []*election.Validator, error) {
+	proposerElection, err := election.Get(conf.election.Election)
+	if err != nil {
+		return nil, nil, err
+	}
+	powers := conf.election.VotingPowers(nodeIDs)
+	if conf.election.StakeWeighted {
+		if powers, err = GetEpochStakeVotingPowers(impl.stateDB, epoch, nodeIDs); err != nil {
+			return nil, nil, err
+		}
+	}
+	return proposerElection, election.NewValidators(nodeIDs, powers), nil
+}
+
+// createSlashRwSet slash the bonded tokens of the validators which double signed or missed too many proposals
+// to the reward pool, and jail them for the configured epochs. The slashed validator addresses are returned.
+func (impl *DPoSImpl) createSlashRwSet(epoch *syscontract.Epoch, conf *economicsConfig, stats *epochStats,
+	entries map[string]*validatorEntry, block *commonpb.Block, blockTxRwSet map[string]*commonpb.TxRWSet,
+	pending *commonpb.TxRWSet) (*commonpb.TxRWSet, map[string]struct{}, error) {
+
+	rwSet := &commonpb.TxRWSet{TxId: ModuleName}
+	slashed := make(map[string]struct{})
+	stakeContractAddr := dposmgr.StakeContractAddr()
+	releaseEpoch := make([]byte, 8)
+	binary.BigEndian.PutUint64(releaseEpoch, epoch.EpochId+1+conf.jailEpochs)
+
+	for _, addr := range sortedValidatorAddrs(entries) {
+		var rate uint64
+		_, doubleSigned := stats.doubleSigns[addr]
+		if doubleSigned {
+			rate = conf.doubleSignSlashRate
+		} else if conf.missedProposalThreshold > 0 && stats.Missed[addr] >= conf.missedProposalThreshold {
+			rate = conf.missedProposalSlashRate
+		} else {
+			continue
+		}
+		entry := entries[addr]
+		slashed[addr] = struct{}{}
+		entry.val.Jailed = true
+		rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{
+			ContractName: syscontract.SystemContract_DPOS_STAKE.String(),
+			Key:          toJailReleaseKey(addr),
+			Value:        releaseEpoch,
+		})
+		impl.log.Infof("jail validator %s until epoch %d, double signed: %v, missed proposals: %d",
+			addr, epoch.EpochId+1+conf.jailEpochs, doubleSigned, stats.Missed[addr])
+
+		if conf.rewardPoolAddr == "" || rate == 0 {
+			continue
+		}
+		tokens, ok := big.NewInt(0).SetString(entry.val.Tokens, 10)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid tokens of validator %s: %s", addr, entry.val.Tokens)
+		}
+		amount := big.NewInt(0).Mul(tokens, big.NewInt(int64(rate)))
+		amount.Div(amount, big.NewInt(100))
+		if amount.Sign() == 0 {
+			continue
+		}
+		entry.val.Tokens = tokens.Sub(tokens, amount).String()
+		if err := impl.transferRwSet(stakeContractAddr, conf.rewardPoolAddr, amount,
+			rwSet, pending, block, blockTxRwSet); err != nil {
+			return nil, nil, err
+		}
+		impl.log.Infof("slash validator %s tokens: %s, remain: %s", addr, amount.String(), entry.val.Tokens)
+	}
+	return rwSet, slashed, nil
+}
+
+// createRewardRwSet distribute the epoch reward from the reward pool to the validators by the blocks they
+// proposed in the epoch. The commission is paid to the validator address, and the rest is bonded to the
+// validator, which raises the value of its delegators' shares.
+func (impl *DPoSImpl) createRewardRwSet(conf *economicsConfig, stats *epochStats,
+	entries map[string]*validatorEntry, slashed map[string]struct{}, block *commonpb.Block,
+	blockTxRwSet map[string]*commonpb.TxRWSet, pending *commonpb.TxRWSet) (*commonpb.TxRWSet, error) {
+
+	rwSet := &commonpb.TxRWSet{TxId: ModuleName}
+	if conf.rewardPoolAddr == "" || conf.epochReward.Sign() == 0 {
+		return rwSet, nil
+	}
+
+	total := uint64(0)
+	for addr, proposed := range stats.Proposed {
+		if _, ok := slashed[addr]; ok {
+			continue
+		}
+		if _, ok := entries[addr]; ok {
+			total += proposed
+		}
+	}
+	if total == 0 {
+		return rwSet, nil
+	}
+
+	epochReward := big.NewInt(0).Set(conf.epochReward)
+	poolBalance, err := impl.latestBalanceOf(conf.rewardPoolAddr, pending, block, blockTxRwSet)
+	if err != nil {
+		return nil, err
+	}
+	if poolBalance.Cmp(epochReward) < 0 {
+		impl.log.Warnf("reward pool balance %s is less than epoch reward %s", poolBalance.String(),
+			epochReward.String())
+		epochReward = poolBalance
+	}
+
+	stakeContractAddr := dposmgr.StakeContractAddr()
+	for _, addr := range sortedValidatorAddrs(entries) {
+		if _, ok := slashed[addr]; ok || stats.Proposed[addr] == 0 {
+			continue
+		}
+		entry := entries[addr]
+		reward := big.NewInt(0).Mul(epochReward, big.NewInt(0).SetUint64(stats.Proposed[addr]))
+		reward.Div(reward, big.NewInt(0).SetUint64(total))
+		commission := big.NewInt(0).Mul(reward, big.NewInt(int64(conf.commissionRate)))
+		commission.Div(commission, big.NewInt(100))
+		delegated := big.NewInt(0).Sub(reward, commission)
+
+		if commission.Sign() > 0 {
+			if err = impl.transferRwSet(conf.rewardPoolAddr, addr, commission,
+				rwSet, pending, block, blockTxRwSet); err != nil {
+				return nil, err
+			}
+		}
+		if delegated.Sign() > 0 {
+			tokens, ok := big.NewInt(0).SetString(entry.val.Tokens, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid tokens of validator %s: %s", addr, entry.val.Tokens)
+			}
+			entry.val.Tokens = tokens.Add(tokens, delegated).String()
+			if err = impl.transferRwSet(conf.rewardPoolAddr, stakeContractAddr, delegated,
+				rwSet, pending, block, blockTxRwSet); err != nil {
+				return nil, err
+			}
+		}
+		impl.log.Debugf("reward validator %s, proposed: %d, commission: %s, delegated: %s",
+			addr, stats.Proposed[addr], commission.String(), delegated.String())
+	}
+	return rwSet, nil
+}
+
+// createJailReleaseRwSet unjail the validators jailed by DPoS whose jail term ends with the ending epoch
+func (impl *DPoSImpl) createJailReleaseRwSet(epoch *syscontract.Epoch, entries map[string]*validatorEntry,
+	slashed map[string]struct{}, block *commonpb.Block,
+	blockTxRwSet map[string]*commonpb.TxRWSet) (*commonpb.TxRWSet, error) {
+
+	rwSet := &commonpb.TxRWSet{TxId: ModuleName}
+	for _, addr := range sortedValidatorAddrs(entries) {
+		entry := entries[addr]
+		if _, ok := slashed[addr]; ok || !entry.val.Jailed {
+			continue
+		}
+		releaseBz, err := impl.getState(syscontract.SystemContract_DPOS_STAKE.String(),
+			toJailReleaseKey(addr), block, blockTxRwSet)
+		if err != nil {
+			return nil, err
+		}
+		// jailed by the stake contract, not by DPoS
+		if len(releaseBz) != 8 {
+			continue
+		}
+		if binary.BigEndian.Uint64(releaseBz) > epoch.EpochId+1 {
+			continue
+		}
+		entry.val.Jailed = false
+		rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{
+			ContractName: syscontract.SystemContract_DPOS_STAKE.String(),
+			Key:          toJailReleaseKey(addr),
+			Value:        nil,
+		})
+		impl.log.Infof("release jailed validator %s in epoch %d", addr, epoch.EpochId+1)
+	}
+	return rwSet, nil
+}
+
+// hasJailedByDPoS whether any validator is still jailed by DPoS and may be released
+func (impl *DPoSImpl) hasJailedByDPoS() bool {
+	iterRange := util.BytesPrefix([]byte(keyJailReleasePrefix))
+	iter, err := impl.stateDB.SelectObject(
+		syscontract.SystemContract_DPOS_STAKE.String(), iterRange.Start, iterRange.Limit)
+	if err != nil {
+		impl.log.Warnf("select jailed validators failed, reason: %s", err)
+		return false
+	}
+	defer iter.Release()
+	return iter.Next()
+}
+
+// getAllValidators read all validators of the stake contract, the states written by the txs of block are applied
+func (impl *DPoSImpl) getAllValidators(block *commonpb.Block,
+	blockTxRwSet map[string]*commonpb.TxRWSet) (map[string]*validatorEntry, error) {
+
+	iterRange := util.BytesPrefix(dposmgr.ToValidatorPrefix())
+	iter, err := impl.stateDB.SelectObject(
+		syscontract.SystemContract_DPOS_STAKE.String(), iterRange.Start, iterRange.Limit)
+	if err != nil {
+		impl.log.Errorf("read contract: %s error: %s", syscontract.SystemContract_DPOS_STAKE.String(), err)
+		return nil, err
+	}
+	defer iter.Release()
+
+	entries := make(map[string]*validatorEntry)
+	for iter.Next() {
+		kv, err := iter.Value()
+		if err != nil {
+			impl.log.Errorf("iterator read error: %s", err)
+			return nil, err
+		}
+		value, err := impl.getState(syscontract.SystemContract_DPOS_STAKE.String(), kv.Key, block, blockTxRwSet)
+		if err != nil {
+			return nil, err
+		}
+		if len(value) == 0 {
+			continue
+		}
+		val := &syscontract.Validator{}
+		if err = proto.Unmarshal(value, val); err != nil {
+			impl.log.Errorf("unmarshal validator failed, reason: %s", err)
+			return nil, err
+		}
+		entries[val.ValidatorAddress] = &validatorEntry{key: kv.Key, val: val}
+	}
+	return entries, nil
+}
+
+// validatorChanged whether the marshaled validator differs from its state before the economics applied
+func (impl *DPoSImpl) validatorChanged(entry *validatorEntry, bz []byte, block *commonpb.Block,
+	blockTxRwSet map[string]*commonpb.TxRWSet) bool {
+	before, err := impl.getState(syscontract.SystemContract_DPOS_STAKE.String(), entry.key, block, blockTxRwSet)
+	if err != nil {
+		return true
+	}
+	return string(before) != string(bz)
+}
+
+// transferRwSet move amount from one address to another, the writes are appended to both rwSet and pending
+func (impl *DPoSImpl) transferRwSet(from, to string, amount *big.Int, rwSet, pending *commonpb.TxRWSet,
+	block *commonpb.Block, blockTxRwSet map[string]*commonpb.TxRWSet) error {
+
+	fromBalance, err := impl.latestBalanceOf(from, pending, block, blockTxRwSet)
+	if err != nil {
+		return err
+	}
+	subWrite, _, err := impl.subBalanceRwSet(from, fromBalance, amount.String())
+	if err != nil {
+		return err
+	}
+	rwSet.TxWrites = append(rwSet.TxWrites, subWrite)
+	pending.TxWrites = append(pending.TxWrites, subWrite)
+
+	toBalance, err := impl.latestBalanceOf(to, pending, block, blockTxRwSet)
+	if err != nil {
+		return err
+	}
+	addWrite, _, err := impl.addBalanceRwSet(to, toBalance, amount.String())
+	if err != nil {
+		return err
+	}
+	rwSet.TxWrites = append(rwSet.TxWrites, addWrite)
+	pending.TxWrites = append(pending.TxWrites, addWrite)
+	return nil
+}
+
+// latestBalanceOf the balance of addr, taking the balances written by pending first
+func (impl *DPoSImpl) latestBalanceOf(addr string, pending *commonpb.TxRWSet, block *commonpb.Block,
+	blockTxRwSet map[string]*commonpb.TxRWSet) (*big.Int, error) {
+	key := dposmgr.BalanceKey(addr)
+	for i := len(pending.TxWrites) - 1; i >= 0; i-- {
+		txWrite := pending.TxWrites[i]
+		if txWrite.ContractName != syscontract.SystemContract_DPOS_ERC20.String() || string(txWrite.Key) != key {
+			continue
+		}
+		balance, ok := big.NewInt(0).SetString(string(txWrite.Value), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount: %s", txWrite.Value)
+		}
+		return balance, nil
+	}
+	return impl.balanceOf(addr, block, blockTxRwSet)
+}
+
+func (impl *DPoSImpl) getEpochBlockNum() (uint64, error) {
+	epochBlockNumBz, err := impl.stateDB.ReadObject(
+		syscontract.SystemContract_DPOS_STAKE.String(), []byte(dposmgr.KeyEpochBlockNumber))
+	if err != nil {
+		impl.log.Errorf("load epochBlockNum from db failed, reason: %s", err)
+		return 0, err
+	}
+	if len(epochBlockNumBz) != 8 {
+		return 0, fmt.Errorf("invalid epochBlockNum in stake contract: %x", epochBlockNumBz)
+	}
+	return binary.BigEndian.Uint64(epochBlockNumBz), nil
+}
+
+func sortedValidatorAddrs(entries map[string]*validatorEntry) []string {
+	addrs := make([]string, 0, len(entries))
+	for addr := range entries {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+func toJailReleaseKey(validatorAddr string) []byte {
+	return []byte(keyJailReleasePrefix + validatorAddr)
+}
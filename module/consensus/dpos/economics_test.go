@@ -0,0 +1,107 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"chainmaker.org/chainmaker/vm-native/v2/dposmgr"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundProposer(t *testing.T) {
+	proposerElection, err := election.Get(election.RoundRobin)
+	require.Nil(t, err)
+	validators := election.NewValidators([]string{"node1", "node2", "node3"}, nil)
+	require.Equal(t, "node3", proposerElection.Proposer(validators, 1, 0, 1, nil))
+	require.Equal(t, "node1", proposerElection.Proposer(validators, 1, 1, 1, nil))
+	require.Equal(t, "node1", proposerElection.Proposer(validators, 2, 0, 1, nil))
+	require.Equal(t, "node2", proposerElection.Proposer(validators, 2, 0, 2, nil))
+}
+
+func TestDPoSImpl_CreateRewardRwSet(t *testing.T) {
+	impl, fn := initTestImpl(t)
+	defer fn()
+
+	conf := &economicsConfig{
+		rewardPoolAddr: testAddr,
+		epochReward:    big.NewInt(1000),
+		commissionRate: 10,
+	}
+	stats := &epochStats{Proposed: map[string]uint64{"val1": 3, "val2": 1, "val3": 2}}
+	entries := map[string]*validatorEntry{
+		"val1": {val: &syscontract.Validator{ValidatorAddress: "val1", Tokens: "100"}},
+		"val2": {val: &syscontract.Validator{ValidatorAddress: "val2", Tokens: "100"}},
+		"val3": {val: &syscontract.Validator{ValidatorAddress: "val3", Tokens: "100"}},
+	}
+	slashed := map[string]struct{}{"val3": {}}
+	pending := &common.TxRWSet{}
+
+	rwSet, err := impl.createRewardRwSet(conf, stats, entries, slashed, &common.Block{}, nil, pending)
+	require.NoError(t, err)
+	require.NotEmpty(t, rwSet.TxWrites)
+
+	// 1. the slashed validator is not rewarded
+	require.Equal(t, "100", entries["val3"].val.Tokens)
+	// 2. rewards are shared by the proposed blocks, 10% commission is paid to the validator
+	require.Equal(t, "775", entries["val1"].val.Tokens)
+	require.Equal(t, "325", entries["val2"].val.Tokens)
+	balance, err := impl.latestBalanceOf("val1", pending, &common.Block{}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 75, balance.Int64())
+	// 3. the reward is paid by the pool
+	balance, err = impl.latestBalanceOf(testAddr, pending, &common.Block{}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, testAddrBalance-1000, balance.Int64())
+	balance, err = impl.latestBalanceOf(dposmgr.StakeContractAddr(), pending, &common.Block{}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 10000+675+225, balance.Int64())
+}
+
+func TestDPoSImpl_CreateSlashRwSet(t *testing.T) {
+	impl, fn := initTestImpl(t)
+	defer fn()
+
+	conf := &economicsConfig{
+		rewardPoolAddr:          testAddr,
+		epochReward:             big.NewInt(0),
+		missedProposalThreshold: 2,
+		missedProposalSlashRate: 10,
+		doubleSignSlashRate:     50,
+		jailEpochs:              2,
+	}
+	stats := &epochStats{
+		Missed:      map[string]uint64{"val1": 2, "val2": 1},
+		doubleSigns: map[string]struct{}{"val3": {}},
+	}
+	entries := map[string]*validatorEntry{
+		"val1": {val: &syscontract.Validator{ValidatorAddress: "val1", Tokens: "1000"}},
+		"val2": {val: &syscontract.Validator{ValidatorAddress: "val2", Tokens: "1000"}},
+		"val3": {val: &syscontract.Validator{ValidatorAddress: "val3", Tokens: "1000"}},
+	}
+	pending := &common.TxRWSet{}
+
+	_, slashed, err := impl.createSlashRwSet(&syscontract.Epoch{EpochId: 5}, conf, stats, entries,
+		&common.Block{}, nil, pending)
+	require.NoError(t, err)
+	require.Len(t, slashed, 2)
+	require.True(t, entries["val1"].val.Jailed)
+	require.Equal(t, "900", entries["val1"].val.Tokens)
+	require.False(t, entries["val2"].val.Jailed)
+	require.Equal(t, "1000", entries["val2"].val.Tokens)
+	require.True(t, entries["val3"].val.Jailed)
+	require.Equal(t, "500", entries["val3"].val.Tokens)
+
+	balance, err := impl.latestBalanceOf(testAddr, pending, &common.Block{}, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, testAddrBalance+100+500, balance.Int64())
+}
@@ -0,0 +1,108 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dpos
+
+import (
+	"encoding/binary"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/consensus"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/gogo/protobuf/proto"
+)
+
+// The double sign evidences are recorded by the consensus engine in the consensus args of the block header,
+// so they are covered by the block hash. The store applies the writes in the consensus args to the state,
+// where an evidence is kept under two keys: the height key, which DPoS removes after slashing on the evidence,
+// and the id key, which prevents the same misbehavior from being committed twice.
+const (
+	// DoubleSignEvidenceContract the contract name of the state keys of the committed evidences
+	DoubleSignEvidenceContract = "DOUBLE_SIGN_EVIDENCE"
+
+	keyEvidenceHeightPrefix = "EVIDENCE_HEIGHT_"
+	keyEvidenceIdPrefix     = "EVIDENCE_ID_"
+)
+
+// DoubleSignEvidenceWrites return the writes which record the json encoded evidence committed at blockHeight
+func DoubleSignEvidenceWrites(blockHeight uint64, id string, evidence []byte) []*common.TxWrite {
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint64(height, blockHeight)
+	return []*common.TxWrite{
+		{
+			ContractName: DoubleSignEvidenceContract,
+			Key:          toEvidenceHeightKey(blockHeight, id),
+			Value:        evidence,
+		},
+		{
+			ContractName: DoubleSignEvidenceContract,
+			Key:          toEvidenceIdKey(id),
+			Value:        height,
+		},
+	}
+}
+
+// GetDoubleSignEvidences return the json encoded evidences recorded in the consensus args
+func GetDoubleSignEvidences(args *consensus.BlockHeaderConsensusArgs) [][]byte {
+	var evidences [][]byte
+	for _, txWrite := range getDoubleSignEvidenceWrites(args) {
+		if isEvidenceHeightKey(txWrite.Key) {
+			evidences = append(evidences, txWrite.Value)
+		}
+	}
+	return evidences
+}
+
+// IsDoubleSignEvidenceCommitted whether an evidence of the misbehavior has been committed
+func IsDoubleSignEvidenceCommitted(store protocol.BlockchainStore, id string) (bool, error) {
+	height, err := store.ReadObject(DoubleSignEvidenceContract, toEvidenceIdKey(id))
+	if err != nil {
+		return false, err
+	}
+	return len(height) > 0, nil
+}
+
+// GetConsensusArgs unmarshal the consensus args of the block header, an empty one is returned if absent
+func GetConsensusArgs(block *common.Block) (*consensus.BlockHeaderConsensusArgs, error) {
+	args := &consensus.BlockHeaderConsensusArgs{}
+	if len(block.Header.ConsensusArgs) == 0 {
+		return args, nil
+	}
+	if err := proto.Unmarshal(block.Header.ConsensusArgs, args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func getDoubleSignEvidenceWrites(args *consensus.BlockHeaderConsensusArgs) []*common.TxWrite {
+	if args == nil || args.ConsensusData == nil {
+		return nil
+	}
+	var txWrites []*common.TxWrite
+	for _, txWrite := range args.ConsensusData.TxWrites {
+		if txWrite.ContractName == DoubleSignEvidenceContract {
+			txWrites = append(txWrites, txWrite)
+		}
+	}
+	return txWrites
+}
+
+func toEvidenceHeightKey(blockHeight uint64, id string) []byte {
+	key := make([]byte, 0, len(keyEvidenceHeightPrefix)+8+len(id))
+	key = append(key, keyEvidenceHeightPrefix...)
+	key = append(key, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(key[len(keyEvidenceHeightPrefix):], blockHeight)
+	return append(key, id...)
+}
+
+func isEvidenceHeightKey(key []byte) bool {
+	return len(key) > len(keyEvidenceHeightPrefix)+8 && string(key[:len(keyEvidenceHeightPrefix)]) ==
+		keyEvidenceHeightPrefix
+}
+
+func toEvidenceIdKey(id string) []byte {
+	return []byte(keyEvidenceIdPrefix + id)
+}
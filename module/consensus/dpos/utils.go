@@ -8,11 +8,14 @@ package dpos
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"sort"
 	"strings"
 
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
 	"chainmaker.org/chainmaker/vm-native/v2/dposmgr"
 
 	configPb "chainmaker.org/chainmaker/pb-go/v2/config"
@@ -145,6 +148,67 @@ func GetNodeIDsFromValidators(store protocol.BlockchainStore, validators []strin
 	return nodeIDs, nil
 }
 
+// GetEpochInfoAtHeight returns the epoch of the block at height. The epoch created by the block at height h
+// covers the blocks after h, so the epochs are walked back from the latest one until the epoch created before height.
+func GetEpochInfoAtHeight(store protocol.BlockchainStore, height uint64) (*syscontract.Epoch, error) {
+	epoch, err := GetLatestEpochInfo(store)
+	if err != nil {
+		return nil, err
+	}
+	for epoch.EpochId > 0 {
+		key := dposmgr.ToEpochKey(fmt.Sprintf("%d", epoch.EpochId-1))
+		val, err := store.ReadObject(syscontract.SystemContract_DPOS_STAKE.String(), key)
+		if err != nil {
+			return nil, fmt.Errorf("read contract: %s key: %s, error: %s",
+				syscontract.SystemContract_DPOS_STAKE.String(), key, err)
+		}
+		if len(val) == 0 {
+			break
+		}
+		prev := &syscontract.Epoch{}
+		if err = proto.Unmarshal(val, prev); err != nil {
+			return nil, fmt.Errorf("unmarshal epoch failed, reason: %s", err)
+		}
+		if height > prev.NextEpochCreateHeight {
+			break
+		}
+		epoch = prev
+	}
+	return epoch, nil
+}
+
+// GetEpochStakeVotingPowers returns the voting powers of the validators of epoch scaled from the stakes
+// snapshotted when the epoch was created. The validators of the epochs without the snapshot, such as
+// the genesis epoch, have voting power 1.
+func GetEpochStakeVotingPowers(store protocol.BlockchainStore, epoch *syscontract.Epoch,
+	nodeIDs []string) (map[string]uint64, error) {
+	key := toEpochStakesKey(epoch.EpochId)
+	bz, err := store.ReadObject(syscontract.SystemContract_DPOS_STAKE.String(), key)
+	if err != nil {
+		return nil, fmt.Errorf("read the stakes of epoch[%d] failed, reason: %s", epoch.EpochId, err)
+	}
+	if len(bz) == 0 {
+		return (&election.Config{}).VotingPowers(nodeIDs), nil
+	}
+	var tokens []string
+	if err = json.Unmarshal(bz, &tokens); err != nil {
+		return nil, fmt.Errorf("unmarshal the stakes of epoch[%d] failed, reason: %s", epoch.EpochId, err)
+	}
+	stakes := make([]*big.Int, 0, len(tokens))
+	for i, token := range tokens {
+		stake, ok := big.NewInt(0).SetString(token, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid stake of the validator[%d] of epoch[%d]: %s", i, epoch.EpochId, token)
+		}
+		stakes = append(stakes, stake)
+	}
+	return election.StakeVotingPowers(nodeIDs, stakes)
+}
+
+func toEpochStakesKey(epochId uint64) []byte {
+	return []byte(fmt.Sprintf("%s%d", keyEpochStakesPrefix, epochId))
+}
+
 func GetChainConfig(store protocol.BlockchainStore) (*configPb.ChainConfig, error) {
 	var chainConfig configPb.ChainConfig
 	bytes, err := store.ReadObject(
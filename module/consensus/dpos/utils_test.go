@@ -214,3 +214,56 @@ func TestGetNodeIDsFromValidators(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, ids, []string{"nodeId1", "nodeId2", "nodeId3"})
 }
+
+func TestGetEpochInfoAtHeight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// epoch 1 is created by block 10, epoch 2 by block 20
+	epochs := map[string]*syscontract.Epoch{
+		string(dposmgr.ToEpochKey("0")): {EpochId: 0, NextEpochCreateHeight: 10},
+		string(dposmgr.ToEpochKey("1")): {EpochId: 1, NextEpochCreateHeight: 20},
+		dposmgr.KeyCurrentEpoch:         {EpochId: 2, NextEpochCreateHeight: 30},
+	}
+	mockStore := mock.NewMockBlockchainStore(ctrl)
+	mockStore.EXPECT().ReadObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(contractName string, key []byte) ([]byte, error) {
+			if epoch, ok := epochs[string(key)]; ok {
+				return epoch.Marshal()
+			}
+			return nil, nil
+		}).AnyTimes()
+	for height, epochId := range map[uint64]uint64{1: 0, 10: 0, 11: 1, 20: 1, 21: 2, 30: 2, 31: 2} {
+		epoch, err := GetEpochInfoAtHeight(mockStore, height)
+		require.NoError(t, err)
+		require.EqualValues(t, epochId, epoch.EpochId, "height %d", height)
+	}
+}
+
+func TestGetEpochStakeVotingPowers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stakes := map[string][]byte{
+		string(toEpochStakesKey(1)): []byte(`["300","100"]`),
+		string(toEpochStakesKey(2)): []byte(`["300","x"]`),
+	}
+	mockStore := mock.NewMockBlockchainStore(ctrl)
+	mockStore.EXPECT().ReadObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(contractName string, key []byte) ([]byte, error) {
+			return stakes[string(key)], nil
+		}).AnyTimes()
+
+	nodeIDs := []string{"node1", "node2"}
+	// the genesis epoch has no stakes snapshotted
+	powers, err := GetEpochStakeVotingPowers(mockStore, &syscontract.Epoch{EpochId: 0}, nodeIDs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"node1": 1, "node2": 1}, powers)
+
+	powers, err = GetEpochStakeVotingPowers(mockStore, &syscontract.Epoch{EpochId: 1}, nodeIDs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"node1": 300, "node2": 100}, powers)
+
+	_, err = GetEpochStakeVotingPowers(mockStore, &syscontract.Epoch{EpochId: 2}, nodeIDs)
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package gaslimit parses the block gas limit in the consensus ext config. It is shared by the core, which
+// enforces the limit on the blocks, and the consensus engines, which reject an invalid limit on chain config
+// updates.
+package gaslimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"chainmaker.org/chainmaker/pb-go/v2/config"
+)
+
+// BlockGasLimitKey the key of consensus ext config, the max gas used by all txs of a block,
+// 0 or not configured means unlimited
+const BlockGasLimitKey = "BLOCK_gas_limit"
+
+// ParseBlockGasLimit parses the block gas limit in the consensus ext config of the chain config
+func ParseBlockGasLimit(chainConfig *config.ChainConfig) (uint64, error) {
+	if chainConfig == nil || chainConfig.Consensus == nil {
+		return 0, nil
+	}
+	for _, kv := range chainConfig.Consensus.ExtConfig {
+		if kv.Key != BlockGasLimitKey {
+			continue
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(kv.Value)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %s", BlockGasLimitKey, kv.Value)
+		}
+		return limit, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gaslimit
+
+import (
+	"testing"
+
+	"chainmaker.org/chainmaker/pb-go/v2/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBlockGasLimit(t *testing.T) {
+	limit, err := ParseBlockGasLimit(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), limit)
+
+	chainConfig := &config.ChainConfig{Consensus: &config.ConsensusConfig{}}
+	limit, err = ParseBlockGasLimit(chainConfig)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), limit)
+
+	chainConfig.Consensus.ExtConfig = []*config.ConfigKeyValue{{Key: BlockGasLimitKey, Value: " 100000 "}}
+	limit, err = ParseBlockGasLimit(chainConfig)
+	require.NoError(t, err)
+	require.Equal(t, uint64(100000), limit)
+
+	for _, value := range []string{"-1", "abc", "", "1.5"} {
+		chainConfig.Consensus.ExtConfig = []*config.ConfigKeyValue{{Key: BlockGasLimitKey, Value: value}}
+		_, err = ParseBlockGasLimit(chainConfig)
+		require.Error(t, err, value)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 
+	"chainmaker.org/chainmaker-go/consensus/gaslimit"
 	"chainmaker.org/chainmaker/logger/v2"
 	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
 	configPb "chainmaker.org/chainmaker/pb-go/v2/config"
@@ -190,6 +191,10 @@ func checkChainConfig(chainConfig *configPb.ChainConfig,
 			if v < MinimumIntervalTimeOutMill {
 				return false, fmt.Errorf("set %s is too minimum, %d < %d", RoundTimeoutIntervalMill, v, MinimumIntervalTimeOutMill)
 			}
+		case gaslimit.BlockGasLimitKey:
+			if _, err = gaslimit.ParseBlockGasLimit(chainConfig); err != nil {
+				return false, err
+			}
 		}
 	}
 	return true, nil
@@ -24,11 +24,13 @@ import (
 	"github.com/thoas/go-funk"
 	"go.uber.org/zap"
 
+	"chainmaker.org/chainmaker-go/consensus/gaslimit"
 	"chainmaker.org/chainmaker/chainconf/v2"
 	commonErrors "chainmaker.org/chainmaker/common/v2/errors"
 	"chainmaker.org/chainmaker/common/v2/msgbus"
 	"chainmaker.org/chainmaker/localconf/v2"
 	"chainmaker.org/chainmaker/logger/v2"
+	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
 	"chainmaker.org/chainmaker/pb-go/v2/common"
 	"chainmaker.org/chainmaker/pb-go/v2/config"
 	"chainmaker.org/chainmaker/pb-go/v2/consensus"
@@ -99,6 +101,8 @@ type ConsensusRaftImpl struct {
 	appliedIndex  uint64
 	proposedIndex uint64
 	idToNodeId    sync.Map
+	// promotingLearners the learners proposed to be promoted by the leader and the proposed time
+	promotingLearners map[uint64]time.Time
 
 	proposedBlockC chan *common.Block
 	verifyResultC  chan *consensus.VerifyResult
@@ -156,6 +160,7 @@ func New(config ConsensusRaftImplConfig) (*ConsensusRaftImpl, error) {
 	consensus.verifyResultC = make(chan *consensuspb.VerifyResult, DefaultChanCap)
 	consensus.blockInfoC = make(chan *common.BlockInfo, DefaultChanCap)
 	consensus.confChangeC = make(chan raftpb.ConfChange, DefaultChanCap)
+	consensus.promotingLearners = make(map[uint64]time.Time)
 	consensus.walSaveC = make(chan interface{}, DefaultChanCap)
 	consensus.blockVerifier = config.BlockVerifier
 	consensus.blockCommitter = config.BlockCommitter
@@ -353,6 +358,7 @@ func (consensus *ConsensusRaftImpl) serve() {
 		case <-ticker.C:
 			consensus.node.Tick()
 			consensus.logger.Debugf("[%x] status: %s", consensus.Id, consensus.node.Status())
+			consensus.maybePromoteLearners()
 		case ready := <-consensus.node.Ready():
 			if exit := consensus.NodeReady(ready); exit {
 				consensus.logger.Debugf("exit consensus when process ready message")
@@ -403,7 +409,11 @@ func (consensus *ConsensusRaftImpl) NodeReady(ready etcdraft.Ready) (exit bool)
 		consensus.maybeTriggerSnapshot(configChanged)
 	}
 	if ready.SoftState != nil {
-		consensus.isLeader = atomic.LoadUint64(&ready.SoftState.Lead) == consensus.Id
+		isLeader := atomic.LoadUint64(&ready.SoftState.Lead) == consensus.Id
+		if isLeader != consensus.isLeader {
+			consensus.promotingLearners = make(map[uint64]time.Time)
+		}
+		consensus.isLeader = isLeader
 	}
 	consensus.sendProposeState(consensus.isLeader)
 	return false
@@ -451,6 +461,13 @@ func (consensus *ConsensusRaftImpl) ProposeBlock(block *common.Block) {
 	data = mustMarshal(block)
 	consensus.logger.Debugf("[%x] propose block height：%+v", consensus.Id, block.Header.BlockHeight)
 	if err := consensus.node.Propose(context.TODO(), data); err != nil {
+		if err == etcdraft.ErrProposalDropped {
+			// the proposal is dropped when the leadership is being transferred,
+			// the block will be proposed again by the new leader
+			consensus.logger.Warnf("[%x] propose block(%d-%x) dropped", consensus.Id,
+				block.Header.BlockHeight, block.Header.BlockHash)
+			return
+		}
 		consensus.logger.Panicf("[%x] propose error: %v", consensus.Id, err)
 	}
 	consensus.proposedIndex = block.Header.BlockHeight
@@ -540,6 +557,7 @@ func (consensus *ConsensusRaftImpl) publishEntries(ents []raftpb.Entry) (ok bool
 				consensus.logger.Panicf("[%x] unmarshal config change error: %v", consensus.Id, err)
 			}
 			consensus.confState = *consensus.node.ApplyConfChange(cc)
+			delete(consensus.promotingLearners, cc.NodeID)
 			var idToNodes map[uint64]string
 			consensus.peers, idToNodes = consensus.getPeersFromChainConf()
 			for id, node := range idToNodes {
@@ -765,7 +783,8 @@ func (consensus *ConsensusRaftImpl) sendProposeState(isProposer bool) {
 func (consensus *ConsensusRaftImpl) Verify(
 	consensusType consensuspb.ConsensusType,
 	chainConfig *config.ChainConfig) error {
-	return nil
+	_, err := gaslimit.ParseBlockGasLimit(chainConfig)
+	return err
 }
 
 func (consensus *ConsensusRaftImpl) getPeersFromChainConf() ([]uint64, map[uint64]string) {
@@ -808,9 +827,11 @@ func (consensus *ConsensusRaftImpl) processConfigChange() bool {
 			}
 			consensus.confChangeC <- cc
 		}
+		// the added nodes join as learners, and are promoted to voters after catching up,
+		// so that the quorum is not affected by the nodes which have not synced the log
 		for _, node := range added {
 			cc := raftpb.ConfChange{
-				Type:   raftpb.ConfChangeAddNode,
+				Type:   raftpb.ConfChangeAddLearnerNode,
 				NodeID: node,
 			}
 			consensus.confChangeC <- cc
@@ -827,28 +848,82 @@ func (consensus *ConsensusRaftImpl) processConfigChange() bool {
 // is qulified with the consensus algorithm. It should return nil
 // error when verify successfully, and return corresponding error
 // when failed.
-func VerifyBlockSignatures(block *common.Block) error {
+// The block of raft is signed by the leader proposing it, so the signer
+// must be a consensus node in the chain config at the height of block,
+// and the signature must be valid over the block hash.
+func VerifyBlockSignatures(chainConf protocol.ChainConf, ac protocol.AccessControlProvider,
+	block *common.Block) error {
 	if block == nil || block.Header == nil ||
 		block.AdditionalData == nil || block.AdditionalData.ExtraData == nil {
 		return fmt.Errorf("invalid block")
 	}
+	height := block.Header.BlockHeight
 	byt, ok := block.AdditionalData.ExtraData[protocol.RAFTAddtionalDataKey]
 	if !ok {
-		return fmt.Errorf("block.AdditionalData.ExtraData[RAFTAddtionalDataKey] not exist")
+		return fmt.Errorf("block(%d).AdditionalData.ExtraData[RAFTAddtionalDataKey] not exist", height)
 	}
 
 	additionalData := &AdditionalData{}
-	json.Unmarshal(byt, additionalData)
-
+	if err := json.Unmarshal(byt, additionalData); err != nil {
+		return fmt.Errorf("unmarshal raft additional data of block(%d) failed: %v", height, err)
+	}
 	endorsement := new(common.EndorsementEntry)
-	mustUnmarshal(additionalData.Signature, endorsement)
-
+	if err := proto.Unmarshal(additionalData.Signature, endorsement); err != nil {
+		return fmt.Errorf("unmarshal endorsement of block(%d) failed: %v", height, err)
+	}
+	if endorsement.Signer == nil || len(endorsement.Signature) == 0 {
+		return fmt.Errorf("endorsement of block(%d) has no signer or signature", height)
+	}
 	if !bytes.Equal(block.Header.Signature, endorsement.Signature) {
-		return fmt.Errorf("block.AdditionalData.ExtraData[RAFTAddtionalDataKey] not exist")
+		return fmt.Errorf("signature of block(%d) header mismatches the endorsement", height)
+	}
+
+	chainConfig, err := chainConf.GetChainConfigFromFuture(height)
+	if err != nil {
+		return fmt.Errorf("get chain config at block(%d) failed: %v", height, err)
+	}
+	hash, err := utils.CalcBlockHash(chainConfig.Crypto.Hash, block)
+	if err != nil {
+		return fmt.Errorf("calc hash of block(%d) failed: %v", height, err)
+	}
+	if !bytes.Equal(hash, block.Header.BlockHash) {
+		return fmt.Errorf("hash of block(%d) mismatches, expect: %x, got: %x", height, hash, block.Header.BlockHash)
+	}
+	if err = verifySignerOrgIsConsensusOrg(chainConfig, endorsement.Signer); err != nil {
+		return fmt.Errorf("block(%d-%x) %v", height, block.Header.BlockHash, err)
+	}
+
+	principal, err := ac.CreatePrincipal(
+		protocol.ResourceNameConsensusNode,
+		[]*common.EndorsementEntry{endorsement},
+		block.Header.BlockHash,
+	)
+	if err != nil {
+		return fmt.Errorf("new principal of block(%d-%x) failed: %v", height, block.Header.BlockHash, err)
+	}
+	result, err := ac.VerifyPrincipal(principal)
+	if err != nil {
+		return fmt.Errorf("verify signature of block(%d-%x) failed: %v", height, block.Header.BlockHash, err)
+	}
+	if !result {
+		return fmt.Errorf("verify signature of block(%d-%x) failed: invalid signature or signer is not "+
+			"a consensus node", height, block.Header.BlockHash)
 	}
 	return nil
 }
 
+// verifySignerOrgIsConsensusOrg checks the org of signer has consensus nodes in chainConfig. The node id of
+// signer can not be derived from its sign key, which differs from the tls key of the node in cert mode, so
+// the signer is checked to be a consensus node by the access control with ResourceNameConsensusNode.
+func verifySignerOrgIsConsensusOrg(chainConfig *config.ChainConfig, signer *pbac.Member) error {
+	for _, org := range chainConfig.Consensus.Nodes {
+		if org.OrgId == signer.OrgId && len(org.NodeId) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("signer org [%s] has no consensus node", signer.OrgId)
+}
+
 func computeRaftIdFromNodeId(nodeId string) uint64 {
 	return uint64(binary.BigEndian.Uint64([]byte(nodeId[len(nodeId)-8:])))
 }
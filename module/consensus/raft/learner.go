@@ -0,0 +1,141 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	etcdraft "go.etcd.io/etcd/raft/v3"
+	"go.etcd.io/etcd/raft/v3/raftpb"
+	"go.etcd.io/etcd/raft/v3/tracker"
+)
+
+var (
+	// learnerCatchUpEntries a learner is promoted to voter once its log is within the entries of the commit index
+	learnerCatchUpEntries = uint64(10)
+	// learnerPromoteRetryInterval the promotion is proposed again if it is not applied in the interval,
+	// raft drops the config change proposed while another one is pending
+	learnerPromoteRetryInterval = 10 * time.Second
+	// transferLeadershipTimeout the max time to wait for the transferee becoming the leader
+	transferLeadershipTimeout = 10 * time.Second
+)
+
+// maybePromoteLearners proposes to promote the learners which have caught up with the leader,
+// it is called on every tick and does nothing if the node is not the leader
+func (consensus *ConsensusRaftImpl) maybePromoteLearners() {
+	if !consensus.isLeader {
+		return
+	}
+	status := consensus.node.Status()
+	if len(status.Config.Learners) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, id := range caughtUpLearners(status) {
+		if proposedAt, ok := consensus.promotingLearners[id]; ok && now.Sub(proposedAt) < learnerPromoteRetryInterval {
+			continue
+		}
+		consensus.promotingLearners[id] = now
+		consensus.logger.Infof("[%x] learner [%x] has caught up, match: %d, commit: %d, promote it to voter",
+			consensus.Id, id, status.Progress[id].Match, status.Commit)
+		consensus.confChangeC <- raftpb.ConfChange{
+			Type:   raftpb.ConfChangeAddNode,
+			NodeID: id,
+		}
+	}
+}
+
+// caughtUpLearners returns the learners replicating the log within learnerCatchUpEntries of the commit index
+func caughtUpLearners(status etcdraft.Status) []uint64 {
+	var ids []uint64
+	for id := range status.Config.Learners {
+		pr, ok := status.Progress[id]
+		if !ok || pr.State != tracker.StateReplicate {
+			continue
+		}
+		if pr.Match+learnerCatchUpEntries >= status.Commit {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// selectTransferee returns the voter with the longest log except the leader, the smaller id wins a tie
+func selectTransferee(status etcdraft.Status) uint64 {
+	var (
+		transferee = uint64(etcdraft.None)
+		maxMatch   uint64
+	)
+	for id := range status.Config.Voters.IDs() {
+		if id == status.Lead {
+			continue
+		}
+		pr, ok := status.Progress[id]
+		if !ok {
+			continue
+		}
+		if transferee == etcdraft.None || pr.Match > maxMatch || (pr.Match == maxMatch && id < transferee) {
+			transferee, maxMatch = id, pr.Match
+		}
+	}
+	return transferee
+}
+
+// TransferLeadership transfers the leadership to the voter nodeId, e.g. to drain the leader before
+// maintenance. If nodeId is empty, the node must be the leader and the leadership is transferred to
+// the voter with the longest log. It returns the node id of the new leader after the transfer.
+func (consensus *ConsensusRaftImpl) TransferLeadership(nodeId string) (string, error) {
+	status := consensus.node.Status()
+	if status.Lead == etcdraft.None {
+		return "", errors.New("there is no leader now")
+	}
+
+	var transferee uint64
+	if nodeId == "" {
+		if status.Lead != consensus.Id {
+			return "", errors.New("the node is not the leader, the node id of the transferee must be specified")
+		}
+		if transferee = selectTransferee(status); transferee == etcdraft.None {
+			return "", errors.New("there is no other voter to transfer the leadership to")
+		}
+	} else {
+		if len(nodeId) < 8 {
+			return "", fmt.Errorf("invalid node id [%s]", nodeId)
+		}
+		transferee = computeRaftIdFromNodeId(nodeId)
+		if _, ok := status.Config.Voters.IDs()[transferee]; !ok {
+			return "", fmt.Errorf("node [%s] is not a voter", nodeId)
+		}
+	}
+
+	if transferee != status.Lead {
+		consensus.logger.Infof("[%x] transfer leadership from [%x] to [%x]", consensus.Id, status.Lead, transferee)
+		ctx, cancel := context.WithTimeout(context.Background(), transferLeadershipTimeout)
+		defer cancel()
+		consensus.node.TransferLeadership(ctx, status.Lead, transferee)
+		for consensus.node.Status().Lead != transferee {
+			select {
+			case <-ctx.Done():
+				return "", fmt.Errorf("transfer leadership to [%x] timeout", transferee)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+
+	value, ok := consensus.idToNodeId.Load(transferee)
+	if !ok {
+		return "", fmt.Errorf("unknown node id of [%x]", transferee)
+	}
+	return value.(string), nil
+}
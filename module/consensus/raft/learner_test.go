@@ -0,0 +1,67 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package raft
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	etcdraft "go.etcd.io/etcd/raft/v3"
+	"go.etcd.io/etcd/raft/v3/quorum"
+	"go.etcd.io/etcd/raft/v3/tracker"
+)
+
+func newTestStatus(lead, commit uint64, voters, learners []uint64,
+	progress map[uint64]tracker.Progress) etcdraft.Status {
+	status := etcdraft.Status{Progress: progress}
+	status.Lead = lead
+	status.Commit = commit
+	status.Config.Voters = quorum.JointConfig{quorum.MajorityConfig{}, nil}
+	for _, id := range voters {
+		status.Config.Voters[0][id] = struct{}{}
+	}
+	if len(learners) > 0 {
+		status.Config.Learners = make(map[uint64]struct{})
+		for _, id := range learners {
+			status.Config.Learners[id] = struct{}{}
+		}
+	}
+	return status
+}
+
+func TestCaughtUpLearners(t *testing.T) {
+	status := newTestStatus(1, 100, []uint64{1, 2, 3}, []uint64{4, 5, 6, 7},
+		map[uint64]tracker.Progress{
+			1: {Match: 100, State: tracker.StateReplicate},
+			2: {Match: 100, State: tracker.StateReplicate},
+			3: {Match: 100, State: tracker.StateReplicate},
+			4: {Match: 95, State: tracker.StateReplicate},
+			5: {Match: 50, State: tracker.StateReplicate},
+			6: {Match: 100, State: tracker.StateSnapshot},
+			7: {Match: 90, State: tracker.StateReplicate},
+		})
+	require.Equal(t, []uint64{4, 7}, caughtUpLearners(status))
+
+	status = newTestStatus(1, 100, []uint64{1}, nil, map[uint64]tracker.Progress{1: {Match: 100}})
+	require.Empty(t, caughtUpLearners(status))
+}
+
+func TestSelectTransferee(t *testing.T) {
+	status := newTestStatus(1, 100, []uint64{1, 2, 3, 4}, []uint64{5},
+		map[uint64]tracker.Progress{
+			1: {Match: 100},
+			2: {Match: 98},
+			3: {Match: 99},
+			4: {Match: 99},
+			5: {Match: 100},
+		})
+	require.Equal(t, uint64(3), selectTransferee(status))
+
+	status = newTestStatus(1, 100, []uint64{1}, []uint64{2},
+		map[uint64]tracker.Progress{1: {Match: 100}, 2: {Match: 100}})
+	require.Equal(t, uint64(etcdraft.None), selectTransferee(status))
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package raft
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"chainmaker.org/chainmaker/common/v2/crypto"
+	"chainmaker.org/chainmaker/common/v2/crypto/asym"
+	"chainmaker.org/chainmaker/common/v2/helper"
+	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	configpb "chainmaker.org/chainmaker/pb-go/v2/config"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/protocol/v2/mock"
+	"chainmaker.org/chainmaker/utils/v2"
+)
+
+const (
+	testOrg1Id = "wx-org1"
+	testOrg2Id = "wx-org2"
+)
+
+// newTestPkSigner returns a public key member of orgId and the node id derived from another tls key,
+// like a node signing the blocks with a key which is not its tls key
+func newTestPkSigner(t *testing.T, orgId string) (*pbac.Member, string) {
+	signKey, err := asym.GenerateKeyPair(crypto.ECC_NISTP256)
+	require.Nil(t, err)
+	pkPem, err := signKey.PublicKey().String()
+	require.Nil(t, err)
+	tlsKey, err := asym.GenerateKeyPair(crypto.ECC_NISTP256)
+	require.Nil(t, err)
+	nodeId, err := helper.CreateLibp2pPeerIdWithPublicKey(tlsKey.PublicKey())
+	require.Nil(t, err)
+	return &pbac.Member{OrgId: orgId, MemberType: pbac.MemberType_PUBLIC_KEY, MemberInfo: []byte(pkPem)}, nodeId
+}
+
+func newTestChainConf(ctrl *gomock.Controller, org1NodeId string) *mock.MockChainConf {
+	chainConf := mock.NewMockChainConf(ctrl)
+	chainConf.EXPECT().GetChainConfigFromFuture(gomock.Any()).AnyTimes().Return(&configpb.ChainConfig{
+		Crypto: &configpb.CryptoConfig{Hash: "SHA256"},
+		Consensus: &configpb.ConsensusConfig{
+			Nodes: []*configpb.OrgConfig{
+				{OrgId: testOrg1Id, NodeId: []string{org1NodeId}},
+			},
+		},
+	}, nil)
+	return chainConf
+}
+
+func newTestBlock(t *testing.T, signer *pbac.Member) *commonpb.Block {
+	block := &commonpb.Block{
+		Header: &commonpb.BlockHeader{
+			ChainId:     "test",
+			BlockHeight: 10,
+		},
+	}
+	hash, err := utils.CalcBlockHash("SHA256", block)
+	require.Nil(t, err)
+	block.Header.BlockHash = hash
+	block.Header.Signature = []byte("signature")
+
+	data, err := json.Marshal(AdditionalData{
+		Signature: mustMarshal(&commonpb.EndorsementEntry{Signer: signer, Signature: block.Header.Signature}),
+	})
+	require.Nil(t, err)
+	block.AdditionalData = &commonpb.AdditionalData{
+		ExtraData: map[string][]byte{protocol.RAFTAddtionalDataKey: data},
+	}
+	return block
+}
+
+func TestVerifyBlockSignatures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	signer, nodeId := newTestPkSigner(t, testOrg1Id)
+	chainConf := newTestChainConf(ctrl, nodeId)
+	ac := mock.NewMockAccessControlProvider(ctrl)
+	ac.EXPECT().CreatePrincipal(protocol.ResourceNameConsensusNode, gomock.Any(), gomock.Any()).
+		AnyTimes().Return(nil, nil)
+	ac.EXPECT().VerifyPrincipal(gomock.Any()).Times(2).Return(true, nil)
+
+	// the sign key of the consensus node differs from its tls key
+	block := newTestBlock(t, signer)
+	require.Nil(t, VerifyBlockSignatures(chainConf, ac, block))
+
+	block = newTestBlock(t, &pbac.Member{OrgId: testOrg1Id, MemberType: pbac.MemberType_CERT_HASH,
+		MemberInfo: []byte("member1")})
+	require.Nil(t, VerifyBlockSignatures(chainConf, ac, block))
+
+	// the signer which is not a consensus node is rejected by the access control
+	ac.EXPECT().VerifyPrincipal(gomock.Any()).Return(false, nil)
+	require.NotNil(t, VerifyBlockSignatures(chainConf, ac, block))
+
+	ac.EXPECT().VerifyPrincipal(gomock.Any()).Return(false, errors.New("invalid signature"))
+	require.NotNil(t, VerifyBlockSignatures(chainConf, ac, block))
+}
+
+func TestVerifyBlockSignaturesInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	signer, nodeId := newTestPkSigner(t, testOrg1Id)
+	chainConf := newTestChainConf(ctrl, nodeId)
+	ac := mock.NewMockAccessControlProvider(ctrl)
+	ac.EXPECT().CreatePrincipal(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+	ac.EXPECT().VerifyPrincipal(gomock.Any()).AnyTimes().Return(true, nil)
+
+	tests := []struct {
+		name   string
+		modify func(block *commonpb.Block)
+	}{
+		{"nil additional data", func(block *commonpb.Block) {
+			block.AdditionalData = nil
+		}},
+		{"without raft data", func(block *commonpb.Block) {
+			delete(block.AdditionalData.ExtraData, protocol.RAFTAddtionalDataKey)
+		}},
+		{"malformed raft data", func(block *commonpb.Block) {
+			block.AdditionalData.ExtraData[protocol.RAFTAddtionalDataKey] = []byte("{")
+		}},
+		{"malformed endorsement", func(block *commonpb.Block) {
+			data, _ := json.Marshal(AdditionalData{Signature: []byte{0xff, 0xff}})
+			block.AdditionalData.ExtraData[protocol.RAFTAddtionalDataKey] = data
+		}},
+		{"signature mismatch", func(block *commonpb.Block) {
+			block.Header.Signature = []byte("other")
+		}},
+		{"hash mismatch", func(block *commonpb.Block) {
+			block.Header.TxCount = 1
+		}},
+		{"signer org without consensus node", func(block *commonpb.Block) {
+			*block = *newTestBlock(t, &pbac.Member{OrgId: testOrg2Id, MemberType: signer.MemberType,
+				MemberInfo: signer.MemberInfo})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := newTestBlock(t, signer)
+			tt.modify(block)
+			require.NotNil(t, VerifyBlockSignatures(chainConf, ac, block))
+		})
+	}
+}
@@ -68,6 +68,8 @@ func (consensus *ConsensusSoloImpl) Start() error {
 // Stop implements the Stop method of ConsensusEngine interface.
 // TODO: implement Stop method
 func (consensus *ConsensusSoloImpl) Stop() error {
+	consensus.msgbus.UnRegister(msgbus.ProposedBlock, consensus)
+	consensus.msgbus.UnRegister(msgbus.VerifyResult, consensus)
 	clog.Infof("ConsensusSoloImpl %s stoped", consensus.id)
 	return nil
 }
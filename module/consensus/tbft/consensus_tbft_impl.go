@@ -15,6 +15,8 @@ import (
 	"sync"
 	"time"
 
+	"chainmaker.org/chainmaker-go/consensus/gaslimit"
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
 	"chainmaker.org/chainmaker/chainconf/v2"
 	"chainmaker.org/chainmaker/common/v2/crypto/asym"
 	"chainmaker.org/chainmaker/common/v2/helper"
@@ -82,6 +84,7 @@ type ConsensusTBFTImpl struct {
 	ac                 protocol.AccessControlProvider
 	dbHandle           protocol.DBHandle
 	ledgerCache        protocol.LedgerCache
+	store              protocol.BlockchainStore
 	chainConf          protocol.ChainConf
 	netService         protocol.NetService
 	msgbus             msgbus.MessageBus
@@ -98,6 +101,7 @@ type ConsensusTBFTImpl struct {
 
 	gossip        *gossipService
 	timeScheduler *timeScheduler
+	evidencePool  *evidencePool
 
 	proposedBlockC chan *consensuspb.ProposalBlock
 	verifyResultC  chan *consensuspb.VerifyResult
@@ -121,6 +125,7 @@ type ConsensusTBFTImplConfig struct {
 	Ac          protocol.AccessControlProvider
 	DbHandle    protocol.DBHandle
 	LedgerCache protocol.LedgerCache
+	Store       protocol.BlockchainStore
 	ChainConf   protocol.ChainConf
 	NetService  protocol.NetService
 	MsgBus      msgbus.MessageBus
@@ -138,6 +143,7 @@ func New(config ConsensusTBFTImplConfig) (*ConsensusTBFTImpl, error) {
 	consensus.ac = config.Ac
 	consensus.dbHandle = config.DbHandle
 	consensus.ledgerCache = config.LedgerCache
+	consensus.store = config.Store
 	consensus.chainConf = config.ChainConf
 	consensus.netService = config.NetService
 	consensus.msgbus = config.MsgBus
@@ -169,6 +175,7 @@ func New(config ConsensusTBFTImplConfig) (*ConsensusTBFTImpl, error) {
 	consensus.consensusStateCache = newConsensusStateCache(defaultConsensusStateCacheSize)
 	consensus.timeScheduler = newTimeSheduler(consensus.logger, config.Id)
 	consensus.gossip = newGossipService(consensus.logger, consensus)
+	consensus.evidencePool = newEvidencePool(consensus.logger, consensus.dbHandle)
 
 	return consensus, nil
 }
@@ -186,7 +193,11 @@ func (consensus *ConsensusTBFTImpl) Start() error {
 
 	consensus.logger.Infof("start ConsensusTBFTImpl[%s]", consensus.Id)
 	consensus.timeScheduler.Start()
-	err := consensus.replayWal()
+	err := consensus.evidencePool.load()
+	if err != nil {
+		return err
+	}
+	err = consensus.replayWal()
 	if err != nil {
 		return err
 	}
@@ -209,6 +220,10 @@ func (consensus *ConsensusTBFTImpl) Stop() error {
 
 	consensus.logger.Infof("[%s](%d/%d/%s) stopped", consensus.Id, consensus.Height, consensus.Round,
 		consensus.Step)
+	consensus.msgbus.UnRegister(msgbus.ProposedBlock, consensus)
+	consensus.msgbus.UnRegister(msgbus.VerifyResult, consensus)
+	consensus.msgbus.UnRegister(msgbus.RecvConsensusMsg, consensus)
+	consensus.msgbus.UnRegister(msgbus.BlockInfo, consensus)
 	err := consensus.wal.Sync()
 	if err != nil {
 		return err
@@ -255,7 +270,12 @@ func (consensus *ConsensusTBFTImpl) OnMessage(message *msgbus.Message) {
 				consensus.logger.Errorf("receive message failed, error message BlockInfo = nil")
 				return
 			}
-			consensus.blockHeightC <- blockInfo.Block.Header.BlockHeight
+			consensus.evidencePool.markCommitted(blockInfo.Block)
+			// the message delivered while stopping is dropped, nobody receives it any more
+			select {
+			case consensus.blockHeightC <- blockInfo.Block.Header.BlockHeight:
+			case <-consensus.closeC:
+			}
 		} else {
 			panic(fmt.Errorf("error message type"))
 		}
@@ -279,12 +299,15 @@ func (consensus *ConsensusTBFTImpl) Verify(consensusType consensuspb.ConsensusTy
 		return errors.New(errMsg)
 	}
 	config := chainConfig.Consensus
-	_, _, _, _, err := consensus.extractConsensusConfig(config)
+	if _, _, _, _, err := consensus.extractConsensusConfig(config); err != nil {
+		return err
+	}
+	_, err := gaslimit.ParseBlockGasLimit(chainConfig)
 	return err
 }
 
-func (consensus *ConsensusTBFTImpl) updateChainConfig() (addedValidators []string, removedValidators []string,
-	err error) {
+func (consensus *ConsensusTBFTImpl) updateChainConfig(height uint64) (addedValidators []string,
+	removedValidators []string, err error) {
 	consensus.logger.Debugf("[%s](%d/%d/%v) update chain config",
 		consensus.Id, consensus.Height, consensus.Round, consensus.Step)
 
@@ -314,7 +337,35 @@ func (consensus *ConsensusTBFTImpl) updateChainConfig() (addedValidators []strin
 			consensus.logger.Errorf("update Proposer per Blocks failed err: %s", err)
 		}
 	}
-	return consensus.validatorSet.updateValidators(validators)
+	if addedValidators, removedValidators, err = consensus.validatorSet.updateValidators(validators); err != nil {
+		return nil, nil, err
+	}
+	return addedValidators, removedValidators, consensus.updateVotingPowers(validators, height)
+}
+
+// updateVotingPowers updates the voting powers and the proposer election of the validatorSet for height,
+// the hash of the last committed block is the seed of the proposer election
+func (consensus *ConsensusTBFTImpl) updateVotingPowers(validators []string, height uint64) error {
+	chainConfig := consensus.chainConf.ChainConfig()
+	electionConfig, err := election.ParseConfig(chainConfig.Consensus.ExtConfig)
+	if err != nil {
+		return err
+	}
+	if err = consensus.validatorSet.updateProposerElection(electionConfig.Election); err != nil {
+		return err
+	}
+	votingPowers, err := GetValidatorVotingPowers(chainConfig, consensus.store, validators, height)
+	if err != nil {
+		return err
+	}
+	consensus.validatorSet.updateVotingPowers(votingPowers)
+	if block := consensus.ledgerCache.GetLastCommittedBlock(); block != nil && block.Header.BlockHeight+1 == height {
+		consensus.validatorSet.updateElectionSeed(height, block.Header.BlockHash)
+	} else {
+		consensus.logger.Warnf("[%s] the block before height %d is not committed, no seed of proposer election",
+			consensus.Id, height)
+	}
+	return nil
 }
 
 func (consensus *ConsensusTBFTImpl) extractConsensusConfig(config *config.ConsensusConfig) (validators []string,
@@ -345,6 +396,7 @@ func (consensus *ConsensusTBFTImpl) extractConsensusConfig(config *config.Consen
 		}
 	}
 
+	_, err = election.ParseConfig(config.ExtConfig)
 	return
 }
 
@@ -445,6 +497,13 @@ func (consensus *ConsensusTBFTImpl) handleProposedBlock(proposedBlock *consensus
 		return
 	}
 
+	// add the round and the pending evidences in block before DPoS, which keeps them in consensus args
+	if err := consensus.addEvidencesToBlock(block); err != nil {
+		consensus.logger.Errorf("[%s](%d/%d/%s) add evidences to block failed, reason: %s",
+			consensus.Id, consensus.Height, consensus.Round, consensus.Step, err)
+		return
+	}
+
 	// add DPoS consensus args in block
 	if consensus.dpos != nil {
 		if err := consensus.dpos.CreateDPoSRWSet(block.Header.PreBlockHash, proposedBlock); err != nil {
@@ -549,6 +608,11 @@ func (consensus *ConsensusTBFTImpl) handleVerifyResult(verifyResult *consensuspb
 		return
 	}
 
+	if err := consensus.verifyBlockEvidences(consensus.VerifingProposal); err != nil {
+		consensus.logger.Warnf("verify block evidences failed, reason: %s", err)
+		return
+	}
+
 	if consensus.chainConf.ChainConfig().Consensus.Type == consensuspb.ConsensusType_DPOS {
 		if err := consensus.dpos.VerifyConsensusArgs(verifyResult.VerifiedBlock, verifyResult.TxsRwSet); err != nil {
 			consensus.logger.Warnf("verify block DPoS consensus failed, reason: %s", err)
@@ -846,6 +910,9 @@ func (consensus *ConsensusTBFTImpl) addVote(vote *Vote, replayMode bool) error {
 	if !added || err != nil {
 		consensus.logger.Infof("[%s](%d/%d/%s) addVote %v, added: %v, err: %v",
 			consensus.Id, consensus.Height, consensus.Round, consensus.Step, vote, added, err)
+		if errors.Is(err, ErrVoteForDifferentHash) && !replayMode {
+			consensus.handleEquivocation(vote)
+		}
 		return err
 	}
 
@@ -965,7 +1032,7 @@ func (consensus *ConsensusTBFTImpl) enterNewHeight(height uint64, replayMode boo
 			consensus.Id, consensus.Height, consensus.Round, consensus.Step, height)
 		return
 	}
-	addedValidators, removedValidators, err := consensus.updateChainConfig()
+	addedValidators, removedValidators, err := consensus.updateChainConfig(height)
 	if err != nil {
 		consensus.logger.Errorf("[%s](%v/%v/%v) update chain config failed: %v",
 			consensus.Id, consensus.Height, consensus.Round, consensus.Step, err)
@@ -617,3 +617,26 @@ func TestConsensusTBFTImpl_FourNode_KeepGrowing_LeaderDown(t *testing.T) {
 	wg.Wait()
 }
 */
+
+func TestConsensusTBFTImpl_OnMessageAfterStop(t *testing.T) {
+	consensus := &ConsensusTBFTImpl{
+		logger:       cmLogger,
+		closeC:       make(chan struct{}),
+		blockHeightC: make(chan uint64),
+		evidencePool: newEvidencePool(cmLogger, nil),
+	}
+	close(consensus.closeC)
+
+	// the block info delivered while stopping does not block the message bus
+	done := make(chan struct{})
+	go func() {
+		consensus.OnMessage(&msgbus.Message{Topic: msgbus.BlockInfo, Payload: &common.BlockInfo{
+			Block: &common.Block{Header: &common.BlockHeader{BlockHeight: 1}}}})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("OnMessage blocks after the consensus stops")
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package election elects the proposer of each round of TBFT and computes the voting power of the validators.
+// It is shared by TBFT and the DPoS economics, which replays the proposers of the rounds to count the missed
+// proposals, so both of them must elect the same proposer.
+package election
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"chainmaker.org/chainmaker/pb-go/v2/config"
+)
+
+// The keys of consensus ext config
+const (
+	// ProposerElectionKey the name of the proposer election, ROUND_ROBIN by default
+	ProposerElectionKey = "TBFT_proposer_election"
+	// ValidatorWeightsKey the voting power of the validators, a json object of node id to weight,
+	// the validators not in it have weight 1. All the validators have weight 1 by default.
+	// For DPoS, it can be DPOS_STAKE to weight the validators with their bonded tokens.
+	ValidatorWeightsKey = "TBFT_validator_weights"
+	// ValidatorWeightsDPoSStake the value of ValidatorWeightsKey to weight the validators with their stakes
+	// snapshotted when the epoch is created
+	ValidatorWeightsDPoSStake = "DPOS_STAKE"
+)
+
+// The proposer elections registered by default
+const (
+	// RoundRobin the validators propose in turn regardless of the voting power
+	RoundRobin = "ROUND_ROBIN"
+	// WeightedRoundRobin the validators propose in turn, each one proposes in proportion to its voting power
+	WeightedRoundRobin = "WEIGHTED_ROUND_ROBIN"
+	// Random the proposer is picked in proportion to the voting power with the seed hashed from the previous
+	// block hash, height and round. The proposers of a height are unknown until the previous block is committed.
+	Random = "RANDOM"
+)
+
+const (
+	// MaxTotalVotingPower the stakes are scaled down to keep the total voting power under it,
+	// so that the quorum maths of the vote sets never overflows
+	MaxTotalVotingPower = uint64(1) << 40
+	// maxScheduleLength the weights are scaled down to keep a round of WEIGHTED_ROUND_ROBIN in it
+	maxScheduleLength = uint64(1024)
+)
+
+// Validator a validator and its voting power
+type Validator struct {
+	NodeId      string
+	VotingPower uint64
+}
+
+// Election elects the proposer of a round, every node must elect the same proposer with the same input
+type Election interface {
+	// Proposer returns the node id of the proposer of height and round, empty if it can not be elected.
+	// validators are not empty and sorted by node id, preBlockHash is the hash of the block at height-1.
+	Proposer(validators []*Validator, height uint64, round int32, blocksPerProposer uint64,
+		preBlockHash []byte) string
+}
+
+var elections = map[string]Election{
+	RoundRobin:         roundRobin{},
+	WeightedRoundRobin: weightedRoundRobin{},
+	Random:             random{},
+}
+
+// Register add the election to the registry, it can be selected by ProposerElectionKey.
+// If the name already registered, will panic.
+func Register(name string, election Election) {
+	if _, found := elections[name]; found {
+		panic("proposer election[" + name + "] already registered!")
+	}
+	elections[name] = election
+}
+
+// Get return the election registered with name, RoundRobin if name is empty
+func Get(name string) (Election, error) {
+	if name == "" {
+		name = RoundRobin
+	}
+	election, ok := elections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s: %s", ProposerElectionKey, name)
+	}
+	return election, nil
+}
+
+// Config the proposer election and the validator weights in the consensus ext config
+type Config struct {
+	Election      string
+	Weights       map[string]uint64
+	StakeWeighted bool
+}
+
+// ParseConfig parses and validates the proposer election and the validator weights in extConfig
+func ParseConfig(extConfig []*config.ConfigKeyValue) (*Config, error) {
+	conf := &Config{Election: RoundRobin}
+	for _, kv := range extConfig {
+		value := strings.TrimSpace(string(kv.Value))
+		switch kv.Key {
+		case ProposerElectionKey:
+			if _, err := Get(value); err != nil {
+				return nil, err
+			}
+			if value != "" {
+				conf.Election = value
+			}
+		case ValidatorWeightsKey:
+			if value == ValidatorWeightsDPoSStake {
+				conf.StakeWeighted = true
+				continue
+			}
+			if err := json.Unmarshal([]byte(value), &conf.Weights); err != nil {
+				return nil, fmt.Errorf("invalid %s: %s, %s", ValidatorWeightsKey, value, err)
+			}
+			var total uint64
+			for nodeId, weight := range conf.Weights {
+				if weight == 0 || weight > MaxTotalVotingPower {
+					return nil, fmt.Errorf("invalid %s: weight of %s should be in [1, %d]",
+						ValidatorWeightsKey, nodeId, MaxTotalVotingPower)
+				}
+				if total += weight; total > MaxTotalVotingPower {
+					return nil, fmt.Errorf("invalid %s: total weight should not be greater than %d",
+						ValidatorWeightsKey, MaxTotalVotingPower)
+				}
+			}
+		}
+	}
+	return conf, nil
+}
+
+// VotingPowers returns the voting power of the nodes in the weights, the others have voting power 1
+func (conf *Config) VotingPowers(nodeIDs []string) map[string]uint64 {
+	powers := make(map[string]uint64, len(nodeIDs))
+	for _, nodeId := range nodeIDs {
+		powers[nodeId] = 1
+		if weight, ok := conf.Weights[nodeId]; ok {
+			powers[nodeId] = weight
+		}
+	}
+	return powers
+}
+
+// StakeVotingPowers scales the stakes of the nodes to voting powers, the total voting power is not greater
+// than MaxTotalVotingPower and each node has voting power 1 at least
+func StakeVotingPowers(nodeIDs []string, stakes []*big.Int) (map[string]uint64, error) {
+	if len(nodeIDs) != len(stakes) {
+		return nil, fmt.Errorf("%d stakes for %d nodes", len(stakes), len(nodeIDs))
+	}
+	total := big.NewInt(0)
+	for i, stake := range stakes {
+		if stake == nil || stake.Sign() < 0 {
+			return nil, fmt.Errorf("invalid stake of %s: %v", nodeIDs[i], stake)
+		}
+		total.Add(total, stake)
+	}
+	// divisor = ceil(total / MaxTotalVotingPower), leaving room for the validators raised to 1
+	divisor := big.NewInt(1)
+	maxTotal := new(big.Int).SetUint64(MaxTotalVotingPower - uint64(len(nodeIDs)))
+	if total.Cmp(maxTotal) > 0 {
+		divisor.Add(maxTotal, big.NewInt(-1))
+		divisor.Add(divisor, total)
+		divisor.Div(divisor, maxTotal)
+	}
+	powers := make(map[string]uint64, len(nodeIDs))
+	for i, nodeId := range nodeIDs {
+		power := new(big.Int).Div(stakes[i], divisor).Uint64()
+		if power == 0 {
+			power = 1
+		}
+		powers[nodeId] = power
+	}
+	return powers, nil
+}
+
+// NewValidators returns the validators sorted by node id with the voting powers, 1 if not in powers
+func NewValidators(nodeIDs []string, powers map[string]uint64) []*Validator {
+	validators := make([]*Validator, 0, len(nodeIDs))
+	for _, nodeId := range nodeIDs {
+		power, ok := powers[nodeId]
+		if !ok {
+			power = 1
+		}
+		validators = append(validators, &Validator{NodeId: nodeId, VotingPower: power})
+	}
+	sort.SliceStable(validators, func(i, j int) bool {
+		return validators[i].NodeId < validators[j].NodeId
+	})
+	return validators
+}
+
+// slot the index of the proposing turn of height and round
+func slot(height uint64, round int32, blocksPerProposer uint64) uint64 {
+	return (height+1)/blocksPerProposer + uint64(round)
+}
+
+// pick returns the validator at offset of the weight line made of the validators
+func pick(validators []*Validator, offset uint64) string {
+	for _, v := range validators {
+		if offset < v.VotingPower {
+			return v.NodeId
+		}
+		offset -= v.VotingPower
+	}
+	return validators[len(validators)-1].NodeId
+}
+
+func totalVotingPower(validators []*Validator) uint64 {
+	var total uint64
+	for _, v := range validators {
+		total += v.VotingPower
+	}
+	return total
+}
+
+type roundRobin struct{}
+
+func (roundRobin) Proposer(validators []*Validator, height uint64, round int32, blocksPerProposer uint64,
+	_ []byte) string {
+	size := int32(len(validators))
+	heightOffset := int32((height + 1) / blocksPerProposer)
+	roundOffset := round % size
+	return validators[(heightOffset+roundOffset)%size].NodeId
+}
+
+// weightedRoundRobin is the smooth weighted round robin, the proposing turns of a validator spread over a round
+// instead of being consecutive. The weights are scaled down to bound the length of a round.
+type weightedRoundRobin struct{}
+
+func (weightedRoundRobin) Proposer(validators []*Validator, height uint64, round int32,
+	blocksPerProposer uint64, _ []byte) string {
+	weights := make([]uint64, len(validators))
+	total := totalVotingPower(validators)
+	var scaledTotal uint64
+	for i, v := range validators {
+		weights[i] = v.VotingPower
+		if total > maxScheduleLength {
+			weights[i] = v.VotingPower * maxScheduleLength / total
+		}
+		if weights[i] == 0 {
+			weights[i] = 1
+		}
+		scaledTotal += weights[i]
+	}
+
+	turn := slot(height, round, blocksPerProposer) % scaledTotal
+	current := make([]int64, len(validators))
+	var selected int
+	for i := uint64(0); i <= turn; i++ {
+		selected = 0
+		for j := range current {
+			current[j] += int64(weights[j])
+			if current[j] > current[selected] {
+				selected = j
+			}
+		}
+		current[selected] -= int64(scaledTotal)
+	}
+	return validators[selected].NodeId
+}
+
+// random picks the proposer in proportion to the voting power with the seed sha256(preBlockHash, slot).
+// The seed is not a VRF output, the proposer of the previous block can still bias it by choosing the content
+// of its block, but nobody can tell the proposers of a height before the previous block is committed.
+type random struct{}
+
+func (random) Proposer(validators []*Validator, height uint64, round int32, blocksPerProposer uint64,
+	preBlockHash []byte) string {
+	if len(preBlockHash) == 0 {
+		return ""
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], slot(height, round, blocksPerProposer))
+	hasher := sha256.New()
+	hasher.Write(preBlockHash)
+	hasher.Write(buf[:])
+	seed := hasher.Sum(nil)
+	offset := binary.BigEndian.Uint64(seed[:8]) % totalVotingPower(validators)
+	return pick(validators, offset)
+}
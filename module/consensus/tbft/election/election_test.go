@@ -0,0 +1,137 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package election
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"chainmaker.org/chainmaker/pb-go/v2/config"
+)
+
+func testBlockHash(height uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], height)
+	hash := sha256.Sum256(buf[:])
+	return hash[:]
+}
+
+func countProposers(t *testing.T, name string, validators []*Validator, heights uint64) map[string]int {
+	election, err := Get(name)
+	require.Nil(t, err)
+	counts := make(map[string]int)
+	for height := uint64(1); height <= heights; height++ {
+		preBlockHash := testBlockHash(height - 1)
+		proposer := election.Proposer(validators, height, 0, 1, preBlockHash)
+		require.Equal(t, proposer, election.Proposer(validators, height, 0, 1, preBlockHash))
+		counts[proposer]++
+	}
+	return counts
+}
+
+func TestRoundRobin(t *testing.T) {
+	validators := NewValidators([]string{"node3", "node1", "node2"}, map[string]uint64{"node1": 10})
+	counts := countProposers(t, RoundRobin, validators, 300)
+	require.Equal(t, map[string]int{"node1": 100, "node2": 100, "node3": 100}, counts)
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	validators := NewValidators([]string{"node1", "node2", "node3"}, map[string]uint64{"node1": 3, "node2": 2})
+	counts := countProposers(t, WeightedRoundRobin, validators, 600)
+	require.Equal(t, map[string]int{"node1": 300, "node2": 200, "node3": 100}, counts)
+
+	// the proposing turns of node1 are not consecutive in a round
+	election, _ := Get(WeightedRoundRobin)
+	var proposers []string
+	for height := uint64(5); height < 11; height++ {
+		proposers = append(proposers, election.Proposer(validators, height, 0, 1, nil))
+	}
+	require.Equal(t, []string{"node1", "node2", "node1", "node3", "node2", "node1"}, proposers)
+
+	// the next round of a height is proposed by the next turn
+	require.Equal(t, election.Proposer(validators, 6, 0, 1, nil), election.Proposer(validators, 5, 1, 1, nil))
+
+	// large weights are scaled down
+	validators = NewValidators([]string{"node1", "node2"}, map[string]uint64{"node1": 3 << 30, "node2": 1 << 30})
+	counts = countProposers(t, WeightedRoundRobin, validators, 1024)
+	require.Equal(t, map[string]int{"node1": 768, "node2": 256}, counts)
+}
+
+func TestRandom(t *testing.T) {
+	validators := NewValidators([]string{"node1", "node2"}, map[string]uint64{"node1": 9})
+	counts := countProposers(t, Random, validators, 10000)
+	require.InDelta(t, 9000, counts["node1"], 300)
+	require.InDelta(t, 1000, counts["node2"], 300)
+
+	// the proposer depends on the previous block hash, and is not elected without it
+	election, _ := Get(Random)
+	proposers := make(map[string]struct{})
+	for height := uint64(0); height < 100; height++ {
+		proposers[election.Proposer(validators, 10, 0, 1, testBlockHash(height))] = struct{}{}
+	}
+	require.Len(t, proposers, 2)
+	require.Equal(t, "", election.Proposer(validators, 10, 0, 1, nil))
+}
+
+func TestRegister(t *testing.T) {
+	require.Panics(t, func() { Register(RoundRobin, roundRobin{}) })
+	_, err := Get("unknown")
+	require.NotNil(t, err)
+	election, err := Get("")
+	require.Nil(t, err)
+	require.Equal(t, roundRobin{}, election)
+}
+
+func TestParseConfig(t *testing.T) {
+	conf, err := ParseConfig(nil)
+	require.Nil(t, err)
+	require.Equal(t, RoundRobin, conf.Election)
+	require.Equal(t, map[string]uint64{"node1": 1, "node2": 1}, conf.VotingPowers([]string{"node1", "node2"}))
+
+	conf, err = ParseConfig([]*config.ConfigKeyValue{
+		{Key: ProposerElectionKey, Value: WeightedRoundRobin},
+		{Key: ValidatorWeightsKey, Value: `{"node1": 5}`},
+	})
+	require.Nil(t, err)
+	require.Equal(t, WeightedRoundRobin, conf.Election)
+	require.Equal(t, map[string]uint64{"node1": 5, "node2": 1}, conf.VotingPowers([]string{"node1", "node2"}))
+
+	conf, err = ParseConfig([]*config.ConfigKeyValue{{Key: ValidatorWeightsKey, Value: ValidatorWeightsDPoSStake}})
+	require.Nil(t, err)
+	require.True(t, conf.StakeWeighted)
+
+	for _, kv := range []*config.ConfigKeyValue{
+		{Key: ProposerElectionKey, Value: "unknown"},
+		{Key: ValidatorWeightsKey, Value: "{"},
+		{Key: ValidatorWeightsKey, Value: `{"node1": 0}`},
+		{Key: ValidatorWeightsKey, Value: `{"node1": 1099511627776, "node2": 1}`},
+	} {
+		_, err = ParseConfig([]*config.ConfigKeyValue{kv})
+		require.NotNil(t, err, kv.Value)
+	}
+}
+
+func TestStakeVotingPowers(t *testing.T) {
+	powers, err := StakeVotingPowers([]string{"node1", "node2"}, []*big.Int{big.NewInt(300), big.NewInt(100)})
+	require.Nil(t, err)
+	require.Equal(t, map[string]uint64{"node1": 300, "node2": 100}, powers)
+
+	large, _ := new(big.Int).SetString("3000000000000000000000000", 10)
+	small, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+	powers, err = StakeVotingPowers([]string{"node1", "node2", "node3"}, []*big.Int{large, small, big.NewInt(1)})
+	require.Nil(t, err)
+	require.LessOrEqual(t, powers["node1"]+powers["node2"]+powers["node3"], MaxTotalVotingPower)
+	require.Equal(t, powers["node1"], 3*powers["node2"])
+	require.Equal(t, uint64(1), powers["node3"])
+
+	_, err = StakeVotingPowers([]string{"node1"}, nil)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,541 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tbft
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"chainmaker.org/chainmaker-go/consensus/dpos"
+	"chainmaker.org/chainmaker/common/v2/msgbus"
+	"chainmaker.org/chainmaker/logger/v2"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
+	tbftpb "chainmaker.org/chainmaker/pb-go/v2/consensus/tbft"
+	netpb "chainmaker.org/chainmaker/pb-go/v2/net"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	// maxEvidencesPerBlock the max number of evidences included in one block
+	maxEvidencesPerBlock = 16
+
+	evidenceKeyPrefix = "tbft_evidence_"
+)
+
+var (
+	ErrInvalidEvidence = errors.New("invalid evidence")
+)
+
+// Evidence proves that a validator signed two votes of the same type for different
+// hashes at the same height and round. The json encoding is compatible with
+// dpos.DoubleSignEvidence, the evidences are recorded in the consensus args of block
+// with dpos.DoubleSignEvidenceWrites, which are covered by the block hash.
+type Evidence struct {
+	NodeId   string          `json:"node_id"`
+	Height   uint64          `json:"height"`
+	Round    int32           `json:"round"`
+	VoteType tbftpb.VoteType `json:"vote_type"`
+	// VoteA and VoteB are the marshaled conflicting votes with endorsements,
+	// VoteA is the one with the smaller hash
+	VoteA []byte `json:"vote_a"`
+	VoteB []byte `json:"vote_b"`
+	// Reporter is the node which detected the equivocation and signed the evidence
+	Reporter    string                   `json:"reporter"`
+	Endorsement *common.EndorsementEntry `json:"endorsement"`
+}
+
+// NewEvidence create an unsigned evidence from two conflicting votes
+func NewEvidence(reporter string, voteA, voteB *Vote) *Evidence {
+	if bytes.Compare(voteA.Hash, voteB.Hash) > 0 {
+		voteA, voteB = voteB, voteA
+	}
+	return &Evidence{
+		NodeId:   voteA.Voter,
+		Height:   voteA.Height,
+		Round:    voteA.Round,
+		VoteType: voteA.Type,
+		VoteA:    mustMarshal(voteA.ToProto()),
+		VoteB:    mustMarshal(voteB.ToProto()),
+		Reporter: reporter,
+	}
+}
+
+// Id return the identity of the misbehavior, evidences reported by different nodes
+// for the same misbehavior share the same id
+func (e *Evidence) Id() string {
+	return fmt.Sprintf("%s/%d/%d/%s", e.NodeId, e.Height, e.Round, e.VoteType)
+}
+
+// signBytes return the bytes signed by the reporter
+func (e *Evidence) signBytes() ([]byte, error) {
+	evidence := *e
+	evidence.Endorsement = nil
+	return json.Marshal(&evidence)
+}
+
+// votes unmarshal the conflicting votes in evidence
+func (e *Evidence) votes() (voteA, voteB *tbftpb.Vote, err error) {
+	voteA, voteB = new(tbftpb.Vote), new(tbftpb.Vote)
+	if err = proto.Unmarshal(e.VoteA, voteA); err != nil {
+		return nil, nil, fmt.Errorf("%w unmarshal vote a failed, %v", ErrInvalidEvidence, err)
+	}
+	if err = proto.Unmarshal(e.VoteB, voteB); err != nil {
+		return nil, nil, fmt.Errorf("%w unmarshal vote b failed, %v", ErrInvalidEvidence, err)
+	}
+	return voteA, voteB, nil
+}
+
+// checkBasic check the consistency between the evidence and its votes, signatures are not verified
+func (e *Evidence) checkBasic() error {
+	voteA, voteB, err := e.votes()
+	if err != nil {
+		return err
+	}
+	for _, v := range []*tbftpb.Vote{voteA, voteB} {
+		if v.Voter != e.NodeId || v.Height != e.Height || v.Round != e.Round || v.Type != e.VoteType {
+			return fmt.Errorf("%w vote %s(%d/%d/%s) unmatched with evidence %s",
+				ErrInvalidEvidence, v.Voter, v.Height, v.Round, v.Type, e.Id())
+		}
+		if v.Endorsement == nil {
+			return fmt.Errorf("%w vote without endorsement", ErrInvalidEvidence)
+		}
+	}
+	if bytes.Compare(voteA.Hash, voteB.Hash) >= 0 {
+		return fmt.Errorf("%w votes are not conflicting or not in order", ErrInvalidEvidence)
+	}
+	if e.Endorsement == nil {
+		return fmt.Errorf("%w evidence without endorsement", ErrInvalidEvidence)
+	}
+	return nil
+}
+
+// EvidenceRecord is an evidence known by the node, CommittedHeight is the height of the
+// block which includes the evidence, and it is zero if the evidence is still pending
+type EvidenceRecord struct {
+	Evidence        *Evidence `json:"evidence"`
+	CommittedHeight uint64    `json:"committed_height"`
+}
+
+// evidencePool keeps the evidences collected by the node, the records are persisted
+// in the consensus db so that pending evidences survive restarts
+type evidencePool struct {
+	sync.Mutex
+	logger   *logger.CMLogger
+	dbHandle protocol.DBHandle
+	records  map[string]*EvidenceRecord
+}
+
+func newEvidencePool(logger *logger.CMLogger, dbHandle protocol.DBHandle) *evidencePool {
+	return &evidencePool{
+		logger:   logger,
+		dbHandle: dbHandle,
+		records:  make(map[string]*EvidenceRecord),
+	}
+}
+
+// load read the persisted evidence records from db
+func (pool *evidencePool) load() error {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if pool.dbHandle == nil {
+		return nil
+	}
+	iter, err := pool.dbHandle.NewIteratorWithPrefix([]byte(evidenceKeyPrefix))
+	if err != nil {
+		return err
+	}
+	defer iter.Release()
+
+	for iter.Next() {
+		record := new(EvidenceRecord)
+		if err = json.Unmarshal(iter.Value(), record); err != nil || record.Evidence == nil {
+			pool.logger.Warnf("skip invalid evidence record %s, %v", iter.Key(), err)
+			continue
+		}
+		pool.records[record.Evidence.Id()] = record
+	}
+	return iter.Error()
+}
+
+func (pool *evidencePool) persist(record *EvidenceRecord) error {
+	if pool.dbHandle == nil {
+		return nil
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return pool.dbHandle.Put([]byte(evidenceKeyPrefix+record.Evidence.Id()), value)
+}
+
+// has return whether an evidence of the same misbehavior is known
+func (pool *evidencePool) has(id string) bool {
+	pool.Lock()
+	defer pool.Unlock()
+
+	_, ok := pool.records[id]
+	return ok
+}
+
+// isCommitted return whether an evidence of the same misbehavior has been included in block
+func (pool *evidencePool) isCommitted(id string) bool {
+	pool.Lock()
+	defer pool.Unlock()
+
+	record, ok := pool.records[id]
+	return ok && record.CommittedHeight > 0
+}
+
+// add adds a verified evidence as pending, added is false if the misbehavior is known
+func (pool *evidencePool) add(evidence *Evidence) (added bool, err error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	id := evidence.Id()
+	if _, ok := pool.records[id]; ok {
+		return false, nil
+	}
+	record := &EvidenceRecord{Evidence: evidence}
+	if err = pool.persist(record); err != nil {
+		return false, err
+	}
+	pool.records[id] = record
+	return true, nil
+}
+
+// pendingEvidences return the pending evidences which can be included in block at height,
+// those committed by the blocks not yet received by the pool are skipped by the state of store
+func (pool *evidencePool) pendingEvidences(height uint64, store protocol.BlockchainStore) []*Evidence {
+	pool.Lock()
+	defer pool.Unlock()
+
+	evidences := make([]*Evidence, 0)
+	for _, record := range pool.records {
+		if record.CommittedHeight != 0 || record.Evidence.Height >= height {
+			continue
+		}
+		if store != nil {
+			committed, err := dpos.IsDoubleSignEvidenceCommitted(store, record.Evidence.Id())
+			if err != nil || committed {
+				continue
+			}
+		}
+		evidences = append(evidences, record.Evidence)
+	}
+	sort.Slice(evidences, func(i, j int) bool {
+		return evidences[i].Id() < evidences[j].Id()
+	})
+	if len(evidences) > maxEvidencesPerBlock {
+		evidences = evidences[:maxEvidencesPerBlock]
+	}
+	return evidences
+}
+
+// markCommitted marks the evidences included in block as committed
+func (pool *evidencePool) markCommitted(block *common.Block) {
+	evidences, err := getEvidencesFromBlock(block)
+	if err != nil {
+		pool.logger.Errorf("get evidences from block %d failed, %v", block.Header.BlockHeight, err)
+		return
+	}
+	if len(evidences) == 0 {
+		return
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+	for _, evidence := range evidences {
+		id := evidence.Id()
+		record, ok := pool.records[id]
+		if ok && record.CommittedHeight > 0 {
+			continue
+		}
+		if !ok {
+			record = &EvidenceRecord{Evidence: evidence}
+		}
+		record.CommittedHeight = block.Header.BlockHeight
+		if err = pool.persist(record); err != nil {
+			pool.logger.Errorf("persist evidence %s failed, %v", id, err)
+		}
+		pool.records[id] = record
+		pool.logger.Infof("evidence %s committed in block %d", id, block.Header.BlockHeight)
+	}
+}
+
+// list return all the evidence records ordered by id
+func (pool *evidencePool) list() []*EvidenceRecord {
+	pool.Lock()
+	defer pool.Unlock()
+
+	records := make([]*EvidenceRecord, 0, len(pool.records))
+	for _, record := range pool.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Evidence.Id() < records[j].Evidence.Id()
+	})
+	return records
+}
+
+// getEvidencesFromBlock return the evidences recorded in the consensus args of block
+func getEvidencesFromBlock(block *common.Block) ([]*Evidence, error) {
+	if block == nil || block.Header == nil {
+		return nil, nil
+	}
+	args, err := dpos.GetConsensusArgs(block)
+	if err != nil {
+		return nil, err
+	}
+	var evidences []*Evidence
+	for _, data := range dpos.GetDoubleSignEvidences(args) {
+		evidence := new(Evidence)
+		if err = json.Unmarshal(data, evidence); err != nil {
+			return nil, err
+		}
+		evidences = append(evidences, evidence)
+	}
+	return evidences, nil
+}
+
+// handleEquivocation builds an evidence from the conflicting votes and keeps it, the votes are
+// sent to the other validators, so that they detect the equivocation and keep the evidence too
+func (consensus *ConsensusTBFTImpl) handleEquivocation(vote *Vote) {
+	voteSet := consensus.heightRoundVoteSet.getVoteSet(vote.Round, vote.Type)
+	if voteSet == nil {
+		return
+	}
+	existing, ok := voteSet.Votes[vote.Voter]
+	if !ok || existing.Endorsement == nil || vote.Endorsement == nil {
+		return
+	}
+
+	evidence := NewEvidence(consensus.Id, existing, vote)
+	if consensus.evidencePool.has(evidence.Id()) {
+		return
+	}
+	consensus.logger.Warnf("[%s](%d/%d/%s) detect equivocation of %s, %v and %v",
+		consensus.Id, consensus.Height, consensus.Round, consensus.Step, vote.Voter, existing, vote)
+
+	if err := consensus.signEvidence(evidence); err != nil {
+		return
+	}
+	if _, err := consensus.evidencePool.add(evidence); err != nil {
+		consensus.logger.Errorf("[%s](%d/%d/%s) add evidence %s failed, %v",
+			consensus.Id, consensus.Height, consensus.Round, consensus.Step, evidence.Id(), err)
+		return
+	}
+	consensus.broadcastVotes(existing, vote)
+}
+
+func (consensus *ConsensusTBFTImpl) signEvidence(evidence *Evidence) error {
+	signBytes, err := evidence.signBytes()
+	if err != nil {
+		return err
+	}
+	sig, err := consensus.singer.Sign(consensus.chainConf.ChainConfig().Crypto.Hash, signBytes)
+	if err != nil {
+		consensus.logger.Errorf("[%s](%d/%d/%v) sign evidence %s failed: %v",
+			consensus.Id, consensus.Height, consensus.Round, consensus.Step, evidence.Id(), err)
+		return err
+	}
+
+	serializeMember, err := consensus.singer.GetMember()
+	if err != nil {
+		consensus.logger.Errorf("[%s](%d/%d/%v) get serialize member failed: %v",
+			consensus.Id, consensus.Height, consensus.Round, consensus.Step, err)
+		return err
+	}
+	evidence.Endorsement = &common.EndorsementEntry{
+		Signer:    serializeMember,
+		Signature: sig,
+	}
+	return nil
+}
+
+// verifyEvidence verifies the signatures of both votes and the reporter
+func (consensus *ConsensusTBFTImpl) verifyEvidence(evidence *Evidence) error {
+	if err := evidence.checkBasic(); err != nil {
+		return err
+	}
+	voteA, voteB, err := evidence.votes()
+	if err != nil {
+		return err
+	}
+	if err = consensus.verifyVote(voteA); err != nil {
+		return fmt.Errorf("%w verify vote a failed, %v", ErrInvalidEvidence, err)
+	}
+	if err = consensus.verifyVote(voteB); err != nil {
+		return fmt.Errorf("%w verify vote b failed, %v", ErrInvalidEvidence, err)
+	}
+
+	signBytes, err := evidence.signBytes()
+	if err != nil {
+		return err
+	}
+	principal, err := consensus.ac.CreatePrincipal(
+		protocol.ResourceNameConsensusNode,
+		[]*common.EndorsementEntry{evidence.Endorsement},
+		signBytes,
+	)
+	if err != nil {
+		return fmt.Errorf("%w new principal failed, %v", ErrInvalidEvidence, err)
+	}
+	result, err := consensus.ac.VerifyPrincipal(principal)
+	if err != nil {
+		return fmt.Errorf("%w verify reporter signature failed, %v", ErrInvalidEvidence, err)
+	}
+	if !result {
+		return fmt.Errorf("%w verify reporter signature result: %v", ErrInvalidEvidence, result)
+	}
+	return nil
+}
+
+// verifyBlockEvidences verifies the consensus args recorded by the engine in the proposed block. The round
+// must be that of the proposal, and the evidences must be valid and not committed before. The other writes
+// are only allowed in the consensus args of DPoS, which verifies them.
+func (consensus *ConsensusTBFTImpl) verifyBlockEvidences(proposal *Proposal) error {
+	block := proposal.Block
+	args, err := dpos.GetConsensusArgs(block)
+	if err != nil {
+		return fmt.Errorf("%w %v", ErrInvalidEvidence, err)
+	}
+	if args.ConsensusData == nil {
+		return nil
+	}
+	if args.Round != int64(proposal.Round) {
+		return fmt.Errorf("%w round %d of consensus args unmatched with proposal round %d",
+			ErrInvalidEvidence, args.Round, proposal.Round)
+	}
+
+	var evidenceWrites []*common.TxWrite
+	for _, txWrite := range args.ConsensusData.TxWrites {
+		if txWrite.ContractName == dpos.DoubleSignEvidenceContract {
+			evidenceWrites = append(evidenceWrites, txWrite)
+		} else if consensus.chainConf.ChainConfig().Consensus.Type != consensuspb.ConsensusType_DPOS {
+			return fmt.Errorf("%w unexpected write of contract %s in consensus args",
+				ErrInvalidEvidence, txWrite.ContractName)
+		}
+	}
+	evidences, err := getEvidencesFromBlock(block)
+	if err != nil {
+		return fmt.Errorf("%w %v", ErrInvalidEvidence, err)
+	}
+	if len(evidences) > maxEvidencesPerBlock {
+		return fmt.Errorf("%w too many evidences: %d", ErrInvalidEvidence, len(evidences))
+	}
+
+	expectedWrites := make([]*common.TxWrite, 0, len(evidenceWrites))
+	ids := make(map[string]struct{}, len(evidences))
+	for _, evidence := range evidences {
+		id := evidence.Id()
+		if evidence.Height >= block.Header.BlockHeight {
+			return fmt.Errorf("%w evidence %s is not earlier than block %d",
+				ErrInvalidEvidence, id, block.Header.BlockHeight)
+		}
+		if _, ok := ids[id]; ok {
+			return fmt.Errorf("%w duplicate evidence %s", ErrInvalidEvidence, id)
+		}
+		ids[id] = struct{}{}
+		committed, err := dpos.IsDoubleSignEvidenceCommitted(consensus.store, id)
+		if err != nil {
+			return err
+		}
+		if committed {
+			return fmt.Errorf("%w evidence %s has been committed", ErrInvalidEvidence, id)
+		}
+		if err = consensus.verifyEvidence(evidence); err != nil {
+			return err
+		}
+		data, err := json.Marshal(evidence)
+		if err != nil {
+			return err
+		}
+		expectedWrites = append(expectedWrites,
+			dpos.DoubleSignEvidenceWrites(block.Header.BlockHeight, id, data)...)
+	}
+	if len(expectedWrites) != len(evidenceWrites) {
+		return fmt.Errorf("%w unexpected evidence writes in consensus args", ErrInvalidEvidence)
+	}
+	for i, txWrite := range evidenceWrites {
+		if !bytes.Equal(txWrite.Key, expectedWrites[i].Key) || !bytes.Equal(txWrite.Value, expectedWrites[i].Value) {
+			return fmt.Errorf("%w unexpected evidence write %s in consensus args", ErrInvalidEvidence, txWrite.Key)
+		}
+	}
+	return nil
+}
+
+// addEvidencesToBlock records the round and the pending evidences in the consensus args of the proposed block,
+// it is called before the block is hashed, so the evidences are covered by the block hash. The consensus args
+// are always recorded by DPoS, which counts the proposals by the round.
+func (consensus *ConsensusTBFTImpl) addEvidencesToBlock(block *common.Block) error {
+	block.Header.ConsensusArgs = nil
+	consensusType := consensus.chainConf.ChainConfig().Consensus.Type
+	evidences := consensus.evidencePool.pendingEvidences(block.Header.BlockHeight, consensus.store)
+	if len(evidences) == 0 && consensusType != consensuspb.ConsensusType_DPOS {
+		return nil
+	}
+
+	rwSet := &common.TxRWSet{}
+	for _, evidence := range evidences {
+		data, err := json.Marshal(evidence)
+		if err != nil {
+			return err
+		}
+		rwSet.TxWrites = append(rwSet.TxWrites,
+			dpos.DoubleSignEvidenceWrites(block.Header.BlockHeight, evidence.Id(), data)...)
+	}
+	args, err := proto.Marshal(&consensuspb.BlockHeaderConsensusArgs{
+		ConsensusType: int64(consensusType),
+		Round:         int64(consensus.Round),
+		ConsensusData: rwSet,
+	})
+	if err != nil {
+		return err
+	}
+	block.Header.ConsensusArgs = args
+	if len(evidences) > 0 {
+		consensus.logger.Infof("[%s](%d/%d/%s) add %d evidences to block %d",
+			consensus.Id, consensus.Height, consensus.Round, consensus.Step, len(evidences), block.Header.BlockHeight)
+	}
+	return nil
+}
+
+// broadcastVotes sends the conflicting votes to the other validators
+func (consensus *ConsensusTBFTImpl) broadcastVotes(votes ...*Vote) {
+	consensus.validatorSet.Lock()
+	validators := append([]string{}, consensus.validatorSet.Validators...)
+	consensus.validatorSet.Unlock()
+	for _, vote := range votes {
+		tbftMsg := createPrevoteMsg(vote)
+		if vote.Type == tbftpb.VoteType_VOTE_PRECOMMIT {
+			tbftMsg = createPrecommitMsg(vote)
+		}
+		payload := mustMarshal(tbftMsg)
+		for _, validator := range validators {
+			if validator == consensus.Id {
+				continue
+			}
+			consensus.msgbus.Publish(msgbus.SendConsensusMsg, &netpb.NetMsg{
+				Payload: payload,
+				Type:    netpb.NetMsg_CONSENSUS_MSG,
+				To:      validator,
+			})
+		}
+	}
+}
+
+// GetEvidences return the json encoded evidence records collected by the node
+func (consensus *ConsensusTBFTImpl) GetEvidences() ([]byte, error) {
+	return json.Marshal(consensus.evidencePool.list())
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tbft
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"chainmaker.org/chainmaker-go/consensus/dpos"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	consensuspb "chainmaker.org/chainmaker/pb-go/v2/consensus"
+	tbftpb "chainmaker.org/chainmaker/pb-go/v2/consensus/tbft"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvidence(height uint64, round int32) *Evidence {
+	voteA := NewVote(tbftpb.VoteType_VOTE_PREVOTE, org1NodeId, height, round, []byte("hash-b"))
+	voteA.Endorsement = &commonpb.EndorsementEntry{Signature: []byte("sig-b")}
+	voteB := NewVote(tbftpb.VoteType_VOTE_PREVOTE, org1NodeId, height, round, []byte("hash-a"))
+	voteB.Endorsement = &commonpb.EndorsementEntry{Signature: []byte("sig-a")}
+
+	evidence := NewEvidence(org2NodeId, voteA, voteB)
+	evidence.Endorsement = &commonpb.EndorsementEntry{Signature: []byte("reporter")}
+	return evidence
+}
+
+func TestEvidence_CheckBasic(t *testing.T) {
+	evidence := newTestEvidence(10, 1)
+	require.Nil(t, evidence.checkBasic())
+
+	// votes are ordered by hash
+	voteA, voteB, err := evidence.votes()
+	require.Nil(t, err)
+	require.Equal(t, []byte("hash-a"), voteA.Hash)
+	require.Equal(t, []byte("hash-b"), voteB.Hash)
+
+	// same vote twice is not an equivocation
+	evidence.VoteB = evidence.VoteA
+	require.True(t, errors.Is(evidence.checkBasic(), ErrInvalidEvidence))
+
+	// vote of another voter
+	other := NewVote(tbftpb.VoteType_VOTE_PREVOTE, org3NodeId, 10, 1, []byte("hash-c"))
+	other.Endorsement = &commonpb.EndorsementEntry{}
+	evidence = newTestEvidence(10, 1)
+	evidence.VoteB = mustMarshal(other.ToProto())
+	require.True(t, errors.Is(evidence.checkBasic(), ErrInvalidEvidence))
+
+	// without reporter signature
+	evidence = newTestEvidence(10, 1)
+	evidence.Endorsement = nil
+	require.True(t, errors.Is(evidence.checkBasic(), ErrInvalidEvidence))
+}
+
+func TestEvidencePool(t *testing.T) {
+	pool := newEvidencePool(cmLogger, nil)
+
+	evidence1 := newTestEvidence(10, 0)
+	evidence2 := newTestEvidence(12, 0)
+	added, err := pool.add(evidence1)
+	require.Nil(t, err)
+	require.True(t, added)
+	added, err = pool.add(newTestEvidence(10, 0))
+	require.Nil(t, err)
+	require.False(t, added)
+	added, err = pool.add(evidence2)
+	require.Nil(t, err)
+	require.True(t, added)
+
+	// only the evidences earlier than the block are included
+	require.Len(t, pool.pendingEvidences(11, nil), 1)
+	require.Len(t, pool.pendingEvidences(13, nil), 2)
+
+	data, err := json.Marshal(evidence1)
+	require.Nil(t, err)
+	args, err := proto.Marshal(&consensuspb.BlockHeaderConsensusArgs{
+		ConsensusType: int64(consensuspb.ConsensusType_TBFT),
+		ConsensusData: &commonpb.TxRWSet{TxWrites: dpos.DoubleSignEvidenceWrites(13, evidence1.Id(), data)},
+	})
+	require.Nil(t, err)
+	block := &commonpb.Block{Header: &commonpb.BlockHeader{BlockHeight: 13, ConsensusArgs: args}}
+	pool.markCommitted(block)
+
+	require.True(t, pool.isCommitted(evidence1.Id()))
+	require.False(t, pool.isCommitted(evidence2.Id()))
+	require.Len(t, pool.pendingEvidences(14, nil), 1)
+
+	records := pool.list()
+	require.Len(t, records, 2)
+	require.Equal(t, uint64(13), records[0].CommittedHeight)
+	require.Equal(t, uint64(0), records[1].CommittedHeight)
+
+	// the evidences recorded in block can be read by DPoS
+	consensusArgs, err := dpos.GetConsensusArgs(block)
+	require.Nil(t, err)
+	recorded := dpos.GetDoubleSignEvidences(consensusArgs)
+	require.Len(t, recorded, 1)
+	dposEvidence := &dpos.DoubleSignEvidence{}
+	require.Nil(t, json.Unmarshal(recorded[0], dposEvidence))
+	require.Equal(t, org1NodeId, dposEvidence.NodeId)
+	require.Equal(t, uint64(10), dposEvidence.Height)
+}
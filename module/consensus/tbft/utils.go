@@ -12,6 +12,7 @@ import (
 	"fmt"
 
 	"chainmaker.org/chainmaker-go/consensus/dpos"
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
 	"chainmaker.org/chainmaker/logger/v2"
 	"chainmaker.org/chainmaker/pb-go/v2/common"
 	"chainmaker.org/chainmaker/pb-go/v2/config"
@@ -55,6 +56,33 @@ func GetValidatorListFromLedger(store protocol.BlockchainStore) (validators []st
 	return nodeIDs, nil
 }
 
+// GetValidatorVotingPowers returns the voting power of the validators configured by election.ValidatorWeightsKey
+// for the block at height, the validators have voting power 1 by default. With DPOS_STAKE weights, the voting
+// powers are scaled from the stakes snapshotted when the epoch of height was created.
+func GetValidatorVotingPowers(chainConfig *config.ChainConfig, store protocol.BlockchainStore,
+	validators []string, height uint64) (map[string]uint64, error) {
+	conf, err := election.ParseConfig(chainConfig.Consensus.ExtConfig)
+	if err != nil {
+		return nil, err
+	}
+	if !conf.StakeWeighted {
+		return conf.VotingPowers(validators), nil
+	}
+	if chainConfig.Consensus.Type != consensus.ConsensusType_DPOS {
+		return nil, fmt.Errorf("%s %s is only supported by DPoS",
+			election.ValidatorWeightsKey, election.ValidatorWeightsDPoSStake)
+	}
+	epoch, err := dpos.GetEpochInfoAtHeight(store, height)
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs, err := dpos.GetNodeIDsFromValidators(store, epoch.ProposerVector)
+	if err != nil {
+		return nil, err
+	}
+	return dpos.GetEpochStakeVotingPowers(store, epoch, nodeIDs)
+}
+
 // VerifyBlockSignatures verifies whether the signatures in block
 // is qulified with the consensus algorithm. It should return nil
 // error when verify successfully, and return corresponding error
@@ -86,8 +114,14 @@ func VerifyBlockSignatures(chainConf protocol.ChainConf,
 		return err
 	}
 
+	votingPowers, err := GetValidatorVotingPowers(chainConfig, store, validators, height)
+	if err != nil {
+		return err
+	}
+
 	logger := logger.GetLoggerByChain(logger.MODULE_CONSENSUS, chainConfig.ChainId)
 	validatorSet := newValidatorSet(logger, validators, DefaultBlocksPerProposer)
+	validatorSet.updateVotingPowers(votingPowers)
 	voteSet := NewVoteSetFromProto(logger, voteSetProto, validatorSet)
 	hash, ok := voteSet.twoThirdsMajority()
 	if !ok {
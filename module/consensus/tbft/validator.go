@@ -12,6 +12,7 @@ import (
 	"sort"
 	"sync"
 
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
 	"chainmaker.org/chainmaker/logger/v2"
 )
 
@@ -24,6 +25,14 @@ type validatorSet struct {
 	logger            *logger.CMLogger
 	Validators        []string
 	blocksPerProposer uint64
+	// VotingPowers the voting power of the validators, 1 if not in it
+	VotingPowers     map[string]uint64
+	totalVotingPower uint64
+	electionName     string
+	election         election.Election
+	// preBlockHash the hash of the block at seedHeight-1, the seed of the proposer election at seedHeight
+	seedHeight   uint64
+	preBlockHash []byte
 }
 
 func newValidatorSet(logger *logger.CMLogger, validators []string, blocksPerProposer uint64) *validatorSet {
@@ -35,7 +44,11 @@ func newValidatorSet(logger *logger.CMLogger, validators []string, blocksPerProp
 		logger:            logger,
 		Validators:        validators,
 		blocksPerProposer: blocksPerProposer,
+		VotingPowers:      make(map[string]uint64),
+		totalVotingPower:  uint64(len(validators)),
+		electionName:      election.RoundRobin,
 	}
+	valSet.election, _ = election.Get(election.RoundRobin)
 	valSet.logger.Infof("new validator set: %v", validators)
 
 	return valSet
@@ -94,16 +107,98 @@ func (valSet *validatorSet) hasValidator(validator string) bool {
 	return false
 }
 
+// GetProposer returns the proposer of height and round elected by the proposer election
 func (valSet *validatorSet) GetProposer(height uint64, round int32) (validator string, err error) {
 	if valSet.isNilOrEmpty() {
 		return "", ErrInvalidIndex
 	}
 
-	heightOffset := int32((height + 1) / valSet.blocksPerProposer)
-	roundOffset := round % valSet.Size()
-	proposerIndex := (heightOffset + roundOffset) % valSet.Size()
+	valSet.Lock()
+	defer valSet.Unlock()
+	validators := election.NewValidators(valSet.Validators, valSet.VotingPowers)
+	var preBlockHash []byte
+	if height == valSet.seedHeight {
+		preBlockHash = valSet.preBlockHash
+	}
+	proposer := valSet.election.Proposer(validators, height, round, valSet.blocksPerProposer, preBlockHash)
+	if proposer == "" {
+		return "", fmt.Errorf("no proposer of %d/%d elected by %s", height, round, valSet.electionName)
+	}
+	return proposer, nil
+}
+
+// updateElectionSeed sets the hash of the block at height-1 as the seed of the proposer election at height
+func (valSet *validatorSet) updateElectionSeed(height uint64, preBlockHash []byte) {
+	valSet.Lock()
+	defer valSet.Unlock()
+	valSet.seedHeight = height
+	valSet.preBlockHash = preBlockHash
+}
+
+// VotingPower returns the voting power of validator, 0 if it is not in the validatorSet
+func (valSet *validatorSet) VotingPower(validator string) uint64 {
+	valSet.Lock()
+	defer valSet.Unlock()
+	if !valSet.hasValidator(validator) {
+		return 0
+	}
+	return valSet.votingPower(validator)
+}
+
+func (valSet *validatorSet) votingPower(validator string) uint64 {
+	if power, ok := valSet.VotingPowers[validator]; ok {
+		return power
+	}
+	return 1
+}
+
+// TotalVotingPower returns the sum of the voting power of the validators
+func (valSet *validatorSet) TotalVotingPower() uint64 {
+	if valSet == nil {
+		return 0
+	}
+	valSet.Lock()
+	defer valSet.Unlock()
+	return valSet.totalVotingPower
+}
 
-	return valSet.getByIndex(proposerIndex)
+// quorum returns the voting power more than 2/3 of the total
+func (valSet *validatorSet) quorum() uint64 {
+	return valSet.TotalVotingPower()*2/3 + 1
+}
+
+func (valSet *validatorSet) updateTotalVotingPower() {
+	valSet.totalVotingPower = 0
+	for _, v := range valSet.Validators {
+		valSet.totalVotingPower += valSet.votingPower(v)
+	}
+}
+
+// updateVotingPowers replaces the voting powers, the validators not in powers have voting power 1
+func (valSet *validatorSet) updateVotingPowers(powers map[string]uint64) {
+	valSet.Lock()
+	defer valSet.Unlock()
+
+	valSet.VotingPowers = powers
+	valSet.updateTotalVotingPower()
+	valSet.logger.Infof("update voting powers: %v, total: %d", powers, valSet.totalVotingPower)
+}
+
+// updateProposerElection replaces the proposer election with the one registered with name
+func (valSet *validatorSet) updateProposerElection(name string) error {
+	e, err := election.Get(name)
+	if err != nil {
+		return err
+	}
+
+	valSet.Lock()
+	defer valSet.Unlock()
+	if valSet.electionName != name {
+		valSet.logger.Infof("update proposer election from %s to %s", valSet.electionName, name)
+	}
+	valSet.electionName = name
+	valSet.election = e
+	return nil
 }
 
 func (valSet *validatorSet) updateValidators(validators []string) (addedValidators []string, removedValidators []string,
@@ -135,6 +230,7 @@ func (valSet *validatorSet) updateValidators(validators []string) (addedValidato
 	})
 
 	valSet.Validators = validators
+	valSet.updateTotalVotingPower()
 
 	sort.SliceStable(addedValidators, func(i, j int) bool {
 		return addedValidators[i] < addedValidators[j]
@@ -156,15 +252,3 @@ func (valSet *validatorSet) updateBlocksPerProposer(blocks uint64) error {
 
 	return nil
 }
-
-func (valSet *validatorSet) getByIndex(index int32) (validator string, err error) {
-	if index < 0 || index >= valSet.Size() {
-		return "", ErrInvalidIndex
-	}
-
-	valSet.Lock()
-	defer valSet.Unlock()
-
-	val := valSet.Validators[index]
-	return val, nil
-}
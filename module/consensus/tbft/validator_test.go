@@ -9,6 +9,11 @@ package tbft
 import (
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"chainmaker.org/chainmaker-go/consensus/tbft/election"
+	tbftpb "chainmaker.org/chainmaker/pb-go/v2/consensus/tbft"
 )
 
 func TestValidatorSetUpdateValidators(t *testing.T) {
@@ -95,3 +100,67 @@ func TestValidatorSetUpdateValidators(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatorSetVotingPower(t *testing.T) {
+	valSet := newValidatorSet(cmLogger, []string{"node1", "node2", "node3", "node4"}, 1)
+	require.Equal(t, uint64(4), valSet.TotalVotingPower())
+	require.Equal(t, uint64(3), valSet.quorum())
+
+	valSet.updateVotingPowers(map[string]uint64{"node1": 7})
+	require.Equal(t, uint64(7), valSet.VotingPower("node1"))
+	require.Equal(t, uint64(1), valSet.VotingPower("node2"))
+	require.Equal(t, uint64(0), valSet.VotingPower("node5"))
+	require.Equal(t, uint64(10), valSet.TotalVotingPower())
+	require.Equal(t, uint64(7), valSet.quorum())
+
+	_, _, err := valSet.updateValidators([]string{"node1", "node2"})
+	require.Nil(t, err)
+	require.Equal(t, uint64(8), valSet.TotalVotingPower())
+
+	require.NotNil(t, valSet.updateProposerElection("unknown"))
+	require.Nil(t, valSet.updateProposerElection(election.WeightedRoundRobin))
+	proposer, err := valSet.GetProposer(1, 0)
+	require.Nil(t, err)
+	require.True(t, valSet.HasValidator(proposer))
+
+	// RANDOM elects the proposer only at the height of the seed
+	require.Nil(t, valSet.updateProposerElection(election.Random))
+	_, err = valSet.GetProposer(1, 0)
+	require.NotNil(t, err)
+	valSet.updateElectionSeed(1, []byte("hash of block 0"))
+	proposer, err = valSet.GetProposer(1, 0)
+	require.Nil(t, err)
+	require.True(t, valSet.HasValidator(proposer))
+	_, err = valSet.GetProposer(2, 0)
+	require.NotNil(t, err)
+}
+
+func TestVoteSetWeightedMajority(t *testing.T) {
+	valSet := newValidatorSet(cmLogger, []string{"node1", "node2", "node3", "node4"}, 1)
+	valSet.updateVotingPowers(map[string]uint64{"node1": 7})
+	hash := []byte("hash")
+
+	voteSet := NewVoteSet(cmLogger, tbftpb.VoteType_VOTE_PREVOTE, 1, 0, valSet)
+	for _, voter := range []string{"node2", "node3", "node4"} {
+		added, err := voteSet.AddVote(NewVote(tbftpb.VoteType_VOTE_PREVOTE, voter, 1, 0, hash))
+		require.Nil(t, err)
+		require.True(t, added)
+	}
+	require.Equal(t, uint64(3), voteSet.Sum)
+	require.False(t, voteSet.HasTwoThirdsMajority())
+	require.False(t, voteSet.hasTwoThirdsNoMajority())
+
+	added, err := voteSet.AddVote(NewVote(tbftpb.VoteType_VOTE_PREVOTE, "node1", 1, 0, hash))
+	require.Nil(t, err)
+	require.True(t, added)
+	require.Equal(t, uint64(10), voteSet.Sum)
+	require.True(t, voteSet.HasTwoThirdsMajority())
+
+	// node1 reaches majority alone
+	voteSet = NewVoteSet(cmLogger, tbftpb.VoteType_VOTE_PRECOMMIT, 1, 0, valSet)
+	_, err = voteSet.AddVote(NewVote(tbftpb.VoteType_VOTE_PRECOMMIT, "node1", 1, 0, hash))
+	require.Nil(t, err)
+	majority, ok := voteSet.twoThirdsMajority()
+	require.True(t, ok)
+	require.Equal(t, hash, majority)
+}
@@ -139,7 +139,7 @@ func (v *Vote) String() string {
 	return fmt.Sprintf("Vote{%s-%s(%d/%d)-%x}", v.Type, v.Voter, v.Height, v.Round, v.Hash)
 }
 
-// BlockVotes traces the vote from different voter
+// BlockVotes traces the vote from different voter, Sum is the voting power of the voters
 type BlockVotes struct {
 	Votes map[string]*Vote
 	Sum   uint64
@@ -181,12 +181,13 @@ func (bv *BlockVotes) ToProto() *tbftpb.BlockVotes {
 	return bvProto
 }
 
-func (bv *BlockVotes) addVote(vote *Vote) {
+func (bv *BlockVotes) addVote(vote *Vote, votingPower uint64) {
 	bv.Votes[vote.Voter] = vote
-	bv.Sum++
+	bv.Sum += votingPower
 }
 
-// VoteSet wraps tbftpb.VoteSet and validatorSet
+// VoteSet wraps tbftpb.VoteSet and validatorSet, Sum is the voting power of the voters,
+// a hash reaches majority once its voters have more than 2/3 of the total voting power
 type VoteSet struct {
 	logger       *logger.CMLogger
 	Type         tbftpb.VoteType
@@ -318,8 +319,9 @@ func (vs *VoteSet) AddVote(vote *Vote) (added bool, err error) {
 			ErrVoteForDifferentHash, v.Hash, vote.Hash)
 	}
 
+	votingPower := vs.validators.VotingPower(vote.Voter)
 	vs.Votes[vote.Voter] = vote
-	vs.Sum++
+	vs.Sum += votingPower
 
 	hashStr := base64.StdEncoding.EncodeToString(vote.Hash)
 	votesByBlock, ok := vs.VotesByBlock[hashStr]
@@ -329,9 +331,9 @@ func (vs *VoteSet) AddVote(vote *Vote) (added bool, err error) {
 	}
 
 	oldSum := votesByBlock.Sum
-	quorum := uint64(vs.validators.Size()*2/3 + 1)
+	quorum := vs.validators.quorum()
 
-	votesByBlock.addVote(vote)
+	votesByBlock.addVote(vote, votingPower)
 	vs.logger.Debugf("VoteSet(%s/%d/%d) AddVote %s(%s/%d/%d/%x) "+
 		"oldSum: %d, quorum: %d, sum: %d",
 		vs.Type, vs.Height, vs.Round, vote.Voter, vote.Type, vote.Height, vote.Round,
@@ -380,9 +382,10 @@ func (vs *VoteSet) hasTwoThirdsAny() bool {
 	}
 
 	ret := true
-	leftSum := uint64(vs.validators.Size()) - vs.Sum
+	leftSum := vs.validators.TotalVotingPower() - vs.Sum
+	quorum := vs.validators.quorum()
 	for _, v := range vs.VotesByBlock {
-		if (v.Sum + leftSum) >= uint64(vs.validators.Size()*2/3+1) {
+		if (v.Sum + leftSum) >= quorum {
 			ret = false
 			break
 		}
@@ -398,7 +401,7 @@ func (vs *VoteSet) hasTwoThirdsNoMajority() bool {
 		return false
 	}
 
-	return vs.Sum >= uint64(vs.validators.Size()*2/3+1)
+	return vs.Sum >= vs.validators.quorum()
 }
 
 type roundVoteSet struct {
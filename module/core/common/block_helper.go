@@ -10,10 +10,12 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"chainmaker.org/chainmaker-go/consensus/gaslimit"
 	"chainmaker.org/chainmaker-go/core/common/scheduler"
 	"chainmaker.org/chainmaker-go/core/provider/conf"
 	"chainmaker.org/chainmaker-go/subscriber"
@@ -32,6 +34,10 @@ import (
 
 const (
 	DEFAULTDURATION = 1000 // default proposal duration, millis seconds
+
+	// BlockGasUsedKey is the key of block.AdditionalData.ExtraData, under which the gas used by all txs
+	// of the block is recorded in decimal
+	BlockGasUsedKey = "GasUsed"
 )
 
 type BlockBuilderConf struct {
@@ -45,6 +51,7 @@ type BlockBuilderConf struct {
 	ChainConf       protocol.ChainConf // chain config
 	Log             protocol.Logger
 	StoreHelper     conf.StoreHelper
+	Pipeline        *VerifyPipeline // may be nil, to propose blocks on top of the committing block
 }
 
 type BlockBuilder struct {
@@ -58,6 +65,7 @@ type BlockBuilder struct {
 	chainConf       protocol.ChainConf // chain config
 	log             protocol.Logger
 	storeHelper     conf.StoreHelper
+	pipeline        *VerifyPipeline
 }
 
 func NewBlockBuilder(conf *BlockBuilderConf) *BlockBuilder {
@@ -72,11 +80,31 @@ func NewBlockBuilder(conf *BlockBuilderConf) *BlockBuilder {
 		chainConf:       conf.ChainConf,
 		log:             conf.Log,
 		storeHelper:     conf.StoreHelper,
+		pipeline:        conf.Pipeline,
 	}
 
 	return creatorBlock
 }
 
+// RetryTxs puts the txs back into the tx pool, except the txs which used more gas than the block gas limit.
+// Those txs can not be packed into any block, so they are removed from the tx pool.
+func RetryTxs(txPool protocol.TxPool, chainConf protocol.ChainConf, txs []*commonpb.Transaction,
+	log protocol.Logger) {
+	gasLimit, _ := gaslimit.ParseBlockGasLimit(chainConf.ChainConfig())
+	retryTxs := make([]*commonpb.Transaction, 0, len(txs))
+	removeTxs := make([]*commonpb.Transaction, 0)
+	for _, tx := range txs {
+		if scheduler.ExceedsBlockGasLimit(tx, gasLimit) {
+			log.Warnf("remove tx id:%s from txpool, used gas %d exceeds the block gas limit %d",
+				tx.Payload.TxId, scheduler.TxGasUsed(tx), gasLimit)
+			removeTxs = append(removeTxs, tx)
+			continue
+		}
+		retryTxs = append(retryTxs, tx)
+	}
+	txPool.RetryAndRemoveTxs(retryTxs, removeTxs)
+}
+
 func (bb *BlockBuilder) GenerateNewBlock(proposingHeight uint64, preHash []byte, txBatch []*commonpb.Transaction) (
 	*commonpb.Block, []int64, error) {
 	timeLasts := make([]int64, 0)
@@ -85,6 +113,9 @@ func (bb *BlockBuilder) GenerateNewBlock(proposingHeight uint64, preHash []byte,
 	if lastBlock == nil {
 		return nil, nil, fmt.Errorf("no pre block found [%d] (%x)", proposingHeight-1, preHash)
 	}
+	if err := bb.pipeline.CheckParent(preHash); err != nil {
+		return nil, nil, fmt.Errorf("pre block [%d] (%x) is rolled back", proposingHeight-1, preHash)
+	}
 	isConfigBlock := false
 	if len(txBatch) == 1 && utils.IsConfigTx(txBatch[0]) {
 		isConfigBlock = true
@@ -125,6 +156,7 @@ func (bb *BlockBuilder) GenerateNewBlock(proposingHeight uint64, preHash []byte,
 
 	vmLasts := utils.CurrentTimeMillisSeconds() - vmStartTick
 	timeLasts = append(timeLasts, ssLasts, vmLasts)
+	SetBlockGasUsed(block, scheduler.BlockGasUsed(block.Txs))
 
 	// deal with the special situation：
 	// 1. only one tx and schedule time out
@@ -147,7 +179,7 @@ func (bb *BlockBuilder) GenerateNewBlock(proposingHeight uint64, preHash []byte,
 
 	finalizeLasts := utils.CurrentTimeMillisSeconds() - finalizeStartTick
 	timeLasts = append(timeLasts, finalizeLasts)
-	// get txs schedule timeout and put back to txpool
+	// get txs schedule timeout or left out by the block gas limit and put back to txpool
 	var txsTimeout = make([]*commonpb.Transaction, 0)
 	if len(txRWSetMap) < len(txBatch) {
 		// if tx not in txRWSetMap, tx should be put back to txpool
@@ -156,12 +188,17 @@ func (bb *BlockBuilder) GenerateNewBlock(proposingHeight uint64, preHash []byte,
 				txsTimeout = append(txsTimeout, tx)
 			}
 		}
-		bb.txPool.RetryAndRemoveTxs(txsTimeout, nil)
+		RetryTxs(bb.txPool, bb.chainConf, txsTimeout, bb.log)
 	}
 
 	// cache proposed block
 	bb.log.Debugf("set proposed block(%d,%x)", block.Header.BlockHeight, block.Header.BlockHash)
-	if err = bb.proposalCache.SetProposedBlock(block, txRWSetMap, contractEventMap, true); err != nil {
+	if bb.pipeline != nil {
+		err = bb.pipeline.SetProposedBlock(block, txRWSetMap, contractEventMap)
+	} else {
+		err = bb.proposalCache.SetProposedBlock(block, txRWSetMap, contractEventMap, true)
+	}
+	if err != nil {
 		return block, timeLasts, err
 	}
 	bb.proposalCache.SetProposedAt(block.Header.BlockHeight)
@@ -427,6 +464,49 @@ func CheckBlockDigests(block *commonpb.Block, txHashes [][]byte, hashType string
 	return nil
 }
 
+// SetBlockGasUsed records the gas used by the txs of the block in the additional data
+func SetBlockGasUsed(block *commonpb.Block, gasUsed uint64) {
+	if block.AdditionalData == nil {
+		block.AdditionalData = &commonpb.AdditionalData{}
+	}
+	if block.AdditionalData.ExtraData == nil {
+		block.AdditionalData.ExtraData = make(map[string][]byte)
+	}
+	block.AdditionalData.ExtraData[BlockGasUsedKey] = []byte(strconv.FormatUint(gasUsed, 10))
+}
+
+// GetBlockGasUsed returns the gas used recorded in the additional data of the block, false if not recorded
+func GetBlockGasUsed(block *commonpb.Block) (uint64, bool, error) {
+	if block.AdditionalData == nil || block.AdditionalData.ExtraData == nil {
+		return 0, false, nil
+	}
+	value, ok := block.AdditionalData.ExtraData[BlockGasUsedKey]
+	if !ok {
+		return 0, false, nil
+	}
+	gasUsed, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid block gas used %s", value)
+	}
+	return gasUsed, true, nil
+}
+
+// IsBlockGasValid, to check if the gas used by the txs of the block is under the gas limit,
+// and is equal with the gas used recorded in the block
+func IsBlockGasValid(block *commonpb.Block, gasUsed uint64, gasLimit uint64) error {
+	if gasLimit > 0 && gasUsed > gasLimit {
+		return fmt.Errorf("block gas used %d exceeds the limit %d", gasUsed, gasLimit)
+	}
+	recorded, ok, err := GetBlockGasUsed(block)
+	if err != nil {
+		return err
+	}
+	if ok && recorded != gasUsed {
+		return fmt.Errorf("block gas used expect %d, got %d", recorded, gasUsed)
+	}
+	return nil
+}
+
 func CheckVacuumBlock(block *commonpb.Block, consensusType consensus.ConsensusType) error {
 	if block.Header.TxCount == 0 {
 		if utils.CanProposeEmptyBlock(consensusType) {
@@ -591,6 +671,17 @@ func (vb *VerifierBlock) ValidateBlock(
 	//	v.txPool.AddTrustedTx(newAddTx)
 	//}
 
+	// the tx results have been verified, so are the gas used by the txs
+	gasLimit, err := gaslimit.ParseBlockGasLimit(vb.chainConf.ChainConfig())
+	if err != nil {
+		vb.log.Errorf("block [%d] gas is unlimited, %s", block.Header.BlockHeight, err)
+	}
+	gasUsed := scheduler.BlockGasUsed(block.Txs)
+	if err = IsBlockGasValid(block, gasUsed, gasLimit); err != nil {
+		return nil, nil, timeLasts, fmt.Errorf("verify failed [%d](%x), %s ",
+			block.Header.BlockHeight, block.Header.BlockHash, err)
+	}
+
 	// get contract events
 	contractEventMap := make(map[string][]*commonpb.ContractEvent)
 	for _, tx := range block.Txs {
@@ -647,6 +738,7 @@ type BlockCommitterImpl struct {
 	metricBlockCommitTime *prometheus.HistogramVec // metric block commit time
 	storeHelper           conf.StoreHelper
 	blockInterval         int64
+	pipeline              *VerifyPipeline // verify pipeline, to roll back the blocks verified on uncommitted blocks
 }
 
 type BlockCommitterConfig struct {
@@ -661,6 +753,7 @@ type BlockCommitterConfig struct {
 	Subscriber      *subscriber.EventSubscriber
 	Verifier        protocol.BlockVerifier
 	StoreHelper     conf.StoreHelper
+	Pipeline        *VerifyPipeline
 }
 
 func NewBlockCommitter(config BlockCommitterConfig, log protocol.Logger) (protocol.BlockCommitter, error) {
@@ -677,6 +770,7 @@ func NewBlockCommitter(config BlockCommitterConfig, log protocol.Logger) (protoc
 		subscriber:      config.Subscriber,
 		verifier:        config.Verifier,
 		storeHelper:     config.StoreHelper,
+		pipeline:        config.Pipeline,
 	}
 
 	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
@@ -760,11 +854,15 @@ func (chain *BlockCommitterImpl) isBlockLegal(blk *commonpb.Block) error {
 }
 
 func (chain *BlockCommitterImpl) AddBlock(block *commonpb.Block) (err error) {
+	committing := false
 	defer func() {
 		panicErr := recover()
 		if err == nil {
 			if panicErr != nil {
 				err = fmt.Errorf(fmt.Sprint(panicErr))
+				if committing {
+					chain.pipeline.EndCommit(block, err)
+				}
 			} else {
 				return
 			}
@@ -808,14 +906,23 @@ func (chain *BlockCommitterImpl) AddBlock(block *commonpb.Block) (err error) {
 		lastProposed.Header = block.Header
 		lastProposed.AdditionalData = block.AdditionalData
 	}
+	// the additional data of the block is not sent by the proposer with the consensus message turbo function
+	if _, ok, _ := GetBlockGasUsed(lastProposed); !ok {
+		SetBlockGasUsed(lastProposed, scheduler.BlockGasUsed(lastProposed.Txs))
+	}
 
 	checkLasts := utils.CurrentTimeMillisSeconds() - startTick
+	chain.pipeline.BeginCommit(lastProposed)
+	committing = true
 	dbLasts, snapshotLasts, confLasts, otherLasts, pubEvent, blockInfo, err := chain.commonCommit.CommitBlock(
 		lastProposed, rwSetMap, conEventMap)
 	if err != nil {
 		chain.log.Errorf("block common commit failed: %s, blockHeight: (%d)",
 			err.Error(), lastProposed.Header.BlockHeight)
 	}
+	// roll back the blocks verified on top of this block if it failed, or on top of its forks otherwise
+	chain.pipeline.EndCommit(lastProposed, err)
+	committing = false
 
 	// Remove txs from txpool. Remove will invoke proposeSignal from txpool if pool size > txcount
 	startPoolTick := utils.CurrentTimeMillisSeconds()
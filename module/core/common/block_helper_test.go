@@ -11,9 +11,11 @@ import (
 	"testing"
 	"time"
 
+	"chainmaker.org/chainmaker-go/consensus/gaslimit"
 	"chainmaker.org/chainmaker/common/v2/crypto/hash"
 	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
 	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	configpb "chainmaker.org/chainmaker/pb-go/v2/config"
 	"chainmaker.org/chainmaker/protocol/v2"
 	"chainmaker.org/chainmaker/protocol/v2/test"
 	"chainmaker.org/chainmaker/utils/v2"
@@ -193,3 +195,63 @@ func FinalizeBlockSync(
 
 	return nil
 }
+
+func TestIsBlockGasValid(t *testing.T) {
+	block := createBlock(10)
+	_, ok, err := GetBlockGasUsed(block)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoError(t, IsBlockGasValid(block, 100, 0))
+	require.NoError(t, IsBlockGasValid(block, 100, 100))
+	require.Error(t, IsBlockGasValid(block, 101, 100))
+
+	SetBlockGasUsed(block, 100)
+	gasUsed, ok, err := GetBlockGasUsed(block)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(100), gasUsed)
+	require.NoError(t, IsBlockGasValid(block, 100, 0))
+	require.Error(t, IsBlockGasValid(block, 99, 0))
+
+	block.AdditionalData.ExtraData[BlockGasUsedKey] = []byte("abc")
+	require.Error(t, IsBlockGasValid(block, 100, 0))
+}
+
+type retryRecorderTxPool struct {
+	protocol.TxPool
+	retried []*commonpb.Transaction
+	removed []*commonpb.Transaction
+}
+
+func (p *retryRecorderTxPool) RetryAndRemoveTxs(retryTxs []*commonpb.Transaction,
+	removeTxs []*commonpb.Transaction) {
+	p.retried = append(p.retried, retryTxs...)
+	p.removed = append(p.removed, removeTxs...)
+}
+
+func TestRetryTxs(t *testing.T) {
+	newGasTx := func(txId string, gasUsed uint64) *commonpb.Transaction {
+		tx := createNewTestTx(txId)
+		tx.Result = &commonpb.Result{ContractResult: &commonpb.ContractResult{GasUsed: gasUsed}}
+		return tx
+	}
+	notExecuted := createNewTestTx("notExecuted")
+	notExecuted.Result = nil
+	txs := []*commonpb.Transaction{newGasTx("fit", 100), newGasTx("oversized", 101), notExecuted}
+	chainConf := &blockConfigChainConf{chainConfig: &configpb.ChainConfig{Consensus: &configpb.ConsensusConfig{
+		ExtConfig: []*configpb.ConfigKeyValue{{Key: gaslimit.BlockGasLimitKey, Value: "100"}},
+	}}}
+
+	// the oversized tx can not be packed into any block, so it is removed instead of retried
+	txPool := &retryRecorderTxPool{}
+	RetryTxs(txPool, chainConf, txs, &test.GoLogger{})
+	require.Equal(t, []*commonpb.Transaction{txs[0], txs[2]}, txPool.retried)
+	require.Equal(t, []*commonpb.Transaction{txs[1]}, txPool.removed)
+
+	// all txs are retried without the gas limit
+	chainConf.chainConfig.Consensus.ExtConfig = nil
+	txPool = &retryRecorderTxPool{}
+	RetryTxs(txPool, chainConf, txs, &test.GoLogger{})
+	require.Equal(t, txs, txPool.retried)
+	require.Empty(t, txPool.removed)
+}
@@ -123,7 +123,7 @@ func (cb *CommitBlock) CommitBlock(
 			}
 			eventsInfo = append(eventsInfo, eventInfo)
 		}
-		cb.msgBus.Publish(msgbus.ContractEventInfo, &commonpb.ContractEventInfoList{ContractEvents: eventsInfo})
+		cb.msgBus.PublishSafe(msgbus.ContractEventInfo, &commonpb.ContractEventInfoList{ContractEvents: eventsInfo})
 		pubEvent = utils.CurrentTimeMillisSeconds() - startPublishContractEventTick
 	}
 	startOtherTick := utils.CurrentTimeMillisSeconds()
@@ -0,0 +1,462 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"chainmaker.org/chainmaker/localconf/v2"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/panjf2000/ants/v2"
+)
+
+const (
+	defaultHotKeyThreshold    = 2
+	defaultMaxConflictRetries = 3
+	// maxConflictShrink the pool is shrunk at most to a quarter by the conflicts of the last batch
+	maxConflictShrink = 0.75
+	// maxPredictedMethods the predictor forgets all methods once it has learned more methods than this
+	maxPredictedMethods = 4096
+	// maxPredictedKeys the keys of a method are not predicted if a tx of it accesses more keys than this
+	maxPredictedKeys = 64
+
+	// DeclaredReadKeysParam the optional parameter of a tx declaring the keys it reads, a JSON array of
+	// "<contract name>#<key>", which takes precedence over the predicted keys in the conflict-aware schedule
+	DeclaredReadKeysParam = "__READ_KEYS__"
+	// DeclaredWriteKeysParam the optional parameter of a tx declaring the keys it writes, in the same format
+	DeclaredWriteKeysParam = "__WRITE_KEYS__"
+)
+
+// ConflictAwareConfig the config of the conflict-aware schedule, scheduler.conflict_aware in chainmaker.yml
+type ConflictAwareConfig struct {
+	// Enabled conflict-aware schedule switch, default is false
+	Enabled bool `mapstructure:"enabled"`
+	// HotKeyThreshold a key written by a tx is hot if it is predicted to be accessed by the number of txs or more
+	// in a batch, the txs accessing a hot key are executed serially. Default is 2.
+	HotKeyThreshold int `mapstructure:"hot_key_threshold"`
+	// MaxConflictRetries a tx conflicted more times is executed serially after the others. Default is 3.
+	MaxConflictRetries int `mapstructure:"max_conflict_retries"`
+	// MaxPoolSize the max size of the goroutine pool, 0 means the pool capacity of the store
+	MaxPoolSize int `mapstructure:"max_pool_size"`
+}
+
+var (
+	conflictAwareConfig   = &ConflictAwareConfig{}
+	conflictAwareConfigMu sync.RWMutex
+)
+
+// SetConflictAwareConfig sets the config of the conflict-aware schedule for all chains
+func SetConflictAwareConfig(config *ConflictAwareConfig) {
+	if config.HotKeyThreshold < defaultHotKeyThreshold {
+		config.HotKeyThreshold = defaultHotKeyThreshold
+	}
+	if config.MaxConflictRetries <= 0 {
+		config.MaxConflictRetries = defaultMaxConflictRetries
+	}
+	conflictAwareConfigMu.Lock()
+	defer conflictAwareConfigMu.Unlock()
+	conflictAwareConfig = config
+}
+
+func getConflictAwareConfig() *ConflictAwareConfig {
+	conflictAwareConfigMu.RLock()
+	defer conflictAwareConfigMu.RUnlock()
+	return conflictAwareConfig
+}
+
+// scheduleStats the statistics of scheduling a batch
+type scheduleStats struct {
+	conflicts int64 // times of the txs failed to apply because of read/write conflicts
+	retries   int64 // times of the txs executed again after conflicts
+	serial    int64 // number of the txs executed serially after conflicting too many times
+	outOfGas  int64 // number of the txs left out of the block since the block gas limit is reached
+	lanes     int   // number of the tasks executed in parallel
+	poolSize  int
+}
+
+// adaptivePoolSize returns the size of the goroutine pool to run the tasks, it is bounded by the capacity,
+// and shrinks with the conflict ratio of the last batch since conflicted txs are executed again
+func adaptivePoolSize(capacity, tasks int, conflictRatio float64) int {
+	size := capacity
+	if tasks < size {
+		size = tasks
+	}
+	size = int(math.Ceil(float64(size) * (1 - math.Min(conflictRatio, maxConflictShrink))))
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// predictedKeys the keys accessed by a tx, declared by the tx or predicted from the previous executions
+// of the same method
+type predictedKeys struct {
+	reads  map[string]struct{}
+	writes map[string]struct{}
+}
+
+// keyPredictor predicts the keys accessed by a tx to be the keys accessed by the last two txs invoking the
+// same method of the same contract, e.g. a counter or the total supply updated by every invocation
+type keyPredictor struct {
+	mu      sync.Mutex
+	last    map[string]*predictedKeys // method -> keys accessed by the last tx
+	predict map[string]*predictedKeys // method -> keys accessed by both of the last two txs
+}
+
+func newKeyPredictor() *keyPredictor {
+	return &keyPredictor{
+		last:    make(map[string]*predictedKeys),
+		predict: make(map[string]*predictedKeys),
+	}
+}
+
+func methodOf(tx *commonpb.Transaction) string {
+	return tx.Payload.ContractName + "#" + tx.Payload.Method
+}
+
+// observe learns the keys accessed by the executed txs
+func (kp *keyPredictor) observe(txs []*commonpb.Transaction, txRWSetMap map[string]*commonpb.TxRWSet) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	for _, tx := range txs {
+		rwSet, ok := txRWSetMap[tx.Payload.TxId]
+		if !ok || rwSet == nil {
+			continue
+		}
+		method := methodOf(tx)
+		keys := &predictedKeys{reads: make(map[string]struct{}), writes: make(map[string]struct{})}
+		for _, read := range rwSet.TxReads {
+			keys.reads[read.ContractName+"#"+string(read.Key)] = struct{}{}
+		}
+		for _, write := range rwSet.TxWrites {
+			keys.writes[write.ContractName+"#"+string(write.Key)] = struct{}{}
+		}
+		if len(keys.reads)+len(keys.writes) > maxPredictedKeys {
+			delete(kp.last, method)
+			delete(kp.predict, method)
+			continue
+		}
+		if len(kp.last) >= maxPredictedMethods {
+			kp.last = make(map[string]*predictedKeys)
+			kp.predict = make(map[string]*predictedKeys)
+		}
+		if last, ok := kp.last[method]; ok {
+			kp.predict[method] = &predictedKeys{
+				reads:  intersectKeys(last.reads, keys.reads),
+				writes: intersectKeys(last.writes, keys.writes),
+			}
+		}
+		kp.last[method] = keys
+	}
+}
+
+func (kp *keyPredictor) keysOf(tx *commonpb.Transaction) *predictedKeys {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	return kp.predict[methodOf(tx)]
+}
+
+func intersectKeys(a, b map[string]struct{}) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for key := range a {
+		if _, ok := b[key]; ok {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// groupTxs groups the txs into lanes by the declared or predicted keys. A key written by a tx is hot if it is
+// accessed by hotKeyThreshold or more txs, the txs accessing the same hot keys are put into one lane, the other txs
+// are put into lanes of their own. The lanes are ordered by their first txs, and the txs in a lane keep the order
+// of the batch, so the grouping is deterministic.
+func groupTxs(txBatch []*commonpb.Transaction, keysOf func(*commonpb.Transaction) *predictedKeys,
+	hotKeyThreshold int) [][]*commonpb.Transaction {
+	accessors := make(map[string][]int)
+	written := make(map[string]bool)
+	for i, tx := range txBatch {
+		keys := keysOf(tx)
+		if keys == nil {
+			continue
+		}
+		for key := range keys.writes {
+			accessors[key] = append(accessors[key], i)
+			written[key] = true
+		}
+		for key := range keys.reads {
+			if _, ok := keys.writes[key]; !ok {
+				accessors[key] = append(accessors[key], i)
+			}
+		}
+	}
+
+	parent := make([]int, len(txBatch))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for key, txIndexes := range accessors {
+		if !written[key] || len(txIndexes) < hotKeyThreshold {
+			continue
+		}
+		for _, i := range txIndexes[1:] {
+			// the smaller index is the root, so the root of a lane is its first tx
+			ri, r0 := find(i), find(txIndexes[0])
+			if ri < r0 {
+				parent[r0] = ri
+			} else {
+				parent[ri] = r0
+			}
+		}
+	}
+
+	laneOf := make(map[int]int)
+	lanes := make([][]*commonpb.Transaction, 0)
+	for i, tx := range txBatch {
+		root := find(i)
+		lane, ok := laneOf[root]
+		if !ok {
+			lane = len(lanes)
+			laneOf[root] = lane
+			lanes = append(lanes, nil)
+		}
+		lanes[lane] = append(lanes[lane], tx)
+	}
+	return lanes
+}
+
+// scheduleConflictAware executes the txs grouped into lanes by the declared or predicted keys, the lanes run
+// in parallel and the txs in a lane run serially. A tx conflicted more than MaxConflictRetries times is executed
+// after all lanes finish, one by one in the order of the batch, where it can not conflict any more.
+func (ts *TxScheduler) scheduleConflictAware(block *commonpb.Block, txBatch []*commonpb.Transaction,
+	snapshot protocol.Snapshot, gasMeter *blockGasMeter, config *ConflictAwareConfig) (*scheduleStats, error) {
+	stats := &scheduleStats{}
+	lanes := groupTxs(txBatch, ts.keysOf, config.HotKeyThreshold)
+	stats.lanes = len(lanes)
+	if len(lanes) == 0 {
+		return stats, nil
+	}
+
+	capacity := ts.StoreHelper.GetPoolCapacity()
+	if config.MaxPoolSize > 0 && config.MaxPoolSize < capacity {
+		capacity = config.MaxPoolSize
+	}
+	stats.poolSize = adaptivePoolSize(capacity, len(lanes), ts.lastConflictRatio)
+	goRoutinePool, err := ants.NewPool(stats.poolSize, ants.WithPreAlloc(true))
+	if err != nil {
+		return nil, err
+	}
+	defer goRoutinePool.Release()
+
+	txIndexes := make(map[string]int, len(txBatch))
+	for i, tx := range txBatch {
+		txIndexes[tx.Payload.TxId] = i
+	}
+	var (
+		deferredMu sync.Mutex
+		deferred   []*commonpb.Transaction
+	)
+	runTx := func(tx *commonpb.Transaction) {
+		for retry := 0; ; retry++ {
+			if ts.executeAndApply(tx, snapshot, block, gasMeter, stats) {
+				return
+			}
+			atomic.AddInt64(&stats.conflicts, 1)
+			if retry >= config.MaxConflictRetries {
+				deferredMu.Lock()
+				deferred = append(deferred, tx)
+				deferredMu.Unlock()
+				return
+			}
+			atomic.AddInt64(&stats.retries, 1)
+		}
+	}
+	timeoutC := time.After(ScheduleTimeout * time.Second)
+	if err = runLanes(goRoutinePool, lanes, runTx, timeoutC, ts.scheduleFinishC); err != nil {
+		ts.log.Warnf("block [%d] %s", block.Header.BlockHeight, err)
+		return stats, nil
+	}
+
+	sort.Slice(deferred, func(i, j int) bool {
+		return txIndexes[deferred[i].Payload.TxId] < txIndexes[deferred[j].Payload.TxId]
+	})
+	stats.serial = int64(len(deferred))
+	for _, tx := range deferred {
+		if err = checkStopped(timeoutC, ts.scheduleFinishC); err != nil {
+			ts.log.Warnf("block [%d] %s", block.Header.BlockHeight, err)
+			return stats, nil
+		}
+		for retry := 0; ; retry++ {
+			if retry > config.MaxConflictRetries {
+				ts.log.Warnf("failed to apply tx id:%s serially, skip it", tx.Payload.GetTxId())
+				break
+			}
+			stats.retries++
+			if ts.executeAndApply(tx, snapshot, block, gasMeter, stats) {
+				break
+			}
+			stats.conflicts++
+		}
+	}
+	return stats, nil
+}
+
+var (
+	errScheduleTimeout = errors.New("schedule reached time limit")
+	errScheduleHalted  = errors.New("schedule halted")
+)
+
+// runLanes runs the lanes in the goroutine pool, the txs in a lane are run one by one by runTx. Once the
+// schedule reaches the time limit or is halted, the lanes stop before their next txs and runLanes waits for
+// the txs being run, so that no tx is applied to the snapshot after it returns.
+func runLanes(pool *ants.Pool, lanes [][]*commonpb.Transaction, runTx func(*commonpb.Transaction),
+	timeoutC <-chan time.Time, haltC <-chan bool) error {
+	var (
+		wg      sync.WaitGroup
+		stopped int32
+	)
+	doneC := make(chan struct{})
+	go func() {
+		// the lanes are submitted here since Submit blocks while the pool is full
+		for _, lane := range lanes {
+			lane := lane
+			wg.Add(1)
+			if err := pool.Submit(func() {
+				defer wg.Done()
+				for _, tx := range lane {
+					if atomic.LoadInt32(&stopped) == 1 {
+						return
+					}
+					runTx(tx)
+				}
+			}); err != nil {
+				wg.Done()
+			}
+		}
+		wg.Wait()
+		close(doneC)
+	}()
+
+	var err error
+	select {
+	case <-doneC:
+		return nil
+	case <-timeoutC:
+		err = errScheduleTimeout
+	case <-haltC:
+		err = errScheduleHalted
+	}
+	atomic.StoreInt32(&stopped, 1)
+	<-doneC
+	return err
+}
+
+// checkStopped returns an error if the schedule has reached the time limit or has been halted
+func checkStopped(timeoutC <-chan time.Time, haltC <-chan bool) error {
+	select {
+	case <-timeoutC:
+		return errScheduleTimeout
+	case <-haltC:
+		return errScheduleHalted
+	default:
+		return nil
+	}
+}
+
+// keysOf returns the keys declared by the tx, or the keys predicted from the txs of the same method
+func (ts *TxScheduler) keysOf(tx *commonpb.Transaction) *predictedKeys {
+	if keys := declaredKeys(tx); keys != nil {
+		return keys
+	}
+	return ts.keyPredictor.keysOf(tx)
+}
+
+// declaredKeys returns the keys declared by the DeclaredReadKeysParam and DeclaredWriteKeysParam parameters
+// of the tx, nil if the tx declares none or a declaration is malformed. The declared keys only group the txs,
+// the DAG of the block is built from the keys really accessed.
+func declaredKeys(tx *commonpb.Transaction) *predictedKeys {
+	var keys *predictedKeys
+	for _, param := range tx.Payload.Parameters {
+		if param.Key != DeclaredReadKeysParam && param.Key != DeclaredWriteKeysParam {
+			continue
+		}
+		var declared []string
+		if err := json.Unmarshal(param.Value, &declared); err != nil {
+			return nil
+		}
+		if keys == nil {
+			keys = &predictedKeys{reads: make(map[string]struct{}), writes: make(map[string]struct{})}
+		}
+		set := keys.reads
+		if param.Key == DeclaredWriteKeysParam {
+			set = keys.writes
+		}
+		for _, key := range declared {
+			set[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// executeAndApply executes the tx and applies it to the snapshot, it returns false if the tx conflicted.
+// The tx is left out of the block if the block gas limit would be exceeded by it.
+func (ts *TxScheduler) executeAndApply(tx *commonpb.Transaction, snapshot protocol.Snapshot,
+	block *commonpb.Block, gasMeter *blockGasMeter, stats *scheduleStats) bool {
+	var start time.Time
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		start = time.Now()
+	}
+	txSimContext, specialTxType, runVmSuccess := ts.executeTx(tx, snapshot, block)
+	tx.Result = txSimContext.GetTxResult()
+	gasUsed := TxGasUsed(tx)
+	if !gasMeter.consume(gasUsed) {
+		ts.leaveOutOfGas(tx, gasUsed, gasMeter, stats)
+		return true
+	}
+	applyResult, applySize := snapshot.ApplyTxSimContext(txSimContext, specialTxType, runVmSuccess, false)
+	if !applyResult {
+		gasMeter.refund(gasUsed)
+		ts.log.Debugf("failed to apply tx id:%s, conflicted", tx.Payload.GetTxId())
+		return false
+	}
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		ts.metricVMRunTime.WithLabelValues(tx.Payload.ChainId).Observe(time.Since(start).Seconds())
+	}
+	ts.log.Debugf("apply to snapshot tx id:%s, result:%+v, apply count:%d",
+		tx.Payload.GetTxId(), txSimContext.GetTxResult(), applySize)
+	return true
+}
+
+// reportScheduleStats logs the conflicts and retries of the block, and records them in the metrics
+func (ts *TxScheduler) reportScheduleStats(block *commonpb.Block, txBatchSize int, stats *scheduleStats) {
+	// the txs may be still running if the schedule reached time limit
+	conflicts, retries := atomic.LoadInt64(&stats.conflicts), atomic.LoadInt64(&stats.retries)
+	outOfGas := atomic.LoadInt64(&stats.outOfGas)
+	if txBatchSize > 0 {
+		ts.lastConflictRatio = float64(conflicts) / float64(txBatchSize)
+	}
+	ts.log.Infof("block [%d] schedule conflicts %d, retries %d, serial txs %d, lanes %d, pool size %d, "+
+		"out of gas txs %d", block.Header.BlockHeight, conflicts, retries, stats.serial, stats.lanes, stats.poolSize,
+		outOfGas)
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		ts.metricTxConflictCount.WithLabelValues(block.Header.ChainId).Observe(float64(conflicts))
+		ts.metricTxRetryCount.WithLabelValues(block.Header.ChainId).Observe(float64(retries))
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/panjf2000/ants/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newConflictTestTx(txId, method string) *commonpb.Transaction {
+	return &commonpb.Transaction{
+		Payload: &commonpb.Payload{
+			TxId:         txId,
+			ContractName: "counter",
+			Method:       method,
+		},
+	}
+}
+
+func newConflictTestRWSet(txId string, reads, writes []string) *commonpb.TxRWSet {
+	rwSet := &commonpb.TxRWSet{TxId: txId}
+	for _, key := range reads {
+		rwSet.TxReads = append(rwSet.TxReads, &commonpb.TxRead{ContractName: "counter", Key: []byte(key)})
+	}
+	for _, key := range writes {
+		rwSet.TxWrites = append(rwSet.TxWrites, &commonpb.TxWrite{ContractName: "counter", Key: []byte(key)})
+	}
+	return rwSet
+}
+
+func laneTxIds(lanes [][]*commonpb.Transaction) [][]string {
+	ids := make([][]string, 0, len(lanes))
+	for _, lane := range lanes {
+		laneIds := make([]string, 0, len(lane))
+		for _, tx := range lane {
+			laneIds = append(laneIds, tx.Payload.TxId)
+		}
+		ids = append(ids, laneIds)
+	}
+	return ids
+}
+
+func TestKeyPredictor(t *testing.T) {
+	kp := newKeyPredictor()
+	tx1 := newConflictTestTx("tx1", "inc")
+	tx2 := newConflictTestTx("tx2", "inc")
+	require.Nil(t, kp.keysOf(tx1))
+
+	// the keys accessed by both of the last two txs are predicted
+	kp.observe([]*commonpb.Transaction{tx1, tx2}, map[string]*commonpb.TxRWSet{
+		"tx1": newConflictTestRWSet("tx1", []string{"total", "alice"}, []string{"total", "alice"}),
+		"tx2": newConflictTestRWSet("tx2", []string{"total", "bob"}, []string{"total", "bob"}),
+	})
+	keys := kp.keysOf(newConflictTestTx("tx3", "inc"))
+	require.NotNil(t, keys)
+	require.Equal(t, map[string]struct{}{"counter#total": {}}, keys.writes)
+	require.Equal(t, map[string]struct{}{"counter#total": {}}, keys.reads)
+	require.Nil(t, kp.keysOf(newConflictTestTx("tx4", "get")))
+
+	// a method accessing too many keys is not predicted
+	var many []string
+	for i := 0; i <= maxPredictedKeys; i++ {
+		many = append(many, string(rune('a'+i%26))+string(rune('a'+i/26)))
+	}
+	kp.observe([]*commonpb.Transaction{tx1}, map[string]*commonpb.TxRWSet{
+		"tx1": newConflictTestRWSet("tx1", nil, many),
+	})
+	require.Nil(t, kp.keysOf(tx1))
+}
+
+func TestGroupTxs(t *testing.T) {
+	predicted := map[string]*predictedKeys{
+		"tx1": {writes: map[string]struct{}{"total": {}}},
+		"tx2": {reads: map[string]struct{}{"meta": {}}},
+		"tx3": {reads: map[string]struct{}{"total": {}}},
+		"tx4": {reads: map[string]struct{}{"meta": {}}},
+		"tx5": {writes: map[string]struct{}{"total": {}, "supply": {}}},
+		"tx6": {writes: map[string]struct{}{"supply": {}}},
+	}
+	keysOf := func(tx *commonpb.Transaction) *predictedKeys {
+		return predicted[tx.Payload.TxId]
+	}
+	var txs []*commonpb.Transaction
+	for _, txId := range []string{"tx0", "tx1", "tx2", "tx3", "tx4", "tx5", "tx6", "tx7"} {
+		txs = append(txs, newConflictTestTx(txId, "inc"))
+	}
+
+	// the keys only read are not hot, total and supply are chained into one lane
+	lanes := groupTxs(txs, keysOf, 2)
+	require.Equal(t, [][]string{{"tx0"}, {"tx1", "tx3", "tx5", "tx6"}, {"tx2"}, {"tx4"}, {"tx7"}},
+		laneTxIds(lanes))
+
+	// supply is accessed by 2 txs only, which is not hot with the threshold 3
+	lanes = groupTxs(txs, keysOf, 3)
+	require.Equal(t, [][]string{{"tx0"}, {"tx1", "tx3", "tx5"}, {"tx2"}, {"tx4"}, {"tx6"}, {"tx7"}},
+		laneTxIds(lanes))
+
+	require.Empty(t, groupTxs(nil, keysOf, 2))
+}
+
+func TestAdaptivePoolSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		capacity      int
+		tasks         int
+		conflictRatio float64
+		want          int
+	}{
+		{"bounded by tasks", 100, 10, 0, 10},
+		{"bounded by capacity", 100, 1000, 0, 100},
+		{"shrink by conflicts", 100, 1000, 0.5, 50},
+		{"shrink at most", 100, 1000, 3, 25},
+		{"at least one", 100, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, adaptivePoolSize(tt.capacity, tt.tasks, tt.conflictRatio))
+		})
+	}
+}
+
+func TestDeclaredKeys(t *testing.T) {
+	tx := newConflictTestTx("tx1", "transfer")
+	require.Nil(t, declaredKeys(tx))
+
+	tx.Payload.Parameters = []*commonpb.KeyValuePair{
+		{Key: "amount", Value: []byte("10")},
+		{Key: DeclaredReadKeysParam, Value: []byte(`["token#alice"]`)},
+		{Key: DeclaredWriteKeysParam, Value: []byte(`["token#alice","token#bob"]`)},
+	}
+	keys := declaredKeys(tx)
+	require.NotNil(t, keys)
+	require.Equal(t, map[string]struct{}{"token#alice": {}}, keys.reads)
+	require.Equal(t, map[string]struct{}{"token#alice": {}, "token#bob": {}}, keys.writes)
+
+	// the declared keys take precedence over the predicted keys
+	ts := &TxScheduler{keyPredictor: newKeyPredictor()}
+	ts.keyPredictor.observe([]*commonpb.Transaction{newConflictTestTx("tx2", "transfer"),
+		newConflictTestTx("tx3", "transfer")}, map[string]*commonpb.TxRWSet{
+		"tx2": newConflictTestRWSet("tx2", nil, []string{"total"}),
+		"tx3": newConflictTestRWSet("tx3", nil, []string{"total"}),
+	})
+	require.Equal(t, keys, ts.keysOf(tx))
+	require.Equal(t, map[string]struct{}{"counter#total": {}}, ts.keysOf(newConflictTestTx("tx4", "transfer")).writes)
+
+	// a malformed declaration is ignored
+	tx.Payload.Parameters = []*commonpb.KeyValuePair{{Key: DeclaredWriteKeysParam, Value: []byte("token#alice")}}
+	require.Nil(t, declaredKeys(tx))
+}
+
+func TestRunLanes(t *testing.T) {
+	pool, err := ants.NewPool(2)
+	require.NoError(t, err)
+	defer pool.Release()
+
+	var txs []*commonpb.Transaction
+	for _, txId := range []string{"tx0", "tx1", "tx2", "tx3"} {
+		txs = append(txs, newConflictTestTx(txId, "inc"))
+	}
+	lanes := [][]*commonpb.Transaction{{txs[0], txs[1]}, {txs[2]}, {txs[3]}}
+
+	var mu sync.Mutex
+	var ran []string
+	runTx := func(tx *commonpb.Transaction) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, tx.Payload.TxId)
+	}
+	require.NoError(t, runLanes(pool, lanes, runTx, nil, nil))
+	require.ElementsMatch(t, []string{"tx0", "tx1", "tx2", "tx3"}, ran)
+
+	// the running txs are waited for once the schedule times out, and no tx is run after it
+	ran = nil
+	startedC, releaseC := make(chan struct{}, 2), make(chan struct{})
+	blockingTx := func(tx *commonpb.Transaction) {
+		startedC <- struct{}{}
+		<-releaseC
+		runTx(tx)
+	}
+	timeoutC := make(chan time.Time)
+	resultC := make(chan error)
+	go func() {
+		resultC <- runLanes(pool, lanes, blockingTx, timeoutC, nil)
+	}()
+	<-startedC
+	<-startedC
+	timeoutC <- time.Now()
+	select {
+	case <-resultC:
+		t.Fatal("returned before the running txs finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(releaseC)
+	require.Equal(t, errScheduleTimeout, <-resultC)
+	require.Len(t, ran, 2)
+	require.NotContains(t, ran, "tx1")
+}
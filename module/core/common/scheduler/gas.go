@@ -0,0 +1,76 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"sync/atomic"
+
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// TxGasUsed returns the gas used by the executed tx
+func TxGasUsed(tx *commonpb.Transaction) uint64 {
+	if tx.Result == nil || tx.Result.ContractResult == nil {
+		return 0
+	}
+	return tx.Result.ContractResult.GasUsed
+}
+
+// ExceedsBlockGasLimit returns true if the gas used by the executed tx exceeds the block gas limit,
+// so that the tx can not be packed into any block
+func ExceedsBlockGasLimit(tx *commonpb.Transaction, gasLimit uint64) bool {
+	return gasLimit > 0 && TxGasUsed(tx) > gasLimit
+}
+
+// BlockGasUsed returns the gas used by all txs of the block
+func BlockGasUsed(txs []*commonpb.Transaction) uint64 {
+	var gasUsed uint64
+	for _, tx := range txs {
+		gasUsed += TxGasUsed(tx)
+	}
+	return gasUsed
+}
+
+// blockGasMeter counts the gas used by the txs applied to a block, it is safe for concurrent use
+type blockGasMeter struct {
+	limit uint64 // 0 means unlimited
+	used  uint64
+}
+
+func newBlockGasMeter(limit uint64) *blockGasMeter {
+	return &blockGasMeter{limit: limit}
+}
+
+// consume adds the gas to the used gas, it returns false and adds nothing if the limit would be exceeded
+func (m *blockGasMeter) consume(gas uint64) bool {
+	for {
+		used := atomic.LoadUint64(&m.used)
+		if m.limit > 0 && (used+gas > m.limit || used+gas < used) {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(&m.used, used, used+gas) {
+			return true
+		}
+	}
+}
+
+// refund gives back the gas consumed by a tx which failed to apply
+func (m *blockGasMeter) refund(gas uint64) {
+	atomic.AddUint64(&m.used, ^(gas - 1))
+}
+
+// leaveOutOfGas counts the tx left out of the block since the block gas limit would be exceeded by it
+func (ts *TxScheduler) leaveOutOfGas(tx *commonpb.Transaction, gasUsed uint64, gasMeter *blockGasMeter,
+	stats *scheduleStats) {
+	atomic.AddInt64(&stats.outOfGas, 1)
+	if ExceedsBlockGasLimit(tx, gasMeter.limit) {
+		ts.log.Warnf("tx id:%s used gas %d exceeds the block gas limit %d, it can not be packed into any block",
+			tx.Payload.GetTxId(), gasUsed, gasMeter.limit)
+		return
+	}
+	ts.log.Debugf("tx id:%s used gas %d, block gas limit reached", tx.Payload.GetTxId(), gasUsed)
+}
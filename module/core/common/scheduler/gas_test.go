@@ -0,0 +1,53 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExceedsBlockGasLimit(t *testing.T) {
+	tx := &commonpb.Transaction{Result: &commonpb.Result{ContractResult: &commonpb.ContractResult{GasUsed: 100}}}
+	require.False(t, ExceedsBlockGasLimit(tx, 0))
+	require.False(t, ExceedsBlockGasLimit(tx, 100))
+	require.True(t, ExceedsBlockGasLimit(tx, 99))
+	// the tx not executed yet
+	require.False(t, ExceedsBlockGasLimit(&commonpb.Transaction{}, 1))
+}
+
+func TestBlockGasMeter(t *testing.T) {
+	meter := newBlockGasMeter(100)
+	require.True(t, meter.consume(60))
+	require.False(t, meter.consume(50))
+	require.True(t, meter.consume(40))
+	require.False(t, meter.consume(1))
+	meter.refund(40)
+	require.True(t, meter.consume(0))
+	require.True(t, meter.consume(40))
+
+	unlimited := newBlockGasMeter(0)
+	require.True(t, unlimited.consume(math.MaxUint64))
+	require.True(t, unlimited.consume(1))
+
+	// the gas consumed concurrently never exceeds the limit
+	meter = newBlockGasMeter(1000)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meter.consume(30)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, uint64(990), meter.used)
+}
@@ -12,8 +12,10 @@ import (
 	"fmt"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"chainmaker.org/chainmaker-go/consensus/gaslimit"
 	"chainmaker.org/chainmaker-go/core/provider/conf"
 	"chainmaker.org/chainmaker/localconf/v2"
 	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
@@ -36,8 +38,13 @@ type TxScheduler struct {
 	log             protocol.Logger
 	chainConf       protocol.ChainConf // chain config
 
-	metricVMRunTime *prometheus.HistogramVec
-	StoreHelper     conf.StoreHelper
+	metricVMRunTime       *prometheus.HistogramVec
+	metricTxConflictCount *prometheus.HistogramVec // conflicts of the txs in a block
+	metricTxRetryCount    *prometheus.HistogramVec // retries of the txs in a block
+	StoreHelper           conf.StoreHelper
+
+	keyPredictor      *keyPredictor // predicts the keys accessed by txs for the conflict-aware schedule
+	lastConflictRatio float64       // conflicts per tx of the last batch, to shrink the conflict-aware pool
 }
 
 // Transaction dependency in adjacency table representation
@@ -51,19 +58,82 @@ func (ts *TxScheduler) Schedule(block *commonpb.Block, txBatch []*commonpb.Trans
 	defer ts.lock.Unlock()
 	txRWSetMap := make(map[string]*commonpb.TxRWSet)
 	txBatchSize := len(txBatch)
+	ts.log.Infof("schedule tx batch start, size %d", txBatchSize)
+	startTime := time.Now()
+
+	// an invalid limit is rejected by the chain config update, it may only come from the genesis block
+	gasLimit, err := gaslimit.ParseBlockGasLimit(ts.chainConf.ChainConfig())
+	if err != nil {
+		ts.log.Errorf("block [%d] gas is unlimited, %s", block.Header.BlockHeight, err)
+	}
+	gasMeter := newBlockGasMeter(gasLimit)
+
+	var stats *scheduleStats
+	conflictAware := getConflictAwareConfig()
+	if conflictAware.Enabled {
+		stats, err = ts.scheduleConflictAware(block, txBatch, snapshot, gasMeter, conflictAware)
+	} else {
+		stats, err = ts.scheduleParallel(block, txBatch, snapshot, gasMeter)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Build DAG from read-write table
+	snapshot.Seal()
+	timeCostA := time.Since(startTime)
+	block.Dag = snapshot.BuildDAG(ts.chainConf.ChainConfig().Contract.EnableSqlSupport)
+
+	// Execute special tx sequentially, and add to dag
+	if len(snapshot.GetSpecialTxTable()) > 0 {
+		// the txs left out by the block gas limit are never applied
+		ts.simulateSpecialTxs(block.Dag, snapshot, block, txBatchSize-int(atomic.LoadInt64(&stats.outOfGas)))
+	}
+
+	timeCostB := time.Since(startTime)
+	ts.log.Infof("schedule tx batch finished, success %d, time used %v, time used (dag include) %v ",
+		len(block.Dag.Vertexes), timeCostA, timeCostB)
+	block.Txs = snapshot.GetTxTable()
+	txRWSetTable := snapshot.GetTxRWSetTable()
+	for _, txRWSet := range txRWSetTable {
+		if txRWSet != nil {
+			txRWSetMap[txRWSet.TxId] = txRWSet
+		}
+	}
+	contractEventMap := make(map[string][]*commonpb.ContractEvent)
+	for _, tx := range block.Txs {
+		event := tx.Result.ContractResult.ContractEvent
+		contractEventMap[tx.Payload.TxId] = event
+	}
+	//ts.dumpDAG(block.Dag, block.Txs)
+	if localconf.ChainMakerConfig.SchedulerConfig.RWSetLog {
+		ts.log.Debugf("rwset %v", txRWSetMap)
+	}
+	ts.reportScheduleStats(block, txBatchSize, stats)
+	if conflictAware.Enabled {
+		ts.keyPredictor.observe(block.Txs, txRWSetMap)
+	}
+	return txRWSetMap, contractEventMap, nil
+}
+
+// scheduleParallel executes all txs in parallel, and executes a tx again once it conflicts with the others.
+// A tx is left out of the block if the block gas limit would be exceeded by it.
+func (ts *TxScheduler) scheduleParallel(block *commonpb.Block, txBatch []*commonpb.Transaction,
+	snapshot protocol.Snapshot, gasMeter *blockGasMeter) (*scheduleStats, error) {
+	txBatchSize := len(txBatch)
+	stats := &scheduleStats{lanes: txBatchSize}
 	runningTxC := make(chan *commonpb.Transaction, txBatchSize)
 	timeoutC := time.After(ScheduleTimeout * time.Second)
 	finishC := make(chan bool)
-	ts.log.Infof("schedule tx batch start, size %d", txBatchSize)
 	var goRoutinePool *ants.Pool
 	var err error
+	var doneCount int64 // txs applied or left out
 
-	poolCapacity := ts.StoreHelper.GetPoolCapacity()
-	if goRoutinePool, err = ants.NewPool(poolCapacity, ants.WithPreAlloc(true)); err != nil {
-		return nil, nil, err
+	stats.poolSize = ts.StoreHelper.GetPoolCapacity()
+	if goRoutinePool, err = ants.NewPool(stats.poolSize, ants.WithPreAlloc(true)); err != nil {
+		return nil, err
 	}
 	defer goRoutinePool.Release()
-	startTime := time.Now()
 	go func() {
 		for {
 			select {
@@ -81,10 +151,22 @@ func (ts *TxScheduler) Schedule(block *commonpb.Block, txBatch []*commonpb.Trans
 					txSimContext, specialTxType, runVmSuccess := ts.executeTx(tx, snapshot, block)
 					tx.Result = txSimContext.GetTxResult()
 
+					gasUsed := TxGasUsed(tx)
+					if !gasMeter.consume(gasUsed) {
+						ts.leaveOutOfGas(tx, gasUsed, gasMeter, stats)
+						// If all transactions have been successfully added to dag or left out
+						if atomic.AddInt64(&doneCount, 1) == int64(txBatchSize) {
+							finishC <- true
+						}
+						return
+					}
 					// Apply failed means this tx's read set conflict with other txs' write set
 					applyResult, applySize := snapshot.ApplyTxSimContext(txSimContext, specialTxType,
 						runVmSuccess, false)
 					if !applyResult {
+						gasMeter.refund(gasUsed)
+						atomic.AddInt64(&stats.conflicts, 1)
+						atomic.AddInt64(&stats.retries, 1)
 						runningTxC <- tx
 					} else {
 						if localconf.ChainMakerConfig.MonitorConfig.Enabled {
@@ -93,10 +175,10 @@ func (ts *TxScheduler) Schedule(block *commonpb.Block, txBatch []*commonpb.Trans
 						}
 						ts.log.Debugf("apply to snapshot tx id:%s, result:%+v, apply count:%d",
 							tx.Payload.GetTxId(), txSimContext.GetTxResult(), applySize)
-					}
-					// If all transactions have been successfully added to dag
-					if applySize >= txBatchSize {
-						finishC <- true
+						// If all transactions have been successfully added to dag or left out
+						if atomic.AddInt64(&doneCount, 1) == int64(txBatchSize) {
+							finishC <- true
+						}
 					}
 				})
 				if err != nil {
@@ -126,36 +208,7 @@ func (ts *TxScheduler) Schedule(block *commonpb.Block, txBatch []*commonpb.Trans
 
 	// Wait for schedule finish signal
 	<-ts.scheduleFinishC
-	// Build DAG from read-write table
-	snapshot.Seal()
-	timeCostA := time.Since(startTime)
-	block.Dag = snapshot.BuildDAG(ts.chainConf.ChainConfig().Contract.EnableSqlSupport)
-
-	// Execute special tx sequentially, and add to dag
-	if len(snapshot.GetSpecialTxTable()) > 0 {
-		ts.simulateSpecialTxs(block.Dag, snapshot, block, txBatchSize)
-	}
-
-	timeCostB := time.Since(startTime)
-	ts.log.Infof("schedule tx batch finished, success %d, time used %v, time used (dag include) %v ",
-		len(block.Dag.Vertexes), timeCostA, timeCostB)
-	block.Txs = snapshot.GetTxTable()
-	txRWSetTable := snapshot.GetTxRWSetTable()
-	for _, txRWSet := range txRWSetTable {
-		if txRWSet != nil {
-			txRWSetMap[txRWSet.TxId] = txRWSet
-		}
-	}
-	contractEventMap := make(map[string][]*commonpb.ContractEvent)
-	for _, tx := range block.Txs {
-		event := tx.Result.ContractResult.ContractEvent
-		contractEventMap[tx.Payload.TxId] = event
-	}
-	//ts.dumpDAG(block.Dag, block.Txs)
-	if localconf.ChainMakerConfig.SchedulerConfig.RWSetLog {
-		ts.log.Debugf("rwset %v", txRWSetMap)
-	}
-	return txRWSetMap, contractEventMap, nil
+	return stats, nil
 }
 
 // SimulateWithDag based on the dag in the block, perform scheduling and execution transactions
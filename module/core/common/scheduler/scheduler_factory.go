@@ -39,10 +39,12 @@ func newTxScheduler(vmMgr protocol.VmManager, chainConf protocol.ChainConf, stor
 		log:             log,
 		chainConf:       chainConf,
 		StoreHelper:     storeHelper,
+		keyPredictor:    newKeyPredictor(),
 	}
 	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
 		txScheduler.metricVMRunTime = monitor.NewHistogramVec(monitor.SUBSYSTEM_CORE_PROPOSER_SCHEDULER, "metric_vm_run_time",
 			"VM run time metric", []float64{0.005, 0.01, 0.015, 0.05, 0.1, 1, 10}, "chainId")
+		txScheduler.initConflictMetrics()
 	}
 	return txScheduler
 }
@@ -60,6 +62,7 @@ func newTxSchedulerEvidence(vmMgr protocol.VmManager, chainConf protocol.ChainCo
 			log:             log,
 			chainConf:       chainConf,
 			StoreHelper:     storeHelper,
+			keyPredictor:    newKeyPredictor(),
 		},
 	}
 
@@ -71,6 +74,16 @@ func newTxSchedulerEvidence(vmMgr protocol.VmManager, chainConf protocol.ChainCo
 			[]float64{0.005, 0.01, 0.015, 0.05, 0.1, 1, 10},
 			"chainId",
 		)
+		txSchedulerEvidence.delegate.initConflictMetrics()
 	}
 	return txSchedulerEvidence
 }
+
+// initConflictMetrics creates the metrics of the conflicts and retries of the txs in a block
+func (ts *TxScheduler) initConflictMetrics() {
+	countBuckets := []float64{0, 1, 5, 10, 50, 100, 500, 1000, 5000}
+	ts.metricTxConflictCount = monitor.NewHistogramVec(monitor.SUBSYSTEM_CORE_PROPOSER_SCHEDULER,
+		"metric_tx_conflict_count", "tx read/write conflicts in a block metric", countBuckets, "chainId")
+	ts.metricTxRetryCount = monitor.NewHistogramVec(monitor.SUBSYSTEM_CORE_PROPOSER_SCHEDULER,
+		"metric_tx_retry_count", "tx retries after conflicts in a block metric", countBuckets, "chainId")
+}
@@ -0,0 +1,148 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"chainmaker.org/chainmaker/common/v2/monitor"
+	"chainmaker.org/chainmaker/localconf/v2"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/utils/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TxRecoverer puts the txs of a discarded self-proposed block back into the tx pool, unless they are
+// packed in another pending block or committed already.
+type TxRecoverer struct {
+	chainId         string
+	log             protocol.Logger
+	chainConf       protocol.ChainConf
+	ledgerCache     protocol.LedgerCache
+	proposalCache   protocol.ProposalCache
+	txPool          protocol.TxPool
+	blockchainStore protocol.BlockchainStore
+
+	metricRecoveredTxCounter *prometheus.CounterVec // txs of the discarded blocks put back into the tx pool
+	metricLostTxCounter      *prometheus.CounterVec // txs of the discarded blocks which can not be put back
+}
+
+type TxRecovererConfig struct {
+	ChainId         string
+	Log             protocol.Logger
+	ChainConf       protocol.ChainConf
+	LedgerCache     protocol.LedgerCache
+	ProposalCache   protocol.ProposalCache
+	TxPool          protocol.TxPool
+	BlockchainStore protocol.BlockchainStore
+}
+
+// DiscardedTxs the txs of a discarded block
+type DiscardedTxs struct {
+	Recovered []*commonpb.Transaction // neither pending nor committed, to be put back into the tx pool
+	Pending   []*commonpb.Transaction // packed in another block in the proposal cache
+	Committed []*commonpb.Transaction // committed in the ledger
+	Lost      []*commonpb.Transaction // expired, or failed to check whether committed
+}
+
+func NewTxRecoverer(config TxRecovererConfig) *TxRecoverer {
+	r := &TxRecoverer{
+		chainId:         config.ChainId,
+		log:             config.Log,
+		chainConf:       config.ChainConf,
+		ledgerCache:     config.LedgerCache,
+		proposalCache:   config.ProposalCache,
+		txPool:          config.TxPool,
+		blockchainStore: config.BlockchainStore,
+	}
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		r.metricRecoveredTxCounter = monitor.NewCounterVec(monitor.SUBSYSTEM_CORE_PROPOSER,
+			"metric_recovered_tx_counter", "txs of the discarded blocks put back into the tx pool", "chainId")
+		r.metricLostTxCounter = monitor.NewCounterVec(monitor.SUBSYSTEM_CORE_PROPOSER,
+			"metric_lost_tx_counter", "txs of the discarded blocks which can not be put back into the tx pool",
+			"chainId")
+	}
+	return r
+}
+
+// Recover puts the txs of the discarded block back into the tx pool if they are neither pending nor committed,
+// and removes the committed and lost ones from the tx pool. The pending txs are kept in the tx pool until the
+// block packing them is committed, or put back into the tx pool if that block is discarded too.
+// The block must have been cleared from the proposal cache.
+func (r *TxRecoverer) Recover(discarded *commonpb.Block) *DiscardedTxs {
+	txs := r.Classify(discarded)
+	removed := make([]*commonpb.Transaction, 0, len(txs.Committed)+len(txs.Lost))
+	removed = append(removed, txs.Committed...)
+	removed = append(removed, txs.Lost...)
+	r.txPool.RetryAndRemoveTxs(txs.Recovered, removed)
+
+	r.log.Infof("discard self proposed block [%d](%x), txs recovered %d, pending %d, committed %d, lost %d",
+		discarded.Header.BlockHeight, discarded.Header.BlockHash,
+		len(txs.Recovered), len(txs.Pending), len(txs.Committed), len(txs.Lost))
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		r.metricRecoveredTxCounter.WithLabelValues(r.chainId).Add(float64(len(txs.Recovered)))
+		r.metricLostTxCounter.WithLabelValues(r.chainId).Add(float64(len(txs.Lost)))
+	}
+	return txs
+}
+
+// Classify classifies the txs of the discarded block by the other blocks in the proposal cache and the ledger
+func (r *TxRecoverer) Classify(discarded *commonpb.Block) *DiscardedTxs {
+	txs := &DiscardedTxs{}
+	if len(discarded.Txs) == 0 {
+		return txs
+	}
+	pending := r.pendingTxIds(discarded.Header.BlockHeight)
+
+	var txTimeout int64
+	if blockConfig := r.chainConf.ChainConfig().Block; blockConfig != nil && blockConfig.TxTimestampVerify {
+		txTimeout = int64(blockConfig.TxTimeout)
+	}
+	now := utils.CurrentTimeSeconds()
+	for _, tx := range discarded.Txs {
+		if _, ok := pending[tx.Payload.TxId]; ok {
+			txs.Pending = append(txs.Pending, tx)
+			continue
+		}
+		exist, err := r.blockchainStore.TxExists(tx.Payload.TxId)
+		if err != nil {
+			r.log.Warnf("failed to check whether tx %s is committed, %s", tx.Payload.TxId, err)
+			txs.Lost = append(txs.Lost, tx)
+			continue
+		}
+		if exist {
+			txs.Committed = append(txs.Committed, tx)
+			continue
+		}
+		if txTimeout > 0 && (now-tx.Payload.Timestamp > txTimeout || tx.Payload.Timestamp-now > txTimeout) {
+			txs.Lost = append(txs.Lost, tx)
+			continue
+		}
+		txs.Recovered = append(txs.Recovered, tx)
+	}
+	return txs
+}
+
+// pendingTxIds returns the ids of the txs in the uncommitted blocks of the proposal cache,
+// from the height after the committed one to the highest one above the discarded height
+func (r *TxRecoverer) pendingTxIds(discardedHeight uint64) map[string]struct{} {
+	txIds := make(map[string]struct{})
+	currentHeight, err := r.ledgerCache.CurrentHeight()
+	if err != nil {
+		return txIds
+	}
+	for height := currentHeight + 1; ; height++ {
+		blocks := r.proposalCache.GetProposedBlocksAt(height)
+		if len(blocks) == 0 && height >= discardedHeight {
+			return txIds
+		}
+		for _, b := range blocks {
+			for _, tx := range b.Txs {
+				txIds[tx.Payload.TxId] = struct{}{}
+			}
+		}
+	}
+}
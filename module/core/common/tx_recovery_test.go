@@ -0,0 +1,115 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"chainmaker.org/chainmaker-go/core/cache"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	configpb "chainmaker.org/chainmaker/pb-go/v2/config"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/protocol/v2/test"
+	"chainmaker.org/chainmaker/utils/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type txExistsStore struct {
+	protocol.BlockchainStore
+	committed map[string]bool
+}
+
+func (s *txExistsStore) TxExists(txId string) (bool, error) {
+	if txId == "unknown" {
+		return false, errors.New("store closed")
+	}
+	return s.committed[txId], nil
+}
+
+type blockConfigChainConf struct {
+	protocol.ChainConf
+	chainConfig *configpb.ChainConfig
+}
+
+func (c *blockConfigChainConf) ChainConfig() *configpb.ChainConfig {
+	return c.chainConfig
+}
+
+func createRecoveryBlock(height uint64, hash, preHash string, txIds ...string) *commonpb.Block {
+	block := createPipelineBlock(height, hash, preHash)
+	for _, txId := range txIds {
+		block.Txs = append(block.Txs, &commonpb.Transaction{
+			Payload: &commonpb.Payload{TxId: txId, Timestamp: utils.CurrentTimeSeconds()},
+		})
+	}
+	return block
+}
+
+func TestTxRecovererClassify(t *testing.T) {
+	ledgerCache := cache.NewLedgerCache("Chain1")
+	ledgerCache.SetLastCommittedBlock(createPipelineBlock(1, "hash1", "hash0"))
+	proposalCache := cache.NewProposalCache(nil, ledgerCache)
+	recoverer := NewTxRecoverer(TxRecovererConfig{
+		ChainId: "Chain1",
+		Log:     &test.GoLogger{},
+		ChainConf: &blockConfigChainConf{chainConfig: &configpb.ChainConfig{
+			Block: &configpb.BlockConfig{TxTimestampVerify: true, TxTimeout: 600},
+		}},
+		LedgerCache:     ledgerCache,
+		ProposalCache:   proposalCache,
+		BlockchainStore: &txExistsStore{committed: map[string]bool{"committed": true}},
+	})
+
+	// the blocks proposed by the others at the height of the discarded block and above it are pending
+	require.NoError(t, proposalCache.SetProposedBlock(
+		createRecoveryBlock(2, "hash2'", "hash1", "pending2"), nil, nil, false))
+	require.NoError(t, proposalCache.SetProposedBlock(
+		createRecoveryBlock(3, "hash3'", "hash2'", "pending3"), nil, nil, false))
+
+	discarded := createRecoveryBlock(2, "hash2", "hash1",
+		"recovered", "pending2", "pending3", "committed", "unknown", "expired")
+	discarded.Txs[5].Payload.Timestamp -= 601
+	txs := recoverer.Classify(discarded)
+
+	txIds := func(txs []*commonpb.Transaction) []string {
+		ids := make([]string, 0, len(txs))
+		for _, tx := range txs {
+			ids = append(ids, tx.Payload.TxId)
+		}
+		return ids
+	}
+	require.Equal(t, []string{"recovered"}, txIds(txs.Recovered))
+	require.Equal(t, []string{"pending2", "pending3"}, txIds(txs.Pending))
+	require.Equal(t, []string{"committed"}, txIds(txs.Committed))
+	require.Equal(t, []string{"unknown", "expired"}, txIds(txs.Lost))
+}
+
+func TestTxRecovererRecover(t *testing.T) {
+	ledgerCache := cache.NewLedgerCache("Chain1")
+	ledgerCache.SetLastCommittedBlock(createPipelineBlock(1, "hash1", "hash0"))
+	proposalCache := cache.NewProposalCache(nil, ledgerCache)
+	txPool := &retryRecorderTxPool{}
+	recoverer := NewTxRecoverer(TxRecovererConfig{
+		ChainId:         "Chain1",
+		Log:             &test.GoLogger{},
+		ChainConf:       &blockConfigChainConf{chainConfig: &configpb.ChainConfig{}},
+		LedgerCache:     ledgerCache,
+		ProposalCache:   proposalCache,
+		TxPool:          txPool,
+		BlockchainStore: &txExistsStore{committed: map[string]bool{"committed": true}},
+	})
+	require.NoError(t, proposalCache.SetProposedBlock(
+		createRecoveryBlock(2, "hash2'", "hash1", "pending"), nil, nil, false))
+
+	discarded := createRecoveryBlock(2, "hash2", "hash1", "recovered", "pending", "committed", "unknown")
+	recoverer.Recover(discarded)
+
+	// the pending tx is left in the tx pool until the other block is committed
+	require.Equal(t, []*commonpb.Transaction{discarded.Txs[0]}, txPool.retried)
+	require.Equal(t, []*commonpb.Transaction{discarded.Txs[2], discarded.Txs[3]}, txPool.removed)
+}
@@ -0,0 +1,436 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"chainmaker.org/chainmaker-go/core/provider/conf"
+	"chainmaker.org/chainmaker/common/v2/monitor"
+	"chainmaker.org/chainmaker/localconf/v2"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	speculationVerified   = "verified"    // a block is verified on top of an uncommitted block
+	speculationRolledBack = "rolled_back" // a verified block is discarded since its parent is not committed
+	speculationAborted    = "aborted"     // a verifying block is aborted since its parent is not committed
+)
+
+// ErrSpeculationRolledBack is returned when a block is verified on top of a block which failed to commit
+// or lost to another block at the same height
+var ErrSpeculationRolledBack = errors.New("parent block rolled back")
+
+// VerifyPipeline lets block N+1 be verified, or proposed, while block N is still committing. The snapshot of N+1
+// is linked to the uncommitted snapshot of N by the snapshot manager, the pipeline keeps track of the blocks built
+// that way, rolls them back when N fails to commit or another block is committed at the height of N, and measures
+// how long the verification of N+1 overlaps the commit of N. The proposal cache, tx pool and store are never called
+// with the lock of the pipeline held. A nil VerifyPipeline does nothing, the blocks are cached by the callers.
+type VerifyPipeline struct {
+	chainId         string
+	log             protocol.Logger
+	ledgerCache     protocol.LedgerCache
+	proposalCache   protocol.ProposalCache
+	txPool          protocol.TxPool
+	blockchainStore protocol.BlockchainStore
+	storeHelper     conf.StoreHelper
+
+	mu           sync.Mutex
+	speculations map[string]*speculation // block hash -> block verified on top of an uncommitted block
+	commits      map[string]*commit      // block hash -> commit in progress
+	rolledBack   map[string]uint64       // block hash -> height, blocks whose children must not be verified
+
+	metricOverlapTime        *prometheus.HistogramVec // time of verifying a block while committing its parent
+	metricSpeculationCounter *prometheus.CounterVec   // counts of the blocks verified on uncommitted blocks
+}
+
+type VerifyPipelineConfig struct {
+	ChainId         string
+	Log             protocol.Logger
+	LedgerCache     protocol.LedgerCache
+	ProposalCache   protocol.ProposalCache
+	TxPool          protocol.TxPool
+	BlockchainStore protocol.BlockchainStore
+	StoreHelper     conf.StoreHelper
+}
+
+type timeSpan struct {
+	start time.Time
+	end   time.Time
+}
+
+type commit struct {
+	block *commonpb.Block
+	span  timeSpan
+}
+
+// speculation is a block verified, or being verified, on top of a block which has not been committed
+type speculation struct {
+	height       uint64
+	parentHash   string
+	verify       timeSpan
+	parentCommit *timeSpan // set once the parent is committed
+	aborted      bool
+}
+
+func NewVerifyPipeline(config VerifyPipelineConfig) *VerifyPipeline {
+	p := &VerifyPipeline{
+		chainId:         config.ChainId,
+		log:             config.Log,
+		ledgerCache:     config.LedgerCache,
+		proposalCache:   config.ProposalCache,
+		txPool:          config.TxPool,
+		blockchainStore: config.BlockchainStore,
+		storeHelper:     config.StoreHelper,
+		speculations:    make(map[string]*speculation),
+		commits:         make(map[string]*commit),
+		rolledBack:      make(map[string]uint64),
+	}
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		p.metricOverlapTime = monitor.NewHistogramVec(monitor.SUBSYSTEM_CORE_VERIFIER, "metric_pipeline_overlap_time",
+			"time of verifying a block while its parent is committing", []float64{0.005, 0.01, 0.015, 0.05, 0.1, 1, 10},
+			"chainId")
+		p.metricSpeculationCounter = monitor.NewCounterVec(monitor.SUBSYSTEM_CORE_VERIFIER,
+			"metric_pipeline_speculation_counter", "blocks verified on top of uncommitted blocks", "chainId", "result")
+	}
+	return p
+}
+
+// CheckParent returns ErrSpeculationRolledBack if the block of preHash has been rolled back,
+// so that no block can be built on top of it
+func (p *VerifyPipeline) CheckParent(preHash []byte) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.rolledBack[string(preHash)]; ok {
+		return ErrSpeculationRolledBack
+	}
+	return nil
+}
+
+// CommittingBlock returns the block being committed, nil if there is none
+func (p *VerifyPipeline) CommittingBlock() *commonpb.Block {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.commits {
+		return c.block
+	}
+	return nil
+}
+
+// BeginVerify registers the verification of the block after its parent is fetched.
+// The block is verified speculatively if its parent has not been committed, and it fails if the parent
+// has been rolled back.
+func (p *VerifyPipeline) BeginVerify(block *commonpb.Block) error {
+	if p == nil {
+		return nil
+	}
+	currentHeight, err := p.ledgerCache.CurrentHeight()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.rolledBack[string(block.Header.PreBlockHash)]; ok {
+		return ErrSpeculationRolledBack
+	}
+	if err != nil {
+		return nil
+	}
+	// the blocks at the committed heights are not speculative any more, whether or not they were ended
+	for h, s := range p.speculations {
+		if s.height <= currentHeight {
+			delete(p.speculations, h)
+		}
+	}
+	if currentHeight+1 >= block.Header.BlockHeight {
+		return nil
+	}
+	p.speculations[string(block.Header.BlockHash)] = &speculation{
+		height:     block.Header.BlockHeight,
+		parentHash: string(block.Header.PreBlockHash),
+		verify:     timeSpan{start: time.Now()},
+	}
+	p.log.Debugf("verify block [%d](%x) on top of uncommitted block [%d](%x)", block.Header.BlockHeight,
+		block.Header.BlockHash, block.Header.BlockHeight-1, block.Header.PreBlockHash)
+	return nil
+}
+
+// EndVerify finishes the verification of the block without caching it,
+// e.g. the verification failed or the block is not cached by the consensus.
+func (p *VerifyPipeline) EndVerify(block *commonpb.Block, verifyErr error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hash := string(block.Header.BlockHash)
+	if s, ok := p.speculations[hash]; ok && (verifyErr != nil || s.aborted) {
+		delete(p.speculations, hash)
+		return
+	}
+	p.finishVerify(hash)
+}
+
+// SetVerifiedBlock finishes the verification of the block and caches it in the proposal cache, unless the
+// verification is aborted because the parent of the block has been rolled back in the meantime.
+// The sql transaction of an aborted block is rolled back.
+func (p *VerifyPipeline) SetVerifiedBlock(block *commonpb.Block, rwSetMap map[string]*commonpb.TxRWSet,
+	eventMap map[string][]*commonpb.ContractEvent) error {
+	return p.cacheBlock(block, rwSetMap, eventMap, false)
+}
+
+// SetProposedBlock caches the block proposed by this node in the proposal cache, unless the parent of the block
+// has been rolled back in the meantime. The proposer rolls back the sql transaction of an aborted block.
+func (p *VerifyPipeline) SetProposedBlock(block *commonpb.Block, rwSetMap map[string]*commonpb.TxRWSet,
+	eventMap map[string][]*commonpb.ContractEvent) error {
+	return p.cacheBlock(block, rwSetMap, eventMap, true)
+}
+
+func (p *VerifyPipeline) cacheBlock(block *commonpb.Block, rwSetMap map[string]*commonpb.TxRWSet,
+	eventMap map[string][]*commonpb.ContractEvent, selfProposed bool) error {
+	if p.abortIfRolledBack(block) {
+		p.discard(block, selfProposed)
+		return ErrSpeculationRolledBack
+	}
+	if err := p.proposalCache.SetProposedBlock(block, rwSetMap, eventMap, selfProposed); err != nil {
+		p.mu.Lock()
+		delete(p.speculations, string(block.Header.BlockHash))
+		p.mu.Unlock()
+		return err
+	}
+
+	// the parent may be rolled back while the block is being cached
+	p.mu.Lock()
+	_, discarded := p.rolledBack[string(block.Header.BlockHash)]
+	p.mu.Unlock()
+	if discarded {
+		// removed by the rollback already
+		return ErrSpeculationRolledBack
+	}
+	if p.abortIfRolledBack(block) {
+		p.proposalCache.ClearTheBlock(block)
+		p.discard(block, selfProposed)
+		return ErrSpeculationRolledBack
+	}
+	p.mu.Lock()
+	p.finishVerify(string(block.Header.BlockHash))
+	p.mu.Unlock()
+	return nil
+}
+
+// abortIfRolledBack marks the block as rolled back if its verification is aborted or its parent is rolled back
+func (p *VerifyPipeline) abortIfRolledBack(block *commonpb.Block) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hash := string(block.Header.BlockHash)
+	s, ok := p.speculations[hash]
+	_, parentRolledBack := p.rolledBack[string(block.Header.PreBlockHash)]
+	if !parentRolledBack && (!ok || !s.aborted) {
+		return false
+	}
+	delete(p.speculations, hash)
+	p.rolledBack[hash] = block.Header.BlockHeight
+	return true
+}
+
+func (p *VerifyPipeline) discard(block *commonpb.Block, selfProposed bool) {
+	p.log.Infof("discard block [%d](%x) built on top of rolled back block [%x]",
+		block.Header.BlockHeight, block.Header.BlockHash, block.Header.PreBlockHash)
+	if selfProposed {
+		return
+	}
+	if err := p.storeHelper.RollBack(block, p.blockchainStore); err != nil {
+		p.log.Errorf("block [%d] rollback sql failed: %s", block.Header.BlockHeight, err)
+	}
+}
+
+func (p *VerifyPipeline) finishVerify(hash string) {
+	s, ok := p.speculations[hash]
+	if !ok {
+		return
+	}
+	s.verify.end = time.Now()
+	p.incSpeculation(speculationVerified)
+	if s.parentCommit != nil {
+		p.observeOverlap(s)
+		delete(p.speculations, hash)
+	}
+}
+
+// BeginCommit is called when the block starts committing
+func (p *VerifyPipeline) BeginCommit(block *commonpb.Block) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commits[string(block.Header.BlockHash)] = &commit{block: block, span: timeSpan{start: time.Now()}}
+}
+
+// EndCommit is called when the block finishes committing. If the commit failed, the blocks built on top of
+// it are rolled back, otherwise the blocks built on top of the other blocks at the same height are.
+func (p *VerifyPipeline) EndCommit(block *commonpb.Block, commitErr error) {
+	if p == nil {
+		return
+	}
+	hash := string(block.Header.BlockHash)
+	height := block.Header.BlockHeight
+
+	p.mu.Lock()
+	c, ok := p.commits[hash]
+	if !ok {
+		c = &commit{block: block, span: timeSpan{start: time.Now()}}
+	}
+	c.span.end = time.Now()
+	delete(p.commits, hash)
+	if commitErr == nil {
+		// the block may be committed after retrying
+		delete(p.rolledBack, hash)
+		for childHash, s := range p.speculations {
+			if s.parentHash != hash {
+				continue
+			}
+			s.parentCommit = &c.span
+			if !s.verify.end.IsZero() {
+				p.observeOverlap(s)
+				delete(p.speculations, childHash)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	if commitErr != nil {
+		p.log.Warnf("block [%d](%x) failed to commit, roll back the blocks built on top of it: %s",
+			height, block.Header.BlockHash, commitErr)
+		p.rollbackDescendants([]*commonpb.Block{block}, block)
+		return
+	}
+
+	forks := make([]*commonpb.Block, 0)
+	for _, b := range p.proposalCache.GetProposedBlocksAt(height) {
+		if string(b.Header.BlockHash) != hash {
+			forks = append(forks, b)
+		}
+	}
+	if len(forks) > 0 {
+		p.rollbackDescendants(forks, block)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for h, s := range p.speculations {
+		if s.height <= height {
+			delete(p.speculations, h)
+		}
+	}
+	for h, rolledBackHeight := range p.rolledBack {
+		if rolledBackHeight < height {
+			delete(p.rolledBack, h)
+		}
+	}
+}
+
+// rollbackDescendants discards the blocks built on top of the roots from the proposal cache and aborts
+// the ones being verified. The transactions of the discarded blocks, except those in keep, are put back
+// into the tx pool. The blocks are marked as rolled back before they are discarded, so that no block
+// is cached on top of them in the meantime.
+func (p *VerifyPipeline) rollbackDescendants(roots []*commonpb.Block, keep *commonpb.Block) {
+	parents := make(map[string]struct{}, len(roots))
+	p.mu.Lock()
+	for _, b := range roots {
+		parents[string(b.Header.BlockHash)] = struct{}{}
+		p.rolledBack[string(b.Header.BlockHash)] = b.Header.BlockHeight
+	}
+	p.mu.Unlock()
+	keepTxs := make(map[string]struct{}, len(keep.Txs))
+	for _, tx := range keep.Txs {
+		keepTxs[tx.Payload.TxId] = struct{}{}
+	}
+
+	retryTxs := make([]*commonpb.Transaction, 0)
+	for height := roots[0].Header.BlockHeight + 1; len(parents) > 0; height++ {
+		p.mu.Lock()
+		for _, s := range p.speculations {
+			if _, ok := parents[s.parentHash]; ok && s.verify.end.IsZero() && !s.aborted {
+				s.aborted = true
+				p.incSpeculation(speculationAborted)
+			}
+		}
+		p.mu.Unlock()
+
+		children := make(map[string]struct{})
+		discarded := make([]*commonpb.Block, 0)
+		for _, b := range p.proposalCache.GetProposedBlocksAt(height) {
+			if _, ok := parents[string(b.Header.PreBlockHash)]; ok {
+				children[string(b.Header.BlockHash)] = struct{}{}
+				discarded = append(discarded, b)
+			}
+		}
+		p.mu.Lock()
+		for _, b := range discarded {
+			p.rolledBack[string(b.Header.BlockHash)] = b.Header.BlockHeight
+			delete(p.speculations, string(b.Header.BlockHash))
+			p.incSpeculation(speculationRolledBack)
+		}
+		p.mu.Unlock()
+
+		for _, b := range discarded {
+			p.log.Infof("roll back block [%d](%x) built on top of uncommitted block [%x]",
+				b.Header.BlockHeight, b.Header.BlockHash, b.Header.PreBlockHash)
+			p.proposalCache.ClearTheBlock(b)
+			if err := p.storeHelper.RollBack(b, p.blockchainStore); err != nil {
+				p.log.Errorf("block [%d] rollback sql failed: %s", b.Header.BlockHeight, err)
+			}
+			for _, tx := range b.Txs {
+				if _, ok := keepTxs[tx.Payload.TxId]; !ok {
+					retryTxs = append(retryTxs, tx)
+				}
+			}
+		}
+		parents = children
+	}
+
+	if len(retryTxs) > 0 {
+		p.txPool.RetryAndRemoveTxs(retryTxs, nil)
+	}
+}
+
+func (p *VerifyPipeline) observeOverlap(s *speculation) {
+	overlap := overlapTime(s.verify, *s.parentCommit)
+	p.log.Debugf("verify block [%d] overlaps commit of its parent for %v", s.height, overlap)
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		p.metricOverlapTime.WithLabelValues(p.chainId).Observe(overlap.Seconds())
+	}
+}
+
+func (p *VerifyPipeline) incSpeculation(result string) {
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		p.metricSpeculationCounter.WithLabelValues(p.chainId, result).Inc()
+	}
+}
+
+// overlapTime returns how long the two spans overlap
+func overlapTime(a, b timeSpan) time.Duration {
+	start, end := a.start, a.end
+	if b.start.After(start) {
+		start = b.start
+	}
+	if b.end.Before(end) {
+		end = b.end
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
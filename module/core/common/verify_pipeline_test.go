@@ -0,0 +1,201 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"chainmaker.org/chainmaker-go/core/cache"
+	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	commonpb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/protocol/v2/test"
+	"github.com/stretchr/testify/require"
+)
+
+type rollbackRecorder struct {
+	rolledBack []uint64
+}
+
+func (r *rollbackRecorder) RollBack(block *commonpb.Block, _ protocol.BlockchainStore) error {
+	r.rolledBack = append(r.rolledBack, block.Header.BlockHeight)
+	return nil
+}
+
+func (r *rollbackRecorder) BeginDbTransaction(protocol.BlockchainStore, string) {}
+
+func (r *rollbackRecorder) GetPoolCapacity() int {
+	return 0
+}
+
+func createPipelineBlock(height uint64, hash, preHash string) *commonpb.Block {
+	return &commonpb.Block{
+		Header: &commonpb.BlockHeader{
+			ChainId:        "Chain1",
+			BlockHeight:    height,
+			PreBlockHash:   []byte(preHash),
+			BlockHash:      []byte(hash),
+			BlockTimestamp: int64(len(hash)) + int64(hash[len(hash)-1]),
+			Proposer:       &accesscontrol.Member{MemberInfo: []byte(hash)},
+		},
+	}
+}
+
+func newTestPipeline(committed *commonpb.Block) (*VerifyPipeline, protocol.ProposalCache, *rollbackRecorder) {
+	ledgerCache := cache.NewLedgerCache("Chain1")
+	ledgerCache.SetLastCommittedBlock(committed)
+	proposalCache := cache.NewProposalCache(nil, ledgerCache)
+	recorder := &rollbackRecorder{}
+	pipeline := NewVerifyPipeline(VerifyPipelineConfig{
+		ChainId:       "Chain1",
+		Log:           &test.GoLogger{},
+		LedgerCache:   ledgerCache,
+		ProposalCache: proposalCache,
+		StoreHelper:   recorder,
+	})
+	return pipeline, proposalCache, recorder
+}
+
+func TestOverlapTime(t *testing.T) {
+	now := time.Now()
+	at := func(ms int) time.Time {
+		return now.Add(time.Duration(ms) * time.Millisecond)
+	}
+	tests := []struct {
+		name   string
+		verify timeSpan
+		commit timeSpan
+		want   time.Duration
+	}{
+		{"disjoint", timeSpan{at(0), at(10)}, timeSpan{at(20), at(30)}, 0},
+		{"adjacent", timeSpan{at(0), at(10)}, timeSpan{at(10), at(30)}, 0},
+		{"partial", timeSpan{at(0), at(20)}, timeSpan{at(10), at(30)}, 10 * time.Millisecond},
+		{"contained", timeSpan{at(5), at(15)}, timeSpan{at(0), at(30)}, 10 * time.Millisecond},
+		{"containing", timeSpan{at(0), at(30)}, timeSpan{at(5), at(10)}, 5 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, overlapTime(tt.verify, tt.commit))
+			require.Equal(t, tt.want, overlapTime(tt.commit, tt.verify))
+		})
+	}
+}
+
+func TestVerifyPipelineRollbackOnCommitFailure(t *testing.T) {
+	block1 := createPipelineBlock(1, "hash1", "hash0")
+	pipeline, proposalCache, recorder := newTestPipeline(block1)
+
+	block2 := createPipelineBlock(2, "hash2", "hash1")
+	require.NoError(t, pipeline.BeginVerify(block2))
+	require.NoError(t, pipeline.SetVerifiedBlock(block2, nil, nil))
+	require.Empty(t, pipeline.speculations)
+
+	// block3 is verified on top of block2 which is not committed, block4 is still verifying
+	block3 := createPipelineBlock(3, "hash3", "hash2")
+	require.NoError(t, pipeline.BeginVerify(block3))
+	require.NoError(t, pipeline.SetVerifiedBlock(block3, nil, nil))
+	block4 := createPipelineBlock(4, "hash4", "hash3")
+	require.NoError(t, pipeline.BeginVerify(block4))
+	require.Len(t, pipeline.speculations, 2)
+
+	pipeline.BeginCommit(block2)
+	pipeline.EndCommit(block2, errors.New("commit failed"))
+
+	require.Empty(t, proposalCache.GetProposedBlocksAt(3))
+	require.Equal(t, []uint64{3}, recorder.rolledBack)
+	require.Equal(t, ErrSpeculationRolledBack, pipeline.SetVerifiedBlock(block4, nil, nil))
+	require.Empty(t, proposalCache.GetProposedBlocksAt(4))
+	require.Equal(t, []uint64{3, 4}, recorder.rolledBack)
+
+	// no block can be verified on top of the rolled back blocks
+	require.Equal(t, ErrSpeculationRolledBack, pipeline.BeginVerify(createPipelineBlock(3, "hash3'", "hash2")))
+	require.Equal(t, ErrSpeculationRolledBack, pipeline.BeginVerify(createPipelineBlock(4, "hash4'", "hash3")))
+
+	// block2 is committed after retrying
+	pipeline.BeginCommit(block2)
+	pipeline.EndCommit(block2, nil)
+	require.NoError(t, pipeline.BeginVerify(createPipelineBlock(3, "hash3'", "hash2")))
+}
+
+func TestVerifyPipelineRollbackForks(t *testing.T) {
+	block1 := createPipelineBlock(1, "hash1", "hash0")
+	pipeline, proposalCache, recorder := newTestPipeline(block1)
+
+	block2a := createPipelineBlock(2, "hash2a", "hash1")
+	block2b := createPipelineBlock(2, "hash2b", "hash1")
+	block3a := createPipelineBlock(3, "hash3a", "hash2a")
+	block3b := createPipelineBlock(3, "hash3b", "hash2b")
+	block4b := createPipelineBlock(4, "hash4b", "hash3b")
+	for _, b := range []*commonpb.Block{block2a, block2b, block3a, block3b, block4b} {
+		require.NoError(t, pipeline.BeginVerify(b))
+		require.NoError(t, pipeline.SetVerifiedBlock(b, nil, nil))
+	}
+	require.Len(t, pipeline.speculations, 3)
+
+	pipeline.BeginCommit(block2a)
+	pipeline.EndCommit(block2a, nil)
+
+	require.Len(t, proposalCache.GetProposedBlocksAt(3), 1)
+	require.Equal(t, block3a, proposalCache.GetProposedBlocksAt(3)[0])
+	require.Empty(t, proposalCache.GetProposedBlocksAt(4))
+	require.Equal(t, []uint64{3, 4}, recorder.rolledBack)
+	// block3a is not speculative any more since its parent is committed
+	require.Empty(t, pipeline.speculations)
+}
+
+func TestVerifyPipelineNil(t *testing.T) {
+	var pipeline *VerifyPipeline
+	block := createPipelineBlock(2, "hash2", "hash1")
+	require.NoError(t, pipeline.BeginVerify(block))
+	require.NoError(t, pipeline.CheckParent(block.Header.PreBlockHash))
+	pipeline.EndVerify(block, nil)
+	pipeline.BeginCommit(block)
+	require.Nil(t, pipeline.CommittingBlock())
+	pipeline.EndCommit(block, errors.New("commit failed"))
+}
+
+func TestVerifyPipelinePruneStaleSpeculations(t *testing.T) {
+	block1 := createPipelineBlock(1, "hash1", "hash0")
+	pipeline, _, _ := newTestPipeline(block1)
+
+	// the verification of block3 is never ended
+	require.NoError(t, pipeline.BeginVerify(createPipelineBlock(3, "hash3", "hash2")))
+	require.Len(t, pipeline.speculations, 1)
+
+	pipeline.ledgerCache.SetLastCommittedBlock(createPipelineBlock(3, "hash3", "hash2"))
+	require.NoError(t, pipeline.BeginVerify(createPipelineBlock(4, "hash4", "hash3")))
+	require.Empty(t, pipeline.speculations)
+}
+
+func TestVerifyPipelineProposeOnCommittingBlock(t *testing.T) {
+	block1 := createPipelineBlock(1, "hash1", "hash0")
+	pipeline, proposalCache, recorder := newTestPipeline(block1)
+
+	block2 := createPipelineBlock(2, "hash2", "hash1")
+	require.NoError(t, pipeline.SetVerifiedBlock(block2, nil, nil))
+	pipeline.BeginCommit(block2)
+	require.Equal(t, block2, pipeline.CommittingBlock())
+
+	block3 := createPipelineBlock(3, "hash3", "hash2")
+	require.NoError(t, pipeline.CheckParent(block3.Header.PreBlockHash))
+	require.NoError(t, pipeline.SetProposedBlock(block3, nil, nil))
+	require.Equal(t, block3, proposalCache.GetSelfProposedBlockAt(3))
+
+	pipeline.EndCommit(block2, errors.New("commit failed"))
+	require.Nil(t, pipeline.CommittingBlock())
+	require.Nil(t, proposalCache.GetSelfProposedBlockAt(3))
+	require.Equal(t, []uint64{3}, recorder.rolledBack)
+
+	// the proposer rolls back the sql of its aborted block
+	block3b := createPipelineBlock(3, "hash3b", "hash2")
+	require.Equal(t, ErrSpeculationRolledBack, pipeline.CheckParent(block3b.Header.PreBlockHash))
+	require.Equal(t, ErrSpeculationRolledBack, pipeline.SetProposedBlock(block3b, nil, nil))
+	require.Empty(t, proposalCache.GetProposedBlocksAt(3))
+	require.Equal(t, []uint64{3}, recorder.rolledBack)
+}
@@ -62,6 +62,8 @@ type BlockProposerImpl struct {
 
 	blockBuilder *common.BlockBuilder
 	storeHelper  conf.StoreHelper
+	txRecoverer  *common.TxRecoverer    // recovers the txs of the discarded self proposed blocks
+	pipeline     *common.VerifyPipeline // may be nil, to roll back the blocks proposed on top of uncommitted blocks
 }
 
 type BlockProposerConfig struct {
@@ -77,6 +79,7 @@ type BlockProposerConfig struct {
 	AC              protocol.AccessControlProvider
 	BlockchainStore protocol.BlockchainStore
 	StoreHelper     conf.StoreHelper
+	Pipeline        *common.VerifyPipeline
 }
 
 const (
@@ -104,6 +107,7 @@ func NewBlockProposer(config BlockProposerConfig, log protocol.Logger) (protocol
 		log:             log,
 		finishProposeC:  make(chan bool),
 		storeHelper:     config.StoreHelper,
+		pipeline:        config.Pipeline,
 	}
 
 	var err error
@@ -140,9 +144,19 @@ func NewBlockProposer(config BlockProposerConfig, log protocol.Logger) (protocol
 		ChainConf:       blockProposerImpl.chainConf,
 		Log:             blockProposerImpl.log,
 		StoreHelper:     blockProposerImpl.storeHelper,
+		Pipeline:        blockProposerImpl.pipeline,
 	}
 
 	blockProposerImpl.blockBuilder = common.NewBlockBuilder(bbConf)
+	blockProposerImpl.txRecoverer = common.NewTxRecoverer(common.TxRecovererConfig{
+		ChainId:         blockProposerImpl.chainId,
+		Log:             blockProposerImpl.log,
+		ChainConf:       blockProposerImpl.chainConf,
+		LedgerCache:     blockProposerImpl.ledgerCache,
+		ProposalCache:   blockProposerImpl.proposalCache,
+		TxPool:          blockProposerImpl.txPool,
+		BlockchainStore: blockProposerImpl.blockchainStore,
+	})
 
 	return blockProposerImpl, nil
 }
@@ -258,12 +272,9 @@ func (bp *BlockProposerImpl) proposing(height uint64, preHash []byte) *commonpb.
 			return nil
 		}
 		bp.proposalCache.ClearTheBlock(selfProposedBlock)
-		// Note: It is not possible to re-add the transactions in the deleted block to txpool; because some transactions may
-		// be included in other blocks to be confirmed, and it is impossible to quickly exclude these pending transactions
-		// that have been entered into the block. Comprehensive considerations, directly discard this block is the optimal
-		// choice. This processing method may only cause partial transaction loss at the current node, but it can be solved
-		// by rebroadcasting on the client side.
-		bp.txPool.RetryAndRemoveTxs(nil, selfProposedBlock.Txs)
+		// put the txs of the discarded block back into txpool, except those included in other blocks to be confirmed
+		// or committed already
+		bp.txRecoverer.Recover(selfProposedBlock)
 
 	}
 
@@ -300,7 +311,7 @@ func (bp *BlockProposerImpl) proposing(height uint64, preHash []byte) *commonpb.
 		if sqlErr := bp.storeHelper.RollBack(block, bp.blockchainStore); sqlErr != nil {
 			bp.log.Errorf("block [%d] rollback sql failed: %s", block.Header.BlockHeight, sqlErr)
 		}
-		bp.txPool.RetryAndRemoveTxs(checkedBatch, nil) // put txs back to txpool
+		common.RetryTxs(bp.txPool, bp.chainConf, checkedBatch, bp.log) // put txs back to txpool
 		return nil
 	}
 	_, txsRwSet, _ := bp.proposalCache.GetProposedBlock(block)
@@ -45,6 +45,7 @@ type BlockVerifierImpl struct {
 	mu             sync.Mutex                     // to avoid concurrent map modify
 	verifierBlock  *common.VerifierBlock
 	storeHelper    conf.StoreHelper
+	pipeline       *common.VerifyPipeline // verify pipeline, to verify blocks on top of uncommitted blocks, may be nil
 
 	metricBlockVerifyTime *prometheus.HistogramVec // metrics monitor
 }
@@ -62,6 +63,7 @@ type BlockVerifierConfig struct {
 	TxPool          protocol.TxPool
 	VmMgr           protocol.VmManager
 	StoreHelper     conf.StoreHelper
+	Pipeline        *common.VerifyPipeline
 }
 
 func NewBlockVerifier(config BlockVerifierConfig, log protocol.Logger) (protocol.BlockVerifier, error) {
@@ -81,6 +83,7 @@ func NewBlockVerifier(config BlockVerifierConfig, log protocol.Logger) (protocol
 		log:           log,
 		txPool:        config.TxPool,
 		storeHelper:   config.StoreHelper,
+		pipeline:      config.Pipeline,
 	}
 
 	conf := &common.VerifierBlockConf{
@@ -171,6 +174,16 @@ func (v *BlockVerifierImpl) VerifyBlock(block *commonpb.Block, mode protocol.Ver
 	if err != nil {
 		return err
 	}
+	// the last block may not be committed yet, then the block is verified on its uncommitted snapshot
+	if err = v.pipeline.BeginVerify(block); err != nil {
+		v.log.Warnf("verify failed [%d](%x), %s", block.Header.BlockHeight, block.Header.BlockHash, err.Error())
+		return err
+	}
+	defer func() {
+		if err != nil {
+			v.pipeline.EndVerify(block, err)
+		}
+	}()
 
 	startPoolTick := utils.CurrentTimeMillisSeconds()
 	newBlock, err := common.RecoverBlock(block, mode, v.chainConf, v.txPool, v.log)
@@ -208,9 +221,16 @@ func (v *BlockVerifierImpl) VerifyBlock(block *commonpb.Block, mode protocol.Ver
 	if notSolo {
 		// verify success, cache block and read write set
 		v.log.Debugf("set proposed block(%d,%x)", newBlock.Header.BlockHeight, newBlock.Header.BlockHash)
-		if err = v.proposalCache.SetProposedBlock(newBlock, txRWSetMap, contractEventMap, false); err != nil {
+		if v.pipeline != nil {
+			err = v.pipeline.SetVerifiedBlock(newBlock, txRWSetMap, contractEventMap)
+		} else {
+			err = v.proposalCache.SetProposedBlock(newBlock, txRWSetMap, contractEventMap, false)
+		}
+		if err != nil {
 			return err
 		}
+	} else {
+		v.pipeline.EndVerify(newBlock, nil)
 	}
 
 	// mark transactions in block as pending status in txpool
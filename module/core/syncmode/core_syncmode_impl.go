@@ -64,6 +64,17 @@ func NewCoreEngine(cf *conf.CoreEngineConfig) (*CoreEngine, error) {
 	core.quitC = make(<-chan interface{})
 
 	var err error
+	// blocks proposed or verified on top of uncommitted blocks are rolled back by the committer
+	pipeline := common.NewVerifyPipeline(common.VerifyPipelineConfig{
+		ChainId:         cf.ChainId,
+		Log:             cf.Log,
+		LedgerCache:     cf.LedgerCache,
+		ProposalCache:   cf.ProposalCache,
+		TxPool:          cf.TxPool,
+		BlockchainStore: cf.BlockchainStore,
+		StoreHelper:     cf.StoreHelper,
+	})
+
 	// new a bock proposer
 	proposerConfig := proposer.BlockProposerConfig{
 		ChainId:         cf.ChainId,
@@ -78,6 +89,7 @@ func NewCoreEngine(cf *conf.CoreEngineConfig) (*CoreEngine, error) {
 		AC:              cf.AC,
 		BlockchainStore: cf.BlockchainStore,
 		StoreHelper:     cf.StoreHelper,
+		Pipeline:        pipeline,
 	}
 	core.blockProposer, err = proposer.NewBlockProposer(proposerConfig, cf.Log)
 	if err != nil {
@@ -98,6 +110,7 @@ func NewCoreEngine(cf *conf.CoreEngineConfig) (*CoreEngine, error) {
 		TxPool:          cf.TxPool,
 		VmMgr:           cf.VmMgr,
 		StoreHelper:     cf.StoreHelper,
+		Pipeline:        pipeline,
 	}
 	core.BlockVerifier, err = verifier.NewBlockVerifier(verifierConfig, cf.Log)
 	if err != nil {
@@ -117,6 +130,7 @@ func NewCoreEngine(cf *conf.CoreEngineConfig) (*CoreEngine, error) {
 		Subscriber:      cf.Subscriber,
 		Verifier:        core.BlockVerifier,
 		StoreHelper:     cf.StoreHelper,
+		Pipeline:        pipeline,
 	}
 	core.BlockCommitter, err = common.NewBlockCommitter(committerConfig, cf.Log)
 	if err != nil {
@@ -181,6 +195,11 @@ func (c *CoreEngine) Start() {
 // Stop, stop core engine
 func (c *CoreEngine) Stop() {
 	defer c.log.Infof("core stoped.")
+	c.msgBus.UnRegister(msgbus.ProposeState, c)
+	c.msgBus.UnRegister(msgbus.VerifyBlock, c)
+	c.msgBus.UnRegister(msgbus.CommitBlock, c)
+	c.msgBus.UnRegister(msgbus.TxPoolSignal, c)
+	c.msgBus.UnRegister(msgbus.BuildProposal, c)
 	c.blockProposer.Stop() //nolint: errcheck
 }
 
@@ -0,0 +1,69 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package syncmode
+
+import (
+	"testing"
+
+	"chainmaker.org/chainmaker/common/v2/msgbus"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/protocol/v2/test"
+	"github.com/stretchr/testify/require"
+)
+
+// subscriberMsgBus records the live subscribers of each topic
+type subscriberMsgBus struct {
+	msgbus.MessageBus
+	subscribers map[msgbus.Topic]map[msgbus.Subscriber]struct{}
+}
+
+func (b *subscriberMsgBus) Register(topic msgbus.Topic, sub msgbus.Subscriber) {
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[msgbus.Subscriber]struct{})
+	}
+	b.subscribers[topic][sub] = struct{}{}
+}
+
+func (b *subscriberMsgBus) UnRegister(topic msgbus.Topic, sub msgbus.Subscriber) {
+	delete(b.subscribers[topic], sub)
+}
+
+type testBlockProposer struct {
+	protocol.BlockProposer
+}
+
+func (p *testBlockProposer) Start() error {
+	return nil
+}
+
+func (p *testBlockProposer) Stop() error {
+	return nil
+}
+
+func TestCoreEngineRestartSubscribers(t *testing.T) {
+	bus := &subscriberMsgBus{subscribers: make(map[msgbus.Topic]map[msgbus.Subscriber]struct{})}
+	start := func() *CoreEngine {
+		core := &CoreEngine{msgBus: bus, blockProposer: &testBlockProposer{}, log: &test.GoLogger{}}
+		core.Start()
+		return core
+	}
+
+	// the core engine is kept by pause and resume, the stopped one is replaced when the chain resumes
+	core := start()
+	core.Stop()
+	core = start()
+	for _, topic := range []msgbus.Topic{msgbus.ProposeState, msgbus.VerifyBlock, msgbus.CommitBlock,
+		msgbus.TxPoolSignal, msgbus.BuildProposal} {
+		require.Len(t, bus.subscribers[topic], 1, topic.String())
+		require.Contains(t, bus.subscribers[topic], core, topic.String())
+	}
+
+	core.Stop()
+	for topic, subs := range bus.subscribers {
+		require.Empty(t, subs, topic.String())
+	}
+}
@@ -63,6 +63,8 @@ type BlockProposerImpl struct {
 
 	blockBuilder *common.BlockBuilder
 	storeHelper  conf.StoreHelper
+	txRecoverer  *common.TxRecoverer    // recovers the txs of the discarded self proposed blocks
+	pipeline     *common.VerifyPipeline // may be nil, to propose blocks on top of the committing block
 }
 
 type BlockProposerConfig struct {
@@ -78,6 +80,7 @@ type BlockProposerConfig struct {
 	AC              protocol.AccessControlProvider
 	BlockchainStore protocol.BlockchainStore
 	StoreHelper     conf.StoreHelper
+	Pipeline        *common.VerifyPipeline
 }
 
 const (
@@ -106,6 +109,7 @@ func NewBlockProposer(config BlockProposerConfig, log protocol.Logger) (protocol
 		log:             log,
 		finishProposeC:  make(chan bool),
 		storeHelper:     config.StoreHelper,
+		pipeline:        config.Pipeline,
 	}
 
 	var err error
@@ -143,9 +147,19 @@ func NewBlockProposer(config BlockProposerConfig, log protocol.Logger) (protocol
 		ChainConf:       blockProposerImpl.chainConf,
 		Log:             blockProposerImpl.log,
 		StoreHelper:     config.StoreHelper,
+		Pipeline:        config.Pipeline,
 	}
 
 	blockProposerImpl.blockBuilder = common.NewBlockBuilder(bbConf)
+	blockProposerImpl.txRecoverer = common.NewTxRecoverer(common.TxRecovererConfig{
+		ChainId:         blockProposerImpl.chainId,
+		Log:             blockProposerImpl.log,
+		ChainConf:       blockProposerImpl.chainConf,
+		LedgerCache:     blockProposerImpl.ledgerCache,
+		ProposalCache:   blockProposerImpl.proposalCache,
+		TxPool:          blockProposerImpl.txPool,
+		BlockchainStore: blockProposerImpl.blockchainStore,
+	})
 
 	return blockProposerImpl, nil
 }
@@ -196,7 +210,8 @@ func (bp *BlockProposerImpl) startProposingLoop() {
 /*
  * shouldProposeByBFT, check if node should propose new block
  * Only for *BFT consensus
- * if node is proposer, and node is not propose right now, and last proposed block is committed, then return true
+ * if node is proposer, and node is not propose right now, and last proposed block is committed or committing,
+ * then return true
  */
 func (bp *BlockProposerImpl) shouldProposeByBFT(height uint64) bool {
 	if !bp.isIdle() {
@@ -210,6 +225,11 @@ func (bp *BlockProposerImpl) shouldProposeByBFT(height uint64) bool {
 		return false
 	}
 	currentHeight := committedBlock.Header.BlockHeight
+	if committing := bp.pipeline.CommittingBlock(); committing != nil &&
+		committing.Header.BlockHeight == currentHeight+1 {
+		// the block is proposed on top of the committing block
+		currentHeight = committing.Header.BlockHeight
+	}
 	// proposing height must higher than current height
 	return currentHeight+1 == height
 }
@@ -223,6 +243,11 @@ func (bp *BlockProposerImpl) proposeBlock() {
 		}
 	}()
 	lastBlock := bp.ledgerCache.GetLastCommittedBlock()
+	// propose on top of the committing block, so that proposing overlaps its commit
+	if committing := bp.pipeline.CommittingBlock(); committing != nil &&
+		committing.Header.BlockHeight == lastBlock.Header.BlockHeight+1 {
+		lastBlock = committing
+	}
 	proposingHeight := lastBlock.Header.BlockHeight + 1
 	if !bp.shouldProposeByBFT(proposingHeight) {
 		return
@@ -261,12 +286,9 @@ func (bp *BlockProposerImpl) proposing(height uint64, preHash []byte) *commonpb.
 			return nil
 		}
 		bp.proposalCache.ClearTheBlock(selfProposedBlock)
-		// Note: It is not possible to re-add the transactions in the deleted block to txpool; because some transactions may
-		// be included in other blocks to be confirmed, and it is impossible to quickly exclude these pending transactions
-		// that have been entered into the block. Comprehensive considerations, directly discard this block is the optimal
-		// choice. This processing method may only cause partial transaction loss at the current node, but it can be solved
-		// by rebroadcasting on the client side.
-		bp.txPool.RetryAndRemoveTxs(nil, selfProposedBlock.Txs)
+		// put the txs of the discarded block back into txpool, except those included in other blocks to be confirmed
+		// or committed already
+		bp.txRecoverer.Recover(selfProposedBlock)
 	}
 
 	// retrieve tx batch from tx pool
@@ -301,7 +323,7 @@ func (bp *BlockProposerImpl) proposing(height uint64, preHash []byte) *commonpb.
 		if sqlErr := bp.storeHelper.RollBack(block, bp.blockchainStore); sqlErr != nil {
 			bp.log.Errorf("block [%d] rollback sql failed: %s", block.Header.BlockHeight, sqlErr)
 		}
-		bp.txPool.RetryAndRemoveTxs(checkedBatch, nil) // put txs back to txpool
+		common.RetryTxs(bp.txPool, bp.chainConf, checkedBatch, bp.log) // put txs back to txpool
 		bp.log.Warnf("generate new block failed, %s", err.Error())
 		return nil
 	}
@@ -8,32 +8,48 @@ SPDX-License-Identifier: Apache-2.0
 package monitor
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 
+	"chainmaker.org/chainmaker-go/blockchain"
 	"chainmaker.org/chainmaker/localconf/v2"
 	"chainmaker.org/chainmaker/logger/v2"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// readyMaxSyncLag the node is not ready until the lag of each chain behind the peers is within it
+const readyMaxSyncLag = 3
+
 type MonitorServer struct {
-	httpServer *http.Server
-	log        *logger.CMLogger
+	httpServer       *http.Server
+	chainMakerServer *blockchain.ChainMakerServer
+	log              *logger.CMLogger
+}
+
+// healthResponse the response body of /healthz and /readyz
+type healthResponse struct {
+	Ok     bool                      `json:"ok"`
+	Chains []*blockchain.ChainHealth `json:"chains"`
 }
 
-func NewMonitorServer() *MonitorServer {
+func NewMonitorServer(chainMakerServer *blockchain.ChainMakerServer) *MonitorServer {
 	var log = logger.GetLogger(logger.MODULE_MONITOR)
 
 	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		s := &MonitorServer{
+			chainMakerServer: chainMakerServer,
+			log:              log,
+		}
 		mux := http.NewServeMux()
 		mux.Handle("/metrics", promhttp.Handler())
-		return &MonitorServer{
-			httpServer: &http.Server{
-				Handler: mux,
-			},
-			log: log,
+		mux.HandleFunc("/healthz", s.handleHealthz)
+		mux.HandleFunc("/readyz", s.handleReadyz)
+		s.httpServer = &http.Server{
+			Handler: mux,
 		}
+		return s
 	} else {
 		return &MonitorServer{
 			log: log,
@@ -61,3 +77,40 @@ func (s *MonitorServer) Start() error {
 
 	return nil
 }
+
+// handleHealthz reports the state of each chain, it fails if any chain failed to start
+func (s *MonitorServer) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	chains := s.chainMakerServer.GetAllChainHealth()
+	ok := true
+	for _, chain := range chains {
+		ok = ok && chain.IsHealthy()
+	}
+	s.writeHealth(w, &healthResponse{Ok: ok, Chains: chains})
+}
+
+// handleReadyz reports the state of each chain, it fails until all the chains are running
+// and have caught up with the peers
+func (s *MonitorServer) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	chains := s.chainMakerServer.GetAllChainHealth()
+	ok := len(chains) > 0
+	for _, chain := range chains {
+		ok = ok && chain.IsReady(readyMaxSyncLag)
+	}
+	s.writeHealth(w, &healthResponse{Ok: ok, Chains: chains})
+}
+
+func (s *MonitorServer) writeHealth(w http.ResponseWriter, resp *healthResponse) {
+	bz, err := json.Marshal(resp)
+	if err != nil {
+		s.log.Errorf("marshal health response failed, %s", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if _, err = w.Write(bz); err != nil {
+		s.log.Warnf("write health response failed, %s", err.Error())
+	}
+}
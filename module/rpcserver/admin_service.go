@@ -0,0 +1,246 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	commonErr "chainmaker.org/chainmaker/common/v2/errors"
+	"chainmaker.org/chainmaker/localconf/v2"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"google.golang.org/grpc"
+)
+
+const (
+	// AdminServiceName the name of the gRPC service which manages the node. The requests are query txs
+	// signed by an admin of the org which the node belongs to, whose contract name is the name of the
+	// gRPC method, they are executed by the node locally and are never broadcast or committed.
+	AdminServiceName = "api.AdminNode"
+
+	// AdminMethodListConsensusEvidences lists the misbehavior evidences (e.g. TBFT double sign)
+	// collected by the consensus engine of the node
+	AdminMethodListConsensusEvidences = "ListConsensusEvidences"
+
+	// AdminMethodChainLifecycle pauses, resumes, stops or removes the chain of the request, or gets the status
+	// of it, the operation is the method of the request, see the ChainLifecycle methods
+	AdminMethodChainLifecycle = "ChainLifecycle"
+
+	// AdminMethodRpcBlackList gets or sets the rpc blacklist of the node, see the RpcBlackList methods
+	AdminMethodRpcBlackList = "RpcBlackList"
+
+	// AdminMethodConsensusAdmin operates the consensus engine of the node, see the ConsensusAdmin methods
+	AdminMethodConsensusAdmin = "ConsensusAdmin"
+
+	// adminRequestTimeWindow the max difference between the timestamp of the admin request and the local time,
+	// the tx ids of the requests are kept in the window to reject the replayed requests
+	adminRequestTimeWindow = 60 * time.Second
+)
+
+// AdminNodeServer the admin RPCs of the node
+type AdminNodeServer interface {
+	ListConsensusEvidences(context.Context, *commonPb.TxRequest) (*commonPb.TxResponse, error)
+	ChainLifecycle(context.Context, *commonPb.TxRequest) (*commonPb.TxResponse, error)
+	RpcBlackList(context.Context, *commonPb.TxRequest) (*commonPb.TxResponse, error)
+	ConsensusAdmin(context.Context, *commonPb.TxRequest) (*commonPb.TxResponse, error)
+}
+
+var _ AdminNodeServer = (*ApiService)(nil)
+
+// adminNodeServiceDesc the service is local to the node and not defined in the api proto, so it is written by hand
+var adminNodeServiceDesc = grpc.ServiceDesc{
+	ServiceName: AdminServiceName,
+	HandlerType: (*AdminNodeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		adminMethodDesc(AdminMethodListConsensusEvidences, AdminNodeServer.ListConsensusEvidences),
+		adminMethodDesc(AdminMethodChainLifecycle, AdminNodeServer.ChainLifecycle),
+		adminMethodDesc(AdminMethodRpcBlackList, AdminNodeServer.RpcBlackList),
+		adminMethodDesc(AdminMethodConsensusAdmin, AdminNodeServer.ConsensusAdmin),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin_node",
+}
+
+// RegisterAdminNodeServer - register the admin service to the gRPC server
+func RegisterAdminNodeServer(s *grpc.Server, srv AdminNodeServer) {
+	s.RegisterService(&adminNodeServiceDesc, srv)
+}
+
+func adminMethodDesc(name string,
+	call func(AdminNodeServer, context.Context, *commonPb.TxRequest) (*commonPb.TxResponse, error)) grpc.MethodDesc {
+
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error,
+			interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+
+			req := new(commonPb.TxRequest)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(AdminNodeServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + AdminServiceName + "/" + name,
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(AdminNodeServer), ctx, req.(*commonPb.TxRequest))
+			}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+// ListConsensusEvidences - list the misbehavior evidences collected by the consensus engine
+func (s *ApiService) ListConsensusEvidences(ctx context.Context, req *commonPb.TxRequest) (
+	*commonPb.TxResponse, error) {
+
+	tx, resp, err := s.verifyAdminRequest(ctx, req, AdminMethodListConsensusEvidences)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	chain, err := s.chainMakerServer.GetBlockchain(tx.Payload.ChainId)
+	if err != nil {
+		return s.adminResp(tx, commonPb.TxStatusCode_INTERNAL_ERROR, err, nil), nil
+	}
+	evidences, err := chain.GetConsensusEvidences()
+	if err != nil {
+		return s.adminResp(tx, commonPb.TxStatusCode_CONTRACT_FAIL, err, nil), nil
+	}
+	return s.adminResp(tx, commonPb.TxStatusCode_SUCCESS, nil, evidences), nil
+}
+
+// verifyAdminRequest - verify the signature, the permission, the time window and the replay of the admin request,
+// whose contract name must be the name of the gRPC method. The response is returned if the request is rejected,
+// the error is only returned if the sender is in the blacklist or runs out of its quota.
+func (s *ApiService) verifyAdminRequest(ctx context.Context, req *commonPb.TxRequest, method string) (
+	*commonPb.Transaction, *commonPb.TxResponse, error) {
+
+	tx := &commonPb.Transaction{
+		Payload:   req.Payload,
+		Sender:    req.Sender,
+		Endorsers: req.Endorsers,
+	}
+	if tx.Payload == nil || tx.Sender == nil || tx.Sender.Signer == nil {
+		return nil, &commonPb.TxResponse{
+			Code:    commonPb.TxStatusCode_INVALID_PARAMETER,
+			Message: "payload and sender of the admin request are required",
+		}, nil
+	}
+	if tx.Payload.TxType != commonPb.TxType_QUERY_CONTRACT || tx.Payload.ContractName != method {
+		err := fmt.Errorf("the admin request of method [%s] must be a query tx of contract [%s]", method, method)
+		return nil, s.adminResp(tx, commonPb.TxStatusCode_INVALID_PARAMETER, err, nil), nil
+	}
+
+	if errCode, errMsg := s.validate(tx); errCode != commonErr.ERR_CODE_OK {
+		return nil, s.adminResp(tx, commonPb.TxStatusCode_INTERNAL_ERROR, errors.New(errMsg), nil), nil
+	}
+	if err := s.checkSenderBlackList(tx, method); err != nil {
+		return nil, nil, err
+	}
+	if err := s.identityRateLimiter.allow(tx.Sender, QuotaKindQuery); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkNodeAdmin(tx); err != nil {
+		s.log.Warn(err)
+		return nil, s.adminResp(tx, commonPb.TxStatusCode_NO_PERMISSION, err, nil), nil
+	}
+
+	now := time.Now()
+	timestamp := time.Unix(tx.Payload.Timestamp, 0)
+	if timestamp.Before(now.Add(-adminRequestTimeWindow)) || timestamp.After(now.Add(adminRequestTimeWindow)) {
+		err := fmt.Errorf("the timestamp %d of the admin request is out of the time window %s",
+			tx.Payload.Timestamp, adminRequestTimeWindow)
+		return nil, s.adminResp(tx, commonPb.TxStatusCode_INVALID_PARAMETER, err, nil), nil
+	}
+	if !s.adminReplayCache.add(tx.Payload.ChainId, tx.Payload.TxId, now) {
+		err := fmt.Errorf("the admin request [%s] is replayed", tx.Payload.TxId)
+		s.log.Warn(err)
+		return nil, s.adminResp(tx, commonPb.TxStatusCode_INVALID_PARAMETER, err, nil), nil
+	}
+
+	// audit log format: ip:port|orgId|chainId|TxType|TxId|Timestamp|ContractName|Method
+	s.logBrief.Infof("|%s|%s|%s|%s|%s|%d|%s|%s", GetClientAddr(ctx), tx.Sender.Signer.OrgId,
+		tx.Payload.ChainId, tx.Payload.TxType, tx.Payload.TxId, tx.Payload.Timestamp, tx.Payload.ContractName,
+		tx.Payload.Method)
+	return tx, nil, nil
+}
+
+// checkNodeAdmin - the sender of tx must be an admin of the org which the node belongs to
+func (s *ApiService) checkNodeAdmin(tx *commonPb.Transaction) error {
+	chain, err := s.chainMakerServer.GetBlockchain(tx.Payload.ChainId)
+	if err != nil {
+		return err
+	}
+	member, err := chain.GetAccessControl().NewMember(tx.Sender.Signer)
+	if err != nil {
+		return fmt.Errorf("new member failed, %s", err)
+	}
+	if member.GetRole() != protocol.RoleAdmin || member.GetOrgId() != localconf.ChainMakerConfig.NodeConfig.OrgId {
+		return fmt.Errorf("permission denied, the sender must be an admin of org [%s]",
+			localconf.ChainMakerConfig.NodeConfig.OrgId)
+	}
+	return nil
+}
+
+// adminResp - the response of the admin request, with the error message if err is not nil
+func (s *ApiService) adminResp(tx *commonPb.Transaction, code commonPb.TxStatusCode, err error,
+	result []byte) *commonPb.TxResponse {
+
+	resp := &commonPb.TxResponse{
+		Code:    code,
+		Message: code.String(),
+		TxId:    tx.Payload.TxId,
+	}
+	if err != nil {
+		resp.Message = err.Error()
+		return resp
+	}
+	resp.ContractResult = &commonPb.ContractResult{
+		Code:   0,
+		Result: result,
+	}
+	return resp
+}
+
+// adminReplayCache keeps the tx ids of the admin requests received in the time window
+type adminReplayCache struct {
+	sync.Mutex
+	receivedAt map[string]time.Time
+}
+
+func newAdminReplayCache() *adminReplayCache {
+	return &adminReplayCache{receivedAt: make(map[string]time.Time)}
+}
+
+// add the tx id of the admin request, false is returned if it is received in the time window
+func (c *adminReplayCache) add(chainId, txId string, now time.Time) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	// the requests out of the time window are rejected by timestamp, so their tx ids are no longer needed,
+	// the window is doubled since the timestamp can be ahead of the local time
+	for key, receivedAt := range c.receivedAt {
+		if now.Sub(receivedAt) > 2*adminRequestTimeWindow {
+			delete(c.receivedAt, key)
+		}
+	}
+	key := chainId + "#" + txId
+	if _, ok := c.receivedAt[key]; ok {
+		return false
+	}
+	c.receivedAt[key] = now
+	return true
+}
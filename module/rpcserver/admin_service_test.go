@@ -0,0 +1,28 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminReplayCache(t *testing.T) {
+	cache := newAdminReplayCache()
+	now := time.Now()
+
+	require.True(t, cache.add("chain1", "tx1", now))
+	require.False(t, cache.add("chain1", "tx1", now.Add(adminRequestTimeWindow)))
+	// the same tx id of another chain is a different request
+	require.True(t, cache.add("chain2", "tx1", now))
+
+	// the tx ids out of the time window are pruned, the requests are rejected by timestamp
+	require.True(t, cache.add("chain1", "tx2", now.Add(3*adminRequestTimeWindow)))
+	require.Len(t, cache.receivedAt, 1)
+}
@@ -12,6 +12,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"chainmaker.org/chainmaker-go/blockchain"
 	commonErr "chainmaker.org/chainmaker/common/v2/errors"
@@ -32,6 +33,12 @@ import (
 const (
 	//SYSTEM_CHAIN the system chain name
 	SYSTEM_CHAIN = "system_chain"
+
+	// QueryBlockHeightParam the optional parameter of query tx, which specifies the block height
+	// the contract state is read at, the latest state is read if it is absent.
+	// Only the kv state is read at the height, the blocks and the chain config read through the store
+	// are the latest ones, and it is rejected on the chains with sql support, whose state is not versioned.
+	QueryBlockHeightParam = "__QUERY_BLOCK_HEIGHT__"
 )
 
 var _ apiPb.RpcNodeServer = (*ApiService)(nil)
@@ -42,6 +49,9 @@ type ApiService struct {
 	log                   *logger.CMLogger
 	logBrief              *logger.CMLogger
 	subscriberRateLimiter *rate.Limiter
+	identityRateLimiter   *identityRateLimiter
+	blackList             *blackList
+	adminReplayCache      *adminReplayCache
 	metricQueryCounter    *prometheus.CounterVec
 	metricInvokeCounter   *prometheus.CounterVec
 	ctx                   context.Context
@@ -73,6 +83,7 @@ func NewApiService(ctx context.Context, chainMakerServer *blockchain.ChainMakerS
 		log:                   log,
 		logBrief:              logBrief,
 		subscriberRateLimiter: subscriberRateLimiter,
+		adminReplayCache:      newAdminReplayCache(),
 		ctx:                   ctx,
 	}
 
@@ -93,11 +104,14 @@ func (s *ApiService) SendRequest(ctx context.Context, req *commonPb.TxRequest) (
 			req.Payload.TxId, req.Payload, req.Sender, req.Endorsers)
 	})
 
-	resp := s.invoke(&commonPb.Transaction{
+	resp, err := s.invoke(&commonPb.Transaction{
 		Payload:   req.Payload,
 		Sender:    req.Sender,
 		Endorsers: req.Endorsers,
 		Result:    nil}, protocol.RPC)
+	if err != nil {
+		return nil, err
+	}
 
 	// audit log format: ip:port|orgId|chainId|TxType|TxId|Timestamp|ContractName|Method|retCode|retCodeMsg|retMsg
 	s.logBrief.Infof("|%s|%s|%s|%s|%s|%d|%s|%s|%d|%s|%s", GetClientAddr(ctx), req.Sender.Signer.OrgId,
@@ -145,8 +159,9 @@ func (s *ApiService) getErrMsg(errCode commonErr.ErrCode, err error) string {
 	return fmt.Sprintf("%s, %s", errCode.String(), err.Error())
 }
 
-// invoke contract according to TxType
-func (s *ApiService) invoke(tx *commonPb.Transaction, source protocol.TxSource) *commonPb.TxResponse {
+// invoke contract according to TxType, the error is only returned if the sender is in the blacklist
+// or runs out of its quota
+func (s *ApiService) invoke(tx *commonPb.Transaction, source protocol.TxSource) (*commonPb.TxResponse, error) {
 	var (
 		errCode commonErr.ErrCode
 		errMsg  string
@@ -159,22 +174,34 @@ func (s *ApiService) invoke(tx *commonPb.Transaction, source protocol.TxSource)
 			resp.Code = commonPb.TxStatusCode_INTERNAL_ERROR
 			resp.Message = errMsg
 			resp.TxId = tx.Payload.TxId
-			return resp
+			return resp, nil
+		}
+
+		if err := s.checkSenderBlackList(tx, "SendRequest"); err != nil {
+			return nil, err
+		}
+
+		quotaKind := QuotaKindInvoke
+		if tx.Payload.TxType == commonPb.TxType_QUERY_CONTRACT {
+			quotaKind = QuotaKindQuery
+		}
+		if err := s.identityRateLimiter.allow(tx.Sender, quotaKind); err != nil {
+			return nil, err
 		}
 	}
 
 	switch tx.Payload.TxType {
 	case commonPb.TxType_QUERY_CONTRACT:
-		return s.dealQuery(tx, source)
+		return s.dealQuery(tx, source), nil
 	case commonPb.TxType_INVOKE_CONTRACT:
-		return s.dealTransact(tx, source)
+		return s.dealTransact(tx, source), nil
 	case commonPb.TxType_ARCHIVE:
-		return s.doArchive(tx)
+		return s.doArchive(tx), nil
 	default:
 		return &commonPb.TxResponse{
 			Code:    commonPb.TxStatusCode_INTERNAL_ERROR,
 			Message: commonErr.ERR_CODE_TXTYPE.String(),
-		}
+		}, nil
 	}
 }
 
@@ -227,7 +254,30 @@ func (s *ApiService) dealQuery(tx *commonPb.Transaction, source protocol.TxSourc
 		blockVersion:     protocol.DefaultBlockVersion,
 	}
 
-	contract, err := store.GetContractByName(tx.Payload.ContractName)
+	var simContext protocol.TxSimContext = ctx
+	blockHeight, withHeight, err := s.getQueryBlockHeight(tx.Payload.Parameters)
+	if err != nil {
+		s.log.Warn(err)
+		resp.Code = commonPb.TxStatusCode_INVALID_PARAMETER
+		resp.Message = err.Error()
+		return resp
+	}
+	if withHeight {
+		if resp.Code, err = s.checkQueryBlockHeight(chainId, store, blockHeight); err != nil {
+			s.log.Warn(err)
+			resp.Message = err.Error()
+			return resp
+		}
+		if simContext, err = newTxQueryHistorySimContext(ctx, blockHeight); err != nil {
+			s.log.Error(err)
+			resp.Code = commonPb.TxStatusCode_INTERNAL_ERROR
+			resp.Message = err.Error()
+			return resp
+		}
+	}
+
+	// the contract and its bytecode are those at the queried height
+	contract, err := simContext.GetContractByName(tx.Payload.ContractName)
 	if err != nil {
 		s.log.Error(err)
 		resp.Code = commonPb.TxStatusCode_INTERNAL_ERROR
@@ -238,7 +288,7 @@ func (s *ApiService) dealQuery(tx *commonPb.Transaction, source protocol.TxSourc
 
 	var bytecode []byte
 	if contract.RuntimeType != commonPb.RuntimeType_NATIVE {
-		bytecode, err = store.GetContractBytecode(tx.Payload.ContractName)
+		bytecode, err = simContext.GetContractBytecode(tx.Payload.ContractName)
 		if err != nil {
 			s.log.Error(err)
 			resp.Code = commonPb.TxStatusCode_INTERNAL_ERROR
@@ -248,7 +298,7 @@ func (s *ApiService) dealQuery(tx *commonPb.Transaction, source protocol.TxSourc
 		}
 	}
 	txResult, _, txStatusCode := vmMgr.RunContract(contract, tx.Payload.Method,
-		bytecode, s.kvPair2Map(tx.Payload.Parameters), ctx, 0, tx.Payload.TxType)
+		bytecode, s.queryParams2Map(tx.Payload.Parameters), simContext, 0, tx.Payload.TxType)
 	s.log.DebugDynamic(func() string {
 		contractJson, _ := json.Marshal(contract)
 		return fmt.Sprintf("vmMgr.RunContract: txStatusCode:%d, resultCode:%d, contractName[%s](%s), "+
@@ -348,6 +398,62 @@ func (s *ApiService) dealSystemChainQuery(tx *commonPb.Transaction, vmMgr protoc
 	return resp
 }
 
+// getQueryBlockHeight - get the block height of query tx, withHeight is false if it is not specified
+func (s *ApiService) getQueryBlockHeight(params []*commonPb.KeyValuePair) (
+	blockHeight uint64, withHeight bool, err error) {
+
+	for _, kv := range params {
+		if kv.Key != QueryBlockHeightParam {
+			continue
+		}
+		blockHeight, err = strconv.ParseUint(string(kv.Value), 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid %s [%s], %s", QueryBlockHeightParam, string(kv.Value), err.Error())
+		}
+		return blockHeight, true, nil
+	}
+	return 0, false, nil
+}
+
+// checkQueryBlockHeight - check whether the state at blockHeight can be queried
+func (s *ApiService) checkQueryBlockHeight(chainId string, store protocol.BlockchainStore,
+	blockHeight uint64) (commonPb.TxStatusCode, error) {
+
+	chainConf, err := s.chainMakerServer.GetChainConf(chainId)
+	if err != nil {
+		return commonPb.TxStatusCode_INTERNAL_ERROR, err
+	}
+	// the sql state has no key history to rebuild it at a height
+	if chainConf.ChainConfig().Contract.EnableSqlSupport {
+		return commonPb.TxStatusCode_INVALID_PARAMETER, fmt.Errorf(
+			"query at block height %d failed, it is not supported with sql support enabled", blockHeight)
+	}
+
+	archivedPivot := store.GetArchivedPivot()
+	if archivedPivot > 0 && blockHeight <= archivedPivot {
+		return commonPb.TxStatusCode_ARCHIVED_BLOCK, fmt.Errorf(
+			"query at block height %d failed, blocks up to height %d have been archived", blockHeight, archivedPivot)
+	}
+
+	lastBlock, err := store.GetLastBlock()
+	if err != nil {
+		return commonPb.TxStatusCode_INTERNAL_ERROR, err
+	}
+	if blockHeight > lastBlock.Header.BlockHeight {
+		return commonPb.TxStatusCode_INVALID_PARAMETER, fmt.Errorf(
+			"query at block height %d failed, current block height is %d", blockHeight, lastBlock.Header.BlockHeight)
+	}
+
+	return commonPb.TxStatusCode_SUCCESS, nil
+}
+
+// queryParams2Map - change query parameters to map, the parameters reserved for rpc server are skipped
+func (s *ApiService) queryParams2Map(kvPair []*commonPb.KeyValuePair) map[string][]byte {
+	kvMap := s.kvPair2Map(kvPair)
+	delete(kvMap, QueryBlockHeightParam)
+	return kvMap
+}
+
 // kvPair2Map - change []*commonPb.KeyValuePair to map[string]string
 func (s *ApiService) kvPair2Map(kvPair []*commonPb.KeyValuePair) map[string][]byte {
 	kvMap := make(map[string][]byte)
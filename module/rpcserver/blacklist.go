@@ -0,0 +1,181 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/utils/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BlackListConfig the access list of rpc, rpc.blacklist in chainmaker.yml
+type BlackListConfig struct {
+	// Addresses the denied ips or CIDR ranges
+	Addresses []string `mapstructure:"addresses" json:"addresses"`
+	// AllowAddresses the allowed ips or CIDR ranges, if it is not empty, the others are denied
+	AllowAddresses []string `mapstructure:"allow_addresses" json:"allow_addresses"`
+	// CertHashes the denied cert hashes in hex, computed with the hash type of the chain
+	CertHashes []string `mapstructure:"cert_hashes" json:"cert_hashes"`
+	// Orgs the denied org ids
+	Orgs []string `mapstructure:"orgs" json:"orgs"`
+}
+
+type blackListRules struct {
+	config     *BlackListConfig
+	denyNets   []*net.IPNet
+	allowNets  []*net.IPNet
+	certHashes map[string]struct{}
+	orgs       map[string]struct{}
+}
+
+// blackList is shared by the interceptors, the gateway and the ApiService, the rules are replaced
+// as a whole when it is updated, so the checks never see a partial update.
+type blackList struct {
+	rules atomic.Value // *blackListRules
+}
+
+func newBlackList(config *BlackListConfig) (*blackList, error) {
+	b := &blackList{}
+	if err := b.update(config); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// update validates and applies the config, the current rules are kept if it is invalid
+func (b *blackList) update(config *BlackListConfig) error {
+	rules := &blackListRules{
+		config:     config,
+		certHashes: make(map[string]struct{}, len(config.CertHashes)),
+		orgs:       make(map[string]struct{}, len(config.Orgs)),
+	}
+	var err error
+	if rules.denyNets, err = parseIPNets(config.Addresses); err != nil {
+		return err
+	}
+	if rules.allowNets, err = parseIPNets(config.AllowAddresses); err != nil {
+		return err
+	}
+	for _, certHash := range config.CertHashes {
+		rules.certHashes[strings.ToLower(certHash)] = struct{}{}
+	}
+	for _, org := range config.Orgs {
+		rules.orgs[org] = struct{}{}
+	}
+	b.rules.Store(rules)
+	return nil
+}
+
+func (b *blackList) getConfig() *BlackListConfig {
+	return b.rules.Load().(*blackListRules).config
+}
+
+// checkIp returns ResourceExhausted if the ip is denied or not allowed
+func (b *blackList) checkIp(ipAddr, method string) error {
+	rules := b.rules.Load().(*blackListRules)
+	if len(rules.denyNets) == 0 && len(rules.allowNets) == 0 {
+		return nil
+	}
+
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		errMsg := fmt.Sprintf("%s is rejected by black list, invalid ip [%s]", method, ipAddr)
+		log.Warn(errMsg)
+		return status.Error(codes.ResourceExhausted, errMsg)
+	}
+	if len(rules.allowNets) > 0 && !containsIp(rules.allowNets, ip) {
+		errMsg := fmt.Sprintf("%s is rejected by allow list [%s]", method, ipAddr)
+		log.Warn(errMsg)
+		return status.Error(codes.ResourceExhausted, errMsg)
+	}
+	if containsIp(rules.denyNets, ip) {
+		errMsg := fmt.Sprintf("%s is rejected by black list [%s]", method, ipAddr)
+		log.Warn(errMsg)
+		return status.Error(codes.ResourceExhausted, errMsg)
+	}
+	return nil
+}
+
+// checkSender returns PermissionDenied if the org or cert of the sender is denied
+func (b *blackList) checkSender(sender *commonPb.EndorsementEntry, hashType, method string) error {
+	if sender == nil || sender.Signer == nil {
+		return nil
+	}
+	rules := b.rules.Load().(*blackListRules)
+	signer := sender.Signer
+	if _, ok := rules.orgs[signer.OrgId]; ok {
+		errMsg := fmt.Sprintf("%s is rejected by black list, org [%s]", method, signer.OrgId)
+		log.Warn(errMsg)
+		return status.Error(codes.PermissionDenied, errMsg)
+	}
+	if len(rules.certHashes) == 0 {
+		return nil
+	}
+
+	var certHash string
+	switch signer.MemberType {
+	case pbac.MemberType_CERT:
+		var err error
+		if certHash, err = utils.GetCertificateIdHex(signer.MemberInfo, hashType); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s is rejected, invalid cert, %s", method, err)
+		}
+	case pbac.MemberType_CERT_HASH:
+		certHash = hex.EncodeToString(signer.MemberInfo)
+	default:
+		return nil
+	}
+	if _, ok := rules.certHashes[strings.ToLower(certHash)]; ok {
+		errMsg := fmt.Sprintf("%s is rejected by black list, cert hash [%s]", method, certHash)
+		log.Warn(errMsg)
+		return status.Error(codes.PermissionDenied, errMsg)
+	}
+	return nil
+}
+
+// parseIPNets parses ips and CIDR ranges, an ip is a range of itself
+func parseIPNets(addresses []string) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(addresses))
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if strings.Contains(address, "/") {
+			_, ipNet, err := net.ParseCIDR(address)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR [%s], %s", address, err)
+			}
+			ipNets = append(ipNets, ipNet)
+			continue
+		}
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip [%s]", address)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return ipNets, nil
+}
+
+func containsIp(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// RpcBlackList methods, the method of the admin request of AdminMethodRpcBlackList
+const (
+	RpcBlackListGet = "GET_BLACKLIST"
+	RpcBlackListSet = "SET_BLACKLIST"
+
+	// RpcBlackListParamConfig the parameter of SET_BLACKLIST, the json of BlackListConfig
+	RpcBlackListParamConfig = "blacklist"
+)
+
+// RpcBlackList - get the blacklist in use or replace it with the one in the admin request. The blacklist set is
+// not written back to chainmaker.yml, so it is replaced by rpc.blacklist in chainmaker.yml after restart.
+func (s *ApiService) RpcBlackList(ctx context.Context, req *commonPb.TxRequest) (*commonPb.TxResponse, error) {
+	tx, resp, err := s.verifyAdminRequest(ctx, req, AdminMethodRpcBlackList)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	return s.doRpcBlackList(tx), nil
+}
+
+func (s *ApiService) doRpcBlackList(tx *commonPb.Transaction) *commonPb.TxResponse {
+	var err error
+	switch tx.Payload.Method {
+	case RpcBlackListGet:
+	case RpcBlackListSet:
+		if err = s.setBlackList(tx); err == nil {
+			s.log.Infof("rpc blacklist is set by tx[%s]", tx.Payload.TxId)
+		}
+	default:
+		err = fmt.Errorf("unsupported method [%s] of [%s]", tx.Payload.Method, AdminMethodRpcBlackList)
+	}
+	if err != nil {
+		s.log.Warn(err)
+		return s.adminResp(tx, commonPb.TxStatusCode_CONTRACT_FAIL, err, nil)
+	}
+
+	bz, err := json.Marshal(s.blackList.getConfig())
+	if err != nil {
+		return s.adminResp(tx, commonPb.TxStatusCode_INTERNAL_ERROR, err, nil)
+	}
+	return s.adminResp(tx, commonPb.TxStatusCode_SUCCESS, nil, bz)
+}
+
+func (s *ApiService) setBlackList(tx *commonPb.Transaction) error {
+	for _, param := range tx.Payload.Parameters {
+		if param.Key != RpcBlackListParamConfig {
+			continue
+		}
+		config := &BlackListConfig{}
+		if err := json.Unmarshal(param.Value, config); err != nil {
+			return fmt.Errorf("invalid parameter [%s], %s", RpcBlackListParamConfig, err)
+		}
+		return s.blackList.update(config)
+	}
+	return fmt.Errorf("parameter [%s] is required", RpcBlackListParamConfig)
+}
+
+// checkSenderBlackList - the org and cert of the sender must not be in the blacklist
+func (s *ApiService) checkSenderBlackList(tx *commonPb.Transaction, method string) error {
+	if s.blackList == nil {
+		return nil
+	}
+	chainConf, err := s.chainMakerServer.GetChainConf(tx.Payload.ChainId)
+	if err != nil {
+		return err
+	}
+	return s.blackList.checkSender(tx.Sender, chainConf.ChainConfig().Crypto.Hash, method)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chainmaker.org/chainmaker/logger/v2"
+	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func requireCode(t *testing.T, code codes.Code, err error) {
+	st, _ := status.FromError(err)
+	require.Equal(t, code, st.Code(), err)
+}
+
+func TestBlackList_CheckIp(t *testing.T) {
+	b, err := newBlackList(&BlackListConfig{})
+	require.NoError(t, err)
+	require.NoError(t, b.checkIp("10.10.1.1", "test"))
+
+	// the denied ips and CIDR ranges
+	require.NoError(t, b.update(&BlackListConfig{Addresses: []string{"10.10.0.0/16", " 192.168.1.8 ", "2001:db8::/32"}}))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("10.10.1.1", "test"))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("192.168.1.8", "test"))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("2001:db8::1", "test"))
+	require.NoError(t, b.checkIp("10.11.1.1", "test"))
+	require.NoError(t, b.checkIp("192.168.1.9", "test"))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("unknown", "test"))
+
+	// only the allowed addresses pass, and the denied ones in them are still rejected
+	require.NoError(t, b.update(&BlackListConfig{
+		Addresses:      []string{"192.168.1.8"},
+		AllowAddresses: []string{"192.168.1.0/24"},
+	}))
+	require.NoError(t, b.checkIp("192.168.1.9", "test"))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("192.168.1.8", "test"))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("192.168.2.1", "test"))
+
+	// the invalid config is not applied
+	require.Error(t, b.update(&BlackListConfig{Addresses: []string{"10.10.0.0/33"}}))
+	require.Error(t, b.update(&BlackListConfig{AllowAddresses: []string{"10.10.0"}}))
+	require.NoError(t, b.checkIp("192.168.1.9", "test"))
+	requireCode(t, codes.ResourceExhausted, b.checkIp("192.168.2.1", "test"))
+}
+
+func TestBlackList_CheckSender(t *testing.T) {
+	certHash := []byte{0xab, 0xcd, 0xef}
+	b, err := newBlackList(&BlackListConfig{
+		CertHashes: []string{"ABCDEF"},
+		Orgs:       []string{"org2"},
+	})
+	require.NoError(t, err)
+
+	sender := func(orgId string, memberType pbac.MemberType, memberInfo []byte) *commonPb.EndorsementEntry {
+		return &commonPb.EndorsementEntry{Signer: &pbac.Member{
+			OrgId:      orgId,
+			MemberType: memberType,
+			MemberInfo: memberInfo,
+		}}
+	}
+	// the cert hashes are matched case insensitively
+	requireCode(t, codes.PermissionDenied, b.checkSender(sender("org1", pbac.MemberType_CERT_HASH, certHash),
+		"SHA256", "test"))
+	require.NoError(t, b.checkSender(sender("org1", pbac.MemberType_CERT_HASH, []byte{0xab}), "SHA256", "test"))
+	// the public keys are not matched by the cert hashes
+	require.NoError(t, b.checkSender(sender("org1", pbac.MemberType_PUBLIC_KEY, certHash), "SHA256", "test"))
+	// the invalid cert is rejected
+	requireCode(t, codes.InvalidArgument, b.checkSender(sender("org1", pbac.MemberType_CERT, []byte("cert")),
+		"SHA256", "test"))
+
+	// all the members of the denied org are rejected
+	requireCode(t, codes.PermissionDenied, b.checkSender(sender("org2", pbac.MemberType_CERT_HASH, []byte{0x01}),
+		"SHA256", "test"))
+	require.NoError(t, b.checkSender(nil, "SHA256", "test"))
+}
+
+func TestApiService_SetBlackList(t *testing.T) {
+	b, err := newBlackList(&BlackListConfig{})
+	require.NoError(t, err)
+	s := &ApiService{blackList: b, log: logger.GetLogger(logger.MODULE_RPC)}
+	newTx := func(method string, params ...*commonPb.KeyValuePair) *commonPb.Transaction {
+		return &commonPb.Transaction{Payload: &commonPb.Payload{TxId: "tx1", Method: method, Parameters: params}}
+	}
+
+	config := &BlackListConfig{Addresses: []string{"10.10.0.0/16"}, Orgs: []string{"org2"}}
+	bz, err := json.Marshal(config)
+	require.NoError(t, err)
+	resp := s.doRpcBlackList(newTx(RpcBlackListSet, &commonPb.KeyValuePair{Key: RpcBlackListParamConfig, Value: bz}))
+	require.Equal(t, commonPb.TxStatusCode_SUCCESS, resp.Code, resp.Message)
+	require.Equal(t, bz, resp.ContractResult.Result)
+	requireCode(t, codes.ResourceExhausted, b.checkIp("10.10.1.1", "test"))
+
+	resp = s.doRpcBlackList(newTx(RpcBlackListGet))
+	require.Equal(t, commonPb.TxStatusCode_SUCCESS, resp.Code, resp.Message)
+	require.Equal(t, bz, resp.ContractResult.Result)
+
+	// the invalid blacklist is rejected and the one in use is kept
+	for _, tx := range []*commonPb.Transaction{
+		newTx(RpcBlackListSet),
+		newTx(RpcBlackListSet, &commonPb.KeyValuePair{Key: RpcBlackListParamConfig, Value: []byte("{")}),
+		newTx(RpcBlackListSet, &commonPb.KeyValuePair{Key: RpcBlackListParamConfig,
+			Value: []byte(`{"addresses":["10.10.0"]}`)}),
+		newTx("RELOAD_BLACKLIST"),
+	} {
+		resp = s.doRpcBlackList(tx)
+		require.Equal(t, commonPb.TxStatusCode_CONTRACT_FAIL, resp.Code)
+	}
+	require.Equal(t, config, b.getConfig())
+}
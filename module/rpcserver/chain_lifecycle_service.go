@@ -0,0 +1,94 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// ChainLifecycle methods, the method of the admin request of AdminMethodChainLifecycle
+const (
+	ChainLifecycleGetStatus = "GET_CHAIN_STATUS"
+	ChainLifecyclePause     = "PAUSE_CHAIN"
+	ChainLifecycleResume    = "RESUME_CHAIN"
+	ChainLifecycleStop      = "STOP_CHAIN"
+	ChainLifecycleRemove    = "REMOVE_CHAIN"
+)
+
+// chainStatusResult the result of the chain lifecycle tx
+type chainStatusResult struct {
+	ChainId string `json:"chain_id"`
+	Status  string `json:"status"`
+}
+
+// ChainLifecycle - pause, resume, stop or remove the chain of the admin request, or get the status of it
+func (s *ApiService) ChainLifecycle(ctx context.Context, req *commonPb.TxRequest) (*commonPb.TxResponse, error) {
+	tx, resp, err := s.verifyAdminRequest(ctx, req, AdminMethodChainLifecycle)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	return s.doChainLifecycle(tx), nil
+}
+
+func (s *ApiService) doChainLifecycle(tx *commonPb.Transaction) *commonPb.TxResponse {
+	resp := &commonPb.TxResponse{TxId: tx.Payload.TxId}
+	chainId := tx.Payload.ChainId
+
+	var err error
+	switch tx.Payload.Method {
+	case ChainLifecycleGetStatus:
+	case ChainLifecyclePause:
+		err = s.chainMakerServer.PauseBlockchain(chainId)
+	case ChainLifecycleResume:
+		err = s.chainMakerServer.ResumeBlockchain(chainId)
+	case ChainLifecycleStop:
+		err = s.chainMakerServer.StopBlockchain(chainId)
+	case ChainLifecycleRemove:
+		if err = s.chainMakerServer.RemoveBlockchain(chainId); err == nil {
+			return s.chainLifecycleResp(resp, &chainStatusResult{ChainId: chainId, Status: "REMOVED"})
+		}
+	default:
+		err = fmt.Errorf("unsupported method [%s] of [%s]", tx.Payload.Method, AdminMethodChainLifecycle)
+	}
+	if err != nil {
+		s.log.Warn(err)
+		resp.Code = commonPb.TxStatusCode_CONTRACT_FAIL
+		resp.Message = err.Error()
+		return resp
+	}
+	s.log.Infof("chain lifecycle tx[%s] of chain[%s] success, method: %s", tx.Payload.TxId, chainId,
+		tx.Payload.Method)
+
+	status, err := s.chainMakerServer.GetBlockchainStatus(chainId)
+	if err != nil {
+		resp.Code = commonPb.TxStatusCode_INTERNAL_ERROR
+		resp.Message = err.Error()
+		return resp
+	}
+	return s.chainLifecycleResp(resp, &chainStatusResult{ChainId: chainId, Status: status.String()})
+}
+
+func (s *ApiService) chainLifecycleResp(resp *commonPb.TxResponse, result *chainStatusResult) *commonPb.TxResponse {
+	bz, err := json.Marshal(result)
+	if err != nil {
+		resp.Code = commonPb.TxStatusCode_INTERNAL_ERROR
+		resp.Message = err.Error()
+		return resp
+	}
+	resp.Code = commonPb.TxStatusCode_SUCCESS
+	resp.Message = commonPb.TxStatusCode_SUCCESS.String()
+	resp.ContractResult = &commonPb.ContractResult{
+		Code:   0,
+		Result: bz,
+	}
+	return resp
+}
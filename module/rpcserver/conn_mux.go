@@ -0,0 +1,144 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// http2Preface the connection preface sent by gRPC clients, see RFC 7540 section 3.5
+	http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+	// sniffTimeout the max time to wait for the first bytes of a connection
+	sniffTimeout = 10 * time.Second
+)
+
+var errConnMuxClosed = errors.New("conn mux is closed")
+
+// connMux shares a plaintext listener between gRPC and the http gateway, the connections starting
+// with the http2 preface are served by gRPC, the others are served by the gateway.
+type connMux struct {
+	root      net.Listener
+	grpcL     *muxListener
+	httpL     *muxListener
+	closeC    chan struct{}
+	closeOnce sync.Once
+}
+
+// muxListener is the listener of one protocol, closing it closes the whole connMux
+type muxListener struct {
+	mux   *connMux
+	connC chan net.Conn
+}
+
+// sniffedConn replays the sniffed bytes before reading the connection
+type sniffedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func newConnMux(root net.Listener) *connMux {
+	m := &connMux{
+		root:   root,
+		closeC: make(chan struct{}),
+	}
+	m.grpcL = &muxListener{mux: m, connC: make(chan net.Conn)}
+	m.httpL = &muxListener{mux: m, connC: make(chan net.Conn)}
+	return m
+}
+
+func (m *connMux) serve() {
+	for {
+		conn, err := m.root.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			_ = m.Close()
+			return
+		}
+		go m.dispatch(conn)
+	}
+}
+
+func (m *connMux) dispatch(conn net.Conn) {
+	target, sniffed, err := m.sniff(conn)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	select {
+	case target.connC <- &sniffedConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(sniffed), conn)}:
+	case <-m.closeC:
+		_ = conn.Close()
+	}
+}
+
+// sniff reads until the bytes differ from the http2 preface or the whole preface is received
+func (m *connMux) sniff(conn net.Conn) (*muxListener, []byte, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(sniffTimeout)); err != nil {
+		return nil, nil, err
+	}
+	buf := make([]byte, len(http2Preface))
+	n := 0
+	for n < len(buf) {
+		read, err := conn.Read(buf[n:])
+		n += read
+		if !bytes.HasPrefix([]byte(http2Preface), buf[:n]) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, nil, err
+	}
+	if n == len(buf) && string(buf) == http2Preface {
+		return m.grpcL, buf, nil
+	}
+	return m.httpL, buf[:n], nil
+}
+
+// Close closes the root listener and both protocol listeners
+func (m *connMux) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.closeC)
+		err = m.root.Close()
+	})
+	return err
+}
+
+func (l *muxListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.connC:
+		return conn, nil
+	case <-l.mux.closeC:
+		return nil, errConnMuxClosed
+	}
+}
+
+func (l *muxListener) Close() error {
+	return l.mux.Close()
+}
+
+func (l *muxListener) Addr() net.Addr {
+	return l.mux.root.Addr()
+}
+
+func (c *sniffedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
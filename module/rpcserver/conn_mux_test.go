@@ -0,0 +1,64 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnMux(t *testing.T) {
+	root, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	mux := newConnMux(root)
+	go mux.serve()
+
+	tests := []struct {
+		name     string
+		writes   []string
+		listener net.Listener
+	}{
+		{"grpc", []string{http2Preface + "frames"}, mux.grpcL},
+		{"grpc in pieces", []string{http2Preface[:5], http2Preface[5:] + "frames"}, mux.grpcL},
+		{"http", []string{"GET /v1/getversion HTTP/1.1\r\n\r\n"}, mux.httpL},
+		// the bytes are the prefix of the preface at first, then differ from it
+		{"http like preface", []string{"PRI * HTTP/1.1\r\n\r\n"}, mux.httpL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := net.Dial("tcp", root.Addr().String())
+			require.NoError(t, err)
+			defer client.Close()
+			sent := ""
+			for _, data := range tt.writes {
+				_, err = client.Write([]byte(data))
+				require.NoError(t, err)
+				sent += data
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			conn, err := tt.listener.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+			// the sniffed bytes are replayed to the server
+			received := make([]byte, len(sent))
+			_, err = io.ReadFull(conn, received)
+			require.NoError(t, err)
+			require.Equal(t, sent, string(received))
+		})
+	}
+
+	require.NoError(t, mux.grpcL.Close())
+	_, err = mux.httpL.Accept()
+	require.Equal(t, errConnMuxClosed, err)
+	_, err = mux.grpcL.Accept()
+	require.Equal(t, errConnMuxClosed, err)
+}
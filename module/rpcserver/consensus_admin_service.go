@@ -0,0 +1,67 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+const (
+	// ConsensusAdmin methods, the method of the admin request of AdminMethodConsensusAdmin
+	ConsensusAdminTransferLeadership = "TRANSFER_LEADERSHIP"
+
+	// ConsensusAdminNodeIdParam the node id of the transferee, optional for the leader
+	ConsensusAdminNodeIdParam = "node_id"
+)
+
+// ConsensusAdmin - transfer the leadership of the consensus, e.g. to drain the leader before maintenance
+func (s *ApiService) ConsensusAdmin(ctx context.Context, req *commonPb.TxRequest) (*commonPb.TxResponse, error) {
+	tx, resp, err := s.verifyAdminRequest(ctx, req, AdminMethodConsensusAdmin)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	return s.doConsensusAdmin(tx), nil
+}
+
+func (s *ApiService) doConsensusAdmin(tx *commonPb.Transaction) *commonPb.TxResponse {
+	chain, err := s.chainMakerServer.GetBlockchain(tx.Payload.ChainId)
+	if err != nil {
+		s.log.Error(err)
+		return s.adminResp(tx, commonPb.TxStatusCode_INTERNAL_ERROR, err, nil)
+	}
+
+	var leader string
+	switch tx.Payload.Method {
+	case ConsensusAdminTransferLeadership:
+		var nodeId string
+		for _, kv := range tx.Payload.Parameters {
+			if kv.Key == ConsensusAdminNodeIdParam {
+				nodeId = string(kv.Value)
+			}
+		}
+		if leader, err = chain.TransferConsensusLeadership(nodeId); err == nil {
+			s.log.Infof("consensus leadership is transferred to [%s] by tx[%s]", leader, tx.Payload.TxId)
+		}
+	default:
+		err = fmt.Errorf("unsupported method [%s] of [%s]", tx.Payload.Method, AdminMethodConsensusAdmin)
+	}
+	if err != nil {
+		s.log.Warn(err)
+		return s.adminResp(tx, commonPb.TxStatusCode_CONTRACT_FAIL, err, nil)
+	}
+
+	bz, err := json.Marshal(map[string]string{"leader": leader})
+	if err != nil {
+		return s.adminResp(tx, commonPb.TxStatusCode_INTERNAL_ERROR, err, nil)
+	}
+	return s.adminResp(tx, commonPb.TxStatusCode_SUCCESS, nil, bz)
+}
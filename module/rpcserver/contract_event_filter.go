@@ -0,0 +1,161 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// The parameters of the contract event subscription besides TOPIC and CONTRACT_NAME of syscontract,
+// START_BLOCK and END_BLOCK are encoded like those of the tx subscription, -1 or absent means not set.
+// TOPIC and CONTRACT_NAME accept a comma separated list, or the wildcard.
+const (
+	SubscribeContractEventStartBlock = "START_BLOCK"
+	SubscribeContractEventEndBlock   = "END_BLOCK"
+	// SubscribeContractEventFilters a json array of ContractEventDataFilter, all of them must be matched
+	SubscribeContractEventFilters = "FILTERS"
+	// SubscribeContractEventWildcard matches all topics or all contracts
+	SubscribeContractEventWildcard = "*"
+)
+
+// The operators of ContractEventDataFilter
+const (
+	EventDataFilterOpEq       = "eq"
+	EventDataFilterOpNe       = "ne"
+	EventDataFilterOpPrefix   = "prefix"
+	EventDataFilterOpContains = "contains"
+)
+
+// ContractEventDataFilter a filter on a field of the event data, the field is the element of EventData at Index
+type ContractEventDataFilter struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"` // eq if it is empty
+	Value string `json:"value"`
+}
+
+func (f *ContractEventDataFilter) match(eventData []string) bool {
+	if f.Index >= len(eventData) {
+		return false
+	}
+	data := eventData[f.Index]
+	switch f.Op {
+	case EventDataFilterOpNe:
+		return data != f.Value
+	case EventDataFilterOpPrefix:
+		return strings.HasPrefix(data, f.Value)
+	case EventDataFilterOpContains:
+		return strings.Contains(data, f.Value)
+	default:
+		return data == f.Value
+	}
+}
+
+// contractEventFilter selects the contract events of a subscription
+type contractEventFilter struct {
+	contractNames map[string]struct{} // nil means all contracts
+	topics        map[string]struct{} // nil means all topics
+	dataFilters   []*ContractEventDataFilter
+}
+
+func newContractEventFilter(contractNames, topics string, dataFilters []byte) (*contractEventFilter, error) {
+	var err error
+	filter := &contractEventFilter{}
+	if filter.contractNames, err = parseEventNameList(contractNames); err != nil {
+		return nil, fmt.Errorf("invalid contract name, %s", err)
+	}
+	if filter.topics, err = parseEventNameList(topics); err != nil {
+		return nil, fmt.Errorf("invalid topic, %s", err)
+	}
+	if len(dataFilters) == 0 {
+		return filter, nil
+	}
+	if err = json.Unmarshal(dataFilters, &filter.dataFilters); err != nil {
+		return nil, fmt.Errorf("invalid filters, %s", err)
+	}
+	for _, f := range filter.dataFilters {
+		if f == nil || f.Index < 0 {
+			return nil, errors.New("invalid filters, index must not be negative")
+		}
+		switch f.Op {
+		case "", EventDataFilterOpEq, EventDataFilterOpNe, EventDataFilterOpPrefix, EventDataFilterOpContains:
+		default:
+			return nil, fmt.Errorf("invalid filters, unknown op %s", f.Op)
+		}
+	}
+	return filter, nil
+}
+
+// parseEventNameList parses a comma separated list of names, it returns nil for the wildcard
+func parseEventNameList(list string) (map[string]struct{}, error) {
+	names := make(map[string]struct{})
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == SubscribeContractEventWildcard {
+			return nil, nil
+		}
+		if name != "" {
+			names[name] = struct{}{}
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("empty")
+	}
+	return names, nil
+}
+
+func (f *contractEventFilter) match(event *commonPb.ContractEvent) bool {
+	if f.contractNames != nil {
+		if _, ok := f.contractNames[event.ContractName]; !ok {
+			return false
+		}
+	}
+	if f.topics != nil {
+		if _, ok := f.topics[event.Topic]; !ok {
+			return false
+		}
+	}
+	for _, dataFilter := range f.dataFilters {
+		if !dataFilter.match(event.EventData) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterBlock returns the matched contract events of the txs in the block in the order of the txs, except those
+// skipped, and the position of the last one returned
+func (f *contractEventFilter) filterBlock(block *commonPb.Block, skip func(txIndex, eventIndex int) bool) (
+	eventInfoList *commonPb.ContractEventInfoList, lastTxIndex, lastEventIndex int) {
+	eventInfoList = &commonPb.ContractEventInfoList{}
+	lastTxIndex, lastEventIndex = -1, -1
+	for i, tx := range block.Txs {
+		if tx.Result == nil || tx.Result.ContractResult == nil {
+			continue
+		}
+		for j, event := range tx.Result.ContractResult.ContractEvent {
+			if !f.match(event) || skip(i, j) {
+				continue
+			}
+			lastTxIndex, lastEventIndex = i, j
+			eventInfoList.ContractEvents = append(eventInfoList.ContractEvents, &commonPb.ContractEventInfo{
+				BlockHeight:     block.Header.BlockHeight,
+				ChainId:         block.Header.ChainId,
+				Topic:           event.Topic,
+				TxId:            event.TxId,
+				ContractName:    event.ContractName,
+				ContractVersion: event.ContractVersion,
+				EventData:       event.EventData,
+			})
+		}
+	}
+	return eventInfoList, lastTxIndex, lastEventIndex
+}
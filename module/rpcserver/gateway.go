@@ -0,0 +1,404 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"chainmaker.org/chainmaker-go/blockchain"
+	"chainmaker.org/chainmaker/localconf/v2"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	configPb "chainmaker.org/chainmaker/pb-go/v2/config"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The REST endpoints of the gateway, the bodies are the json form of the gRPC messages
+const (
+	GatewaySendRequestPath = "/v1/sendrequest"
+	GatewaySubscribePath   = "/v1/subscribe"
+	GatewayGetVersionPath  = "/v1/getversion"
+
+	// gatewayServiceName the service label of gateway requests in monitor
+	gatewayServiceName = "gateway"
+
+	// sseHeartbeatInterval the interval of keep alive comments in a subscription stream
+	sseHeartbeatInterval = 15 * time.Second
+)
+
+// GatewayConfig the config of http gateway, rpc.gateway in chainmaker.yml
+type GatewayConfig struct {
+	// Enabled gateway switch, default is false
+	Enabled bool `mapstructure:"enabled"`
+	// Port the port of gateway, 0 means sharing the port of gRPC, which is only supported if tls is disabled
+	Port int `mapstructure:"port"`
+}
+
+// gateway serves SendRequest, Subscribe and GetChainMakerVersion of RpcNode as REST endpoints,
+// behind the same blacklist, rate limit buckets and tls settings as the gRPC server.
+type gateway struct {
+	apiService *ApiService
+	httpServer *http.Server
+	blackList  *blackList
+	bucketMap  *sync.Map
+	marshaler  *jsonpb.Marshaler
+}
+
+func newGateway(apiService *ApiService, blackList *blackList, bucketMap *sync.Map) *gateway {
+	g := &gateway{
+		apiService: apiService,
+		blackList:  blackList,
+		bucketMap:  bucketMap,
+		marshaler:  &jsonpb.Marshaler{EmitDefaults: true, OrigName: true},
+	}
+	mux := http.NewServeMux()
+	mux.Handle(GatewaySendRequestPath, g.intercept(http.MethodPost, g.sendRequest))
+	mux.Handle(GatewaySubscribePath, g.intercept(http.MethodPost, g.subscribe))
+	mux.Handle(GatewayGetVersionPath, g.intercept(http.MethodGet, g.getVersion))
+	g.httpServer = &http.Server{Handler: mux}
+	return g
+}
+
+func (g *gateway) serve(listener net.Listener) {
+	go func() {
+		if err := g.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed &&
+			err != errConnMuxClosed {
+			log.Errorf("gateway Serve failed, %s", err.Error())
+		}
+	}()
+}
+
+func (g *gateway) stop() {
+	if err := g.httpServer.Close(); err != nil {
+		log.Warnf("close gateway failed, %s", err.Error())
+	}
+}
+
+// intercept applies what the gRPC interceptors do to a gateway handler, the handler gets the
+// context with the peer of the client.
+func (g *gateway) intercept(method string,
+	handler func(ctx context.Context, w http.ResponseWriter, r *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if e := recover(); e != nil {
+				log.Errorf("panic stack: %s", string(debug.Stack()))
+				g.writeError(w, status.Errorf(codes.Internal, "Panic err: %v", e))
+			}
+		}()
+
+		ctx := newGatewayContext(r)
+		log.Debugf("[%s] call gateway: %s %s", GetClientAddr(ctx), r.Method, r.URL.Path)
+		if localconf.ChainMakerConfig.MonitorConfig.Enabled && mRecv != nil {
+			mRecv.WithLabelValues(gatewayServiceName, r.URL.Path).Inc()
+		}
+
+		if r.Method != method {
+			g.writeError(w, status.Errorf(codes.Unimplemented, "%s only supports %s", r.URL.Path, method))
+			return
+		}
+
+		ipAddr := getClientIp(ctx)
+		if err := g.blackList.checkIp(ipAddr, r.URL.Path); err != nil {
+			g.writeError(w, err)
+			return
+		}
+
+		rateLimitConfig := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig
+		if rateLimitConfig.Enabled {
+			if err := allowByRateLimit(g.bucketMap, rateLimitConfig.TokenBucketSize,
+				rateLimitConfig.TokenPerSecond, ipAddr, r.URL.Path); err != nil {
+				g.writeError(w, err)
+				return
+			}
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRecvMessageSize)
+		handler(ctx, w, r)
+	})
+}
+
+func (g *gateway) sendRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	req := &commonPb.TxRequest{}
+	if err := g.readBody(r, req); err != nil {
+		g.writeError(w, err)
+		return
+	}
+	if req.Payload == nil {
+		g.writeError(w, status.Error(codes.InvalidArgument, "payload is empty"))
+		return
+	}
+	resp, err := g.apiService.SendRequest(ctx, req)
+	if err != nil {
+		g.writeError(w, err)
+		return
+	}
+	g.writeMessage(w, resp)
+}
+
+func (g *gateway) getVersion(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	resp, err := g.apiService.GetChainMakerVersion(ctx, &configPb.ChainMakerVersionRequest{})
+	if err != nil {
+		g.writeError(w, err)
+		return
+	}
+	g.writeMessage(w, resp)
+}
+
+// subscribe serves the subscription as Server-Sent Events, each result is a "result" event, the
+// stream ends with an "end" event, or an "error" event if it fails after the first result.
+func (g *gateway) subscribe(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	req := &commonPb.TxRequest{}
+	if err := g.readBody(r, req); err != nil {
+		g.writeError(w, err)
+		return
+	}
+	if req.Payload == nil {
+		g.writeError(w, status.Error(codes.InvalidArgument, "payload is empty"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		g.writeError(w, status.Error(codes.Unimplemented, "streaming is not supported by the connection"))
+		return
+	}
+
+	stream := &sseSubscribeServer{
+		ctx:       ctx,
+		w:         w,
+		flusher:   flusher,
+		marshaler: g.marshaler,
+	}
+	heartbeatC := make(chan struct{})
+	defer close(heartbeatC)
+	go stream.heartbeat(heartbeatC)
+
+	err := g.apiService.Subscribe(req, stream)
+	if err != nil && !stream.isStarted() {
+		// nothing is sent yet, so the failure is reported by the http status
+		g.writeError(w, err)
+		return
+	}
+	stream.start()
+	if err != nil {
+		st, _ := status.FromError(err)
+		stream.writeEvent("error", fmt.Sprintf(`{"code":%d,"message":%q}`, st.Code(), st.Message()))
+		return
+	}
+	stream.writeEvent("end", "{}")
+}
+
+func (g *gateway) readBody(r *http.Request, msg proto.Message) error {
+	unmarshaler := &jsonpb.Unmarshaler{}
+	if err := unmarshaler.Unmarshal(r.Body, msg); err != nil && err != io.EOF {
+		return status.Errorf(codes.InvalidArgument, "invalid request body, %s", err)
+	}
+	return nil
+}
+
+func (g *gateway) writeMessage(w http.ResponseWriter, msg proto.Message) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := g.marshaler.Marshal(w, msg); err != nil {
+		log.Warnf("write gateway response failed, %s", err.Error())
+	}
+}
+
+// writeError writes the error in the form of {"code": <gRPC code>, "message": <message>}
+func (g *gateway) writeError(w http.ResponseWriter, err error) {
+	st, _ := status.FromError(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(st.Code()))
+	_, _ = fmt.Fprintf(w, `{"code":%d,"message":%q}`, st.Code(), st.Message())
+}
+
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// newGatewayContext sets the client address and tls state of the request as the gRPC peer, so
+// that the ApiService sees the gateway clients like the gRPC clients
+func newGatewayContext(r *http.Request) context.Context {
+	p := &peer.Peer{}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(r.Context(), p)
+}
+
+// newGatewayTLSConfig creates the tls config of gateway from the rpc tls settings, the trust roots
+// of all chains are the client CAs. Only the standard (non GM) certificates are supported.
+func newGatewayTLSConfig(chainMakerServer *blockchain.ChainMakerServer) (*tls.Config, error) {
+	tlsConfig := localconf.ChainMakerConfig.RpcConfig.TLSConfig
+	cert, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.PrivKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load gateway tls cert failed, %s", err)
+	}
+
+	chainConfs, err := chainMakerServer.GetAllChainConf()
+	if err != nil {
+		return nil, fmt.Errorf("get all chain conf failed, %s", err)
+	}
+	caPool := x509.NewCertPool()
+	for _, chainConf := range chainConfs {
+		for _, orgRoot := range chainConf.ChainConfig().TrustRoots {
+			for _, root := range orgRoot.Root {
+				caPool.AppendCertsFromPEM([]byte(root))
+			}
+		}
+	}
+
+	acs, err := chainMakerServer.GetAllAC()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates:          []tls.Certificate{cert},
+		ClientCAs:             caPool,
+		ClientAuth:            tls.NoClientCert,
+		VerifyPeerCertificate: createVerifyPeerCertificateFunc(acs),
+		MinVersion:            tls.VersionTLS12,
+	}
+	if tlsConfig.Mode == TLS_MODE_TWOWAY {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// sseSubscribeServer implements RpcNode_SubscribeServer with a Server-Sent Events response
+type sseSubscribeServer struct {
+	ctx       context.Context
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	marshaler *jsonpb.Marshaler
+	mu        sync.Mutex
+	started   bool
+}
+
+func (s *sseSubscribeServer) isStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// start writes the header of event stream if it is not written
+func (s *sseSubscribeServer) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+func (s *sseSubscribeServer) writeEvent(event, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data)
+	s.flusher.Flush()
+}
+
+func (s *sseSubscribeServer) heartbeat(doneC chan struct{}) {
+	ticker := time.NewTicker(sseHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.started {
+				_, _ = io.WriteString(s.w, ": ping\n\n")
+				s.flusher.Flush()
+			}
+			s.mu.Unlock()
+		case <-doneC:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *sseSubscribeServer) Send(result *commonPb.SubscribeResult) error {
+	data, err := s.marshaler.MarshalToString(result)
+	if err != nil {
+		return err
+	}
+	if err = s.ctx.Err(); err != nil {
+		return err
+	}
+	s.start()
+	s.writeEvent("result", data)
+	return nil
+}
+
+func (s *sseSubscribeServer) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *sseSubscribeServer) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *sseSubscribeServer) SetTrailer(metadata.MD) {
+}
+
+func (s *sseSubscribeServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sseSubscribeServer) SendMsg(m interface{}) error {
+	result, ok := m.(*commonPb.SubscribeResult)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", m)
+	}
+	return s.Send(result)
+}
+
+func (s *sseSubscribeServer) RecvMsg(interface{}) error {
+	return io.EOF
+}
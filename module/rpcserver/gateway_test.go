@@ -0,0 +1,101 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"chainmaker.org/chainmaker/localconf/v2"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHttpStatusFromCode(t *testing.T) {
+	for code, httpStatus := range map[codes.Code]int{
+		codes.OK:                http.StatusOK,
+		codes.InvalidArgument:   http.StatusBadRequest,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.PermissionDenied:  http.StatusForbidden,
+		codes.NotFound:          http.StatusNotFound,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.Unimplemented:     http.StatusNotImplemented,
+		codes.Unavailable:       http.StatusServiceUnavailable,
+		codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+		codes.Internal:          http.StatusInternalServerError,
+		codes.Unknown:           http.StatusInternalServerError,
+	} {
+		require.Equal(t, httpStatus, httpStatusFromCode(code), code.String())
+	}
+
+	g := &gateway{}
+	w := httptest.NewRecorder()
+	g.writeError(w, status.Error(codes.PermissionDenied, "denied"))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Equal(t, `{"code":7,"message":"denied"}`, w.Body.String())
+}
+
+func TestSseSubscribeServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	stream := &sseSubscribeServer{
+		ctx:       ctx,
+		w:         w,
+		flusher:   w,
+		marshaler: &jsonpb.Marshaler{OrigName: true},
+	}
+	require.False(t, stream.isStarted())
+
+	require.NoError(t, stream.Send(&commonPb.SubscribeResult{Data: []byte("block")}))
+	require.True(t, stream.isStarted())
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	require.Equal(t, "event: result\ndata: {\"data\":\"YmxvY2s=\"}\n\n", w.Body.String())
+
+	// the results are not sent after the client is gone
+	cancel()
+	require.Error(t, stream.Send(&commonPb.SubscribeResult{Data: []byte("block")}))
+	require.Equal(t, 1, strings.Count(w.Body.String(), "event: result"))
+}
+
+func TestGatewaySharesRateLimitWithGrpc(t *testing.T) {
+	rateLimitConfig := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig
+	defer func() {
+		localconf.ChainMakerConfig.RpcConfig.RateLimitConfig = rateLimitConfig
+	}()
+	localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.Enabled = true
+	localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.Type = rateLimitTypeGlobal
+	localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.TokenBucketSize = 1
+	localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.TokenPerSecond = 1
+
+	blackList, err := newBlackList(&BlackListConfig{})
+	require.NoError(t, err)
+	buckets := &sync.Map{}
+	g := newGateway(nil, blackList, buckets)
+
+	// the only token is taken by the gRPC request, so the gateway request is rejected
+	_, err = RateLimitInterceptor(buckets)(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "test"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+	require.NoError(t, err)
+
+	handler := g.intercept(http.MethodGet, func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, GatewayGetVersionPath, nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The kinds of quota, each member has a separate budget of each kind
+const (
+	QuotaKindQuery     = "query"
+	QuotaKindInvoke    = "invoke"
+	QuotaKindSubscribe = "subscribe"
+)
+
+const (
+	// identityBucketIdleTimeout the bucket of a member is removed if it is not used for the duration
+	identityBucketIdleTimeout = 10 * time.Minute
+	// identityBucketCleanInterval the number of new buckets between the cleanings of idle buckets
+	identityBucketCleanInterval = 1024
+)
+
+// IdentityRateLimitConfig the rate limit by the signer of requests, rpc.identity_ratelimit in chainmaker.yml
+type IdentityRateLimitConfig struct {
+	// Enabled identity rate limit switch, default is false
+	Enabled bool `mapstructure:"enabled"`
+	// Default the quotas of each member, used by the orgs and kinds not set in Orgs
+	Default RateLimitQuotas `mapstructure:"default"`
+	// Orgs the quotas of each member of the orgs
+	Orgs []OrgRateLimitQuotas `mapstructure:"orgs"`
+}
+
+// OrgRateLimitQuotas the quotas of each member of an org
+type OrgRateLimitQuotas struct {
+	OrgId           string `mapstructure:"org_id"`
+	RateLimitQuotas `mapstructure:",squash"`
+}
+
+// RateLimitQuotas the quotas of query, invoke and subscribe
+type RateLimitQuotas struct {
+	Query     Quota `mapstructure:"query"`
+	Invoke    Quota `mapstructure:"invoke"`
+	Subscribe Quota `mapstructure:"subscribe"`
+}
+
+// Quota a token bucket. In Default, token_per_second 0 or -1 means unlimited; in Orgs, 0 means
+// using the quota in Default and -1 means unlimited. token_bucket_size 0 means token_per_second.
+type Quota struct {
+	TokenPerSecond  int `mapstructure:"token_per_second"`
+	TokenBucketSize int `mapstructure:"token_bucket_size"`
+}
+
+func (q RateLimitQuotas) get(kind string) Quota {
+	switch kind {
+	case QuotaKindQuery:
+		return q.Query
+	case QuotaKindInvoke:
+		return q.Invoke
+	default:
+		return q.Subscribe
+	}
+}
+
+type identityBucket struct {
+	limiter  *rate.Limiter
+	lastUsed int64 // unix nano, accessed atomically
+}
+
+// identityRateLimiter limits the requests of each member with the quota of its org. It is applied
+// after the signature of request is verified, so that nobody can spend the quota of others.
+// The lookups do not take any lock, the config and buckets are replaced together by setConfig.
+type identityRateLimiter struct {
+	state atomic.Value // *identityRateLimitState
+}
+
+// identityRateLimitState the config and the buckets created with it
+type identityRateLimitState struct {
+	newBuckets int64 // accessed atomically, the first field to be 64-bit aligned
+	config     *IdentityRateLimitConfig
+	orgQuotas  map[string]RateLimitQuotas
+	buckets    sync.Map // map[string]*identityBucket
+}
+
+func newIdentityRateLimiter() *identityRateLimiter {
+	l := &identityRateLimiter{}
+	l.setConfig(&IdentityRateLimitConfig{})
+	return l
+}
+
+// setConfig replaces the config, the buckets are reset
+func (l *identityRateLimiter) setConfig(config *IdentityRateLimitConfig) {
+	orgQuotas := make(map[string]RateLimitQuotas, len(config.Orgs))
+	for _, org := range config.Orgs {
+		orgQuotas[org.OrgId] = org.RateLimitQuotas
+	}
+	l.state.Store(&identityRateLimitState{
+		config:    config,
+		orgQuotas: orgQuotas,
+	})
+}
+
+// allow returns ResourceExhausted naming the quota if the sender runs out of the quota of kind
+func (l *identityRateLimiter) allow(sender *commonPb.EndorsementEntry, kind string) error {
+	if l == nil || sender == nil || sender.Signer == nil {
+		return nil
+	}
+	state := l.state.Load().(*identityRateLimitState)
+	if !state.config.Enabled {
+		return nil
+	}
+
+	orgId := sender.Signer.OrgId
+	quota, quotaName := state.getQuota(orgId, kind)
+	if quota.TokenPerSecond <= 0 {
+		return nil
+	}
+	memberInfoHash := sha256.Sum256(sender.Signer.MemberInfo)
+	member := hex.EncodeToString(memberInfoHash[:])
+	key := fmt.Sprintf("%s/%s/%d/%s", kind, orgId, sender.Signer.MemberType, member)
+
+	now := time.Now()
+	value, ok := state.buckets.Load(key)
+	if !ok {
+		bucketSize := quota.TokenBucketSize
+		if bucketSize <= 0 {
+			bucketSize = quota.TokenPerSecond
+		}
+		value, ok = state.buckets.LoadOrStore(key,
+			&identityBucket{limiter: rate.NewLimiter(rate.Limit(quota.TokenPerSecond), bucketSize)})
+		if !ok {
+			state.cleanIfRequire(now)
+		}
+	}
+	bucket := value.(*identityBucket)
+	atomic.StoreInt64(&bucket.lastUsed, now.UnixNano())
+	if !bucket.limiter.AllowN(now, 1) {
+		errMsg := fmt.Sprintf("%s request of member [%s] of org [%s] is rejected by ratelimit quota [%s], "+
+			"try later pls", kind, member[:8], orgId, quotaName)
+		log.Warn(errMsg)
+		return status.Error(codes.ResourceExhausted, errMsg)
+	}
+	return nil
+}
+
+// getQuota returns the quota and its name in config
+func (s *identityRateLimitState) getQuota(orgId, kind string) (Quota, string) {
+	if quotas, ok := s.orgQuotas[orgId]; ok {
+		if quota := quotas.get(kind); quota.TokenPerSecond != 0 {
+			return quota, fmt.Sprintf("identity_ratelimit.orgs[%s].%s", orgId, kind)
+		}
+	}
+	return s.config.Default.get(kind), "identity_ratelimit.default." + kind
+}
+
+// cleanIfRequire removes the idle buckets after every identityBucketCleanInterval new buckets
+func (s *identityRateLimitState) cleanIfRequire(now time.Time) {
+	if atomic.AddInt64(&s.newBuckets, 1)%identityBucketCleanInterval != 0 {
+		return
+	}
+	s.buckets.Range(func(key, value interface{}) bool {
+		if now.Sub(time.Unix(0, atomic.LoadInt64(&value.(*identityBucket).lastUsed))) > identityBucketIdleTimeout {
+			s.buckets.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,105 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"sync"
+	"testing"
+
+	pbac "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSender(orgId, member string) *commonPb.EndorsementEntry {
+	return &commonPb.EndorsementEntry{Signer: &pbac.Member{OrgId: orgId, MemberInfo: []byte(member)}}
+}
+
+// allowed returns the number of requests allowed in n requests
+func allowed(l *identityRateLimiter, sender *commonPb.EndorsementEntry, kind string, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if l.allow(sender, kind) == nil {
+			count++
+		}
+	}
+	return count
+}
+
+func TestIdentityRateLimiter_OrgQuotas(t *testing.T) {
+	l := newIdentityRateLimiter()
+	// disabled by default
+	require.Equal(t, 10, allowed(l, newTestSender("org1", "user1"), QuotaKindInvoke, 10))
+
+	l.setConfig(&IdentityRateLimitConfig{
+		Enabled: true,
+		Default: RateLimitQuotas{
+			Query:  Quota{TokenPerSecond: 1, TokenBucketSize: 3},
+			Invoke: Quota{TokenPerSecond: 1, TokenBucketSize: 2},
+		},
+		Orgs: []OrgRateLimitQuotas{
+			{OrgId: "org2", RateLimitQuotas: RateLimitQuotas{
+				Query:  Quota{TokenPerSecond: -1},
+				Invoke: Quota{TokenPerSecond: 1, TokenBucketSize: 5},
+			}},
+		},
+	})
+
+	// the default quotas
+	require.Equal(t, 3, allowed(l, newTestSender("org1", "user1"), QuotaKindQuery, 10))
+	require.Equal(t, 2, allowed(l, newTestSender("org1", "user1"), QuotaKindInvoke, 10))
+	// the unset quota is unlimited
+	require.Equal(t, 10, allowed(l, newTestSender("org1", "user1"), QuotaKindSubscribe, 10))
+
+	// the quotas of org2, -1 is unlimited
+	require.Equal(t, 10, allowed(l, newTestSender("org2", "user1"), QuotaKindQuery, 10))
+	require.Equal(t, 5, allowed(l, newTestSender("org2", "user1"), QuotaKindInvoke, 10))
+
+	// the quota name is in the error
+	err := l.allow(newTestSender("org2", "user1"), QuotaKindInvoke)
+	require.Contains(t, err.Error(), "identity_ratelimit.orgs[org2].invoke")
+
+	// the buckets are reset with the config
+	l.setConfig(&IdentityRateLimitConfig{Enabled: true, Default: RateLimitQuotas{
+		Invoke: Quota{TokenPerSecond: 1, TokenBucketSize: 1}}})
+	require.Equal(t, 1, allowed(l, newTestSender("org2", "user1"), QuotaKindInvoke, 10))
+}
+
+func TestIdentityRateLimiter_MemberBuckets(t *testing.T) {
+	l := newIdentityRateLimiter()
+	l.setConfig(&IdentityRateLimitConfig{
+		Enabled: true,
+		Default: RateLimitQuotas{Invoke: Quota{TokenPerSecond: 1, TokenBucketSize: 2}},
+	})
+
+	// each member has its own bucket, the members of the same name in different orgs are different
+	require.Equal(t, 2, allowed(l, newTestSender("org1", "user1"), QuotaKindInvoke, 10))
+	require.Equal(t, 2, allowed(l, newTestSender("org1", "user2"), QuotaKindInvoke, 10))
+	require.Equal(t, 2, allowed(l, newTestSender("org2", "user1"), QuotaKindInvoke, 10))
+	require.Equal(t, 0, allowed(l, newTestSender("org1", "user1"), QuotaKindInvoke, 10))
+
+	// the requests without signer are not limited here
+	require.NoError(t, l.allow(nil, QuotaKindInvoke))
+	require.NoError(t, l.allow(&commonPb.EndorsementEntry{}, QuotaKindInvoke))
+
+	// the concurrent requests of a member share the bucket
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := allowed(l, newTestSender("org3", "user1"), QuotaKindInvoke, 10)
+			mu.Lock()
+			total += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 2, total)
+}
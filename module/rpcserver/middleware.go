@@ -154,25 +154,20 @@ func getRateLimitBucket(bucketMap *sync.Map, tokenBucketSize, tokenPerSecond int
 	return bucket.(*rate.Limiter)
 }
 
-// RateLimitInterceptor - set ratelimit interceptor
-func RateLimitInterceptor() grpc.UnaryServerInterceptor {
+// RateLimitInterceptor - set ratelimit interceptor, the buckets are shared with the stream interceptor and gateway
+func RateLimitInterceptor(bucketMap *sync.Map) grpc.UnaryServerInterceptor {
 
 	tokenBucketSize := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.TokenBucketSize
 	tokenPerSecond := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.TokenPerSecond
 	enabled := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.Enabled
 
-	bucketMap := sync.Map{}
-
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
 		interface{}, error) {
 
 		if enabled {
-			ipAddr := getClientIp(ctx)
-			bucket := getRateLimitBucket(&bucketMap, tokenBucketSize, tokenPerSecond, ipAddr)
-			if bucket != nil && !bucket.Allow() {
-				errMsg := fmt.Sprintf("%s is rejected by ratelimit, try later pls", info.FullMethod)
-				log.Warn(errMsg)
-				return nil, status.Error(codes.ResourceExhausted, errMsg)
+			if err := allowByRateLimit(bucketMap, tokenBucketSize, tokenPerSecond, getClientIp(ctx),
+				info.FullMethod); err != nil {
+				return nil, err
 			}
 		}
 
@@ -180,41 +175,62 @@ func RateLimitInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
-// BlackListInterceptor - set ip blacklist interceptor
-func BlackListInterceptor() grpc.UnaryServerInterceptor {
+// RateLimitStreamInterceptor - set ratelimit interceptor of stream, each stream takes a token
+func RateLimitStreamInterceptor(bucketMap *sync.Map) grpc.StreamServerInterceptor {
+
+	tokenBucketSize := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.TokenBucketSize
+	tokenPerSecond := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.TokenPerSecond
+	enabled := localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.Enabled
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if enabled {
+			if err := allowByRateLimit(bucketMap, tokenBucketSize, tokenPerSecond, getClientIp(ss.Context()),
+				info.FullMethod); err != nil {
+				return err
+			}
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// allowByRateLimit returns ResourceExhausted naming the bucket if there is no token
+func allowByRateLimit(bucketMap *sync.Map, tokenBucketSize, tokenPerSecond int, ipAddr, method string) error {
+	bucket := getRateLimitBucket(bucketMap, tokenBucketSize, tokenPerSecond, ipAddr)
+	if bucket != nil && !bucket.Allow() {
+		quotaName := "global"
+		if localconf.ChainMakerConfig.RpcConfig.RateLimitConfig.Type != rateLimitTypeGlobal {
+			quotaName = "ip " + ipAddr
+		}
+		errMsg := fmt.Sprintf("%s is rejected by ratelimit quota [%s], try later pls", method, quotaName)
+		log.Warn(errMsg)
+		return status.Error(codes.ResourceExhausted, errMsg)
+	}
+	return nil
+}
 
-	blackIps := localconf.ChainMakerConfig.RpcConfig.BlackList.Addresses
+// BlackListInterceptor - set ip blacklist interceptor, the blacklist can be reloaded at runtime
+func BlackListInterceptor(blackList *blackList) grpc.UnaryServerInterceptor {
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (
 		interface{}, error) {
 
-		ipAddr := getClientIp(ctx)
-		for _, blackIp := range blackIps {
-			if ipAddr == blackIp {
-				errMsg := fmt.Sprintf("%s is rejected by black list [%s]", info.FullMethod, ipAddr)
-				log.Warn(errMsg)
-				return nil, status.Error(codes.ResourceExhausted, errMsg)
-			}
+		if err := blackList.checkIp(getClientIp(ctx), info.FullMethod); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
 	}
 }
 
-// BlackListStreamInterceptor - set ip blacklist interceptor
-func BlackListStreamInterceptor() grpc.StreamServerInterceptor {
-
-	blackIps := localconf.ChainMakerConfig.RpcConfig.BlackList.Addresses
+// BlackListStreamInterceptor - set ip blacklist interceptor, the blacklist can be reloaded at runtime
+func BlackListStreamInterceptor(blackList *blackList) grpc.StreamServerInterceptor {
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 
-		ipAddr := getClientIp(ss.Context())
-		for _, blackIp := range blackIps {
-			if ipAddr == blackIp {
-				errMsg := fmt.Sprintf("%s is rejected by black list [%s]", info.FullMethod, ipAddr)
-				log.Warn(errMsg)
-				return status.Error(codes.ResourceExhausted, errMsg)
-			}
+		if err := blackList.checkIp(getClientIp(ss.Context()), info.FullMethod); err != nil {
+			return err
 		}
 
 		return handler(srv, ss)
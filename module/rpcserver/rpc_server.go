@@ -9,12 +9,14 @@ package rpcserver
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"chainmaker.org/chainmaker-go/blockchain"
@@ -40,6 +42,12 @@ type RPCServer struct {
 	cancel                     context.CancelFunc
 	curChainConfTrustRootsHash string
 	isShutdown                 bool
+	apiService                 *ApiService
+	identityRateLimiter        *identityRateLimiter
+	blackList                  *blackList
+	rateLimitBuckets           *sync.Map // The ratelimit buckets shared by gRPC and gateway
+	gatewayConfig              GatewayConfig
+	gateway                    *gateway
 }
 
 // prom monitor define
@@ -71,7 +79,15 @@ const (
 // NewRPCServer - new RPCServer object
 func NewRPCServer(chainMakerServer *blockchain.ChainMakerServer) (*RPCServer, error) {
 
-	server, err := newGrpc(chainMakerServer)
+	blackList, err := newBlackList(&BlackListConfig{
+		Addresses: localconf.ChainMakerConfig.RpcConfig.BlackList.Addresses,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid rpc blacklist, %s", err.Error())
+	}
+
+	rateLimitBuckets := &sync.Map{}
+	server, err := newGrpc(chainMakerServer, blackList, rateLimitBuckets)
 	if err != nil {
 		return nil, fmt.Errorf("new grpc server failed, %s", err.Error())
 	}
@@ -87,9 +103,12 @@ func NewRPCServer(chainMakerServer *blockchain.ChainMakerServer) (*RPCServer, er
 	}
 
 	return &RPCServer{
-		grpcServer:       server,
-		chainMakerServer: chainMakerServer,
-		log:              logger.GetLogger(logger.MODULE_RPC),
+		grpcServer:          server,
+		chainMakerServer:    chainMakerServer,
+		log:                 logger.GetLogger(logger.MODULE_RPC),
+		identityRateLimiter: newIdentityRateLimiter(),
+		blackList:           blackList,
+		rateLimitBuckets:    rateLimitBuckets,
 	}, nil
 }
 
@@ -127,8 +146,16 @@ func (s *RPCServer) Start() error {
 		return fmt.Errorf("TCP listen failed, %s", err.Error())
 	}
 
+	grpcListener := conn
+	if s.gatewayConfig.Enabled {
+		if grpcListener, err = s.startGateway(conn); err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("start gateway failed, %s", err.Error())
+		}
+	}
+
 	go func() {
-		err = s.grpcServer.Serve(conn)
+		err = s.grpcServer.Serve(grpcListener)
 		if err != nil {
 			s.log.Errorf("grpc Serve failed, %s", err.Error())
 		}
@@ -141,16 +168,78 @@ func (s *RPCServer) Start() error {
 
 // RegisterHandler - register apiservice handler to rpcserver
 func (s *RPCServer) RegisterHandler() error {
-	apiService := NewApiService(s.ctx, s.chainMakerServer)
-	apiPb.RegisterRpcNodeServer(s.grpcServer, apiService)
+	s.apiService = NewApiService(s.ctx, s.chainMakerServer)
+	s.apiService.identityRateLimiter = s.identityRateLimiter
+	s.apiService.blackList = s.blackList
+	apiPb.RegisterRpcNodeServer(s.grpcServer, s.apiService)
+	RegisterAdminNodeServer(s.grpcServer, s.apiService)
 	return nil
 }
 
+// SetGatewayConfig - set the config of http gateway, it takes effect at Start
+func (s *RPCServer) SetGatewayConfig(config GatewayConfig) {
+	s.gatewayConfig = config
+}
+
+// SetIdentityRateLimitConfig - set the quotas of the members by org, the buckets in use are reset
+func (s *RPCServer) SetIdentityRateLimitConfig(config *IdentityRateLimitConfig) {
+	s.identityRateLimiter.setConfig(config)
+}
+
+// SetBlackListConfig - apply the blacklist read from chainmaker.yml, it can be replaced at runtime by the
+// RpcBlackList admin request
+func (s *RPCServer) SetBlackListConfig(config *BlackListConfig) error {
+	return s.blackList.update(config)
+}
+
+// startGateway starts the http gateway, returns the listener for gRPC, which only receives the
+// gRPC connections if the gateway shares the port of gRPC
+func (s *RPCServer) startGateway(grpcConn net.Listener) (net.Listener, error) {
+	s.gateway = newGateway(s.apiService, s.blackList, s.rateLimitBuckets)
+	tlsEnabled := localconf.ChainMakerConfig.RpcConfig.TLSConfig.Mode != TLS_MODE_DISABLE
+	port := s.gatewayConfig.Port
+	if port == 0 || port == localconf.ChainMakerConfig.RpcConfig.Port {
+		if tlsEnabled {
+			return nil, errors.New("gateway can not share the port of gRPC if tls is enabled, set rpc.gateway.port")
+		}
+		mux := newConnMux(grpcConn)
+		go mux.serve()
+		s.gateway.serve(mux.httpL)
+		s.log.Infof("gateway shares the port of gRPC")
+		return mux.grpcL, nil
+	}
+
+	endPoint := fmt.Sprintf(":%d", port)
+	conn, err := net.Listen("tcp", endPoint)
+	if err != nil {
+		return nil, fmt.Errorf("TCP listen failed, %s", err.Error())
+	}
+	if tlsEnabled {
+		tlsConfig, err := newGatewayTLSConfig(s.chainMakerServer)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tls.NewListener(conn, tlsConfig)
+	}
+	s.gateway.serve(conn)
+	s.log.Infof("gateway listen on %s", endPoint)
+	return grpcConn, nil
+}
+
+func (s *RPCServer) stopGateway() {
+	if s.gateway != nil {
+		s.gateway.stop()
+		s.gateway = nil
+	}
+}
+
 // Stop - stop RPCServer
 func (s *RPCServer) Stop() {
 	s.isShutdown = true
 	s.cancel()
 	s.grpcServer.GracefulStop()
+	s.stopGateway()
 	s.log.Info("RPCServer is stopped!")
 }
 
@@ -164,8 +253,9 @@ func (s *RPCServer) Restart(reason string) error {
 
 	s.cancel()
 	s.grpcServer.GracefulStop()
+	s.stopGateway()
 
-	s.grpcServer, err = newGrpc(s.chainMakerServer)
+	s.grpcServer, err = newGrpc(s.chainMakerServer, s.blackList, s.rateLimitBuckets)
 	if err != nil {
 		errMsg := fmt.Sprintf("RPCServer restart for reason [%s], new rpc server failed, %s", reason, err.Error())
 		s.log.Errorf(errMsg)
@@ -258,7 +348,8 @@ func (s *RPCServer) checkAndRestart() error {
 }
 
 // newGrpc - new GRPC object
-func newGrpc(chainMakerServer *blockchain.ChainMakerServer) (*grpc.Server, error) {
+func newGrpc(chainMakerServer *blockchain.ChainMakerServer, blackList *blackList,
+	rateLimitBuckets *sync.Map) (*grpc.Server, error) {
 	var opts []grpc.ServerOption
 	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
 		opts = []grpc.ServerOption{
@@ -266,11 +357,12 @@ func newGrpc(chainMakerServer *blockchain.ChainMakerServer) (*grpc.Server, error
 				RecoveryInterceptor,
 				LoggingInterceptor,
 				MonitorInterceptor,
-				BlackListInterceptor(),
-				RateLimitInterceptor(),
+				BlackListInterceptor(blackList),
+				RateLimitInterceptor(rateLimitBuckets),
 			),
 			grpc_middleware.WithStreamServerChain(
-				BlackListStreamInterceptor(),
+				BlackListStreamInterceptor(blackList),
+				RateLimitStreamInterceptor(rateLimitBuckets),
 			),
 		}
 	} else {
@@ -278,11 +370,12 @@ func newGrpc(chainMakerServer *blockchain.ChainMakerServer) (*grpc.Server, error
 			grpc_middleware.WithUnaryServerChain(
 				RecoveryInterceptor,
 				LoggingInterceptor,
-				BlackListInterceptor(),
-				RateLimitInterceptor(),
+				BlackListInterceptor(blackList),
+				RateLimitInterceptor(rateLimitBuckets),
 			),
 			grpc_middleware.WithStreamServerChain(
-				BlackListStreamInterceptor(),
+				BlackListStreamInterceptor(blackList),
+				RateLimitStreamInterceptor(rateLimitBuckets),
 			),
 		}
 	}
@@ -0,0 +1,169 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiPb "chainmaker.org/chainmaker/pb-go/v2/api"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"github.com/gogo/protobuf/proto"
+)
+
+// The parameters of the block, tx and contract event subscriptions to resume a stream.
+// If WITH_CURSOR is true, the Data of each SubscribeResult is a marshaled KeyValuePair, whose Key is the cursor
+// of the result and Value is the Data without cursor. A stream is resumed after the cursor in CURSOR, every
+// result sent before the cursor (inclusive) is not sent again, START_BLOCK is ignored then.
+// A cursor only resumes the subscription of the same method and parameters except START_BLOCK and END_BLOCK.
+// A stream never skips a block: the blocks missed by the live stream are read from the store, or the stream is
+// closed with an error if they are not found, so that the results are delivered exactly once across resumes.
+const (
+	SubscribeWithCursor = "WITH_CURSOR"
+	SubscribeCursorKey  = "CURSOR"
+)
+
+// SubscribeCursor the position of a subscribe result in the chain, a cursor resumes the same kind of subscription
+// which issued it
+type SubscribeCursor struct {
+	ChainId     string
+	Scope       string // the digest of the method and the filtering parameters of the subscription
+	BlockHeight uint64
+	TxIndex     int // -1 for the results of blocks
+	EventIndex  int // -1 for the results of blocks and txs, the index of the last event for contract events
+}
+
+// String encodes the cursor as chainId:scope:blockHeight:txIndex:eventIndex
+func (c *SubscribeCursor) String() string {
+	return fmt.Sprintf("%s:%s:%d:%d:%d", c.ChainId, c.Scope, c.BlockHeight, c.TxIndex, c.EventIndex)
+}
+
+// ParseSubscribeCursor parses the cursor encoded by SubscribeCursor.String
+func ParseSubscribeCursor(cursor string) (*SubscribeCursor, error) {
+	parts := strings.Split(cursor, ":")
+	if len(parts) != 5 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cursor %s", cursor)
+	}
+	var (
+		c   = &SubscribeCursor{ChainId: parts[0], Scope: parts[1]}
+		err error
+	)
+	if c.BlockHeight, err = strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid cursor %s, %s", cursor, err)
+	}
+	if c.TxIndex, err = strconv.Atoi(parts[3]); err != nil || c.TxIndex < -1 {
+		return nil, fmt.Errorf("invalid cursor %s, bad tx index", cursor)
+	}
+	if c.EventIndex, err = strconv.Atoi(parts[4]); err != nil || c.EventIndex < -1 {
+		return nil, fmt.Errorf("invalid cursor %s, bad event index", cursor)
+	}
+	return c, nil
+}
+
+// after returns whether the position is after the cursor
+func (c *SubscribeCursor) after(blockHeight uint64, txIndex, eventIndex int) bool {
+	if blockHeight != c.BlockHeight {
+		return blockHeight > c.BlockHeight
+	}
+	if txIndex != c.TxIndex {
+		return txIndex > c.TxIndex
+	}
+	return eventIndex > c.EventIndex
+}
+
+// subscribeCursor the cursor state of a subscription stream
+type subscribeCursor struct {
+	chainId    string
+	scope      string
+	withCursor bool
+	resume     *SubscribeCursor // nil if the stream is not resumed
+}
+
+func newSubscribeCursor(chainId, method string, params []*commonPb.KeyValuePair) (*subscribeCursor, error) {
+	var err error
+	cursor := &subscribeCursor{chainId: chainId, scope: subscribeScope(method, params)}
+	for _, kv := range params {
+		if kv.Key == SubscribeWithCursor {
+			cursor.withCursor = string(kv.Value) == TRUE
+		} else if kv.Key == SubscribeCursorKey && len(kv.Value) > 0 {
+			if cursor.resume, err = ParseSubscribeCursor(string(kv.Value)); err != nil {
+				return nil, err
+			}
+			if cursor.resume.ChainId != chainId {
+				return nil, errors.New("the cursor is not issued by the chain")
+			}
+			if cursor.resume.Scope != cursor.scope {
+				return nil, errors.New("the cursor is issued by another kind of subscription or filter")
+			}
+		}
+	}
+	return cursor, nil
+}
+
+// startBlock returns the block height to start the stream from, which is the block of the cursor if resuming
+func (c *subscribeCursor) startBlock(startBlock int64) int64 {
+	if c.resume == nil {
+		return startBlock
+	}
+	return int64(c.resume.BlockHeight)
+}
+
+// skip returns whether the result at the position has been sent before the stream is resumed
+func (c *subscribeCursor) skip(blockHeight uint64, txIndex, eventIndex int) bool {
+	return c.resume != nil && !c.resume.after(blockHeight, txIndex, eventIndex)
+}
+
+// send sends the result at the position, with the cursor attached if the subscriber asks for it
+func (c *subscribeCursor) send(server apiPb.RpcNode_SubscribeServer, result *commonPb.SubscribeResult,
+	blockHeight uint64, txIndex, eventIndex int) error {
+	if !c.withCursor {
+		return server.Send(result)
+	}
+	position := &SubscribeCursor{ChainId: c.chainId, Scope: c.scope, BlockHeight: blockHeight, TxIndex: txIndex,
+		EventIndex: eventIndex}
+	data, err := proto.Marshal(&commonPb.KeyValuePair{Key: position.String(), Value: result.Data})
+	if err != nil {
+		return fmt.Errorf("marshal subscribe result with cursor failed, %s", err)
+	}
+	return server.Send(&commonPb.SubscribeResult{Data: data})
+}
+
+// subscribeScope returns the digest of the method and the parameters selecting the results of a subscription,
+// the parameters of the block range and the cursor are not included
+func subscribeScope(method string, params []*commonPb.KeyValuePair) string {
+	fields := make([]string, 0, len(params))
+	for _, kv := range params {
+		switch kv.Key {
+		case SubscribeWithCursor, SubscribeCursorKey,
+			syscontract.SubscribeBlock_START_BLOCK.String(), syscontract.SubscribeBlock_END_BLOCK.String():
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%q=%q", kv.Key, kv.Value))
+	}
+	sort.Strings(fields)
+	digest := sha256.Sum256([]byte(method + "\n" + strings.Join(fields, "\n")))
+	return hex.EncodeToString(digest[:8])
+}
+
+// nextLiveBlock returns whether a block of the live stream is not sent yet, and the first block to send from,
+// which is before the block if the blocks after the last one sent are missed. lastSendBlockHeight is -1 if no
+// block is sent, then the stream starts from the block.
+func nextLiveBlock(lastSendBlockHeight, blockHeight int64) (fromBlockHeight int64, isNew bool) {
+	if lastSendBlockHeight == -1 {
+		return blockHeight, true
+	}
+	if blockHeight <= lastSendBlockHeight {
+		return -1, false
+	}
+	return lastSendBlockHeight + 1, true
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"testing"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubscribeCursor(t *testing.T) {
+	c := &SubscribeCursor{ChainId: "chain1", Scope: "0123456789abcdef", BlockHeight: 10, TxIndex: 2, EventIndex: -1}
+	parsed, err := ParseSubscribeCursor(c.String())
+	require.NoError(t, err)
+	require.Equal(t, c, parsed)
+
+	for _, cursor := range []string{"", "chain1:10:2:-1", ":scope:10:2:-1", "chain1::10:2:-1",
+		"chain1:scope:x:2:-1", "chain1:scope:10:-2:-1", "chain1:scope:10:2:-2"} {
+		_, err = ParseSubscribeCursor(cursor)
+		require.Error(t, err, cursor)
+	}
+}
+
+func TestSubscribeCursorBinding(t *testing.T) {
+	method := syscontract.SubscribeFunction_SUBSCRIBE_CONTRACT_EVENT.String()
+	params := func(topic string, kvs ...*commonPb.KeyValuePair) []*commonPb.KeyValuePair {
+		return append([]*commonPb.KeyValuePair{
+			{Key: syscontract.SubscribeContractEvent_TOPIC.String(), Value: []byte(topic)},
+			{Key: syscontract.SubscribeContractEvent_CONTRACT_NAME.String(), Value: []byte("contract1")},
+		}, kvs...)
+	}
+	issuer, err := newSubscribeCursor("chain1", method, params("topic1"))
+	require.NoError(t, err)
+	position := &SubscribeCursor{ChainId: "chain1", Scope: issuer.scope, BlockHeight: 5, TxIndex: 1, EventIndex: 0}
+	resume := &commonPb.KeyValuePair{Key: SubscribeCursorKey, Value: []byte(position.String())}
+
+	// 1. the same subscription resumes with another block range
+	cursor, err := newSubscribeCursor("chain1", method, params("topic1", resume,
+		&commonPb.KeyValuePair{Key: SubscribeContractEventStartBlock, Value: []byte("3")}))
+	require.NoError(t, err)
+	require.True(t, cursor.skip(5, 1, 0))
+	require.False(t, cursor.skip(5, 1, 1))
+
+	// 2. the cursor is rejected by another filter, another kind of subscription or another chain
+	_, err = newSubscribeCursor("chain1", method, params("topic2", resume))
+	require.Error(t, err)
+	_, err = newSubscribeCursor("chain1", syscontract.SubscribeFunction_SUBSCRIBE_TX.String(), params("topic1", resume))
+	require.Error(t, err)
+	_, err = newSubscribeCursor("chain2", method, params("topic1", resume))
+	require.Error(t, err)
+}
+
+func TestNextLiveBlock(t *testing.T) {
+	// the stream starts from the first live block if no block is sent
+	from, isNew := nextLiveBlock(-1, 5)
+	require.True(t, isNew)
+	require.EqualValues(t, 5, from)
+
+	// the blocks sent are not sent again
+	_, isNew = nextLiveBlock(5, 5)
+	require.False(t, isNew)
+	_, isNew = nextLiveBlock(5, 3)
+	require.False(t, isNew)
+
+	// the next block
+	from, isNew = nextLiveBlock(5, 6)
+	require.True(t, isNew)
+	require.EqualValues(t, 6, from)
+
+	// the blocks missed are sent from the first one after the last block sent
+	from, isNew = nextLiveBlock(5, 9)
+	require.True(t, isNew)
+	require.EqualValues(t, 6, from)
+}
@@ -51,20 +51,35 @@ func (s *ApiService) Subscribe(req *commonPb.TxRequest, server apiPb.RpcNode_Sub
 		return status.Error(codes.Unauthenticated, errMsg)
 	}
 
+	if err := s.checkSenderBlackList(tx, "Subscribe"); err != nil {
+		return err
+	}
+	if err := s.identityRateLimiter.allow(tx.Sender, QuotaKindSubscribe); err != nil {
+		return err
+	}
+
+	cursor, err := newSubscribeCursor(req.Payload.ChainId, req.Payload.Method, req.Payload.Parameters)
+	if err != nil {
+		errMsg = fmt.Sprintf("invalid subscribe cursor, %s", err)
+		s.log.Error(errMsg)
+		return status.Error(codes.InvalidArgument, errMsg)
+	}
+
 	switch req.Payload.Method {
 	case syscontract.SubscribeFunction_SUBSCRIBE_BLOCK.String():
-		return s.dealBlockSubscription(tx, server)
+		return s.dealBlockSubscription(tx, server, cursor)
 	case syscontract.SubscribeFunction_SUBSCRIBE_TX.String():
-		return s.dealTxSubscription(tx, server)
+		return s.dealTxSubscription(tx, server, cursor)
 	case syscontract.SubscribeFunction_SUBSCRIBE_CONTRACT_EVENT.String():
-		return s.dealContractEventSubscription(tx, server)
+		return s.dealContractEventSubscription(tx, server, cursor)
 	}
 
 	return nil
 }
 
 // dealBlockSubscription - deal block subscribe request
-func (s *ApiService) dealBlockSubscription(tx *commonPb.Transaction, server apiPb.RpcNode_SubscribeServer) error {
+func (s *ApiService) dealBlockSubscription(tx *commonPb.Transaction, server apiPb.RpcNode_SubscribeServer,
+	cursor *subscribeCursor) error {
 	var (
 		err             error
 		errMsg          string
@@ -103,6 +118,7 @@ func (s *ApiService) dealBlockSubscription(tx *commonPb.Transaction, server apiP
 		}
 	}
 
+	startBlock = cursor.startBlock(startBlock)
 	if err = s.checkSubscribeBlockHeight(startBlock, endBlock); err != nil {
 		errCode = commonErr.ERR_CODE_CHECK_PAYLOAD_PARAM_SUBSCRIBE_BLOCK
 		errMsg = s.getErrMsg(errCode, err)
@@ -141,12 +157,12 @@ func (s *ApiService) dealBlockSubscription(tx *commonPb.Transaction, server apiP
 
 	if startBlock == -1 && endBlock == -1 {
 		return s.sendNewBlock(db, tx, server, endBlock, withRWSet, onlyHeader,
-			-1, reqSender, reqSenderOrgId)
+			-1, reqSender, reqSenderOrgId, cursor)
 	}
 
 	if endBlock != -1 && endBlock <= lastBlockHeight {
 		_, err = s.sendHistoryBlock(db, server, startBlockHeight, endBlock,
-			withRWSet, onlyHeader, reqSender, reqSenderOrgId)
+			withRWSet, onlyHeader, reqSender, reqSenderOrgId, cursor)
 
 		if err != nil {
 			s.log.Errorf("sendHistoryBlock failed, %s", err)
@@ -157,7 +173,7 @@ func (s *ApiService) dealBlockSubscription(tx *commonPb.Transaction, server apiP
 	}
 
 	alreadySendHistoryBlockHeight, err := s.sendHistoryBlock(db, server, startBlockHeight, endBlock,
-		withRWSet, onlyHeader, reqSender, reqSenderOrgId)
+		withRWSet, onlyHeader, reqSender, reqSenderOrgId, cursor)
 
 	if err != nil {
 		s.log.Errorf("sendHistoryBlock failed, %s", err)
@@ -167,11 +183,12 @@ func (s *ApiService) dealBlockSubscription(tx *commonPb.Transaction, server apiP
 	s.log.Debugf("after sendHistoryBlock, alreadySendHistoryBlockHeight is %d", alreadySendHistoryBlockHeight)
 
 	return s.sendNewBlock(db, tx, server, endBlock, withRWSet, onlyHeader, alreadySendHistoryBlockHeight,
-		reqSender, reqSenderOrgId)
+		reqSender, reqSenderOrgId, cursor)
 }
 
 // dealTxSubscription - deal tx subscribe request
-func (s *ApiService) dealTxSubscription(tx *commonPb.Transaction, server apiPb.RpcNode_SubscribeServer) error {
+func (s *ApiService) dealTxSubscription(tx *commonPb.Transaction, server apiPb.RpcNode_SubscribeServer,
+	cursor *subscribeCursor) error {
 	var (
 		err          error
 		errMsg       string
@@ -206,6 +223,7 @@ func (s *ApiService) dealTxSubscription(tx *commonPb.Transaction, server apiPb.R
 		}
 	}
 
+	startBlock = cursor.startBlock(startBlock)
 	if err = s.checkSubscribeBlockHeight(startBlock, endBlock); err != nil {
 		errCode = commonErr.ERR_CODE_CHECK_PAYLOAD_PARAM_SUBSCRIBE_TX
 		errMsg = s.getErrMsg(errCode, err)
@@ -229,12 +247,12 @@ func (s *ApiService) dealTxSubscription(tx *commonPb.Transaction, server apiPb.R
 		return err
 	}
 	reqSenderOrgId := tx.Sender.Signer.OrgId
-	return s.doSendTx(tx, db, server, startBlock, endBlock, contractName, txIds, reqSender, reqSenderOrgId)
+	return s.doSendTx(tx, db, server, startBlock, endBlock, contractName, txIds, reqSender, reqSenderOrgId, cursor)
 }
 
 //dealContractEventSubscription - deal contract event subscribe request
 func (s *ApiService) dealContractEventSubscription(tx *commonPb.Transaction,
-	server apiPb.RpcNode_SubscribeServer) error {
+	server apiPb.RpcNode_SubscribeServer, cursor *subscribeCursor) error {
 
 	var (
 		err          error
@@ -243,6 +261,10 @@ func (s *ApiService) dealContractEventSubscription(tx *commonPb.Transaction,
 		payload      = tx.Payload
 		topic        string
 		contractName string
+		dataFilters  []byte
+		startBlock   int64 = -1
+		endBlock     int64 = -1
+		filter       *contractEventFilter
 	)
 
 	for _, kv := range payload.Parameters {
@@ -250,42 +272,112 @@ func (s *ApiService) dealContractEventSubscription(tx *commonPb.Transaction,
 			topic = string(kv.Value)
 		} else if kv.Key == syscontract.SubscribeContractEvent_CONTRACT_NAME.String() {
 			contractName = string(kv.Value)
+		} else if kv.Key == SubscribeContractEventStartBlock {
+			startBlock, err = bytehelper.BytesToInt64(kv.Value)
+		} else if kv.Key == SubscribeContractEventEndBlock {
+			endBlock, err = bytehelper.BytesToInt64(kv.Value)
+		} else if kv.Key == SubscribeContractEventFilters {
+			dataFilters = kv.Value
+		}
+
+		if err != nil {
+			errCode = commonErr.ERR_CODE_CHECK_PAYLOAD_PARAM_SUBSCRIBE_CONTRACT_EVENT
+			errMsg = s.getErrMsg(errCode, err)
+			s.log.Error(errMsg)
+			return status.Error(codes.InvalidArgument, errMsg)
 		}
 	}
 
-	if err = s.checkSubscribeContractEventPayload(topic, contractName); err != nil {
+	startBlock = cursor.startBlock(startBlock)
+	if filter, err = s.checkSubscribeContractEventPayload(topic, contractName, dataFilters,
+		startBlock, endBlock); err != nil {
 		errCode = commonErr.ERR_CODE_CHECK_PAYLOAD_PARAM_SUBSCRIBE_CONTRACT_EVENT
 		errMsg = s.getErrMsg(errCode, err)
 		s.log.Error(errMsg)
 		return status.Error(codes.InvalidArgument, errMsg)
 	}
-	s.log.Infof("Recv contractEventInfo subscribe request: [topic:%v]/[contractName:%v]",
-		topic, contractName)
+	s.log.Infof("Recv contractEventInfo subscribe request: [topic:%v]/[contractName:%v]/[start:%d]/[end:%d]"+
+		"/[filters:%s]", topic, contractName, startBlock, endBlock, dataFilters)
 
-	return s.doSendContractEvent(tx, server, topic, contractName)
+	return s.doSendContractEvent(tx, server, filter, startBlock, endBlock, cursor)
 
 }
 
-func (s *ApiService) checkSubscribeContractEventPayload(topic, contractName string) error {
+func (s *ApiService) checkSubscribeContractEventPayload(topic, contractName string, dataFilters []byte,
+	startBlock, endBlock int64) (*contractEventFilter, error) {
 	if topic == "" || contractName == "" {
-		return errors.New("invalid topic or contract name")
+		return nil, errors.New("invalid topic or contract name")
+	}
+	if err := s.checkSubscribeBlockHeight(startBlock, endBlock); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return newContractEventFilter(contractName, topic, dataFilters)
 }
 
 func (s *ApiService) doSendContractEvent(tx *commonPb.Transaction, server apiPb.RpcNode_SubscribeServer,
-	topic, contractName string) error {
+	filter *contractEventFilter, startBlock, endBlock int64, cursor *subscribeCursor) error {
+
+	var (
+		errCode                       commonErr.ErrCode
+		err                           error
+		errMsg                        string
+		store                         protocol.BlockchainStore
+		lastBlockHeight               int64
+		alreadySendHistoryBlockHeight int64 = -1
+	)
+
+	chainId := tx.Payload.ChainId
+	if store, err = s.chainMakerServer.GetStore(chainId); err != nil {
+		errCode = commonErr.ERR_CODE_GET_STORE
+		errMsg = s.getErrMsg(errCode, err)
+		s.log.Error(errMsg)
+		return status.Error(codes.Internal, errMsg)
+	}
+
+	if startBlock != -1 || endBlock != -1 {
+		if lastBlockHeight, err = s.checkAndGetLastBlockHeight(store, startBlock); err != nil {
+			return err
+		}
+
+		var startBlockHeight int64
+		if startBlock > startBlockHeight {
+			startBlockHeight = startBlock
+		}
+		historyEndBlockHeight := lastBlockHeight
+		if endBlock != -1 && endBlock <= lastBlockHeight {
+			historyEndBlockHeight = endBlock
+		}
+
+		if alreadySendHistoryBlockHeight, err = s.sendHistoryContractEvent(store, server, filter,
+			startBlockHeight, historyEndBlockHeight, cursor); err != nil {
+			s.log.Errorf("sendHistoryContractEvent failed, %s", err)
+			return err
+		}
+
+		if endBlock != -1 && alreadySendHistoryBlockHeight >= endBlock {
+			return status.Error(codes.OK, "OK")
+		}
+	}
+
+	return s.sendNewContractEvent(store, tx, server, filter, endBlock, alreadySendHistoryBlockHeight, cursor)
+}
+
+// sendNewContractEvent - send the contract events of the new blocks to subscriber, the blocks committed after
+// the history ones and before the subscription are read from the store
+func (s *ApiService) sendNewContractEvent(store protocol.BlockchainStore, tx *commonPb.Transaction,
+	server apiPb.RpcNode_SubscribeServer, filter *contractEventFilter,
+	endBlock, alreadySendHistoryBlockHeight int64, cursor *subscribeCursor) error {
 
 	var (
 		errCode         commonErr.ErrCode
 		err             error
 		errMsg          string
 		eventSubscriber *subscriber.EventSubscriber
-		result          *commonPb.SubscribeResult
+		block           *commonPb.Block
 	)
 
-	eventCh := make(chan model.NewContractEvent)
+	blockCh := make(chan model.NewBlockEvent)
 
 	chainId := tx.Payload.ChainId
 	if eventSubscriber, err = s.chainMakerServer.GetEventSubscribe(chainId); err != nil {
@@ -295,41 +387,137 @@ func (s *ApiService) doSendContractEvent(tx *commonPb.Transaction, server apiPb.
 		return status.Error(codes.Internal, errMsg)
 	}
 
-	sub := eventSubscriber.SubscribeContractEvent(eventCh)
+	sub := eventSubscriber.SubscribeBlockEvent(blockCh)
 	defer sub.Unsubscribe()
+	lastSendBlockHeight := alreadySendHistoryBlockHeight
 	for {
 		select {
-		case ev := <-eventCh:
-			contractEventInfoList := ev.ContractEventInfoList.ContractEvents
-			sendEventInfoList := &commonPb.ContractEventInfoList{}
-			for _, EventInfo := range contractEventInfoList {
-				if EventInfo.ContractName != contractName || EventInfo.Topic != topic {
-					continue
-				}
-				sendEventInfoList.ContractEvents = append(sendEventInfoList.ContractEvents, EventInfo)
+		case ev := <-blockCh:
+			block = ev.BlockInfo.Block
+			if lastSendBlockHeight, err = s.sendLiveContractEvent(store, server, filter, block,
+				endBlock, lastSendBlockHeight, cursor); err != nil {
+				return err
 			}
-			if len(sendEventInfoList.ContractEvents) > 0 {
-				if result, err = s.getContractEventSubscribeResult(sendEventInfoList); err != nil {
-					s.log.Error(err.Error())
-					return status.Error(codes.Internal, err.Error())
-				}
-				if err := server.Send(result); err != nil {
-					err = fmt.Errorf("send block info by realtime failed, %s", err)
-					s.log.Error(err.Error())
-					return status.Error(codes.Internal, err.Error())
-				}
+
+			if endBlock != -1 && lastSendBlockHeight >= endBlock {
+				return status.Error(codes.OK, "OK")
 			}
 		case <-server.Context().Done():
 			return nil
+		case err := <-sub.Err():
+			return s.getSubscriptionClosedError(err)
 		case <-s.ctx.Done():
 			return nil
 		}
 	}
 }
 
+// sendLiveContractEvent - send the contract events of a new block to subscriber, the blocks between the last one
+// sent and the new one, which are committed before the subscription or missed by it, are read from the store
+// first, so that no block is skipped silently. It returns the height of the last block sent, -1 if none is sent.
+func (s *ApiService) sendLiveContractEvent(store protocol.BlockchainStore, server apiPb.RpcNode_SubscribeServer,
+	filter *contractEventFilter, block *commonPb.Block, endBlock, lastSendBlockHeight int64,
+	cursor *subscribeCursor) (int64, error) {
+
+	blockHeight := int64(block.Header.BlockHeight)
+	fromBlockHeight, isNew := nextLiveBlock(lastSendBlockHeight, blockHeight)
+	if !isNew {
+		return lastSendBlockHeight, nil
+	}
+	if fromBlockHeight < blockHeight {
+		backfillEndBlockHeight := blockHeight - 1
+		if endBlock != -1 && endBlock < backfillEndBlockHeight {
+			backfillEndBlockHeight = endBlock
+		}
+		s.log.Debugf("send contract events of blocks [%d, %d] from the store",
+			fromBlockHeight, backfillEndBlockHeight)
+		sendBlockHeight, err := s.sendHistoryContractEvent(store, server, filter,
+			fromBlockHeight, backfillEndBlockHeight, cursor)
+		if err != nil {
+			s.log.Errorf("send history contract event failed, %s", err)
+			return -1, err
+		}
+		if sendBlockHeight < backfillEndBlockHeight {
+			return -1, s.getMissingBlockError(sendBlockHeight + 1)
+		}
+		if backfillEndBlockHeight < blockHeight-1 {
+			return backfillEndBlockHeight, nil
+		}
+	}
+	if err := s.sendContractEventsOfBlock(server, filter, block, cursor); err != nil {
+		s.log.Errorf("send contract event by realtime failed, %s", err)
+		return -1, status.Error(codes.Internal, err.Error())
+	}
+	return blockHeight, nil
+}
+
+// sendHistoryContractEvent - send the contract events of the blocks in the store to subscriber,
+// it returns the height of the last block sent
+func (s *ApiService) sendHistoryContractEvent(store protocol.BlockchainStore,
+	server apiPb.RpcNode_SubscribeServer, filter *contractEventFilter,
+	startBlockHeight, endBlockHeight int64, cursor *subscribeCursor) (int64, error) {
+
+	var (
+		err    error
+		errMsg string
+		block  *commonPb.Block
+	)
+
+	i := startBlockHeight
+	for {
+		select {
+		case <-s.ctx.Done():
+			return -1, status.Error(codes.Internal, "chainmaker is restarting, please retry later")
+		default:
+			if err = s.getRateLimitToken(); err != nil {
+				return -1, status.Error(codes.Internal, err.Error())
+			}
+
+			if i > endBlockHeight {
+				return i - 1, nil
+			}
+
+			if block, err = store.GetBlock(uint64(i)); err != nil {
+				errMsg = fmt.Sprintf("get block failed, at [height:%d], %s", i, err)
+				s.log.Error(errMsg)
+				return -1, status.Error(codes.Internal, errMsg)
+			}
+
+			if block == nil {
+				return i - 1, nil
+			}
+
+			if err = s.sendContractEventsOfBlock(server, filter, block, cursor); err != nil {
+				errMsg = fmt.Sprintf("send contract event by history failed, %s", err)
+				s.log.Error(errMsg)
+				return -1, status.Error(codes.Internal, errMsg)
+			}
+
+			i++
+		}
+	}
+}
+
+func (s *ApiService) sendContractEventsOfBlock(server apiPb.RpcNode_SubscribeServer, filter *contractEventFilter,
+	block *commonPb.Block, cursor *subscribeCursor) error {
+
+	blockHeight := block.Header.BlockHeight
+	sendEventInfoList, txIndex, eventIndex := filter.filterBlock(block, func(txIndex, eventIndex int) bool {
+		return cursor.skip(blockHeight, txIndex, eventIndex)
+	})
+	if len(sendEventInfoList.ContractEvents) == 0 {
+		return nil
+	}
+	result, err := s.getContractEventSubscribeResult(sendEventInfoList)
+	if err != nil {
+		return err
+	}
+	return cursor.send(server, result, blockHeight, txIndex, eventIndex)
+}
+
 func (s *ApiService) doSendTx(tx *commonPb.Transaction, db protocol.BlockchainStore,
 	server apiPb.RpcNode_SubscribeServer, startBlock, endBlock int64, contractName string,
-	txIds []string, reqSender protocol.Role, reqSenderOrgId string) error {
+	txIds []string, reqSender protocol.Role, reqSenderOrgId string, cursor *subscribeCursor) error {
 
 	var (
 		txIdsMap                      = make(map[string]struct{})
@@ -343,11 +531,11 @@ func (s *ApiService) doSendTx(tx *commonPb.Transaction, db protocol.BlockchainSt
 
 	if startBlock == -1 && endBlock == -1 {
 		return s.sendNewTx(db, tx, server, startBlock, endBlock, contractName, txIds,
-			txIdsMap, -1, reqSender, reqSenderOrgId)
+			txIdsMap, -1, reqSender, reqSenderOrgId, cursor)
 	}
 
 	if alreadySendHistoryBlockHeight, err = s.doSendHistoryTx(db, server, startBlock, endBlock,
-		contractName, txIds, txIdsMap, reqSender, reqSenderOrgId); err != nil {
+		contractName, txIds, txIdsMap, reqSender, reqSenderOrgId, cursor); err != nil {
 		return err
 	}
 
@@ -356,12 +544,13 @@ func (s *ApiService) doSendTx(tx *commonPb.Transaction, db protocol.BlockchainSt
 	}
 
 	return s.sendNewTx(db, tx, server, startBlock, endBlock, contractName, txIds, txIdsMap,
-		alreadySendHistoryBlockHeight, reqSender, reqSenderOrgId)
+		alreadySendHistoryBlockHeight, reqSender, reqSenderOrgId, cursor)
 }
 
 func (s *ApiService) doSendHistoryTx(db protocol.BlockchainStore, server apiPb.RpcNode_SubscribeServer,
 	startBlock, endBlock int64, contractName string, txIds []string,
-	txIdsMap map[string]struct{}, reqSender protocol.Role, reqSenderOrgId string) (int64, error) {
+	txIdsMap map[string]struct{}, reqSender protocol.Role, reqSenderOrgId string,
+	cursor *subscribeCursor) (int64, error) {
 
 	var (
 		err             error
@@ -384,7 +573,7 @@ func (s *ApiService) doSendHistoryTx(db protocol.BlockchainStore, server apiPb.R
 
 	if endBlock != -1 && endBlock <= lastBlockHeight {
 		_, err = s.sendHistoryTx(db, server, startBlockHeight, endBlock, contractName,
-			txIds, txIdsMap, reqSender, reqSenderOrgId)
+			txIds, txIdsMap, reqSender, reqSenderOrgId, cursor)
 
 		if err != nil {
 			s.log.Errorf("sendHistoryTx failed, %s", err)
@@ -399,7 +588,7 @@ func (s *ApiService) doSendHistoryTx(db protocol.BlockchainStore, server apiPb.R
 	}
 
 	alreadySendHistoryBlockHeight, err := s.sendHistoryTx(db, server, startBlockHeight, endBlock, contractName,
-		txIds, txIdsMap, reqSender, reqSenderOrgId)
+		txIds, txIdsMap, reqSender, reqSenderOrgId, cursor)
 
 	if err != nil {
 		s.log.Errorf("sendHistoryTx failed, %s", err)
@@ -419,7 +608,7 @@ func (s *ApiService) doSendHistoryTx(db protocol.BlockchainStore, server apiPb.R
 func (s *ApiService) sendNewBlock(store protocol.BlockchainStore, tx *commonPb.Transaction,
 	server apiPb.RpcNode_SubscribeServer,
 	endBlockHeight int64, withRWSet, onlyHeader bool, alreadySendHistoryBlockHeight int64,
-	reqSender protocol.Role, reqSenderOrgId string) error {
+	reqSender protocol.Role, reqSenderOrgId string, cursor *subscribeCursor) error {
 
 	var (
 		errCode         commonErr.ErrCode
@@ -442,20 +631,39 @@ func (s *ApiService) sendNewBlock(store protocol.BlockchainStore, tx *commonPb.T
 	sub := eventSubscriber.SubscribeBlockEvent(blockCh)
 	defer sub.Unsubscribe()
 
+	lastSendBlockHeight := alreadySendHistoryBlockHeight
 	for {
 		select {
 		case ev := <-blockCh:
 			blockInfo = ev.BlockInfo
+			blockHeight := int64(blockInfo.Block.Header.BlockHeight)
 
-			if alreadySendHistoryBlockHeight != -1 && int64(blockInfo.Block.Header.BlockHeight) > alreadySendHistoryBlockHeight {
-				_, err = s.sendHistoryBlock(store, server, alreadySendHistoryBlockHeight+1,
-					int64(blockInfo.Block.Header.BlockHeight), withRWSet, onlyHeader, reqSender, reqSenderOrgId)
+			fromBlockHeight, isNew := nextLiveBlock(lastSendBlockHeight, blockHeight)
+			if !isNew {
+				continue
+			}
+			if fromBlockHeight < blockHeight {
+				historyEndBlockHeight := blockHeight
+				if endBlockHeight != -1 && endBlockHeight < historyEndBlockHeight {
+					historyEndBlockHeight = endBlockHeight
+				}
+				lastSendBlockHeight, err = s.sendHistoryBlock(store, server, fromBlockHeight, historyEndBlockHeight,
+					withRWSet, onlyHeader, reqSender, reqSenderOrgId, cursor)
 				if err != nil {
 					s.log.Errorf("send history block failed, %s", err)
 					return err
 				}
+				if lastSendBlockHeight < historyEndBlockHeight {
+					return s.getMissingBlockError(lastSendBlockHeight + 1)
+				}
+				if endBlockHeight != -1 && lastSendBlockHeight >= endBlockHeight {
+					return status.Error(codes.OK, "OK")
+				}
+				continue
+			}
+			lastSendBlockHeight = blockHeight
 
-				alreadySendHistoryBlockHeight = -1
+			if cursor.skip(blockInfo.Block.Header.BlockHeight, -1, -1) {
 				continue
 			}
 
@@ -469,7 +677,7 @@ func (s *ApiService) sendNewBlock(store protocol.BlockchainStore, tx *commonPb.T
 
 			//printAllTxsOfBlock(blockInfo, reqSender, reqSenderOrgId)
 
-			if err = s.dealBlockSubscribeResult(server, blockInfo, withRWSet, onlyHeader); err != nil {
+			if err = s.dealBlockSubscribeResult(server, blockInfo, withRWSet, onlyHeader, cursor); err != nil {
 				s.log.Errorf(err.Error())
 				return status.Error(codes.Internal, err.Error())
 			}
@@ -480,6 +688,8 @@ func (s *ApiService) sendNewBlock(store protocol.BlockchainStore, tx *commonPb.T
 
 		case <-server.Context().Done():
 			return nil
+		case err := <-sub.Err():
+			return s.getSubscriptionClosedError(err)
 		case <-s.ctx.Done():
 			return nil
 		}
@@ -487,7 +697,7 @@ func (s *ApiService) sendNewBlock(store protocol.BlockchainStore, tx *commonPb.T
 }
 
 func (s *ApiService) dealBlockSubscribeResult(server apiPb.RpcNode_SubscribeServer, blockInfo *commonPb.BlockInfo,
-	withRWSet, onlyHeader bool) error {
+	withRWSet, onlyHeader bool, cursor *subscribeCursor) error {
 
 	var (
 		err    error
@@ -505,7 +715,7 @@ func (s *ApiService) dealBlockSubscribeResult(server apiPb.RpcNode_SubscribeServ
 		return fmt.Errorf("get block subscribe result failed, %s", err)
 	}
 
-	if err := server.Send(result); err != nil {
+	if err := cursor.send(server, result, blockInfo.Block.Header.BlockHeight, -1, -1); err != nil {
 		return fmt.Errorf("send block subscribe result by realtime failed, %s", err)
 	}
 
@@ -516,7 +726,7 @@ func (s *ApiService) dealBlockSubscribeResult(server apiPb.RpcNode_SubscribeServ
 func (s *ApiService) sendNewTx(store protocol.BlockchainStore, tx *commonPb.Transaction,
 	server apiPb.RpcNode_SubscribeServer, startBlock, endBlock int64, contractName string,
 	txIds []string, txIdsMap map[string]struct{}, alreadySendHistoryBlockHeight int64,
-	reqSender protocol.Role, reqSenderOrgId string) error {
+	reqSender protocol.Role, reqSenderOrgId string, cursor *subscribeCursor) error {
 
 	var (
 		errCode         commonErr.ErrCode
@@ -539,25 +749,41 @@ func (s *ApiService) sendNewTx(store protocol.BlockchainStore, tx *commonPb.Tran
 	sub := eventSubscriber.SubscribeBlockEvent(blockCh)
 	defer sub.Unsubscribe()
 
+	lastSendBlockHeight := alreadySendHistoryBlockHeight
 	for {
 		select {
 		case ev := <-blockCh:
 			block = ev.BlockInfo.Block
+			blockHeight := int64(block.Header.BlockHeight)
 
-			if alreadySendHistoryBlockHeight != -1 && int64(block.Header.BlockHeight) > alreadySendHistoryBlockHeight {
-				_, err = s.sendHistoryTx(store, server, alreadySendHistoryBlockHeight+1,
-					int64(block.Header.BlockHeight), contractName, txIds, txIdsMap, reqSender, reqSenderOrgId)
+			fromBlockHeight, isNew := nextLiveBlock(lastSendBlockHeight, blockHeight)
+			if !isNew {
+				continue
+			}
+			if fromBlockHeight < blockHeight {
+				historyEndBlockHeight := blockHeight
+				if endBlock != -1 && endBlock < historyEndBlockHeight {
+					historyEndBlockHeight = endBlock
+				}
+				lastSendBlockHeight, err = s.sendHistoryTx(store, server, fromBlockHeight, historyEndBlockHeight,
+					contractName, txIds, txIdsMap, reqSender, reqSenderOrgId, cursor)
 				if err != nil {
 					s.log.Errorf("send history block failed, %s", err)
 					return err
 				}
-
-				alreadySendHistoryBlockHeight = -1
+				if len(txIds) > 0 && len(txIdsMap) == 0 ||
+					endBlock != -1 && lastSendBlockHeight >= endBlock {
+					return status.Error(codes.OK, "OK")
+				}
+				if lastSendBlockHeight < historyEndBlockHeight {
+					return s.getMissingBlockError(lastSendBlockHeight + 1)
+				}
 				continue
 			}
+			lastSendBlockHeight = blockHeight
 
-			if err := s.sendSubscribeTx(server, block.Txs, contractName, txIds, txIdsMap,
-				reqSender, reqSenderOrgId); err != nil {
+			if err := s.sendSubscribeTx(server, block, contractName, txIds, txIdsMap,
+				reqSender, reqSenderOrgId, cursor); err != nil {
 				errMsg = fmt.Sprintf("send subscribe tx failed, %s", err)
 				s.log.Error(errMsg)
 				return status.Error(codes.Internal, errMsg)
@@ -569,12 +795,22 @@ func (s *ApiService) sendNewTx(store protocol.BlockchainStore, tx *commonPb.Tran
 
 		case <-server.Context().Done():
 			return nil
+		case err := <-sub.Err():
+			return s.getSubscriptionClosedError(err)
 		case <-s.ctx.Done():
 			return nil
 		}
 	}
 }
 
+// getMissingBlockError returns the error closing the stream if a block missed by the live stream is not found in
+// the store, so that the subscriber never skips a block silently
+func (s *ApiService) getMissingBlockError(blockHeight int64) error {
+	errMsg := fmt.Sprintf("block %d missed by the subscription is not found in the store", blockHeight)
+	s.log.Error(errMsg)
+	return status.Error(codes.Internal, errMsg)
+}
+
 func (s *ApiService) checkIsFinish(txIds []string, endBlock int64,
 	txIdsMap map[string]struct{}, blockInfo *commonPb.BlockInfo) bool {
 
@@ -604,7 +840,7 @@ func (s *ApiService) getRateLimitToken() error {
 // sendHistoryBlock - send history block to subscriber
 func (s *ApiService) sendHistoryBlock(store protocol.BlockchainStore, server apiPb.RpcNode_SubscribeServer,
 	startBlockHeight, endBlockHeight int64, withRWSet, onlyHeader bool, reqSender protocol.Role,
-	reqSenderOrgId string) (int64, error) {
+	reqSenderOrgId string, cursor *subscribeCursor) (int64, error) {
 
 	var (
 		err    error
@@ -626,6 +862,11 @@ func (s *ApiService) sendHistoryBlock(store protocol.BlockchainStore, server api
 				return i - 1, nil
 			}
 
+			if cursor.skip(uint64(i), -1, -1) {
+				i++
+				continue
+			}
+
 			blockInfo, alreadySendHistoryBlockHeight, err := s.getBlockInfoFromStore(store, i, withRWSet,
 				reqSender, reqSenderOrgId)
 
@@ -643,7 +884,7 @@ func (s *ApiService) sendHistoryBlock(store protocol.BlockchainStore, server api
 				return -1, errors.New(errMsg)
 			}
 
-			if err := server.Send(result); err != nil {
+			if err := cursor.send(server, result, uint64(i), -1, -1); err != nil {
 				errMsg = fmt.Sprintf("send block info by history failed, %s", err)
 				s.log.Error(errMsg)
 				return -1, status.Error(codes.Internal, errMsg)
@@ -728,7 +969,7 @@ func (s *ApiService) sendHistoryTx(store protocol.BlockchainStore,
 	server apiPb.RpcNode_SubscribeServer,
 	startBlockHeight, endBlockHeight int64,
 	contractName string, txIds []string, txIdsMap map[string]struct{},
-	reqSender protocol.Role, reqSenderOrgId string) (int64, error) {
+	reqSender protocol.Role, reqSenderOrgId string, cursor *subscribeCursor) (int64, error) {
 
 	var (
 		err    error
@@ -766,8 +1007,8 @@ func (s *ApiService) sendHistoryTx(store protocol.BlockchainStore,
 				return i - 1, nil
 			}
 
-			if err := s.sendSubscribeTx(server, block.Txs, contractName, txIds, txIdsMap,
-				reqSender, reqSenderOrgId); err != nil {
+			if err := s.sendSubscribeTx(server, block, contractName, txIds, txIdsMap,
+				reqSender, reqSenderOrgId, cursor); err != nil {
 				errMsg = fmt.Sprintf("send subscribe tx failed, %s", err)
 				s.log.Error(errMsg)
 				return -1, status.Error(codes.Internal, errMsg)
@@ -848,26 +1089,24 @@ func (s *ApiService) getContractEventSubscribeResult(contractEventsInfoList *com
 	return result, nil
 }
 func (s *ApiService) sendSubscribeTx(server apiPb.RpcNode_SubscribeServer,
-	txs []*commonPb.Transaction, contractName string, txIds []string,
-	txIdsMap map[string]struct{}, reqSender protocol.Role, reqSenderOrgId string) error {
+	block *commonPb.Block, contractName string, txIds []string,
+	txIdsMap map[string]struct{}, reqSender protocol.Role, reqSenderOrgId string, cursor *subscribeCursor) error {
 
 	var (
 		err error
 	)
 
-	for _, tx := range txs {
-		if contractName == "" && len(txIds) == 0 {
-			if err = s.doSendSubscribeTx(server, tx, reqSender, reqSenderOrgId); err != nil {
-				return err
-			}
+	for i, tx := range block.Txs {
+		if (contractName != "" || len(txIds) > 0) && s.checkIsContinue(tx, contractName, txIds, txIdsMap) {
 			continue
 		}
 
-		if s.checkIsContinue(tx, contractName, txIds, txIdsMap) {
+		if cursor.skip(block.Header.BlockHeight, i, -1) {
 			continue
 		}
 
-		if err = s.doSendSubscribeTx(server, tx, reqSender, reqSenderOrgId); err != nil {
+		if err = s.doSendSubscribeTx(server, tx, reqSender, reqSenderOrgId, cursor, block.Header.BlockHeight,
+			i); err != nil {
 			return err
 		}
 	}
@@ -895,7 +1134,7 @@ func (s *ApiService) checkIsContinue(tx *commonPb.Transaction, contractName stri
 }
 
 func (s *ApiService) doSendSubscribeTx(server apiPb.RpcNode_SubscribeServer, tx *commonPb.Transaction,
-	reqSender protocol.Role, reqSenderOrgId string) error {
+	reqSender protocol.Role, reqSenderOrgId string, cursor *subscribeCursor, blockHeight uint64, txIndex int) error {
 
 	var (
 		err    error
@@ -914,14 +1153,14 @@ func (s *ApiService) doSendSubscribeTx(server apiPb.RpcNode_SubscribeServer, tx
 
 	if isReqSenderLightNode {
 		if isTxRelatedToSender {
-			if err := server.Send(result); err != nil {
+			if err := cursor.send(server, result, blockHeight, txIndex, -1); err != nil {
 				errMsg = fmt.Sprintf("send subscribe tx result failed, %s", err)
 				s.log.Error(errMsg)
 				return errors.New(errMsg)
 			}
 		}
 	} else {
-		if err := server.Send(result); err != nil {
+		if err := cursor.send(server, result, blockHeight, txIndex, -1); err != nil {
 			errMsg = fmt.Sprintf("send subscribe tx result failed, %s", err)
 			s.log.Error(errMsg)
 			return errors.New(errMsg)
@@ -990,3 +1229,13 @@ func (s *ApiService) getRoleFromTx(tx *commonPb.Transaction) (protocol.Role, err
 	ac := bc.GetAccessControl()
 	return utils.GetRoleFromTx(tx, ac)
 }
+
+// getSubscriptionClosedError returns the error to end the stream with when the subscription is closed by the
+// event subscriber, which happens if the consumer is too slow or the chain is stopped
+func (s *ApiService) getSubscriptionClosedError(err error) error {
+	if err == nil {
+		return status.Error(codes.Internal, "chainmaker is restarting, please retry later")
+	}
+	s.log.Warn(err.Error())
+	return status.Error(codes.ResourceExhausted, err.Error())
+}
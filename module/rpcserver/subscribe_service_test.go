@@ -0,0 +1,105 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"context"
+	"testing"
+
+	"chainmaker.org/chainmaker/logger/v2"
+	apiPb "chainmaker.org/chainmaker/pb-go/v2/api"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+)
+
+type blockStore struct {
+	protocol.BlockchainStore
+	blocks map[uint64]*commonPb.Block
+}
+
+func (s *blockStore) GetBlock(height uint64) (*commonPb.Block, error) {
+	return s.blocks[height], nil
+}
+
+type subscribeServer struct {
+	apiPb.RpcNode_SubscribeServer
+	results []*commonPb.SubscribeResult
+}
+
+func (s *subscribeServer) Send(result *commonPb.SubscribeResult) error {
+	s.results = append(s.results, result)
+	return nil
+}
+
+// sentBlockHeights returns the heights of the contract events sent
+func (s *subscribeServer) sentBlockHeights(t *testing.T) []uint64 {
+	var heights []uint64
+	for _, result := range s.results {
+		eventInfoList := &commonPb.ContractEventInfoList{}
+		require.NoError(t, proto.Unmarshal(result.Data, eventInfoList))
+		for _, eventInfo := range eventInfoList.ContractEvents {
+			heights = append(heights, eventInfo.BlockHeight)
+		}
+	}
+	return heights
+}
+
+func createContractEventBlock(height uint64) *commonPb.Block {
+	return &commonPb.Block{
+		Header: &commonPb.BlockHeader{ChainId: "chain1", BlockHeight: height},
+		Txs: []*commonPb.Transaction{{
+			Result: &commonPb.Result{ContractResult: &commonPb.ContractResult{
+				ContractEvent: []*commonPb.ContractEvent{{ContractName: "contract1", Topic: "topic1"}},
+			}},
+		}},
+	}
+}
+
+func TestSendLiveContractEvent(t *testing.T) {
+	s := &ApiService{log: logger.GetLogger(logger.MODULE_RPC), ctx: context.Background()}
+	store := &blockStore{blocks: make(map[uint64]*commonPb.Block)}
+	for height := uint64(1); height <= 10; height++ {
+		store.blocks[height] = createContractEventBlock(height)
+	}
+	filter, err := newContractEventFilter("contract1", "topic1", nil)
+	require.NoError(t, err)
+	cursor := &subscribeCursor{chainId: "chain1"}
+
+	send := func(server *subscribeServer, height uint64, endBlock, lastSendBlockHeight int64) int64 {
+		last, err := s.sendLiveContractEvent(store, server, filter, store.blocks[height], endBlock,
+			lastSendBlockHeight, cursor)
+		require.NoError(t, err)
+		return last
+	}
+
+	// 1. the first live block is sent if no history is sent
+	server := &subscribeServer{}
+	require.EqualValues(t, 3, send(server, 3, -1, -1))
+	require.Equal(t, []uint64{3}, server.sentBlockHeights(t))
+
+	// 2. the blocks sent are not sent again
+	server = &subscribeServer{}
+	require.EqualValues(t, 5, send(server, 4, -1, 5))
+	require.Empty(t, server.results)
+
+	// 3. the missed blocks are read from the store before the new one
+	server = &subscribeServer{}
+	require.EqualValues(t, 6, send(server, 6, -1, 3))
+	require.Equal(t, []uint64{4, 5, 6}, server.sentBlockHeights(t))
+
+	// 4. the blocks after the end block are not sent
+	server = &subscribeServer{}
+	require.EqualValues(t, 8, send(server, 10, 8, 6))
+	require.Equal(t, []uint64{7, 8}, server.sentBlockHeights(t))
+
+	// 5. the subscription fails if the missed blocks are not found
+	delete(store.blocks, 8)
+	_, err = s.sendLiveContractEvent(store, &subscribeServer{}, filter, store.blocks[10], -1, 6, cursor)
+	require.Error(t, err)
+}
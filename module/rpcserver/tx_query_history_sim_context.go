@@ -0,0 +1,395 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	acPb "chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	storePb "chainmaker.org/chainmaker/pb-go/v2/store"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/utils/v2"
+)
+
+// historySelectMaxBlocks the max number of blocks after the queried height which Select scans for the keys
+// deleted since then, Select at an earlier height is rejected to bound the cost of a query
+const historySelectMaxBlocks = 1000
+
+var errHistoryDisabled = errors.New("the history of the keys is not stored, enable the history db of the node " +
+	"to query at a block height")
+
+// txQueryHistorySimContextImpl is a query sim context which reads the contract state
+// as it was after the block at blockHeight was committed, the state is rebuilt from
+// the key history of the store.
+type txQueryHistorySimContextImpl struct {
+	*txQuerySimContextImpl
+	blockHeight uint64
+	blockHeader *commonPb.BlockHeader
+}
+
+// newTxQueryHistorySimContext create a history sim context at blockHeight based on ctx
+func newTxQueryHistorySimContext(ctx *txQuerySimContextImpl,
+	blockHeight uint64) (*txQueryHistorySimContextImpl, error) {
+
+	header, err := ctx.blockchainStore.GetBlockHeaderByHeight(blockHeight)
+	if err != nil {
+		return nil, fmt.Errorf("get block header of height %d failed, %s", blockHeight, err.Error())
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block header of height %d not found", blockHeight)
+	}
+
+	return &txQueryHistorySimContextImpl{
+		txQuerySimContextImpl: ctx,
+		blockHeight:           blockHeight,
+		blockHeader:           header,
+	}, nil
+}
+
+// Get read the value of key at blockHeight
+func (s *txQueryHistorySimContextImpl) Get(contractName string, key []byte) ([]byte, error) {
+	// Get from write set
+	value, done := s.getFromWriteSet(contractName, key)
+	if done {
+		s.putIntoReadSet(contractName, key, value)
+		return value, nil
+	}
+
+	// Get from read set
+	value, done = s.getFromReadSet(contractName, key)
+	if done {
+		return value, nil
+	}
+
+	// Get from key history
+	value, err := s.readObjectAtHeight(contractName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.putIntoReadSet(contractName, key, value)
+	return value, nil
+}
+
+// Select iterate the keys in [startKey, limit) which exist at blockHeight
+func (s *txQueryHistorySimContextImpl) Select(contractName string, startKey []byte, limit []byte) (
+	protocol.StateIterator, error) {
+
+	keys, err := s.collectRangeKeys(contractName, startKey, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]*storePb.KV, 0, len(keys))
+	for _, key := range keys {
+		value, err := s.readObjectAtHeight(contractName, []byte(key))
+		if err != nil {
+			return nil, err
+		}
+		if len(value) == 0 {
+			continue
+		}
+		kvs = append(kvs, &storePb.KV{
+			ContractName: contractName,
+			Key:          []byte(key),
+			Value:        value,
+		})
+	}
+
+	return &kvListIterator{kvs: kvs}, nil
+}
+
+// GetHistoryIterForKey only return the modifications committed no later than blockHeight
+func (s *txQueryHistorySimContextImpl) GetHistoryIterForKey(contractName string,
+	key []byte) (protocol.KeyHistoryIterator, error) {
+
+	iter, err := s.blockchainStore.GetHistoryForKey(contractName, key)
+	if err != nil {
+		return nil, err
+	}
+	if iter == nil {
+		return nil, errHistoryDisabled
+	}
+	return &heightBoundKeyHistoryIterator{iter: iter, maxHeight: s.blockHeight}, nil
+}
+
+// GetBlockchainStore return the store which reads the kv state at blockHeight
+func (s *txQueryHistorySimContextImpl) GetBlockchainStore() protocol.BlockchainStore {
+	return &historyBlockchainStore{BlockchainStore: s.blockchainStore, ctx: s}
+}
+
+func (s *txQueryHistorySimContextImpl) GetBlockHeight() uint64 {
+	return s.blockHeight
+}
+
+func (s *txQueryHistorySimContextImpl) GetBlockProposer() *acPb.Member {
+	return s.blockHeader.Proposer
+}
+
+func (s *txQueryHistorySimContextImpl) CallContract(contract *commonPb.Contract, method string, byteCode []byte,
+	parameter map[string][]byte, gasUsed uint64, refTxType commonPb.TxType) (
+	*commonPb.ContractResult, protocol.ExecOrderTxType, commonPb.TxStatusCode) {
+	return s.callContract(s, contract, method, byteCode, parameter, gasUsed, refTxType)
+}
+
+// GetContractByName read the contract as it was at blockHeight, the native contracts are not versioned
+func (s *txQueryHistorySimContextImpl) GetContractByName(name string) (*commonPb.Contract, error) {
+	if _, ok := syscontract.SystemContract_value[name]; ok {
+		return s.blockchainStore.GetContractByName(name)
+	}
+	data, err := s.readObjectAtHeight(syscontract.SystemContract_CONTRACT_MANAGE.String(),
+		utils.GetContractDbKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("contract %s does not exist at block height %d", name, s.blockHeight)
+	}
+	contract := &commonPb.Contract{}
+	if err = contract.Unmarshal(data); err != nil {
+		return nil, fmt.Errorf("unmarshal contract %s failed, %s", name, err.Error())
+	}
+	return contract, nil
+}
+
+// GetContractBytecode read the bytecode of the contract version at blockHeight
+func (s *txQueryHistorySimContextImpl) GetContractBytecode(name string) ([]byte, error) {
+	bytecode, err := s.readObjectAtHeight(syscontract.SystemContract_CONTRACT_MANAGE.String(),
+		utils.GetContractByteCodeDbKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytecode) == 0 {
+		return nil, fmt.Errorf("bytecode of contract %s does not exist at block height %d", name, s.blockHeight)
+	}
+	return bytecode, nil
+}
+
+func (s *txQueryHistorySimContextImpl) GetCreator(contractName string) *acPb.Member {
+	contract, err := s.GetContractByName(contractName)
+	if err != nil {
+		return nil
+	}
+	return contract.Creator
+}
+
+// readObjectAtHeight return the value written by the last modification no later than blockHeight,
+// nil is returned if the key does not exist or has been deleted at that height
+func (s *txQueryHistorySimContextImpl) readObjectAtHeight(contractName string, key []byte) ([]byte, error) {
+	iter, err := s.blockchainStore.GetHistoryForKey(contractName, key)
+	if err != nil {
+		return nil, err
+	}
+	if iter == nil {
+		return nil, errHistoryDisabled
+	}
+	defer iter.Release()
+
+	// the modifications of the latest block no later than blockHeight
+	var latest []*storePb.KeyModification
+	for iter.Next() {
+		km, err := iter.Value()
+		if err != nil {
+			return nil, err
+		}
+		if km == nil || km.BlockHeight > s.blockHeight {
+			continue
+		}
+		if len(latest) == 0 || km.BlockHeight > latest[0].BlockHeight {
+			latest = []*storePb.KeyModification{km}
+		} else if km.BlockHeight == latest[0].BlockHeight {
+			latest = append(latest, km)
+		}
+	}
+	if len(latest) == 0 {
+		return nil, nil
+	}
+
+	km := latest[0]
+	if len(latest) > 1 {
+		if km, err = s.lastModificationInBlock(latest); err != nil {
+			return nil, err
+		}
+	}
+	if km.IsDelete {
+		return nil, nil
+	}
+	return km.Value, nil
+}
+
+// lastModificationInBlock return the modification of the last tx in the block among those of the same block,
+// the history of a block is not iterated in the order of its txs
+func (s *txQueryHistorySimContextImpl) lastModificationInBlock(kms []*storePb.KeyModification) (
+	*storePb.KeyModification, error) {
+
+	block, err := s.blockchainStore.GetBlock(kms[0].BlockHeight)
+	if err != nil {
+		return nil, fmt.Errorf("get block of height %d failed, %s", kms[0].BlockHeight, err.Error())
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block of height %d not found", kms[0].BlockHeight)
+	}
+	txIndexes := make(map[string]int, len(block.Txs))
+	for i, tx := range block.Txs {
+		txIndexes[tx.Payload.TxId] = i
+	}
+
+	last := kms[0]
+	for _, km := range kms[1:] {
+		if txIndexes[km.TxId] > txIndexes[last.TxId] {
+			last = km
+		}
+	}
+	return last, nil
+}
+
+// collectRangeKeys return the sorted keys in [startKey, limit) which may exist at blockHeight.
+// A key existing at blockHeight either still exists in the latest state, or has been
+// written by a block after blockHeight, so both sources are merged.
+func (s *txQueryHistorySimContextImpl) collectRangeKeys(contractName string, startKey []byte,
+	limit []byte) ([]string, error) {
+
+	lastBlock, err := s.blockchainStore.GetLastBlock()
+	if err != nil {
+		return nil, err
+	}
+	if lastBlock.Header.BlockHeight-s.blockHeight > historySelectMaxBlocks {
+		return nil, fmt.Errorf("select at block height %d is not supported, it is more than %d blocks behind "+
+			"the latest block %d", s.blockHeight, historySelectMaxBlocks, lastBlock.Header.BlockHeight)
+	}
+
+	keySet := make(map[string]struct{})
+	iter, err := s.blockchainStore.SelectObject(contractName, startKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	for iter.Next() {
+		kv, err := iter.Value()
+		if err != nil {
+			iter.Release()
+			return nil, err
+		}
+		keySet[string(kv.Key)] = struct{}{}
+	}
+	iter.Release()
+
+	for height := s.blockHeight + 1; height <= lastBlock.Header.BlockHeight; height++ {
+		txRWSets, err := s.blockchainStore.GetTxRWSetsByHeight(height)
+		if err != nil {
+			return nil, fmt.Errorf("get rwsets of height %d failed, %s", height, err.Error())
+		}
+		for _, txRWSet := range txRWSets {
+			for _, txWrite := range txRWSet.TxWrites {
+				if txWrite.ContractName != contractName {
+					continue
+				}
+				if bytes.Compare(txWrite.Key, startKey) < 0 || bytes.Compare(txWrite.Key, limit) >= 0 {
+					continue
+				}
+				keySet[string(txWrite.Key)] = struct{}{}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// historyBlockchainStore is the store seen by the contracts queried at a block height, it reads the kv state
+// at the height of ctx, the other data such as the blocks and the chain config are read from the latest state
+type historyBlockchainStore struct {
+	protocol.BlockchainStore
+	ctx *txQueryHistorySimContextImpl
+}
+
+func (s *historyBlockchainStore) ReadObject(contractName string, key []byte) ([]byte, error) {
+	return s.ctx.readObjectAtHeight(contractName, key)
+}
+
+func (s *historyBlockchainStore) SelectObject(contractName string, startKey []byte, limit []byte) (
+	protocol.StateIterator, error) {
+	return s.ctx.Select(contractName, startKey, limit)
+}
+
+func (s *historyBlockchainStore) GetContractByName(name string) (*commonPb.Contract, error) {
+	return s.ctx.GetContractByName(name)
+}
+
+func (s *historyBlockchainStore) GetContractBytecode(name string) ([]byte, error) {
+	return s.ctx.GetContractBytecode(name)
+}
+
+// kvListIterator iterate a list of kv in memory
+type kvListIterator struct {
+	kvs   []*storePb.KV
+	index int
+}
+
+func (i *kvListIterator) Next() bool {
+	if i.index < len(i.kvs) {
+		i.index++
+		return true
+	}
+	return false
+}
+
+func (i *kvListIterator) Value() (*storePb.KV, error) {
+	if i.index == 0 || i.index > len(i.kvs) {
+		return nil, errors.New("iterator is not positioned at a kv")
+	}
+	return i.kvs[i.index-1], nil
+}
+
+func (i *kvListIterator) Release() {
+	i.kvs = nil
+}
+
+// heightBoundKeyHistoryIterator skip the key modifications committed after maxHeight
+type heightBoundKeyHistoryIterator struct {
+	iter      protocol.KeyHistoryIterator
+	maxHeight uint64
+	current   *storePb.KeyModification
+	err       error
+}
+
+func (i *heightBoundKeyHistoryIterator) Next() bool {
+	if i.iter == nil {
+		return false
+	}
+	for i.iter.Next() {
+		km, err := i.iter.Value()
+		if err != nil {
+			i.current, i.err = nil, err
+			return true
+		}
+		if km != nil && km.BlockHeight <= i.maxHeight {
+			i.current, i.err = km, nil
+			return true
+		}
+	}
+	return false
+}
+
+func (i *heightBoundKeyHistoryIterator) Value() (*storePb.KeyModification, error) {
+	return i.current, i.err
+}
+
+func (i *heightBoundKeyHistoryIterator) Release() {
+	if i.iter != nil {
+		i.iter.Release()
+	}
+}
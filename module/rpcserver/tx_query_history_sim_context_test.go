@@ -0,0 +1,161 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rpcserver
+
+import (
+	"testing"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	storePb "chainmaker.org/chainmaker/pb-go/v2/store"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/protocol/v2/mock"
+	"chainmaker.org/chainmaker/utils/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeyHistoryIterator struct {
+	kms   []*storePb.KeyModification
+	index int
+}
+
+func (i *testKeyHistoryIterator) Next() bool {
+	i.index++
+	return i.index <= len(i.kms)
+}
+
+func (i *testKeyHistoryIterator) Value() (*storePb.KeyModification, error) {
+	return i.kms[i.index-1], nil
+}
+
+func (i *testKeyHistoryIterator) Release() {}
+
+func newTestHistorySimContext(store protocol.BlockchainStore, blockHeight uint64) *txQueryHistorySimContextImpl {
+	return &txQueryHistorySimContextImpl{
+		txQuerySimContextImpl: &txQuerySimContextImpl{
+			txReadKeyMap:    make(map[string]*commonPb.TxRead),
+			txWriteKeyMap:   make(map[string]*commonPb.TxWrite),
+			blockchainStore: store,
+		},
+		blockHeight: blockHeight,
+		blockHeader: &commonPb.BlockHeader{BlockHeight: blockHeight},
+	}
+}
+
+func TestHistoryGetOrdersSameBlockWritesByTx(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mock.NewMockBlockchainStore(ctrl)
+	// the history is ordered by tx id, tx "b" is committed before tx "a" in block 5
+	store.EXPECT().GetHistoryForKey("c1", []byte("k1")).DoAndReturn(
+		func(contractName string, key []byte) (protocol.KeyHistoryIterator, error) {
+			return &testKeyHistoryIterator{kms: []*storePb.KeyModification{
+				{TxId: "x", BlockHeight: 3, Value: []byte("v3")},
+				{TxId: "a", BlockHeight: 5, Value: []byte("v5a")},
+				{TxId: "b", BlockHeight: 5, Value: []byte("v5b")},
+				{TxId: "y", BlockHeight: 7, Value: []byte("v7")},
+			}}, nil
+		}).AnyTimes()
+	store.EXPECT().GetBlock(uint64(5)).Return(&commonPb.Block{Txs: []*commonPb.Transaction{
+		{Payload: &commonPb.Payload{TxId: "b"}},
+		{Payload: &commonPb.Payload{TxId: "a"}},
+	}}, nil).AnyTimes()
+
+	value, err := newTestHistorySimContext(store, 6).Get("c1", []byte("k1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v5a"), value)
+
+	value, err = newTestHistorySimContext(store, 4).Get("c1", []byte("k1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v3"), value)
+
+	value, err = newTestHistorySimContext(store, 2).Get("c1", []byte("k1"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestHistoryBlockchainStoreReadsAtHeight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mock.NewMockBlockchainStore(ctrl)
+	store.EXPECT().GetHistoryForKey("c1", []byte("k1")).DoAndReturn(
+		func(contractName string, key []byte) (protocol.KeyHistoryIterator, error) {
+			return &testKeyHistoryIterator{kms: []*storePb.KeyModification{
+				{TxId: "x", BlockHeight: 3, Value: []byte("v3")},
+				{TxId: "y", BlockHeight: 7, Value: []byte("v7")},
+			}}, nil
+		}).AnyTimes()
+	// the live state is never read by the contracts reading through the store
+	store.EXPECT().ReadObject(gomock.Any(), gomock.Any()).Times(0)
+
+	value, err := newTestHistorySimContext(store, 5).GetBlockchainStore().ReadObject("c1", []byte("k1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v3"), value)
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mock.NewMockBlockchainStore(ctrl)
+	store.EXPECT().GetHistoryForKey(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	simContext := newTestHistorySimContext(store, 1)
+	_, err := simContext.Get("c1", []byte("k1"))
+	require.Equal(t, errHistoryDisabled, err)
+	_, err = simContext.GetHistoryIterForKey("c1", []byte("k1"))
+	require.Equal(t, errHistoryDisabled, err)
+}
+
+func TestHistorySelectBounded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mock.NewMockBlockchainStore(ctrl)
+	store.EXPECT().GetLastBlock().Return(&commonPb.Block{
+		Header: &commonPb.BlockHeader{BlockHeight: historySelectMaxBlocks + 2}}, nil).AnyTimes()
+
+	_, err := newTestHistorySimContext(store, 1).Select("c1", []byte("a"), []byte("z"))
+	require.Error(t, err)
+}
+
+func TestHistoryContractAtHeight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mock.NewMockBlockchainStore(ctrl)
+	contractV1 := &commonPb.Contract{Name: "c1", Version: "1.0", RuntimeType: commonPb.RuntimeType_WASMER}
+	contractV2 := &commonPb.Contract{Name: "c1", Version: "2.0", RuntimeType: commonPb.RuntimeType_WASMER}
+	v1, err := contractV1.Marshal()
+	require.NoError(t, err)
+	v2, err := contractV2.Marshal()
+	require.NoError(t, err)
+	contractManage := syscontract.SystemContract_CONTRACT_MANAGE.String()
+	store.EXPECT().GetHistoryForKey(contractManage, utils.GetContractDbKey("c1")).DoAndReturn(
+		func(contractName string, key []byte) (protocol.KeyHistoryIterator, error) {
+			return &testKeyHistoryIterator{kms: []*storePb.KeyModification{
+				{TxId: "init", BlockHeight: 2, Value: v1},
+				{TxId: "upgrade", BlockHeight: 8, Value: v2},
+			}}, nil
+		}).AnyTimes()
+	store.EXPECT().GetHistoryForKey(contractManage, utils.GetContractByteCodeDbKey("c1")).DoAndReturn(
+		func(contractName string, key []byte) (protocol.KeyHistoryIterator, error) {
+			return &testKeyHistoryIterator{kms: []*storePb.KeyModification{
+				{TxId: "init", BlockHeight: 2, Value: []byte("code1")},
+				{TxId: "upgrade", BlockHeight: 8, Value: []byte("code2")},
+			}}, nil
+		}).AnyTimes()
+
+	simContext := newTestHistorySimContext(store, 5)
+	contract, err := simContext.GetContractByName("c1")
+	require.NoError(t, err)
+	require.Equal(t, "1.0", contract.Version)
+	bytecode, err := simContext.GetContractBytecode("c1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("code1"), bytecode)
+
+	_, err = newTestHistorySimContext(store, 1).GetContractByName("c1")
+	require.Error(t, err)
+}
@@ -254,6 +254,13 @@ func constructKey(contractName string, key []byte) string {
 func (s *txQuerySimContextImpl) CallContract(contract *commonPb.Contract, method string, byteCode []byte,
 	parameter map[string][]byte, gasUsed uint64, refTxType commonPb.TxType) (
 	*commonPb.ContractResult, protocol.ExecOrderTxType, commonPb.TxStatusCode) {
+	return s.callContract(s, contract, method, byteCode, parameter, gasUsed, refTxType)
+}
+
+// callContract run the contract with simContext, which is the outermost sim context wrapping s
+func (s *txQuerySimContextImpl) callContract(simContext protocol.TxSimContext, contract *commonPb.Contract,
+	method string, byteCode []byte, parameter map[string][]byte, gasUsed uint64, refTxType commonPb.TxType) (
+	*commonPb.ContractResult, protocol.ExecOrderTxType, commonPb.TxStatusCode) {
 	s.gasUsed = gasUsed
 	s.currentDepth = s.currentDepth + 1
 	if s.currentDepth > protocol.CallContractDepth {
@@ -273,13 +280,14 @@ func (s *txQuerySimContextImpl) CallContract(contract *commonPb.Contract, method
 		return contractResult, protocol.ExecOrderTxTypeNormal, commonPb.TxStatusCode_CONTRACT_FAIL
 	}
 	if len(byteCode) == 0 {
-		dbByteCode, err := s.GetContractBytecode(contract.Name)
+		dbByteCode, err := simContext.GetContractBytecode(contract.Name)
 		if err != nil {
 			return nil, protocol.ExecOrderTxTypeNormal, commonPb.TxStatusCode_CONTRACT_FAIL
 		}
 		byteCode = dbByteCode
 	}
-	r, specialTxType, code := s.vmManager.RunContract(contract, method, byteCode, parameter, s, s.gasUsed, refTxType)
+	r, specialTxType, code := s.vmManager.RunContract(contract, method, byteCode, parameter, simContext, s.gasUsed,
+		refTxType)
 
 	result := callContractResult{
 		depth:        s.currentDepth,
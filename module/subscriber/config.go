@@ -0,0 +1,86 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package subscriber
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// SlowConsumerPolicyUnbounded the events are buffered without limit for the slow consumer, so that no event
+	// is dropped as before the buffers were bounded, at the cost of the memory
+	SlowConsumerPolicyUnbounded = "unbounded"
+	// SlowConsumerPolicyDrop the events are dropped while the buffer of the subscription is full
+	SlowConsumerPolicyDrop = "drop"
+	// SlowConsumerPolicyDisconnect the subscription is closed with ErrSlowConsumer once its buffer is full
+	SlowConsumerPolicyDisconnect = "disconnect"
+	// SlowConsumerPolicyBlock the dispatcher waits for the slow consumer at most BlockTimeout, which holds back
+	// the other subscriptions of the chain, and then closes the subscription with ErrSlowConsumer
+	SlowConsumerPolicyBlock = "block"
+
+	defaultQueueSize    = 1024
+	defaultBufferSize   = 128
+	defaultBlockTimeout = 1000
+)
+
+// Config the config of the event dispatcher, subscriber in chainmaker.yml
+type Config struct {
+	// QueueSize the size of the event queue of a chain. Default is 1024.
+	QueueSize int `mapstructure:"queue_size"`
+	// BufferSize the size of the event buffer of a subscription, except for the unbounded policy. Default is 128.
+	BufferSize int `mapstructure:"buffer_size"`
+	// SlowConsumerPolicy what to do when the buffer of a subscription is full, unbounded, drop, disconnect
+	// or block. Default is unbounded.
+	SlowConsumerPolicy string `mapstructure:"slow_consumer_policy"`
+	// BlockTimeout milliseconds waiting for a slow consumer with the block policy. Default is 1000.
+	BlockTimeout int `mapstructure:"block_timeout"`
+}
+
+var (
+	dispatcherConfig   = newDefaultConfig()
+	dispatcherConfigMu sync.RWMutex
+)
+
+func newDefaultConfig() *Config {
+	return &Config{
+		QueueSize:          defaultQueueSize,
+		BufferSize:         defaultBufferSize,
+		SlowConsumerPolicy: SlowConsumerPolicyUnbounded,
+		BlockTimeout:       defaultBlockTimeout,
+	}
+}
+
+// SetConfig sets the config of the event dispatcher for the chains started after it
+func SetConfig(c *Config) error {
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultQueueSize
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = defaultBufferSize
+	}
+	if c.BlockTimeout <= 0 {
+		c.BlockTimeout = defaultBlockTimeout
+	}
+	switch c.SlowConsumerPolicy {
+	case "":
+		c.SlowConsumerPolicy = SlowConsumerPolicyUnbounded
+	case SlowConsumerPolicyUnbounded, SlowConsumerPolicyDrop, SlowConsumerPolicyDisconnect, SlowConsumerPolicyBlock:
+	default:
+		return fmt.Errorf("unknown slow consumer policy %s", c.SlowConsumerPolicy)
+	}
+	dispatcherConfigMu.Lock()
+	defer dispatcherConfigMu.Unlock()
+	dispatcherConfig = c
+	return nil
+}
+
+func getConfig() *Config {
+	dispatcherConfigMu.RLock()
+	defer dispatcherConfigMu.RUnlock()
+	return dispatcherConfig
+}
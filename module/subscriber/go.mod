@@ -4,7 +4,11 @@ go 1.15
 
 require (
 	chainmaker.org/chainmaker/common/v2 v2.1.0
+	chainmaker.org/chainmaker/localconf/v2 v2.1.0
+	chainmaker.org/chainmaker/logger/v2 v2.1.0
 	chainmaker.org/chainmaker/pb-go/v2 v2.1.0
+	chainmaker.org/chainmaker/protocol/v2 v2.1.1
 	github.com/aristanetworks/goarista v0.0.0-20201012165903-2cb20defcd66 // indirect
 	github.com/ethereum/go-ethereum v1.9.25
+	github.com/prometheus/client_golang v1.11.0
 )
@@ -0,0 +1,169 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// The types of sinks
+const (
+	SinkTypeWebhook = "webhook"
+	SinkTypeQueue   = "queue"
+)
+
+const (
+	defaultSpoolDir         = "../data/event_sinks"
+	defaultMaxRetries       = 10
+	defaultRetryInterval    = 1000 // ms
+	defaultMaxRetryInterval = 60000
+	defaultMaxPending       = 10000
+	defaultWebhookTimeout   = 5000 // ms
+)
+
+// Config the event sinks of the node, subscriber.event_sinks in chainmaker.yml
+type Config struct {
+	// SpoolDir the directory of the notifications waiting to be delivered and the dead-letter files,
+	// default is ../data/event_sinks
+	SpoolDir string `mapstructure:"spool_dir"`
+	// Sinks the sinks the notifications are pushed to
+	Sinks []*SinkConfig `mapstructure:"sinks"`
+}
+
+// SinkConfig the config of a sink
+type SinkConfig struct {
+	// Name the unique name of the sink
+	Name string `mapstructure:"name"`
+	// Type webhook or queue
+	Type string `mapstructure:"type"`
+	// ChainIds the chains whose notifications are pushed, all chains if it is empty
+	ChainIds []string `mapstructure:"chain_ids"`
+	// Events the types of the notifications pushed, block, tx or contract_event, all types if it is empty
+	Events []string `mapstructure:"events"`
+	// ContractNames only the txs and contract events of the contracts are pushed if it is not empty
+	ContractNames []string `mapstructure:"contract_names"`
+	// Topics only the contract events of the topics are pushed if it is not empty
+	Topics []string `mapstructure:"topics"`
+	// MaxRetries the notification is written to the dead-letter file after failing to deliver the times,
+	// -1 means retrying forever. Default is 10.
+	MaxRetries int `mapstructure:"max_retries"`
+	// RetryInterval the interval before the first retry in milliseconds, it is doubled for each retry
+	// up to one minute. Default is 1000.
+	RetryInterval int `mapstructure:"retry_interval"`
+	// MaxPending the block events wait while so many notifications are waiting to be delivered.
+	// Default is 10000.
+	MaxPending int `mapstructure:"max_pending"`
+
+	Webhook WebhookConfig `mapstructure:"webhook"`
+	Queue   QueueConfig   `mapstructure:"queue"`
+}
+
+// WebhookConfig the config of a webhook sink
+type WebhookConfig struct {
+	// URL the notifications are posted to
+	URL string `mapstructure:"url"`
+	// Secret the key of the HMAC-SHA256 signature in the X-ChainMaker-Signature header, not signed if it is empty
+	Secret string `mapstructure:"secret"`
+	// Timeout of a request in milliseconds. Default is 5000.
+	Timeout int `mapstructure:"timeout"`
+}
+
+// QueueConfig the config of a queue sink
+type QueueConfig struct {
+	// Provider the name of the queue provider registered by RegisterQueueProvider
+	Provider string `mapstructure:"provider"`
+	// Topic the notifications are published to
+	Topic string `mapstructure:"topic"`
+	// Options passed to the queue provider
+	Options map[string]string `mapstructure:"options"`
+}
+
+var (
+	sinkConfig   = &Config{SpoolDir: defaultSpoolDir}
+	sinkConfigMu sync.RWMutex
+)
+
+// SetConfig sets the config of the event sinks for the chains started after it
+func SetConfig(config *Config) error {
+	if config.SpoolDir == "" {
+		config.SpoolDir = defaultSpoolDir
+	}
+	names := make(map[string]struct{})
+	for _, sc := range config.Sinks {
+		if sc == nil || sc.Name == "" {
+			return errors.New("the name of the sink is empty")
+		}
+		if _, ok := names[sc.Name]; ok {
+			return fmt.Errorf("duplicate sink %s", sc.Name)
+		}
+		names[sc.Name] = struct{}{}
+		if err := sc.check(); err != nil {
+			return fmt.Errorf("invalid sink %s, %s", sc.Name, err)
+		}
+	}
+	sinkConfigMu.Lock()
+	defer sinkConfigMu.Unlock()
+	sinkConfig = config
+	return nil
+}
+
+func getConfig() *Config {
+	sinkConfigMu.RLock()
+	defer sinkConfigMu.RUnlock()
+	return sinkConfig
+}
+
+// check checks the config and sets the defaults
+func (c *SinkConfig) check() error {
+	switch c.Type {
+	case SinkTypeWebhook:
+		if c.Webhook.URL == "" {
+			return errors.New("webhook url is empty")
+		}
+		if c.Webhook.Timeout <= 0 {
+			c.Webhook.Timeout = defaultWebhookTimeout
+		}
+	case SinkTypeQueue:
+		if c.Queue.Provider == "" {
+			return errors.New("queue provider is empty")
+		}
+	default:
+		return fmt.Errorf("unknown type %s", c.Type)
+	}
+	for _, event := range c.Events {
+		switch event {
+		case NotificationTypeBlock, NotificationTypeTx, NotificationTypeContractEvent:
+		default:
+			return fmt.Errorf("unknown event %s", event)
+		}
+	}
+	if c.MaxRetries == 0 || c.MaxRetries < -1 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = defaultRetryInterval
+	}
+	if c.MaxPending <= 0 {
+		c.MaxPending = defaultMaxPending
+	}
+	return nil
+}
+
+// acceptChain returns whether the notifications of the chain are pushed to the sink
+func (c *SinkConfig) acceptChain(chainId string) bool {
+	if len(c.ChainIds) == 0 {
+		return true
+	}
+	for _, id := range c.ChainIds {
+		if id == chainId {
+			return true
+		}
+	}
+	return false
+}
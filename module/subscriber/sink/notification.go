@@ -0,0 +1,162 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// The types of notifications
+const (
+	NotificationTypeBlock         = "block"
+	NotificationTypeTx            = "tx"
+	NotificationTypeContractEvent = "contract_event"
+)
+
+// Notification a notification pushed to the sinks, encoded as json
+type Notification struct {
+	// Id chainId:blockHeight:txIndex:eventIndex, which is unique for each notification, the receivers can
+	// drop the duplicated ones by it. The indexes are -1 for the notifications of blocks and txs.
+	Id          string `json:"id"`
+	Type        string `json:"type"`
+	ChainId     string `json:"chain_id"`
+	BlockHeight uint64 `json:"block_height"`
+
+	// block
+	BlockHash      string `json:"block_hash,omitempty"`
+	BlockTimestamp int64  `json:"block_timestamp,omitempty"`
+	TxCount        uint32 `json:"tx_count,omitempty"`
+
+	// tx and contract event
+	TxId         string `json:"tx_id,omitempty"`
+	ContractName string `json:"contract_name,omitempty"`
+
+	// tx
+	Method   string `json:"method,omitempty"`
+	TxStatus string `json:"tx_status,omitempty"`
+
+	// contract event
+	ContractVersion string   `json:"contract_version,omitempty"`
+	Topic           string   `json:"topic,omitempty"`
+	EventData       []string `json:"event_data,omitempty"`
+}
+
+func notificationId(chainId string, blockHeight uint64, txIndex, eventIndex int) string {
+	return fmt.Sprintf("%s:%d:%d:%d", chainId, blockHeight, txIndex, eventIndex)
+}
+
+// filter selects the notifications of a sink
+type filter struct {
+	events        map[string]struct{} // nil means all types
+	contractNames map[string]struct{} // nil means all contracts
+	topics        map[string]struct{} // nil means all topics
+}
+
+func newFilter(config *SinkConfig) *filter {
+	toSet := func(list []string) map[string]struct{} {
+		if len(list) == 0 {
+			return nil
+		}
+		set := make(map[string]struct{}, len(list))
+		for _, item := range list {
+			set[item] = struct{}{}
+		}
+		return set
+	}
+	return &filter{
+		events:        toSet(config.Events),
+		contractNames: toSet(config.ContractNames),
+		topics:        toSet(config.Topics),
+	}
+}
+
+func (f *filter) acceptType(notificationType string) bool {
+	if f.events == nil {
+		return true
+	}
+	_, ok := f.events[notificationType]
+	return ok
+}
+
+func (f *filter) acceptContract(contractName string) bool {
+	if f.contractNames == nil {
+		return true
+	}
+	_, ok := f.contractNames[contractName]
+	return ok
+}
+
+func (f *filter) acceptTopic(topic string) bool {
+	if f.topics == nil {
+		return true
+	}
+	_, ok := f.topics[topic]
+	return ok
+}
+
+// notificationsOf returns the notifications of the block selected by the filter, in the order of the block,
+// its txs and their contract events
+func (f *filter) notificationsOf(block *commonPb.Block) []*Notification {
+	var (
+		header        = block.Header
+		notifications []*Notification
+	)
+	if f.acceptType(NotificationTypeBlock) {
+		notifications = append(notifications, &Notification{
+			Id:             notificationId(header.ChainId, header.BlockHeight, -1, -1),
+			Type:           NotificationTypeBlock,
+			ChainId:        header.ChainId,
+			BlockHeight:    header.BlockHeight,
+			BlockHash:      hex.EncodeToString(header.BlockHash),
+			BlockTimestamp: header.BlockTimestamp,
+			TxCount:        header.TxCount,
+		})
+	}
+	for i, tx := range block.Txs {
+		if tx.Payload == nil {
+			continue
+		}
+		if f.acceptType(NotificationTypeTx) && f.acceptContract(tx.Payload.ContractName) {
+			n := &Notification{
+				Id:           notificationId(header.ChainId, header.BlockHeight, i, -1),
+				Type:         NotificationTypeTx,
+				ChainId:      header.ChainId,
+				BlockHeight:  header.BlockHeight,
+				TxId:         tx.Payload.TxId,
+				ContractName: tx.Payload.ContractName,
+				Method:       tx.Payload.Method,
+			}
+			if tx.Result != nil {
+				n.TxStatus = tx.Result.Code.String()
+			}
+			notifications = append(notifications, n)
+		}
+		if !f.acceptType(NotificationTypeContractEvent) || tx.Result == nil || tx.Result.ContractResult == nil {
+			continue
+		}
+		for j, event := range tx.Result.ContractResult.ContractEvent {
+			if !f.acceptContract(event.ContractName) || !f.acceptTopic(event.Topic) {
+				continue
+			}
+			notifications = append(notifications, &Notification{
+				Id:              notificationId(header.ChainId, header.BlockHeight, i, j),
+				Type:            NotificationTypeContractEvent,
+				ChainId:         header.ChainId,
+				BlockHeight:     header.BlockHeight,
+				TxId:            event.TxId,
+				ContractName:    event.ContractName,
+				ContractVersion: event.ContractVersion,
+				Topic:           event.Topic,
+				EventData:       event.EventData,
+			})
+		}
+	}
+	return notifications
+}
@@ -0,0 +1,65 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Queue a message queue the notifications are published to, such as kafka or rabbitmq
+type Queue interface {
+	// Publish publishes the json of a notification to the topic, the key is the id of the notification.
+	// It returns after the queue has accepted the message, an error makes the notification retried.
+	Publish(topic, key string, value []byte) error
+	// Close closes the queue when the sink stops
+	Close() error
+}
+
+// QueueProvider creates a queue with the options of the sink config
+type QueueProvider func(options map[string]string) (Queue, error)
+
+var (
+	queueProviders   = make(map[string]QueueProvider)
+	queueProvidersMu sync.RWMutex
+)
+
+// RegisterQueueProvider registers the provider of a kind of queue, which is referred by queue.provider of
+// the sink config. It should be called before the chains start, e.g. in an init function.
+func RegisterQueueProvider(name string, provider QueueProvider) {
+	queueProvidersMu.Lock()
+	defer queueProvidersMu.Unlock()
+	queueProviders[name] = provider
+}
+
+// queueSink publishes the notifications to a queue
+type queueSink struct {
+	queue Queue
+	topic string
+}
+
+func newQueueSink(config *QueueConfig) (*queueSink, error) {
+	queueProvidersMu.RLock()
+	provider, ok := queueProviders[config.Provider]
+	queueProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("queue provider %s is not registered", config.Provider)
+	}
+	queue, err := provider(config.Options)
+	if err != nil {
+		return nil, fmt.Errorf("create queue of %s failed, %s", config.Provider, err)
+	}
+	return &queueSink{queue: queue, topic: config.Topic}, nil
+}
+
+func (q *queueSink) deliver(n *Notification, body []byte) error {
+	return q.queue.Publish(q.topic, n.Id, body)
+}
+
+func (q *queueSink) close() error {
+	return q.queue.Close()
+}
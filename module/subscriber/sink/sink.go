@@ -0,0 +1,298 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"chainmaker.org/chainmaker-go/subscriber/model"
+	"chainmaker.org/chainmaker/common/v2/monitor"
+	"chainmaker.org/chainmaker/localconf/v2"
+	"chainmaker.org/chainmaker/logger/v2"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
+	feed "github.com/ethereum/go-ethereum/event"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The results of the deliveries counted by the metric
+const (
+	deliveryDelivered  = "delivered"
+	deliveryRetried    = "retried"
+	deliveryDeadLetter = "dead_letter"
+)
+
+var (
+	metricDeliveryCounter     *prometheus.CounterVec
+	metricDeliveryCounterOnce sync.Once
+)
+
+// BlockEventSource the source of the block events, it is subscriber.EventSubscriber
+type BlockEventSource interface {
+	SubscribeBlockEvent(ch chan<- model.NewBlockEvent) feed.Subscription
+}
+
+// BlockStore the store the blocks missed by the sink are read from, it is protocol.BlockchainStore
+type BlockStore interface {
+	GetBlock(height uint64) (*commonPb.Block, error)
+}
+
+// deliverer delivers the notifications to the destination of a sink
+type deliverer interface {
+	deliver(n *Notification, body []byte) error
+	close() error
+}
+
+// Sink pushes the notifications of a chain to a webhook or a queue in order. The notifications are spooled on disk
+// before being delivered, and retried until delivered or written to the dead-letter file. The blocks committed
+// while the sink is disconnected or stopped are read from the store, so that no block is missed. A notification
+// may be delivered again after a failure, the id of the notification tells the duplicates.
+type Sink struct {
+	chainId   string
+	config    *SinkConfig
+	log       protocol.Logger
+	filter    *filter
+	deliverer deliverer
+	spool     *spool
+	store     BlockStore
+
+	signalC chan struct{}
+	quitC   chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+// StartSinks starts the sinks configured for the chain, they stop when the source quits
+func StartSinks(chainId string, source BlockEventSource, store BlockStore) ([]*Sink, error) {
+	config := getConfig()
+	sinks := make([]*Sink, 0, len(config.Sinks))
+	for _, sinkConfig := range config.Sinks {
+		if !sinkConfig.acceptChain(chainId) {
+			continue
+		}
+		s, err := NewSink(chainId, sinkConfig, filepath.Join(config.SpoolDir, chainId, sinkConfig.Name),
+			logger.GetLoggerByChain(logger.MODULE_RPC, chainId))
+		if err != nil {
+			for _, started := range sinks {
+				started.Stop()
+			}
+			return nil, err
+		}
+		s.Start(source, store)
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// NewSink creates a sink of the chain, the notifications are spooled in the directory
+func NewSink(chainId string, config *SinkConfig, dir string, log protocol.Logger) (*Sink, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		chainId: chainId,
+		config:  config,
+		log:     log,
+		filter:  newFilter(config),
+		signalC: make(chan struct{}, 1),
+		quitC:   make(chan struct{}),
+	}
+	var err error
+	if config.Type == SinkTypeQueue {
+		if s.deliverer, err = newQueueSink(&config.Queue); err != nil {
+			return nil, err
+		}
+	} else {
+		s.deliverer = newWebhook(&config.Webhook)
+	}
+	if s.spool, err = openSpool(dir, config.MaxPending); err != nil {
+		_ = s.deliverer.close()
+		return nil, err
+	}
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		metricDeliveryCounterOnce.Do(func() {
+			metricDeliveryCounter = monitor.NewCounterVec(monitor.SUBSYSTEM_RPCSERVER,
+				"metric_event_sink_delivery_counter", "deliveries of the notifications to the event sinks",
+				"chainId", "sink", "result")
+		})
+	}
+	return s, nil
+}
+
+// Start subscribes the block events of the source and delivers the notifications left by the last run,
+// the blocks missed since the last run are read from the store
+func (s *Sink) Start(source BlockEventSource, store BlockStore) {
+	s.log.Infof("start event sink %s, %d notifications pending", s.config.Name, s.spool.size())
+	s.store = store
+	s.wg.Add(2)
+	go s.receive(source)
+	go s.deliver()
+}
+
+// Stop stops the sink, the notifications not delivered are kept in the spool
+func (s *Sink) Stop() {
+	s.once.Do(func() {
+		close(s.quitC)
+	})
+	s.wg.Wait()
+}
+
+// Push spools the notifications of the block event selected by the filter of the sink. The blocks between the
+// last one spooled and the block are spooled first, and the block spooled before is ignored. It waits while the
+// spool is full, which holds back the block events, until the sink stops.
+func (s *Sink) Push(ev model.NewBlockEvent) error {
+	block := ev.BlockInfo.Block
+	height := block.Header.BlockHeight
+	lastHeight, ok := s.spool.lastBlockHeight()
+	if ok && height <= lastHeight {
+		return nil
+	}
+	if ok && height > lastHeight+1 {
+		s.log.Infof("event sink %s spools the missed blocks [%d, %d] from the store", s.config.Name,
+			lastHeight+1, height-1)
+		for missedHeight := lastHeight + 1; missedHeight < height; missedHeight++ {
+			missed, err := s.getBlock(missedHeight)
+			if err != nil {
+				return err
+			}
+			if err = s.spoolBlock(missed); err != nil {
+				return err
+			}
+		}
+	}
+	return s.spoolBlock(block)
+}
+
+func (s *Sink) getBlock(height uint64) (*commonPb.Block, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("no store to read the missed block %d from", height)
+	}
+	block, err := s.store.GetBlock(height)
+	if err != nil {
+		return nil, fmt.Errorf("get the missed block %d failed, %s", height, err)
+	}
+	if block == nil {
+		return nil, fmt.Errorf("the missed block %d is not found", height)
+	}
+	return block, nil
+}
+
+// spoolBlock spools the notifications of the block, and then records the height of the block
+func (s *Sink) spoolBlock(block *commonPb.Block) error {
+	defer signal(s.signalC)
+	for _, n := range s.filter.notificationsOf(block) {
+		if err := s.spool.push(n, s.quitC); err == errSinkStopped {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("spool notification %s failed, %s", n.Id, err)
+		}
+	}
+	return s.spool.setLastBlockHeight(block.Header.BlockHeight)
+}
+
+func (s *Sink) receive(source BlockEventSource) {
+	defer s.wg.Done()
+	blockCh := make(chan model.NewBlockEvent)
+	sub := source.SubscribeBlockEvent(blockCh)
+	for {
+		select {
+		case ev := <-blockCh:
+			if err := s.Push(ev); err == errSinkStopped {
+				return
+			} else if err != nil {
+				// the block is spooled again from the store with the next block event
+				s.log.Errorf("event sink %s failed to spool block %d, %s", s.config.Name,
+					ev.BlockInfo.Block.Header.BlockHeight, err)
+			}
+		case err := <-sub.Err():
+			if err == nil {
+				// the source quits
+				s.once.Do(func() {
+					close(s.quitC)
+				})
+				return
+			}
+			s.log.Warnf("event sink %s is disconnected, subscribe again and read the missed blocks "+
+				"from the store, %s", s.config.Name, err)
+			sub = source.SubscribeBlockEvent(blockCh)
+		case <-s.quitC:
+			sub.Unsubscribe()
+			return
+		}
+	}
+}
+
+// deliver delivers the spooled notifications one by one, a failed one is retried before the later ones
+func (s *Sink) deliver() {
+	defer s.wg.Done()
+	defer func() {
+		if err := s.deliverer.close(); err != nil {
+			s.log.Warnf("event sink %s failed to close, %s", s.config.Name, err)
+		}
+	}()
+	for {
+		entry := s.spool.head()
+		if entry == nil {
+			select {
+			case <-s.signalC:
+				continue
+			case <-s.quitC:
+				return
+			}
+		}
+
+		err := s.deliverer.deliver(entry.notification, entry.body)
+		if err == nil {
+			if err = s.spool.remove(entry); err != nil {
+				s.log.Warnf("event sink %s failed to remove notification %s, %s", s.config.Name,
+					entry.notification.Id, err)
+			}
+			s.count(deliveryDelivered)
+			continue
+		}
+
+		entry.attempts++
+		if s.config.MaxRetries != -1 && entry.attempts > s.config.MaxRetries {
+			s.log.Errorf("event sink %s failed to deliver notification %s after %d attempts, "+
+				"write it to the dead-letter file, %s", s.config.Name, entry.notification.Id, entry.attempts, err)
+			if err = s.spool.kill(entry, err.Error()); err != nil {
+				s.log.Errorf("event sink %s failed to write the dead-letter file, %s", s.config.Name, err)
+			}
+			s.count(deliveryDeadLetter)
+			continue
+		}
+		s.log.Warnf("event sink %s failed to deliver notification %s, attempts %d, %s", s.config.Name,
+			entry.notification.Id, entry.attempts, err)
+		s.count(deliveryRetried)
+		select {
+		case <-time.After(s.retryInterval(entry.attempts)):
+		case <-s.quitC:
+			return
+		}
+	}
+}
+
+// retryInterval doubles the interval for each attempt, up to one minute
+func (s *Sink) retryInterval(attempts int) time.Duration {
+	interval := s.config.RetryInterval
+	for i := 1; i < attempts && interval < defaultMaxRetryInterval; i++ {
+		interval *= 2
+	}
+	if interval > defaultMaxRetryInterval {
+		interval = defaultMaxRetryInterval
+	}
+	return time.Duration(interval) * time.Millisecond
+}
+
+func (s *Sink) count(result string) {
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		metricDeliveryCounter.WithLabelValues(s.chainId, s.config.Name, result).Inc()
+	}
+}
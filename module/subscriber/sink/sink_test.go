@@ -0,0 +1,440 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"chainmaker.org/chainmaker-go/subscriber/model"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2/test"
+	feed "github.com/ethereum/go-ethereum/event"
+)
+
+const testChainId = "chain1"
+
+type testSource struct {
+	feed feed.Feed
+}
+
+func (s *testSource) SubscribeBlockEvent(ch chan<- model.NewBlockEvent) feed.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// publish waits for the sink to subscribe before sending the block
+func (s *testSource) publish(t *testing.T, block *commonPb.Block) {
+	ev := model.NewBlockEvent{BlockInfo: &commonPb.BlockInfo{Block: block}}
+	for i := 0; i < 100; i++ {
+		if s.feed.Send(ev) > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no subscriber of the block events")
+}
+
+func newTestBlock(height uint64) *commonPb.Block {
+	return &commonPb.Block{
+		Header: &commonPb.BlockHeader{ChainId: testChainId, BlockHeight: height, TxCount: 2},
+		Txs: []*commonPb.Transaction{
+			{
+				Payload: &commonPb.Payload{TxId: "tx1", ContractName: "c1", Method: "m1"},
+				Result: &commonPb.Result{ContractResult: &commonPb.ContractResult{
+					ContractEvent: []*commonPb.ContractEvent{
+						{TxId: "tx1", ContractName: "c1", Topic: "t1", EventData: []string{"a"}},
+						{TxId: "tx1", ContractName: "c2", Topic: "t2", EventData: []string{"b"}},
+					},
+				}},
+			},
+			{
+				Payload: &commonPb.Payload{TxId: "tx2", ContractName: "c2", Method: "m2"},
+			},
+		},
+	}
+}
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "event_sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timeout")
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		lock      sync.Mutex
+		requests  int
+		delivered []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp := r.Header.Get(HeaderTimestamp)
+		if r.Header.Get(HeaderSignature) != Sign("secret", timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := &Notification{}
+		if err := json.Unmarshal(body, n); err != nil || n.Id != r.Header.Get(HeaderDelivery) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		delivered = append(delivered, n.Id)
+	}))
+	defer server.Close()
+
+	config := &SinkConfig{
+		Name:          "webhook1",
+		Type:          SinkTypeWebhook,
+		Events:        []string{NotificationTypeBlock, NotificationTypeContractEvent},
+		RetryInterval: 1,
+		Webhook:       WebhookConfig{URL: server.URL, Secret: "secret"},
+	}
+	s, err := NewSink(testChainId, config, newTestDir(t), &test.GoLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &testSource{}
+	s.Start(source, nil)
+	defer s.Stop()
+	source.publish(t, newTestBlock(1))
+	source.publish(t, newTestBlock(2))
+
+	expected := []string{"chain1:1:-1:-1", "chain1:1:0:0", "chain1:1:0:1",
+		"chain1:2:-1:-1", "chain1:2:0:0", "chain1:2:0:1"}
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(delivered) == len(expected)
+	})
+	for i, id := range expected {
+		if delivered[i] != id {
+			t.Fatalf("delivered %v, expected %v", delivered, expected)
+		}
+	}
+	if s.spool.size() != 0 {
+		t.Fatalf("%d notifications left in the spool", s.spool.size())
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dir := newTestDir(t)
+	config := &SinkConfig{
+		Name:          "webhook1",
+		Type:          SinkTypeWebhook,
+		Events:        []string{NotificationTypeTx},
+		ContractNames: []string{"c2"},
+		MaxRetries:    2,
+		RetryInterval: 1,
+		Webhook:       WebhookConfig{URL: server.URL},
+	}
+	s, err := NewSink(testChainId, config, dir, &test.GoLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start(&testSource{}, nil)
+	defer s.Stop()
+	if err = s.Push(model.NewBlockEvent{BlockInfo: &commonPb.BlockInfo{Block: newTestBlock(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return s.spool.size() == 0
+	})
+
+	file, err := os.Open(filepath.Join(dir, deadLetterFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var letters []*deadLetter
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		letter := &deadLetter{}
+		if err = json.Unmarshal(scanner.Bytes(), letter); err != nil {
+			t.Fatal(err)
+		}
+		letters = append(letters, letter)
+	}
+	if len(letters) != 1 || letters[0].Notification.Id != "chain1:1:1:-1" || letters[0].Attempts != 3 {
+		t.Fatalf("unexpected dead letters %+v", letters)
+	}
+}
+
+func TestSpoolReload(t *testing.T) {
+	dir := newTestDir(t)
+	sp, err := openSpool(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			// keep the seq growing after the first ones are delivered
+			if err = sp.remove(sp.head()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = sp.push(&Notification{Id: notificationId(testChainId, uint64(i), -1, -1)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sp.setLastBlockHeight(2); err != nil {
+		t.Fatal(err)
+	}
+
+	sp, err = openSpool(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.size() != 2 || sp.head().notification.Id != "chain1:1:-1:-1" || sp.nextSeq != 3 {
+		t.Fatalf("unexpected spool, size %d, next seq %d", sp.size(), sp.nextSeq)
+	}
+	if height, ok := sp.lastBlockHeight(); !ok || height != 2 {
+		t.Fatalf("unexpected last block height %d", height)
+	}
+	if err = sp.remove(sp.head()); err != nil {
+		t.Fatal(err)
+	}
+	if sp.head().notification.Id != "chain1:2:-1:-1" {
+		t.Fatalf("unexpected head %s", sp.head().notification.Id)
+	}
+}
+
+func TestSpoolBackpressure(t *testing.T) {
+	sp, err := openSpool(newTestDir(t), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sp.push(&Notification{Id: "n1"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. the push waits until the sink stops while the spool is full
+	quitC := make(chan struct{})
+	close(quitC)
+	if err = sp.push(&Notification{Id: "n2"}, quitC); err != errSinkStopped {
+		t.Fatalf("expected errSinkStopped, got %v", err)
+	}
+
+	// 2. the push continues once a notification is delivered
+	errC := make(chan error, 1)
+	go func() {
+		errC <- sp.push(&Notification{Id: "n2"}, nil)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err = sp.remove(sp.head()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-errC:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	if sp.size() != 1 || sp.head().notification.Id != "n2" {
+		t.Fatalf("unexpected spool, size %d", sp.size())
+	}
+}
+
+type testStore struct {
+	blocks map[uint64]*commonPb.Block
+}
+
+func (s *testStore) GetBlock(height uint64) (*commonPb.Block, error) {
+	return s.blocks[height], nil
+}
+
+func TestPushMissedBlocks(t *testing.T) {
+	config := &SinkConfig{
+		Name:    "webhook1",
+		Type:    SinkTypeWebhook,
+		Events:  []string{NotificationTypeBlock},
+		Webhook: WebhookConfig{URL: "http://127.0.0.1:1"},
+	}
+	dir := newTestDir(t)
+	s, err := NewSink(testChainId, config, dir, &test.GoLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &testStore{blocks: make(map[uint64]*commonPb.Block)}
+	for height := uint64(1); height <= 5; height++ {
+		store.blocks[height] = newTestBlock(height)
+	}
+	s.store = store
+	push := func(height uint64) error {
+		return s.Push(model.NewBlockEvent{BlockInfo: &commonPb.BlockInfo{Block: newTestBlock(height)}})
+	}
+	ids := func() []string {
+		var ids []string
+		for _, entry := range s.spool.pending {
+			ids = append(ids, entry.notification.Id)
+		}
+		return ids
+	}
+
+	// 1. the blocks missed are read from the store, the blocks spooled are ignored
+	for _, height := range []uint64{1, 3, 2, 3} {
+		if err = push(height); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"chain1:1:-1:-1", "chain1:2:-1:-1", "chain1:3:-1:-1"}
+	if !reflect.DeepEqual(ids(), expected) {
+		t.Fatalf("unexpected notifications %v", ids())
+	}
+
+	// 2. the blocks missed since the last run are read from the store after restarting
+	if s, err = NewSink(testChainId, config, dir, &test.GoLogger{}); err != nil {
+		t.Fatal(err)
+	}
+	s.store = store
+	delete(store.blocks, 5)
+	if err = push(6); err == nil {
+		t.Fatal("the missed block 5 should not be found")
+	}
+	store.blocks[5] = newTestBlock(5)
+	if err = push(6); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, "chain1:4:-1:-1", "chain1:5:-1:-1", "chain1:6:-1:-1")
+	if !reflect.DeepEqual(ids(), expected) {
+		t.Fatalf("unexpected notifications %v", ids())
+	}
+}
+
+type testQueue struct {
+	lock     sync.Mutex
+	messages map[string][]string
+	closed   bool
+}
+
+func (q *testQueue) Publish(topic, key string, value []byte) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed {
+		return errors.New("closed")
+	}
+	q.messages[topic] = append(q.messages[topic], key)
+	return nil
+}
+
+func (q *testQueue) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	return nil
+}
+
+func TestQueueSink(t *testing.T) {
+	queue := &testQueue{messages: make(map[string][]string)}
+	RegisterQueueProvider("test", func(options map[string]string) (Queue, error) {
+		if options["brokers"] != "localhost:9092" {
+			return nil, errors.New("no brokers")
+		}
+		return queue, nil
+	})
+	config := &SinkConfig{
+		Name:   "queue1",
+		Type:   SinkTypeQueue,
+		Events: []string{NotificationTypeContractEvent},
+		Topics: []string{"t2"},
+		Queue: QueueConfig{Provider: "test", Topic: "events",
+			Options: map[string]string{"brokers": "localhost:9092"}},
+	}
+	s, err := NewSink(testChainId, config, newTestDir(t), &test.GoLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &testSource{}
+	s.Start(source, nil)
+	source.publish(t, newTestBlock(1))
+	waitFor(t, func() bool {
+		queue.lock.Lock()
+		defer queue.lock.Unlock()
+		return len(queue.messages["events"]) == 1
+	})
+	s.Stop()
+	if queue.messages["events"][0] != "chain1:1:0:1" || !queue.closed {
+		t.Fatalf("unexpected messages %v", queue.messages)
+	}
+
+	config.Queue.Provider = "unknown"
+	if _, err = NewSink(testChainId, config, newTestDir(t), &test.GoLogger{}); err == nil {
+		t.Fatal("the queue provider should not be found")
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	defer func() {
+		_ = SetConfig(&Config{})
+	}()
+	err := SetConfig(&Config{Sinks: []*SinkConfig{
+		{Name: "a", Type: SinkTypeWebhook, Webhook: WebhookConfig{URL: "http://localhost"}},
+		{Name: "a", Type: SinkTypeWebhook, Webhook: WebhookConfig{URL: "http://localhost"}},
+	}})
+	if err == nil {
+		t.Fatal("duplicate sinks should be rejected")
+	}
+	err = SetConfig(&Config{Sinks: []*SinkConfig{
+		{Name: "a", Type: SinkTypeWebhook, Events: []string{"unknown"}, Webhook: WebhookConfig{URL: "http://localhost"}},
+	}})
+	if err == nil {
+		t.Fatal("unknown event should be rejected")
+	}
+
+	config := &Config{Sinks: []*SinkConfig{
+		{Name: "a", Type: SinkTypeWebhook, ChainIds: []string{"chain2"}, Webhook: WebhookConfig{URL: "http://localhost"}},
+	}}
+	if err = SetConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if getConfig().SpoolDir != defaultSpoolDir || config.Sinks[0].MaxRetries != defaultMaxRetries ||
+		config.Sinks[0].Webhook.Timeout != defaultWebhookTimeout {
+		t.Fatal("the defaults are not set")
+	}
+	sinks, err := StartSinks(testChainId, &testSource{})
+	if err != nil || len(sinks) != 0 {
+		t.Fatalf("the sink of chain2 should not be started for %s", testChainId)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spoolFileExt        = ".json"
+	tempFileExt         = ".tmp"
+	deadLetterFileName  = "dead_letter.log"
+	blockHeightFileName = "block_height"
+)
+
+// errSinkStopped the sink stops while waiting for the spool
+var errSinkStopped = errors.New("event sink stopped")
+
+// spoolEntry a notification waiting to be delivered, stored in a file named by its sequence
+type spoolEntry struct {
+	seq          uint64
+	notification *Notification
+	body         []byte // the json of the notification
+	attempts     int
+}
+
+// deadLetter a line of the dead-letter file
+type deadLetter struct {
+	Time         string        `json:"time"`
+	Reason       string        `json:"reason"`
+	Attempts     int           `json:"attempts"`
+	Notification *Notification `json:"notification"`
+}
+
+// spool keeps the notifications of a sink on disk until they are delivered, so that they are delivered
+// after the node restarts. The notifications which can not be delivered are appended to the dead-letter file.
+// The height of the last block spooled is kept too, so that the blocks missed by the sink are spooled later.
+type spool struct {
+	dir             string
+	deadLetterFile  string
+	blockHeightFile string
+	maxPending      int
+
+	lock           sync.Mutex
+	nextSeq        uint64
+	pending        []*spoolEntry
+	blockHeight    uint64
+	hasBlockHeight bool
+	spaceC         chan struct{} // signals the waiting pusher that a notification is removed
+}
+
+// openSpool opens the spool in the directory and loads the notifications left by the last run
+func openSpool(dir string, maxPending int) (*spool, error) {
+	pendingDir := filepath.Join(dir, "pending")
+	if err := os.MkdirAll(pendingDir, 0755); err != nil {
+		return nil, err
+	}
+	s := &spool{
+		dir:             pendingDir,
+		deadLetterFile:  filepath.Join(dir, deadLetterFileName),
+		blockHeightFile: filepath.Join(dir, blockHeightFileName),
+		maxPending:      maxPending,
+		spaceC:          make(chan struct{}, 1),
+	}
+	if err := s.loadBlockHeight(); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(pendingDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, spoolFileExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		body, err := ioutil.ReadFile(filepath.Join(pendingDir, name))
+		if err != nil {
+			return nil, err
+		}
+		entry := &spoolEntry{seq: seq, body: body, notification: &Notification{}}
+		if err = json.Unmarshal(body, entry.notification); err != nil {
+			return nil, fmt.Errorf("invalid spool file %s, %s", name, err)
+		}
+		s.pending = append(s.pending, entry)
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.pending, func(i, j int) bool {
+		return s.pending[i].seq < s.pending[j].seq
+	})
+	return s, nil
+}
+
+// push stores the notification, it waits while too many ones are waiting until quitC is closed
+func (s *spool) push(n *Notification, quitC <-chan struct{}) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for len(s.pending) >= s.maxPending {
+		s.lock.Unlock()
+		select {
+		case <-s.spaceC:
+		case <-quitC:
+			s.lock.Lock()
+			return errSinkStopped
+		}
+		s.lock.Lock()
+	}
+	entry := &spoolEntry{seq: s.nextSeq, notification: n, body: body}
+	if err = writeFileSync(s.fileOf(entry), body); err != nil {
+		return err
+	}
+	s.nextSeq++
+	s.pending = append(s.pending, entry)
+	return nil
+}
+
+// lastBlockHeight returns the height of the last block spooled, false if no block is spooled
+func (s *spool) lastBlockHeight() (uint64, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.blockHeight, s.hasBlockHeight
+}
+
+// setLastBlockHeight records that the notifications of the block are all spooled
+func (s *spool) setLastBlockHeight(height uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := writeFileSync(s.blockHeightFile, []byte(strconv.FormatUint(height, 10))); err != nil {
+		return err
+	}
+	s.blockHeight, s.hasBlockHeight = height, true
+	return nil
+}
+
+func (s *spool) loadBlockHeight() error {
+	data, err := ioutil.ReadFile(s.blockHeightFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.blockHeight, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+		return fmt.Errorf("invalid block height file, %s", err)
+	}
+	s.hasBlockHeight = true
+	return nil
+}
+
+// head returns the earliest notification waiting, or nil if there is none
+func (s *spool) head() *spoolEntry {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	return s.pending[0]
+}
+
+// remove removes the delivered head notification
+func (s *spool) remove(entry *spoolEntry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.pending) == 0 || s.pending[0] != entry {
+		return nil
+	}
+	s.pending = s.pending[1:]
+	signal(s.spaceC)
+	return os.Remove(s.fileOf(entry))
+}
+
+// kill moves the head notification to the dead-letter file
+func (s *spool) kill(entry *spoolEntry, reason string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.writeDeadLetter(entry.notification, entry.attempts, reason); err != nil {
+		return err
+	}
+	if len(s.pending) > 0 && s.pending[0] == entry {
+		s.pending = s.pending[1:]
+		signal(s.spaceC)
+	}
+	return os.Remove(s.fileOf(entry))
+}
+
+func (s *spool) size() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.pending)
+}
+
+func (s *spool) fileOf(entry *spoolEntry) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", entry.seq, spoolFileExt))
+}
+
+func (s *spool) writeDeadLetter(n *Notification, attempts int, reason string) error {
+	line, err := json.Marshal(&deadLetter{
+		Time:         time.Now().Format(time.RFC3339),
+		Reason:       reason,
+		Attempts:     attempts,
+		Notification: n,
+	})
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.deadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// writeFileSync writes the file through a temporary one and syncs it to the disk, so that the file is
+// either the old one or the whole new one after a crash
+func writeFileSync(name string, data []byte) error {
+	temp := name + tempFileExt
+	file, err := os.OpenFile(temp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+	if err = os.Rename(temp, name); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The headers of the requests posted to the webhooks
+const (
+	// HeaderDelivery the id of the notification
+	HeaderDelivery = "X-ChainMaker-Delivery"
+	// HeaderTimestamp the unix time in seconds when the request is sent
+	HeaderTimestamp = "X-ChainMaker-Timestamp"
+	// HeaderSignature the signature returned by Sign, it is absent if the secret of the webhook is empty
+	HeaderSignature = "X-ChainMaker-Signature"
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of "timestamp.body" with the secret,
+// the receivers of the webhooks verify the requests by it
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhook posts the notifications to a url, a response with a status other than 2xx is a failure
+type webhook struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func newWebhook(config *WebhookConfig) *webhook {
+	return &webhook{
+		url:    config.URL,
+		secret: config.Secret,
+		client: &http.Client{Timeout: time.Duration(config.Timeout) * time.Millisecond},
+	}
+}
+
+func (w *webhook) deliver(n *Notification, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, n.Id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if w.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(w.secret, timestamp, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responds %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *webhook) close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
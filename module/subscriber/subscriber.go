@@ -8,48 +8,259 @@ SPDX-License-Identifier: Apache-2.0
 package subscriber
 
 import (
+	"sync"
+	"time"
+
 	"chainmaker.org/chainmaker-go/subscriber/model"
+	"chainmaker.org/chainmaker/common/v2/monitor"
 	"chainmaker.org/chainmaker/common/v2/msgbus"
+	"chainmaker.org/chainmaker/localconf/v2"
+	"chainmaker.org/chainmaker/logger/v2"
 	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2"
 	feed "github.com/ethereum/go-ethereum/event"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	eventTypeBlock         = "block"
+	eventTypeContractEvent = "contract_event"
 )
 
+// event an event waiting in the queue of the dispatcher
+type event struct {
+	eventType string
+	payload   interface{}
+}
+
 // EventSubscriber - new EventSubscriber struct
+// The events of a chain are dispatched by one goroutine in the order they are published, each subscription
+// buffers its events, and the slow consumers are handled by the policy of the config.
 type EventSubscriber struct {
-	blockFeed         feed.Feed
-	contractEventFeed feed.Feed
+	chainId string
+	config  *Config
+	log     protocol.Logger
+
+	queue chan *event
+	quitC chan struct{}
+	once  sync.Once
+
+	subsLock          sync.RWMutex
+	blockSubs         map[*subscription]struct{}
+	contractEventSubs map[*subscription]struct{}
+
+	metricQueueDepth          *prometheus.HistogramVec // events waiting in the queue when an event is published
+	metricDroppedEventCounter *prometheus.CounterVec   // events not delivered to the slow consumers
 }
 
 // OnMessage - deal msgbus.BlockInfo message
+// It never blocks the message bus. If the queue is full, the event is dropped and the subscriptions of the event
+// are closed with ErrSlowConsumer, so that the subscribers know they missed the event.
 func (s *EventSubscriber) OnMessage(msg *msgbus.Message) {
+	var ev *event
 	if blockInfo, ok := msg.Payload.(*commonPb.BlockInfo); ok {
-		go s.blockFeed.Send(model.NewBlockEvent{BlockInfo: blockInfo})
+		ev = &event{eventType: eventTypeBlock, payload: model.NewBlockEvent{BlockInfo: blockInfo}}
 	}
 	if conEventInfoList, ok := msg.Payload.(*commonPb.ContractEventInfoList); ok {
-		go s.contractEventFeed.Send(model.NewContractEvent{ContractEventInfoList: conEventInfoList})
+		ev = &event{eventType: eventTypeContractEvent,
+			payload: model.NewContractEvent{ContractEventInfoList: conEventInfoList}}
+	}
+	if ev == nil {
+		return
+	}
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		s.metricQueueDepth.WithLabelValues(s.chainId).Observe(float64(len(s.queue)))
+	}
+	select {
+	case s.queue <- ev:
+	case <-s.quitC:
+	default:
+		s.log.Warnf("event queue is full, disconnect all %s subscriptions", ev.eventType)
+		for _, sub := range s.subsSnapshot(ev.eventType) {
+			sub.close(ErrSlowConsumer)
+		}
+		if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+			s.metricDroppedEventCounter.WithLabelValues(s.chainId, ev.eventType).Inc()
+		}
 	}
 }
 
 // OnQuit - deal msgbus OnQuit message
+// The dispatcher stops and all subscriptions are closed.
 func (s *EventSubscriber) OnQuit() {
-	// do nothing
+	s.once.Do(func() {
+		close(s.quitC)
+	})
 }
 
 // NewSubscriber - new and register msgbus.BlockInfo object
-func NewSubscriber(msgBus msgbus.MessageBus) *EventSubscriber {
-	subscriber := &EventSubscriber{}
+func NewSubscriber(chainId string, msgBus msgbus.MessageBus) *EventSubscriber {
+	subscriber := newEventSubscriber(chainId, getConfig())
 	msgBus.Register(msgbus.BlockInfo, subscriber)
 
 	msgBus.Register(msgbus.ContractEventInfo, subscriber)
 	return subscriber
 }
 
+func newEventSubscriber(chainId string, config *Config) *EventSubscriber {
+	subscriber := &EventSubscriber{
+		chainId:           chainId,
+		config:            config,
+		log:               logger.GetLoggerByChain(logger.MODULE_RPC, chainId),
+		queue:             make(chan *event, config.QueueSize),
+		quitC:             make(chan struct{}),
+		blockSubs:         make(map[*subscription]struct{}),
+		contractEventSubs: make(map[*subscription]struct{}),
+	}
+	if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+		subscriber.metricQueueDepth = monitor.NewHistogramVec(monitor.SUBSYSTEM_RPCSERVER,
+			"metric_subscriber_queue_depth", "events waiting in the queue of the subscriber",
+			prometheus.ExponentialBuckets(1, 4, 8), "chainId")
+		subscriber.metricDroppedEventCounter = monitor.NewCounterVec(monitor.SUBSYSTEM_RPCSERVER,
+			"metric_subscriber_dropped_event_counter", "events not delivered to the slow consumers",
+			"chainId", "event")
+	}
+	go subscriber.dispatch()
+	return subscriber
+}
+
 // SubscribeBlockEvent - subscribe block event
 func (s *EventSubscriber) SubscribeBlockEvent(ch chan<- model.NewBlockEvent) feed.Subscription {
-	return s.blockFeed.Subscribe(ch)
+	return s.subscribe(eventTypeBlock, func(payload interface{}, quitC <-chan struct{}) bool {
+		select {
+		case ch <- payload.(model.NewBlockEvent):
+			return true
+		case <-quitC:
+			return false
+		}
+	})
 }
 
 // SubscribeContractEvent - subscribe contract event
 func (s *EventSubscriber) SubscribeContractEvent(ch chan<- model.NewContractEvent) feed.Subscription {
-	return s.contractEventFeed.Subscribe(ch)
+	return s.subscribe(eventTypeContractEvent, func(payload interface{}, quitC <-chan struct{}) bool {
+		select {
+		case ch <- payload.(model.NewContractEvent):
+			return true
+		case <-quitC:
+			return false
+		}
+	})
+}
+
+func (s *EventSubscriber) subscribe(eventType string, deliver deliverFunc) feed.Subscription {
+	limit := s.config.BufferSize
+	if s.config.SlowConsumerPolicy == SlowConsumerPolicyUnbounded {
+		limit = 0
+	}
+	sub := newSubscription(s, eventType, limit, deliver)
+	s.subsLock.Lock()
+	select {
+	case <-s.quitC:
+		s.subsLock.Unlock()
+		sub.close(nil)
+		return sub
+	default:
+	}
+	s.subsOf(eventType)[sub] = struct{}{}
+	s.subsLock.Unlock()
+	go sub.forward()
+	return sub
+}
+
+func (s *EventSubscriber) unsubscribe(sub *subscription) {
+	s.subsLock.Lock()
+	defer s.subsLock.Unlock()
+	delete(s.subsOf(sub.eventType), sub)
+}
+
+func (s *EventSubscriber) subsOf(eventType string) map[*subscription]struct{} {
+	if eventType == eventTypeBlock {
+		return s.blockSubs
+	}
+	return s.contractEventSubs
+}
+
+// dispatch delivers the events in the queue to the subscriptions one by one
+func (s *EventSubscriber) dispatch() {
+	for {
+		select {
+		case ev := <-s.queue:
+			s.dispatchEvent(ev)
+		case <-s.quitC:
+			s.closeAll()
+			return
+		}
+	}
+}
+
+func (s *EventSubscriber) subsSnapshot(eventType string) []*subscription {
+	s.subsLock.RLock()
+	defer s.subsLock.RUnlock()
+	subs := make([]*subscription, 0, len(s.subsOf(eventType)))
+	for sub := range s.subsOf(eventType) {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func (s *EventSubscriber) dispatchEvent(ev *event) {
+	for _, sub := range s.subsSnapshot(ev.eventType) {
+		if sub.push(ev.payload) {
+			continue
+		}
+		switch s.config.SlowConsumerPolicy {
+		case SlowConsumerPolicyBlock:
+			if s.waitAndPush(sub, ev.payload) {
+				continue
+			}
+		case SlowConsumerPolicyDrop:
+			s.log.Debugf("buffer of the %s subscription is full, drop the event", ev.eventType)
+		default:
+			s.log.Warnf("buffer of the %s subscription is full, disconnect the slow consumer", ev.eventType)
+			sub.close(ErrSlowConsumer)
+		}
+		if localconf.ChainMakerConfig.MonitorConfig.Enabled {
+			s.metricDroppedEventCounter.WithLabelValues(s.chainId, ev.eventType).Inc()
+		}
+	}
+}
+
+// waitAndPush waits for the slow consumer to take its pending events at most BlockTimeout, the subscription is
+// closed with ErrSlowConsumer if it times out. It returns true if the event is pushed, or the subscription quits.
+func (s *EventSubscriber) waitAndPush(sub *subscription, payload interface{}) bool {
+	timer := time.NewTimer(time.Duration(s.config.BlockTimeout) * time.Millisecond)
+	defer timer.Stop()
+	for {
+		select {
+		case <-sub.spaceC:
+			if sub.push(payload) {
+				return true
+			}
+		case <-sub.quitC:
+			return true
+		case <-s.quitC:
+			return true
+		case <-timer.C:
+			s.log.Warnf("%s subscription is blocked for %dms, disconnect the slow consumer",
+				sub.eventType, s.config.BlockTimeout)
+			sub.close(ErrSlowConsumer)
+			return false
+		}
+	}
+}
+
+func (s *EventSubscriber) closeAll() {
+	s.subsLock.Lock()
+	subs := make([]*subscription, 0, len(s.blockSubs)+len(s.contractEventSubs))
+	for sub := range s.blockSubs {
+		subs = append(subs, sub)
+	}
+	for sub := range s.contractEventSubs {
+		subs = append(subs, sub)
+	}
+	s.subsLock.Unlock()
+	for _, sub := range subs {
+		sub.close(nil)
+	}
 }
@@ -0,0 +1,183 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package subscriber
+
+import (
+	"testing"
+	"time"
+
+	"chainmaker.org/chainmaker-go/subscriber/model"
+	"chainmaker.org/chainmaker/common/v2/msgbus"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/protocol/v2/test"
+)
+
+const testChainId = "chain1"
+
+// newTestSubscriber returns a subscriber without the dispatcher goroutine, the events are dispatched by the test
+func newTestSubscriber(policy string) *EventSubscriber {
+	return &EventSubscriber{
+		chainId:           testChainId,
+		config:            &Config{QueueSize: 1, BufferSize: 2, SlowConsumerPolicy: policy, BlockTimeout: 50},
+		log:               &test.GoLogger{},
+		queue:             make(chan *event, 1),
+		quitC:             make(chan struct{}),
+		blockSubs:         make(map[*subscription]struct{}),
+		contractEventSubs: make(map[*subscription]struct{}),
+	}
+}
+
+// addTestSubscription registers a subscription without the forwarder, so that its buffer is never taken
+func addTestSubscription(s *EventSubscriber) *subscription {
+	sub := newSubscription(s, eventTypeBlock, s.config.BufferSize, nil)
+	s.blockSubs[sub] = struct{}{}
+	return sub
+}
+
+func blockMessage(height uint64) *msgbus.Message {
+	return &msgbus.Message{
+		Topic:   msgbus.BlockInfo,
+		Payload: &commonPb.BlockInfo{Block: &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: height}}},
+	}
+}
+
+func blockEvent(height uint64) *event {
+	blockInfo := blockMessage(height).Payload.(*commonPb.BlockInfo)
+	return &event{eventType: eventTypeBlock, payload: model.NewBlockEvent{BlockInfo: blockInfo}}
+}
+
+func requireErr(t *testing.T, sub *subscription, expected error) {
+	select {
+	case err := <-sub.Err():
+		if err != expected {
+			t.Fatalf("expected error %v, got %v", expected, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func requireOpen(t *testing.T, sub *subscription) {
+	select {
+	case err := <-sub.Err():
+		t.Fatalf("subscription closed, error: %v", err)
+	default:
+	}
+}
+
+func TestSubscriberDeliverInOrder(t *testing.T) {
+	s := newEventSubscriber(testChainId, newDefaultConfig())
+	defer s.OnQuit()
+	ch := make(chan model.NewBlockEvent)
+	sub := s.SubscribeBlockEvent(ch)
+	defer sub.Unsubscribe()
+
+	// the events are buffered without limit by default while nobody receives them
+	const count = 500
+	for i := uint64(1); i <= count; i++ {
+		s.OnMessage(blockMessage(i))
+	}
+	for i := uint64(1); i <= count; i++ {
+		select {
+		case ev := <-ch:
+			if height := ev.BlockInfo.Block.Header.BlockHeight; height != i {
+				t.Fatalf("expected block %d, got %d", i, height)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestSubscriberDropPolicy(t *testing.T) {
+	s := newTestSubscriber(SlowConsumerPolicyDrop)
+	sub := addTestSubscription(s)
+	for i := uint64(1); i <= 3; i++ {
+		s.dispatchEvent(blockEvent(i))
+	}
+	if len(sub.pending) != 2 {
+		t.Fatalf("expected 2 pending events, got %d", len(sub.pending))
+	}
+	requireOpen(t, sub)
+}
+
+func TestSubscriberDisconnectPolicy(t *testing.T) {
+	s := newTestSubscriber(SlowConsumerPolicyDisconnect)
+	sub := addTestSubscription(s)
+	for i := uint64(1); i <= 3; i++ {
+		s.dispatchEvent(blockEvent(i))
+	}
+	requireErr(t, sub, ErrSlowConsumer)
+	if len(s.blockSubs) != 0 {
+		t.Fatal("the slow consumer is not unsubscribed")
+	}
+}
+
+func TestSubscriberBlockPolicy(t *testing.T) {
+	s := newTestSubscriber(SlowConsumerPolicyBlock)
+	sub := addTestSubscription(s)
+	s.dispatchEvent(blockEvent(1))
+	s.dispatchEvent(blockEvent(2))
+
+	// 1. the dispatcher waits until the consumer takes its events
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		sub.take()
+	}()
+	s.dispatchEvent(blockEvent(3))
+	if len(sub.pending) != 1 {
+		t.Fatalf("expected 1 pending event, got %d", len(sub.pending))
+	}
+	requireOpen(t, sub)
+
+	// 2. the consumer is disconnected once the dispatcher waits more than BlockTimeout
+	s.dispatchEvent(blockEvent(4))
+	start := time.Now()
+	s.dispatchEvent(blockEvent(5))
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("unexpected waiting time %s", elapsed)
+	}
+	requireErr(t, sub, ErrSlowConsumer)
+}
+
+func TestSubscriberOnMessageNeverBlocks(t *testing.T) {
+	s := newTestSubscriber(SlowConsumerPolicyBlock)
+	sub := addTestSubscription(s)
+	done := make(chan struct{})
+	go func() {
+		s.OnMessage(blockMessage(1))
+		s.OnMessage(blockMessage(2))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("OnMessage blocks while the queue is full")
+	}
+	// the event missed by the subscriptions is surfaced to them
+	requireErr(t, sub, ErrSlowConsumer)
+}
+
+func TestSubscriberOnQuit(t *testing.T) {
+	s := newEventSubscriber(testChainId, newDefaultConfig())
+	sub := s.SubscribeContractEvent(make(chan model.NewContractEvent))
+	s.OnQuit()
+	select {
+	case err, ok := <-sub.Err():
+		if ok {
+			t.Fatalf("expected the subscription closed without error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// the subscriptions after quitting are closed at once
+	sub = s.SubscribeBlockEvent(make(chan model.NewBlockEvent))
+	if _, ok := <-sub.Err(); ok {
+		t.Fatal("expected the subscription closed")
+	}
+}
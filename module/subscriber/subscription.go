@@ -0,0 +1,118 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package subscriber
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSlowConsumer the subscription is closed since its buffer is full, with the disconnect or block policy,
+// or since the event queue of the chain is full
+var ErrSlowConsumer = errors.New("subscription closed, the consumer is too slow")
+
+// deliverFunc sends the event to the channel of the subscriber, it returns false if the subscription quits
+type deliverFunc func(payload interface{}, quitC <-chan struct{}) bool
+
+// subscription a subscription of the events, it implements feed.Subscription
+type subscription struct {
+	owner     *EventSubscriber
+	eventType string
+	deliver   deliverFunc
+	quitC     chan struct{}
+	errC      chan error
+	once      sync.Once
+
+	mu      sync.Mutex
+	pending []interface{} // events waiting to be delivered
+	limit   int           // max pending events, 0 means unlimited
+	notifyC chan struct{} // signals the forwarder that events are pending
+	spaceC  chan struct{} // signals the dispatcher that the pending events are taken by the forwarder
+}
+
+func newSubscription(owner *EventSubscriber, eventType string, limit int, deliver deliverFunc) *subscription {
+	return &subscription{
+		owner:     owner,
+		eventType: eventType,
+		deliver:   deliver,
+		quitC:     make(chan struct{}),
+		errC:      make(chan error, 1),
+		limit:     limit,
+		notifyC:   make(chan struct{}, 1),
+		spaceC:    make(chan struct{}, 1),
+	}
+}
+
+// Err returns a channel which receives ErrSlowConsumer if the subscription is disconnected for being slow,
+// the channel is closed when the subscription is closed.
+func (sub *subscription) Err() <-chan error {
+	return sub.errC
+}
+
+// Unsubscribe stops delivering the events to the channel of the subscriber
+func (sub *subscription) Unsubscribe() {
+	sub.close(nil)
+}
+
+func (sub *subscription) close(err error) {
+	sub.once.Do(func() {
+		sub.owner.unsubscribe(sub)
+		if err != nil {
+			sub.errC <- err
+		}
+		close(sub.quitC)
+		close(sub.errC)
+	})
+}
+
+// push appends the event to the pending events without blocking, it returns false if the buffer is full
+func (sub *subscription) push(payload interface{}) bool {
+	sub.mu.Lock()
+	if sub.limit > 0 && len(sub.pending) >= sub.limit {
+		sub.mu.Unlock()
+		return false
+	}
+	sub.pending = append(sub.pending, payload)
+	sub.mu.Unlock()
+	signal(sub.notifyC)
+	return true
+}
+
+// take takes all pending events
+func (sub *subscription) take() []interface{} {
+	sub.mu.Lock()
+	payloads := sub.pending
+	sub.pending = nil
+	sub.mu.Unlock()
+	signal(sub.spaceC)
+	return payloads
+}
+
+// forward sends the pending events to the channel of the subscriber in order
+func (sub *subscription) forward() {
+	for {
+		select {
+		case <-sub.notifyC:
+		case <-sub.quitC:
+			return
+		}
+		for payloads := sub.take(); len(payloads) > 0; payloads = sub.take() {
+			for _, payload := range payloads {
+				if !sub.deliver(payload, sub.quitC) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -43,6 +43,7 @@ type BlockChainSyncServer struct {
 
 	scheduler *Routine // Service that get blocks from other nodes
 	processor *Routine // Service that processes block data, adding valid blocks to the chain
+	sch       *scheduler
 }
 
 func NewBlockChainSyncServer(chainId string,
@@ -75,11 +76,12 @@ func (sync *BlockChainSyncServer) Start() error {
 	// 1. init conf
 	sync.initSyncConfIfRequire()
 	processor := newProcessor(sync, sync.ledgerCache, sync.log)
-	scheduler := newScheduler(sync, sync.ledgerCache,
-		sync.conf.blockPoolSize, sync.conf.timeOut, sync.conf.reqTimeThreshold, sync.conf.batchSizeFromOneNode, sync.log)
+	scheduler := newScheduler(sync, sync.ledgerCache, sync.conf.blockPoolSize, sync.conf.timeOut,
+		sync.conf.reqTimeThreshold, sync.conf.batchSizeFromOneNode, sync.conf.peerBanDuration, sync.log)
 	if scheduler == nil {
 		return fmt.Errorf("init scheduler failed")
 	}
+	sync.sch = scheduler
 	sync.scheduler = NewRoutine("scheduler", scheduler.handler, scheduler.getServiceState, sync.log)
 	sync.processor = NewRoutine("processor", processor.handler, processor.getServiceState, sync.log)
 
@@ -105,6 +107,15 @@ func (sync *BlockChainSyncServer) Start() error {
 	return nil
 }
 
+// GetMaxPeerHeight returns the highest block height of the connected peers,
+// known is false if the status of no peer is received yet
+func (sync *BlockChainSyncServer) GetMaxPeerHeight() (height uint64, known bool) {
+	if atomic.LoadInt32(&sync.start) != 1 || sync.sch == nil {
+		return 0, false
+	}
+	return sync.sch.getMaxPeerHeight()
+}
+
 func (sync *BlockChainSyncServer) initSyncConfIfRequire() {
 	defer func() {
 		sync.log.Infof(sync.conf.print())
@@ -140,6 +151,17 @@ func (sync *BlockChainSyncServer) initSyncConfIfRequire() {
 	if localconf.ChainMakerConfig.SyncConfig.ReqTimeThreshold > 0 {
 		sync.conf.SetReqTimeThreshold(localconf.ChainMakerConfig.SyncConfig.ReqTimeThreshold)
 	}
+	extConfig := getExtConfig()
+	if extConfig.PeerBanDuration > 0 {
+		sync.conf.SetPeerBanDuration(extConfig.PeerBanDuration)
+	}
+	if extConfig.MaxBatchBytes > 0 {
+		sync.conf.SetMaxBatchBytes(extConfig.MaxBatchBytes)
+	}
+	if extConfig.MaxDecompressedBytes > 0 {
+		sync.conf.SetMaxDecompressedBytes(extConfig.MaxDecompressedBytes)
+	}
+	sync.conf.SetCompressResponse(extConfig.CompressResponse)
 }
 
 func (sync *BlockChainSyncServer) blockSyncMsgHandler(from string, msg []byte, msgType netPb.NetMsg_MsgType) error {
@@ -167,7 +189,12 @@ func (sync *BlockChainSyncServer) blockSyncMsgHandler(from string, msg []byte, m
 	case syncPb.SyncMsg_BLOCK_SYNC_REQ:
 		return sync.handleBlockReq(&syncMsg, from)
 	case syncPb.SyncMsg_BLOCK_SYNC_RESP:
-		return sync.scheduler.addTask(&SyncedBlockMsg{msg: syncMsg.Payload, from: from})
+		payload, err := decompressPayload(syncMsg.Payload, sync.conf.maxDecompressedBytes)
+		if err != nil {
+			sync.log.Errorf("fail to decompress the block sync response from node [%s]: %s", from, err)
+			return err
+		}
+		return sync.scheduler.addTask(&SyncedBlockMsg{msg: payload, from: from})
 	}
 	return fmt.Errorf("not support the syncPb.SyncMsg.Type as %d", syncMsg.Type)
 }
@@ -216,52 +243,101 @@ func (sync *BlockChainSyncServer) handleBlockReq(syncMsg *syncPb.SyncMsg, from s
 	return sync.sendBlocks(&req, from)
 }
 
+// sendBlocks sends the requested blocks in as few messages as possible,
+// each message carries the blocks up to maxBatchBytes
 func (sync *BlockChainSyncServer) sendBlocks(req *syncPb.BlockSyncReq, from string) error {
 	var (
-		bz  []byte
-		err error
-		blk *commonPb.Block
+		err       error
+		blk       *commonPb.Block
+		batchSize uint64
+		blks      = make([]*commonPb.Block, 0, req.BatchSize)
 	)
 
+	sendBatch := func() error {
+		if len(blks) == 0 {
+			return nil
+		}
+		batch := &syncPb.SyncBlockBatch{
+			Data: &syncPb.SyncBlockBatch_BlockBatch{BlockBatch: &syncPb.BlockBatch{Batches: blks}},
+		}
+		blks, batchSize = make([]*commonPb.Block, 0, req.BatchSize), 0
+		return sync.sendBlockBatch(batch, from)
+	}
+
 	for i := uint64(0); i < req.BatchSize; i++ {
 		if blk, err = sync.blockChainStore.GetBlock(req.BlockHeight + i); err != nil || blk == nil {
+			if sendErr := sendBatch(); sendErr != nil {
+				return sendErr
+			}
 			return err
 		}
-		if bz, err = proto.Marshal(&syncPb.SyncBlockBatch{
-			Data: &syncPb.SyncBlockBatch_BlockBatch{BlockBatch: &syncPb.BlockBatch{Batches: []*commonPb.Block{blk}}},
-		}); err != nil {
-			return err
-		}
-		if err := sync.sendMsg(syncPb.SyncMsg_BLOCK_SYNC_RESP, bz, from); err != nil {
-			return err
+		size := uint64(proto.Size(blk))
+		if len(blks) > 0 && batchSize+size > sync.conf.maxBatchBytes {
+			if err = sendBatch(); err != nil {
+				return err
+			}
 		}
+		blks = append(blks, blk)
+		batchSize += size
 	}
-	return nil
+	return sendBatch()
 }
 
+// sendInfos sends the requested blocks with rwsets in as few messages as possible,
+// each message carries the block infos up to maxBatchBytes
 func (sync *BlockChainSyncServer) sendInfos(req *syncPb.BlockSyncReq, from string) error {
 	var (
-		bz        []byte
 		err       error
 		blkRwInfo *storePb.BlockWithRWSet
+		batchSize uint64
+		infos     = make([]*commonPb.BlockInfo, 0, req.BatchSize)
 	)
 
+	sendBatch := func() error {
+		if len(infos) == 0 {
+			return nil
+		}
+		batch := &syncPb.SyncBlockBatch{
+			Data: &syncPb.SyncBlockBatch_BlockinfoBatch{BlockinfoBatch: &syncPb.BlockInfoBatch{Batch: infos}},
+		}
+		infos, batchSize = make([]*commonPb.BlockInfo, 0, req.BatchSize), 0
+		return sync.sendBlockBatch(batch, from)
+	}
+
 	for i := uint64(0); i < req.BatchSize; i++ {
 		if blkRwInfo, err = sync.blockChainStore.GetBlockWithRWSets(req.BlockHeight + i); err != nil || blkRwInfo == nil {
+			if sendErr := sendBatch(); sendErr != nil {
+				return sendErr
+			}
 			return err
 		}
 		info := &commonPb.BlockInfo{Block: blkRwInfo.Block, RwsetList: blkRwInfo.TxRWSets}
-		if bz, err = proto.Marshal(&syncPb.SyncBlockBatch{
-			Data: &syncPb.SyncBlockBatch_BlockinfoBatch{BlockinfoBatch: &syncPb.BlockInfoBatch{
-				Batch: []*commonPb.BlockInfo{info}}},
-		}); err != nil {
-			return err
+		size := uint64(proto.Size(info))
+		if len(infos) > 0 && batchSize+size > sync.conf.maxBatchBytes {
+			if err = sendBatch(); err != nil {
+				return err
+			}
 		}
-		if err := sync.sendMsg(syncPb.SyncMsg_BLOCK_SYNC_RESP, bz, from); err != nil {
+		infos = append(infos, info)
+		batchSize += size
+	}
+	return sendBatch()
+}
+
+// sendBlockBatch sends a block sync response, which is compressed if compressResponse is enabled
+func (sync *BlockChainSyncServer) sendBlockBatch(batch *syncPb.SyncBlockBatch, to string) error {
+	bz, err := proto.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	if sync.conf.compressResponse {
+		originSize := len(bz)
+		if bz, err = compressPayload(bz); err != nil {
 			return err
 		}
+		sync.log.Debugf("compress block sync response to node [%s] from %d to %d bytes", to, originSize, len(bz))
 	}
-	return nil
+	return sync.sendMsg(syncPb.SyncMsg_BLOCK_SYNC_RESP, bz, to)
 }
 
 func (sync *BlockChainSyncServer) sendMsg(msgType syncPb.SyncMsg_MsgType, msg []byte, to string) error {
@@ -395,6 +471,9 @@ func (sync *BlockChainSyncServer) Stop() {
 	if !atomic.CompareAndSwapInt32(&sync.start, 1, 0) {
 		return
 	}
+	if sync.msgBus != nil {
+		sync.msgBus.UnRegister(msgbus.BlockInfo, sync)
+	}
 	sync.scheduler.end()
 	sync.processor.end()
 	close(sync.close)
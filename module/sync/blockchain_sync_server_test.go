@@ -11,11 +11,16 @@ import (
 	"testing"
 	"time"
 
+	"chainmaker.org/chainmaker/common/v2/msgbus"
+	mbusmock "chainmaker.org/chainmaker/common/v2/msgbus/mock"
+	"chainmaker.org/chainmaker/logger/v2"
 	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
 	netPb "chainmaker.org/chainmaker/pb-go/v2/net"
 	syncPb "chainmaker.org/chainmaker/pb-go/v2/sync"
 
 	"chainmaker.org/chainmaker/protocol/v2"
+	"chainmaker.org/chainmaker/protocol/v2/mock"
+	"github.com/gogo/protobuf/proto"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 )
@@ -100,6 +105,43 @@ func TestBlockChainSyncServer_Start(t *testing.T) {
 	require.EqualValues(t, "pendingBlockHeight: 12, queue num: 0", implSync.processor.getServiceState())
 }
 
+func TestBlockChainSyncServer_Restart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	subscribers := make(map[msgbus.Topic]map[msgbus.Subscriber]struct{})
+	mockMsgBus := mbusmock.NewMockMessageBus(ctrl)
+	mockMsgBus.EXPECT().Register(gomock.Any(), gomock.Any()).Do(func(topic msgbus.Topic, sub msgbus.Subscriber) {
+		if subscribers[topic] == nil {
+			subscribers[topic] = make(map[msgbus.Subscriber]struct{})
+		}
+		subscribers[topic][sub] = struct{}{}
+	}).AnyTimes()
+	mockMsgBus.EXPECT().UnRegister(gomock.Any(), gomock.Any()).Do(func(topic msgbus.Topic, sub msgbus.Subscriber) {
+		delete(subscribers[topic], sub)
+	}).AnyTimes()
+	mockNet := newMockNet(ctrl)
+	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 10}})
+	mockStore := newMockBlockChainStore(ctrl)
+	mockVerify := newMockVerifier(ctrl)
+	mockCommit := newMockCommitter(ctrl, mockLedger)
+	start := func() protocol.SyncService {
+		sync := NewBlockChainSyncServer("chain1", mockNet, mockMsgBus, mockStore, mockLedger, mockVerify, mockCommit)
+		require.NoError(t, sync.Start())
+		return sync
+	}
+
+	// the stopped sync server is replaced by a new one when the chain resumes:
+	// pause, resume, stop and resume again
+	sync := start()
+	sync.Stop()
+	sync = start()
+	sync.Stop()
+	sync = start()
+	defer sync.Stop()
+	require.Len(t, subscribers[msgbus.BlockInfo], 1)
+	require.Contains(t, subscribers[msgbus.BlockInfo], sync)
+}
+
 func TestSyncMsg_NODE_STATUS_REQ(t *testing.T) {
 	sync, fn := initTestSync(t)
 	defer fn()
@@ -165,3 +207,64 @@ func TestSyncMsg_BLOCK_SYNC_RESP(t *testing.T) {
 		"pendingBlocks num: 109, receivedBlocks num: 0", implSync.scheduler.getServiceState())
 	require.EqualValues(t, "pendingBlockHeight: 12, queue num: 0", implSync.processor.getServiceState())
 }
+
+func TestSendBlocksInBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	payloads := make([][]byte, 0)
+	mockNet := mock.NewMockNetService(ctrl)
+	mockNet.EXPECT().SendMsg(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(msg []byte, msgType netPb.NetMsg_MsgType, to ...string) error {
+			syncMsg := syncPb.SyncMsg{}
+			require.NoError(t, proto.Unmarshal(msg, &syncMsg))
+			require.EqualValues(t, syncPb.SyncMsg_BLOCK_SYNC_RESP, syncMsg.Type)
+			payloads = append(payloads, syncMsg.Payload)
+			return nil
+		}).AnyTimes()
+	mockStore := newMockBlockChainStore(ctrl)
+	for i := uint64(1); i <= 5; i++ {
+		_ = mockStore.PutBlock(&commonPb.Block{Header: &commonPb.BlockHeader{ChainId: "chain1", BlockHeight: i}}, nil)
+	}
+	blkSize := uint64(proto.Size(&commonPb.Block{Header: &commonPb.BlockHeader{ChainId: "chain1", BlockHeight: 1}}))
+	sync := &BlockChainSyncServer{
+		net:             mockNet,
+		blockChainStore: mockStore,
+		conf:            NewBlockSyncServerConf().SetMaxBatchBytes(2 * blkSize),
+		log:             logger.GetLogger(logger.MODULE_SYNC),
+	}
+
+	// 1. blocks are packed up to maxBatchBytes
+	require.NoError(t, sync.sendBlocks(&syncPb.BlockSyncReq{BlockHeight: 1, BatchSize: 5}, "node1"))
+	require.EqualValues(t, 3, len(payloads))
+	for i, num := range []int{2, 2, 1} {
+		batch := syncPb.SyncBlockBatch{}
+		require.NoError(t, proto.Unmarshal(payloads[i], &batch))
+		require.EqualValues(t, num, len(batch.GetBlockBatch().Batches))
+	}
+
+	// 2. the blocks found are sent before the error is returned
+	payloads = payloads[:0]
+	require.Error(t, sync.sendBlocks(&syncPb.BlockSyncReq{BlockHeight: 4, BatchSize: 5}, "node1"))
+	require.EqualValues(t, 1, len(payloads))
+
+	// 3. compressed response can be decompressed
+	payloads = payloads[:0]
+	sync.conf.SetCompressResponse(true).SetMaxBatchBytes(defaultMaxBatchBytes)
+	require.NoError(t, sync.sendBlocks(&syncPb.BlockSyncReq{BlockHeight: 1, BatchSize: 5}, "node1"))
+	require.EqualValues(t, 1, len(payloads))
+	bz, err := decompressPayload(payloads[0], defaultMaxDecompressedBytes)
+	require.NoError(t, err)
+	batch := syncPb.SyncBlockBatch{}
+	require.NoError(t, proto.Unmarshal(bz, &batch))
+	require.EqualValues(t, 5, len(batch.GetBlockBatch().Batches))
+
+	// 4. uncompressed payload is returned as it is
+	bz, err = decompressPayload(payloads[0][:0], defaultMaxDecompressedBytes)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, len(bz))
+
+	// 5. payload decompressed to more than the max size is rejected
+	_, err = decompressPayload(payloads[0], 16)
+	require.Error(t, err)
+}
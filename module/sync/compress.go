@@ -0,0 +1,56 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// gzipMagic is the header of gzip data. A marshaled SyncBlockBatch never starts with
+// 0x1f (field 3 with the invalid wire type 7), so compressed payloads can be recognized
+// without a flag in the message.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compressPayload compresses the marshaled SyncBlockBatch with gzip
+func compressPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressPayload returns the payload as it is if it is not compressed, the payload decompressed to more than
+// maxSize bytes is rejected, so that a peer can not exhaust the memory with a small compressed message
+func decompressPayload(payload []byte, maxSize uint64) ([]byte, error) {
+	if !bytes.HasPrefix(payload, gzipMagic) {
+		return payload, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) > maxSize {
+		return nil, fmt.Errorf("decompressed payload exceeds %d bytes", maxSize)
+	}
+	return data, nil
+}
@@ -9,9 +9,56 @@ package sync
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
+const (
+	// defaultMaxBatchBytes is the default max size of blocks packed in one response message
+	defaultMaxBatchBytes = 4 * 1024 * 1024
+	// defaultMaxDecompressedBytes is the default max size of a block sync response from peers after decompressing
+	defaultMaxDecompressedBytes = 64 * 1024 * 1024
+)
+
+// ExtConfig the settings of sync in chainmaker.yml which are not a part of localconf, 0 means the default
+type ExtConfig struct {
+	// PeerBanDuration the time in seconds a misbehaving peer is excluded from the block requests
+	PeerBanDuration float64 `mapstructure:"peer_ban_duration"`
+	// MaxBatchBytes the max size of blocks packed in one response message, at least one block is packed
+	MaxBatchBytes uint64 `mapstructure:"max_batch_bytes"`
+	// CompressResponse whether to compress the block response messages with gzip
+	CompressResponse bool `mapstructure:"compress_response"`
+	// MaxDecompressedBytes the max size of a block response from peers after decompressing, the responses
+	// exceeding it are dropped, so it must not be less than the max_batch_bytes and the block size of peers
+	MaxDecompressedBytes uint64 `mapstructure:"max_decompressed_bytes"`
+}
+
+var (
+	extConfig   = &ExtConfig{}
+	extConfigMu sync.RWMutex
+)
+
+// SetExtConfig sets the ext config of sync for all chains, it takes effect when the sync service starts
+func SetExtConfig(config *ExtConfig) error {
+	if config.PeerBanDuration < 0 {
+		return fmt.Errorf("peer_ban_duration must not be negative: %v", config.PeerBanDuration)
+	}
+	if config.MaxDecompressedBytes > 0 && config.MaxDecompressedBytes < config.MaxBatchBytes {
+		return fmt.Errorf("max_decompressed_bytes %d is less than max_batch_bytes %d",
+			config.MaxDecompressedBytes, config.MaxBatchBytes)
+	}
+	extConfigMu.Lock()
+	defer extConfigMu.Unlock()
+	extConfig = config
+	return nil
+}
+
+func getExtConfig() *ExtConfig {
+	extConfigMu.RLock()
+	defer extConfigMu.RUnlock()
+	return extConfig
+}
+
 type BlockSyncServerConf struct {
 	timeOut          time.Duration // Timeout of request, unit nanosecond
 	reqTimeThreshold time.Duration // When the difference between the height of the node and the latest height of peers
@@ -21,10 +68,13 @@ type BlockSyncServerConf struct {
 	schedulerTick     time.Duration // The ticker to request block from the peer, unit nanosecond
 	nodeStatusTick    time.Duration // The ticker to request node status from other peers, unit nanosecond
 	dataDetectionTick time.Duration // The ticker to check data in processor
+	peerBanDuration   time.Duration // The time a misbehaving peer is excluded from the block requests
 
 	blockPoolSize        uint64 // Maximum number of blocks to be processed in scheduler
 	batchSizeFromOneNode uint64 // The number of blocks received from each node in a request
-
+	maxBatchBytes        uint64 // The max size of blocks packed in one response message, at least one block is packed
+	compressResponse     bool   // Whether to compress the block response messages with gzip
+	maxDecompressedBytes uint64 // The max size of a block response from peers after decompressing
 }
 
 func NewBlockSyncServerConf() *BlockSyncServerConf {
@@ -32,12 +82,15 @@ func NewBlockSyncServerConf() *BlockSyncServerConf {
 		timeOut:              5 * time.Second,
 		blockPoolSize:        bufferSize,
 		batchSizeFromOneNode: 1,
+		maxBatchBytes:        defaultMaxBatchBytes,
+		maxDecompressedBytes: defaultMaxDecompressedBytes,
 		processBlockTick:     20 * time.Millisecond,
 		livenessTick:         1 * time.Second,
 		nodeStatusTick:       5 * time.Second,
 		schedulerTick:        20 * time.Millisecond,
 		dataDetectionTick:    time.Minute,
 		reqTimeThreshold:     3 * time.Second,
+		peerBanDuration:      defaultPeerBanDuration,
 	}
 }
 
@@ -77,9 +130,26 @@ func (c *BlockSyncServerConf) SetReqTimeThreshold(n float64) *BlockSyncServerCon
 	c.reqTimeThreshold = time.Duration(n * float64(time.Second))
 	return c
 }
+func (c *BlockSyncServerConf) SetMaxBatchBytes(n uint64) *BlockSyncServerConf {
+	c.maxBatchBytes = n
+	return c
+}
+func (c *BlockSyncServerConf) SetCompressResponse(compress bool) *BlockSyncServerConf {
+	c.compressResponse = compress
+	return c
+}
+func (c *BlockSyncServerConf) SetMaxDecompressedBytes(n uint64) *BlockSyncServerConf {
+	c.maxDecompressedBytes = n
+	return c
+}
+func (c *BlockSyncServerConf) SetPeerBanDuration(n float64) *BlockSyncServerConf {
+	c.peerBanDuration = time.Duration(n * float64(time.Second))
+	return c
+}
 func (c *BlockSyncServerConf) print() string {
 	return fmt.Sprintf("blockPoolSize: %d, request timeout: %d, batchSizeFromOneNode: %d"+
-		", processBlockTick: %v, schedulerTick: %v, livenessTick: %v, nodeStatusTick: %v\n",
+		", processBlockTick: %v, schedulerTick: %v, livenessTick: %v, nodeStatusTick: %v, peerBanDuration: %v"+
+		", maxBatchBytes: %d, compressResponse: %v, maxDecompressedBytes: %d\n",
 		c.blockPoolSize, c.timeOut, c.batchSizeFromOneNode, c.processBlockTick, c.schedulerTick, c.livenessTick,
-		c.nodeStatusTick)
+		c.nodeStatusTick, c.peerBanDuration, c.maxBatchBytes, c.compressResponse, c.maxDecompressedBytes)
 }
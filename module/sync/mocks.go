@@ -340,6 +340,7 @@ func newMockLedgerCache(ctrl *gomock.Controller, blk *commonPb.Block) protocol.L
 func newMockMessageBus(ctrl *gomock.Controller) msgbus.MessageBus {
 	mockMsgBus := mbusmock.NewMockMessageBus(ctrl)
 	mockMsgBus.EXPECT().Register(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMsgBus.EXPECT().UnRegister(gomock.Any(), gomock.Any()).AnyTimes()
 	return mockMsgBus
 }
 
@@ -0,0 +1,169 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sync
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPeerBanDuration = time.Minute // The time a misbehaving peer is excluded from the block requests
+	maxConsecutiveFailures = 3           // The number of consecutive timeouts after which the peer is banned
+	validateFailedPenalty  = 5           // A block failed to validate weighs as much as 5 timeouts
+	latencyEwmaFactor      = 0.3         // The weight of the newest sample in the latency moving average
+)
+
+// peerScore records the behaviour of a peer when serving the block requests
+type peerScore struct {
+	latency             time.Duration // Exponentially weighted moving average of the response latency
+	successes           uint64        // The number of requests responded in time
+	failures            uint64        // The number of requests timed out
+	validateFailed      uint64        // The number of blocks which failed to be validated or committed
+	consecutiveFailures uint64
+	penalty             float64 // Decayed by each success, so that a recovered peer is selected again
+	bannedUntil         time.Time
+}
+
+// weight the relative probability of the peer to be selected, higher is better
+func (s *peerScore) weight() float64 {
+	return 1 / ((1 + s.latency.Seconds()) * (1 + s.penalty))
+}
+
+func (s *peerScore) isBanned(now time.Time) bool {
+	return now.Before(s.bannedUntil)
+}
+
+// peerReputation tracks the scores of the peers and selects the peer to request blocks from
+type peerReputation struct {
+	scores      map[string]*peerScore
+	banDuration time.Duration
+	random      *rand.Rand
+}
+
+func newPeerReputation(banDuration time.Duration) *peerReputation {
+	return &peerReputation{
+		scores:      make(map[string]*peerScore),
+		banDuration: banDuration,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (rep *peerReputation) getScore(peer string) *peerScore {
+	score, exist := rep.scores[peer]
+	if !exist {
+		score = &peerScore{}
+		rep.scores[peer] = score
+	}
+	return score
+}
+
+// onResponse records a response received latency after the request
+func (rep *peerReputation) onResponse(peer string, latency time.Duration) {
+	score := rep.getScore(peer)
+	if score.successes == 0 && score.failures == 0 {
+		score.latency = latency
+	} else {
+		score.latency = time.Duration(latencyEwmaFactor*float64(latency) +
+			(1-latencyEwmaFactor)*float64(score.latency))
+	}
+	score.successes++
+	score.consecutiveFailures = 0
+	score.penalty /= 2
+}
+
+// onTimeout records a request timed out, the peer is banned after too many consecutive timeouts
+func (rep *peerReputation) onTimeout(peer string) (banned bool) {
+	score := rep.getScore(peer)
+	score.failures++
+	score.consecutiveFailures++
+	score.penalty++
+	if score.consecutiveFailures >= maxConsecutiveFailures {
+		score.bannedUntil = time.Now().Add(rep.banDuration)
+		score.consecutiveFailures = 0
+		return true
+	}
+	return false
+}
+
+// onValidateFailed records a block from the peer failed to be validated, the peer is banned immediately
+func (rep *peerReputation) onValidateFailed(peer string) {
+	score := rep.getScore(peer)
+	score.validateFailed++
+	score.penalty += validateFailedPenalty
+	score.bannedUntil = time.Now().Add(rep.banDuration)
+}
+
+func (rep *peerReputation) isBanned(peer string) bool {
+	score, exist := rep.scores[peer]
+	return exist && score.isBanned(time.Now())
+}
+
+// selectPeer selects a peer from candidates randomly, the probability is proportional to the peer weight
+// divided by the number of requests pending in the peer. Banned peers are never selected.
+func (rep *peerReputation) selectPeer(candidates []string, pendingReqs func(peer string) int) string {
+	sort.Strings(candidates)
+	var (
+		total   float64
+		now     = time.Now()
+		peers   = make([]string, 0, len(candidates))
+		weights = make([]float64, 0, len(candidates))
+	)
+	for _, peer := range candidates {
+		weight := 1.0
+		if score, exist := rep.scores[peer]; exist {
+			if score.isBanned(now) {
+				continue
+			}
+			weight = score.weight()
+		}
+		weight /= float64(1 + pendingReqs(peer))
+		peers = append(peers, peer)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if len(peers) == 0 {
+		return ""
+	}
+
+	point := rep.random.Float64() * total
+	for i, weight := range weights {
+		if point < weight {
+			return peers[i]
+		}
+		point -= weight
+	}
+	return peers[len(peers)-1]
+}
+
+func (rep *peerReputation) String() string {
+	if len(rep.scores) == 0 {
+		return ""
+	}
+	peers := make([]string, 0, len(rep.scores))
+	for peer := range rep.scores {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+
+	now := time.Now()
+	states := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		score := rep.scores[peer]
+		state := fmt.Sprintf("%s[latency: %v, successes: %d, failures: %d, validateFailed: %d, weight: %.3f",
+			peer, score.latency, score.successes, score.failures, score.validateFailed, score.weight())
+		if score.isBanned(now) {
+			state += fmt.Sprintf(", banned: %v", score.bannedUntil.Sub(now).Round(time.Second))
+		}
+		states = append(states, state+"]")
+	}
+	return strings.Join(states, ", ")
+}
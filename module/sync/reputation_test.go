@@ -0,0 +1,124 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"chainmaker.org/chainmaker/logger/v2"
+	commonPb "chainmaker.org/chainmaker/pb-go/v2/common"
+	syncPb "chainmaker.org/chainmaker/pb-go/v2/sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func noPendingReqs(string) int {
+	return 0
+}
+
+func TestPeerReputation_SelectPeer(t *testing.T) {
+	rep := newPeerReputation(time.Minute)
+	peers := []string{"node1", "node2", "node3"}
+
+	// 1. the peers without scores are selected
+	selected := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		selected[rep.selectPeer(peers, noPendingReqs)]++
+	}
+	require.EqualValues(t, 3, len(selected))
+
+	// 2. slow peer is selected less than fast peer
+	rep.onResponse("node1", 10*time.Millisecond)
+	rep.onResponse("node2", 5*time.Second)
+	require.Greater(t, rep.scores["node1"].weight(), rep.scores["node2"].weight())
+
+	// 3. banned peer is never selected
+	rep.onValidateFailed("node3")
+	require.True(t, rep.isBanned("node3"))
+	for i := 0; i < 100; i++ {
+		require.NotEqual(t, "node3", rep.selectPeer(peers, noPendingReqs))
+	}
+
+	// 4. no peer can be selected when all are banned
+	rep.onValidateFailed("node1")
+	rep.onValidateFailed("node2")
+	require.EqualValues(t, "", rep.selectPeer(peers, noPendingReqs))
+}
+
+func TestPeerReputation_Timeout(t *testing.T) {
+	rep := newPeerReputation(time.Minute)
+
+	for i := 1; i < maxConsecutiveFailures; i++ {
+		require.False(t, rep.onTimeout("node1"))
+	}
+	// a response resets the consecutive failures
+	rep.onResponse("node1", time.Millisecond)
+	require.False(t, rep.onTimeout("node1"))
+	require.False(t, rep.isBanned("node1"))
+
+	for i := 1; i < maxConsecutiveFailures; i++ {
+		rep.onTimeout("node1")
+	}
+	require.True(t, rep.isBanned("node1"))
+	require.EqualValues(t, 1, rep.scores["node1"].successes)
+	require.EqualValues(t, maxConsecutiveFailures+1, rep.scores["node1"].failures)
+}
+
+func TestSchedulerPeerScores(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 10}})
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
+
+	// 1. request blocks from the only peer
+	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 100}})
+	_, _ = sch.handler(SchedulerMsg{})
+	require.EqualValues(t, "node1", sch.pendingBlocks[11])
+
+	// 2. the response is scored
+	bz, err := proto.Marshal(&syncPb.SyncBlockBatch{
+		Data: &syncPb.SyncBlockBatch_BlockBatch{BlockBatch: &syncPb.BlockBatch{Batches: []*commonPb.Block{
+			{Header: &commonPb.BlockHeader{BlockHeight: 11}},
+			{Header: &commonPb.BlockHeader{BlockHeight: 12}},
+		}}},
+	})
+	require.NoError(t, err)
+	_, err = sch.handler(&SyncedBlockMsg{from: "node1", msg: bz})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, sch.reputation.scores["node1"].successes)
+	require.True(t, strings.Contains(sch.getServiceState(), "peer scores: node1[latency: "))
+
+	// 3. the peer sending invalid block is banned and not requested any more
+	_, err = sch.handler(ProcessedBlockResp{from: "node1", status: validateFailed, height: 11})
+	require.NoError(t, err)
+	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 100}})
+	require.EqualValues(t, "", sch.selectPeer(11))
+	require.True(t, strings.Contains(sch.getServiceState(), "validateFailed: 1"))
+	require.True(t, strings.Contains(sch.getServiceState(), "banned: "))
+}
+
+func TestSchedulerCommitErrorNotPenalized(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 10}})
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, time.Hour,
+		logger.GetLogger(logger.MODULE_SYNC))
+	require.Equal(t, time.Hour, sch.reputation.banDuration)
+
+	// the block failing to commit locally has been verified, the peer is requested again
+	_, err := sch.handler(ProcessedBlockResp{from: "node1", status: addErr, height: 11})
+	require.Error(t, err)
+	require.False(t, sch.reputation.isBanned("node1"))
+	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 100}})
+	require.EqualValues(t, "node1", sch.selectPeer(11))
+}
@@ -10,7 +10,7 @@ package sync
 import (
 	"fmt"
 	"math"
-	"sort"
+	"sync/atomic"
 	"time"
 
 	"chainmaker.org/chainmaker/logger/v2"
@@ -41,6 +41,9 @@ type scheduler struct {
 	peerReqTimeout      time.Duration // The maximum timeout for a node response
 	reqTimeThreshold    time.Duration // When the difference between the height of the node and
 	// the latest height of peers is 1, the time interval for requesting
+	reputation    *peerReputation // The scores of peers used to select the peer to request blocks from
+	maxPeerHeight uint64          // The highest height of the connected peers, read by other goroutines atomically
+	hasPeer       int32           // Whether the status of any peer is received, read by other goroutines atomically
 
 	log    *logger.CMLogger
 	sender syncSender
@@ -48,7 +51,8 @@ type scheduler struct {
 }
 
 func newScheduler(sender syncSender, ledger protocol.LedgerCache,
-	maxNum uint64, timeOut, reqTimeThreshold time.Duration, batchesize uint64, log *logger.CMLogger) *scheduler {
+	maxNum uint64, timeOut, reqTimeThreshold time.Duration, batchesize uint64, peerBanDuration time.Duration,
+	log *logger.CMLogger) *scheduler {
 
 	currHeight, err := ledger.CurrentHeight()
 	if err != nil {
@@ -63,6 +67,7 @@ func newScheduler(sender syncSender, ledger protocol.LedgerCache,
 		maxPendingBlocks:    maxNum,
 		BatchesizeInEachReq: batchesize,
 		reqTimeThreshold:    reqTimeThreshold,
+		reputation:          newPeerReputation(peerBanDuration),
 
 		peers:             make(map[string]uint64),
 		blockStates:       make(map[uint64]blockState),
@@ -74,6 +79,7 @@ func newScheduler(sender syncSender, ledger protocol.LedgerCache,
 }
 
 func (sch *scheduler) handler(event queue.Item) (queue.Item, error) {
+	defer sch.updateMaxPeerHeight()
 	switch msg := event.(type) {
 	case NodeStatusMsg:
 		sch.handleNodeStatus(msg)
@@ -91,6 +97,26 @@ func (sch *scheduler) handler(event queue.Item) (queue.Item, error) {
 	return nil, nil
 }
 
+func (sch *scheduler) updateMaxPeerHeight() {
+	var maxHeight uint64
+	for _, height := range sch.peers {
+		if height > maxHeight {
+			maxHeight = height
+		}
+	}
+	hasPeer := int32(0)
+	if len(sch.peers) > 0 {
+		hasPeer = 1
+	}
+	atomic.StoreUint64(&sch.maxPeerHeight, maxHeight)
+	atomic.StoreInt32(&sch.hasPeer, hasPeer)
+}
+
+// getMaxPeerHeight returns the highest height of the peers, known is false until the status of a peer is received
+func (sch *scheduler) getMaxPeerHeight() (height uint64, known bool) {
+	return atomic.LoadUint64(&sch.maxPeerHeight), atomic.LoadInt32(&sch.hasPeer) == 1
+}
+
 func (sch *scheduler) handleNodeStatus(msg NodeStatusMsg) {
 	localCurrBlk := sch.ledger.GetLastCommittedBlock()
 	if old, exist := sch.peers[msg.from]; exist {
@@ -146,6 +172,10 @@ func (sch *scheduler) handleLivinessMsg() {
 	if exist && time.Since(reqTime) > sch.peerReqTimeout {
 		id := sch.pendingBlocks[sch.pendingRecvHeight]
 		sch.log.Debugf("block request [height: %d] time out from node[%s]", sch.pendingRecvHeight, id)
+		if len(id) > 0 && sch.reputation.onTimeout(id) {
+			sch.log.Warnf("node[%s] is banned for %v because of too many request timeouts",
+				id, sch.reputation.banDuration)
+		}
 		if currBlk := sch.ledger.GetLastCommittedBlock(); currBlk != nil &&
 			currBlk.Header.BlockHeight < sch.pendingRecvHeight {
 			sch.blockStates[sch.pendingRecvHeight] = newBlock
@@ -236,21 +266,7 @@ func (sch *scheduler) selectPeer(pendingHeight uint64) string {
 	if len(peers) == 0 {
 		return ""
 	}
-
-	pendingReqInPeers := make(map[int][]string)
-	for i := 0; i < len(peers); i++ {
-		reqNum := sch.getPendingReqInPeer(peers[i])
-		pendingReqInPeers[reqNum] = append(pendingReqInPeers[reqNum], peers[i])
-	}
-	min := math.MaxInt64
-	for num := range pendingReqInPeers {
-		if min > num {
-			min = num
-		}
-	}
-	peers = pendingReqInPeers[min]
-	sort.Strings(peers)
-	return peers[0]
+	return sch.reputation.selectPeer(peers, sch.getPendingReqInPeer)
 }
 
 func (sch *scheduler) getHeight(pendingHeight uint64) []string {
@@ -282,7 +298,12 @@ func (sch *scheduler) handleSyncedBlockMsg(msg *SyncedBlockMsg) (queue.Item, err
 		return nil, nil
 	}
 	needToProcess := false
+	var reqTime time.Time
 	for _, blk := range blkBatch.GetBlockBatch().Batches {
+		// only the responses to the requests sent to the peer are scored
+		if sch.pendingBlocks[blk.Header.BlockHeight] == msg.from && reqTime.IsZero() {
+			reqTime = sch.pendingTime[blk.Header.BlockHeight]
+		}
 		delete(sch.pendingBlocks, blk.Header.BlockHeight)
 		delete(sch.pendingTime, blk.Header.BlockHeight)
 		if _, exist := sch.blockStates[blk.Header.BlockHeight]; exist {
@@ -293,6 +314,9 @@ func (sch *scheduler) handleSyncedBlockMsg(msg *SyncedBlockMsg) (queue.Item, err
 		sch.log.Debugf("received block [height:%d:%x] needToProcess: %v from "+
 			"node [%s]", blk.Header.BlockHeight, blk.Header.BlockHash, needToProcess, msg.from)
 	}
+	if !reqTime.IsZero() {
+		sch.reputation.onResponse(msg.from, time.Since(reqTime))
+	}
 	if needToProcess {
 		return &ReceivedBlocks{
 			blks: blkBatch.GetBlockBatch().Batches,
@@ -315,11 +339,15 @@ func (sch *scheduler) handleProcessedBlockResp(msg ProcessedBlockResp) (queue.It
 	if msg.status == validateFailed {
 		sch.blockStates[msg.height] = newBlock
 		delete(sch.peers, msg.from)
+		sch.reputation.onValidateFailed(msg.from)
+		sch.log.Warnf("node[%s] is banned for %v because block [height: %d] failed to validate",
+			msg.from, sch.reputation.banDuration, msg.height)
 	}
 	if msg.status == dbErr {
 		return nil, fmt.Errorf("query db failed in processor")
 	}
 	if msg.status == addErr {
+		// the block has been verified, so the commit error is local and the peer is not penalized
 		sch.blockStates[msg.height] = newBlock
 		delete(sch.peers, msg.from)
 		return nil, fmt.Errorf("failed add block to chain")
@@ -328,9 +356,13 @@ func (sch *scheduler) handleProcessedBlockResp(msg ProcessedBlockResp) (queue.It
 }
 
 func (sch *scheduler) getServiceState() string {
-	return fmt.Sprintf("pendingRecvHeight: %d, peers num: %d, blockStates num: %d, "+
+	state := fmt.Sprintf("pendingRecvHeight: %d, peers num: %d, blockStates num: %d, "+
 		"pendingBlocks num: %d, receivedBlocks num: %d", sch.pendingRecvHeight, len(sch.peers), len(sch.blockStates),
 		len(sch.pendingBlocks), len(sch.receivedBlocks))
+	if scores := sch.reputation.String(); len(scores) > 0 {
+		state += ", peer scores: " + scores
+	}
+	return state
 }
 
 func (sch *scheduler) isPeerArchivedTooHeight(localHeight, peerArchivedHeight uint64) bool {
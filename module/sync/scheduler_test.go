@@ -24,7 +24,8 @@ func TestNodeStatusMsg(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 100}})
-	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. the peer status is old
 	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 90}})
@@ -68,7 +69,8 @@ func TestNextHeightToReq(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 100}})
-	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. add block status
 	for i := uint64(0); i < 5; i++ {
@@ -93,7 +95,8 @@ func TestIsNeedSync(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 100}})
-	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. add peer status
 	sch.peers["node1"] = 110
@@ -120,7 +123,8 @@ func TestSchedulerMsg(t *testing.T) {
 	defer ctrl.Finish()
 	mockSender := NewMockSender()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 100}})
-	sch := newScheduler(mockSender, mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(mockSender, mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. add peer status
 	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 151}})
@@ -138,7 +142,8 @@ func TestSyncedBlockMsg(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 5}})
-	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	bz, _ := proto.Marshal(&syncPb.SyncBlockBatch{
 		Data: &syncPb.SyncBlockBatch_BlockBatch{BlockBatch: &syncPb.BlockBatch{Batches: []*commonPb.Block{
@@ -162,7 +167,8 @@ func TestProcessedBlockResp(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 5}})
-	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. add ok process result and check result
 	_, err := sch.handler(ProcessedBlockResp{height: 6, status: ok, from: "node1"})
@@ -187,7 +193,8 @@ func TestLivenessMsg(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 5}})
-	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. no any status
 	_, _ = sch.handler(LivenessMsg{})
@@ -212,7 +219,8 @@ func TestSchedulerFlow(t *testing.T) {
 	defer ctrl.Finish()
 	mockSender := NewMockSender()
 	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 10}})
-	sch := newScheduler(mockSender, mockLedger, 100, time.Second, time.Second*3, 2, logger.GetLogger(logger.MODULE_SYNC))
+	sch := newScheduler(mockSender, mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
 
 	// 1. add peers status
 	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 100}})
@@ -254,3 +262,24 @@ func TestSchedulerFlow(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, 98, len(sch.blockStates))
 }
+
+func TestMaxPeerHeight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockLedger := newMockLedgerCache(ctrl, &commonPb.Block{Header: &commonPb.BlockHeader{BlockHeight: 100}})
+	sch := newScheduler(NewMockSender(), mockLedger, 100, time.Second, time.Second*3, 2, defaultPeerBanDuration,
+		logger.GetLogger(logger.MODULE_SYNC))
+	_, known := sch.getMaxPeerHeight()
+	require.False(t, known)
+
+	_, _ = sch.handler(NodeStatusMsg{from: "node1", msg: syncPb.BlockHeightBCM{BlockHeight: 120}})
+	_, _ = sch.handler(NodeStatusMsg{from: "node2", msg: syncPb.BlockHeightBCM{BlockHeight: 110}})
+	height, known := sch.getMaxPeerHeight()
+	require.True(t, known)
+	require.EqualValues(t, 120, height)
+
+	// the peer sending invalid block is removed
+	_, _ = sch.handler(ProcessedBlockResp{from: "node1", status: validateFailed, height: 101})
+	height, _ = sch.getMaxPeerHeight()
+	require.EqualValues(t, 110, height)
+}
@@ -46,7 +46,10 @@ var (
 	txIds        string
 	topic        string
 	contractName string
+	eventFilters string
 	onlyHeader   bool
+	withCursor   bool
+	cursor       string
 
 	conn   *grpc.ClientConn
 	client apipb.RpcNodeClient
@@ -100,7 +103,10 @@ func main() {
 	mainFlags.StringVarP(&txIds, "tx-ids", "I", "", "specify the transaction ids, separated by comma, NOTICE: don't add space between ids")
 	mainFlags.StringVarP(&topic, "topic", "", "topic_vx", "specify the contract event topic")
 	mainFlags.StringVarP(&contractName, "contract-name", "", "claim001", "specify the contract name")
+	mainFlags.StringVarP(&eventFilters, "event-filters", "", "", "specify the json filters on the contract event data, e.g. [{\"index\":0,\"op\":\"eq\",\"value\":\"alice\"}]")
 	mainFlags.BoolVarP(&onlyHeader, "only-header", "H", false, "the results of blocks only contains Header or FUll Data when subscribe block")
+	mainFlags.BoolVarP(&withCursor, "with-cursor", "", false, "print the cursor of each result, which can be used to resume the subscription")
+	mainFlags.StringVarP(&cursor, "cursor", "", "", "specify the cursor to resume the subscription after")
 
 	if mainCmd.Execute() != nil {
 		return
@@ -190,6 +196,12 @@ func generateTxRequest(payload *common.Payload,
 }
 
 func subscribe(ctx context.Context, payload *common.Payload) (<-chan interface{}, error) {
+	if withCursor {
+		payload.Parameters = append(payload.Parameters, &common.KeyValuePair{Key: "WITH_CURSOR", Value: []byte("true")})
+	}
+	if cursor != "" {
+		payload.Parameters = append(payload.Parameters, &common.KeyValuePair{Key: "CURSOR", Value: []byte(cursor)})
+	}
 
 	req, err := generateTxRequest(payload, nil)
 	if err != nil {
@@ -219,6 +231,15 @@ func subscribe(ctx context.Context, payload *common.Payload) (<-chan interface{}
 					return
 				}
 
+				if withCursor {
+					resultWithCursor := &common.KeyValuePair{}
+					if err = proto.Unmarshal(result.Data, resultWithCursor); err != nil {
+						return
+					}
+					fmt.Printf("cursor => %s\n", resultWithCursor.Key)
+					result.Data = resultWithCursor.Value
+				}
+
 				var ret interface{}
 				switch payload.Method {
 				case syscontract.SubscribeFunction_SUBSCRIBE_BLOCK.String():
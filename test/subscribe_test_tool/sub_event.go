@@ -16,6 +16,7 @@ import (
 
 	"chainmaker.org/chainmaker/pb-go/v2/common"
 	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"chainmaker.org/chainmaker/sdk-go/v2/utils"
 )
 
 func SubscribeEventCMD() *cobra.Command {
@@ -27,7 +28,7 @@ func SubscribeEventCMD() *cobra.Command {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			c, err := subscribeContractEvent(ctx, topic, contractName)
+			c, err := subscribeContractEvent(ctx, startBlock, endBlock, topic, contractName, eventFilters)
 			if err != nil {
 				return err
 			}
@@ -60,19 +61,33 @@ func SubscribeEventCMD() *cobra.Command {
 	return cmd
 }
 
-func subscribeContractEvent(ctx context.Context, topic string, contractName string) (<-chan interface{}, error) {
+// subscribeContractEvent the topic and contractName can be a comma separated list or *
+func subscribeContractEvent(ctx context.Context, startBlock, endBlock int64, topic string, contractName string,
+	filters string) (<-chan interface{}, error) {
 
+	params := []*common.KeyValuePair{
+		{
+			Key:   syscontract.SubscribeContractEvent_TOPIC.String(),
+			Value: []byte(topic),
+		},
+		{
+			Key:   syscontract.SubscribeContractEvent_CONTRACT_NAME.String(),
+			Value: []byte(contractName),
+		},
+		{
+			Key:   "START_BLOCK",
+			Value: utils.I64ToBytes(startBlock),
+		},
+		{
+			Key:   "END_BLOCK",
+			Value: utils.I64ToBytes(endBlock),
+		},
+	}
+	if filters != "" {
+		params = append(params, &common.KeyValuePair{Key: "FILTERS", Value: []byte(filters)})
+	}
 	payload := createPayload(chainId, "", common.TxType_SUBSCRIBE, syscontract.SystemContract_SUBSCRIBE_MANAGE.String(),
-		syscontract.SubscribeFunction_SUBSCRIBE_CONTRACT_EVENT.String(), []*common.KeyValuePair{
-			{
-				Key:   syscontract.SubscribeContractEvent_TOPIC.String(),
-				Value: []byte(topic),
-			},
-			{
-				Key:   syscontract.SubscribeContractEvent_CONTRACT_NAME.String(),
-				Value: []byte(contractName),
-			},
-		}, 0,
+		syscontract.SubscribeFunction_SUBSCRIBE_CONTRACT_EVENT.String(), params, 0,
 	)
 
 	return subscribe(ctx, payload)
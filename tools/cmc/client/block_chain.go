@@ -9,10 +9,12 @@ package client
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"chainmaker.org/chainmaker-go/tools/cmc/util"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
 )
 
 func blockChainsCMD() *cobra.Command {
@@ -22,6 +24,16 @@ func blockChainsCMD() *cobra.Command {
 		Long:  "blockchains command",
 	}
 	chainConfigCmd.AddCommand(checkNewBlockchainsCMD())
+	chainConfigCmd.AddCommand(blockchainLifecycleCMD("status", "get the status of the blockchain in the node",
+		chainLifecycleGetStatus))
+	chainConfigCmd.AddCommand(blockchainLifecycleCMD("pause", "pause the consensus and sync of the blockchain in the node",
+		chainLifecyclePause))
+	chainConfigCmd.AddCommand(blockchainLifecycleCMD("resume", "resume the paused or stopped blockchain in the node",
+		chainLifecycleResume))
+	chainConfigCmd.AddCommand(blockchainLifecycleCMD("stop", "stop the blockchain in the node",
+		chainLifecycleStop))
+	chainConfigCmd.AddCommand(blockchainLifecycleCMD("remove", "stop and remove the blockchain from the node",
+		chainLifecycleRemove))
 	return chainConfigCmd
 }
 
@@ -59,3 +71,63 @@ func checkNewBlockchains() error {
 	fmt.Printf("check new blockchains ok \n")
 	return nil
 }
+
+// the chain lifecycle is managed by the admin RPC of the node connected, see ChainLifecycle in rpcserver
+const (
+	adminMethodChainLifecycle = "ChainLifecycle"
+	chainLifecycleGetStatus   = "GET_CHAIN_STATUS"
+	chainLifecyclePause       = "PAUSE_CHAIN"
+	chainLifecycleResume      = "RESUME_CHAIN"
+	chainLifecycleStop        = "STOP_CHAIN"
+	chainLifecycleRemove      = "REMOVE_CHAIN"
+)
+
+func blockchainLifecycleCMD(use, short, method string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  short + ", the sender must be an admin of the org which the node belongs to",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return blockchainLifecycle(method)
+		},
+	}
+
+	attachFlags(cmd, []string{
+		flagUserSignKeyFilePath, flagUserSignCrtFilePath,
+		flagSdkConfPath, flagOrgId, flagChainId, flagUserTlsCrtFilePath, flagUserTlsKeyFilePath,
+	})
+
+	cmd.MarkFlagRequired(flagSdkConfPath)
+	cmd.MarkFlagRequired(flagChainId)
+
+	return cmd
+}
+
+func blockchainLifecycle(method string) error {
+	client, err := util.CreateChainClient(sdkConfPath, chainId, orgId, userTlsCrtFilePath, userTlsKeyFilePath,
+		userSignCrtFilePath, userSignKeyFilePath)
+	if err != nil {
+		return fmt.Errorf("create user client failed, %s", err.Error())
+	}
+	defer client.Stop()
+	resp, err := util.CallAdmin(client, &util.AdminRequest{
+		SdkConfPath:     sdkConfPath,
+		ChainId:         chainId,
+		OrgId:           orgId,
+		UserTlsCrtPath:  userTlsCrtFilePath,
+		UserTlsKeyPath:  userTlsKeyFilePath,
+		UserSignCrtPath: userSignCrtFilePath,
+		UserSignKeyPath: userSignKeyFilePath,
+		Method:          adminMethodChainLifecycle,
+		Operation:       method,
+		Timeout:         time.Duration(DEFAULT_TIMEOUT) * time.Millisecond,
+	})
+	if err != nil {
+		return fmt.Errorf("%s failed, %s", method, err.Error())
+	}
+	if resp.Code != common.TxStatusCode_SUCCESS {
+		return fmt.Errorf("%s failed, %s", method, resp.Message)
+	}
+	fmt.Printf("%s\n", resp.ContractResult.Result)
+	return nil
+}
@@ -75,6 +75,8 @@ var (
 	trustMemberInfoPath string
 	trustMemberRole     string
 	trustMemberNodeId   string
+
+	blackListPath string
 )
 
 const (
@@ -124,6 +126,7 @@ const (
 	flagEpochID                = "epoch-id"
 	flagGrantContractList      = "grant-contract-list"
 	flagRevokeContractList     = "revoke-contract-list"
+	flagBlackListPath          = "blacklist-path"
 )
 
 func ClientCMD() *cobra.Command {
@@ -138,6 +141,8 @@ func ClientCMD() *cobra.Command {
 	clientCmd.AddCommand(getChainMakerServerVersionCMD())
 	clientCmd.AddCommand(certManageCMD())
 	clientCmd.AddCommand(blockChainsCMD())
+	clientCmd.AddCommand(rpcBlackListCMD())
+	clientCmd.AddCommand(consensusAdminCMD())
 
 	return clientCmd
 }
@@ -217,6 +222,9 @@ func init() {
 	flags.StringVar(&epochID, flagEpochID, "", "specify epoch id")
 	flags.StringSliceVar(&grantContractList, flagGrantContractList, nil, "specify grant list")
 	flags.StringSliceVar(&revokeContractList, flagRevokeContractList, nil, "specify revoke list")
+
+	flags.StringVar(&blackListPath, flagBlackListPath, "", "specify the rpc blacklist file path, json format, "+
+		"such as: {\"addresses\":[\"10.10.0.0/16\"],\"allow_addresses\":[],\"cert_hashes\":[],\"orgs\":[]}")
 }
 
 func attachFlags(cmd *cobra.Command, names []string) {
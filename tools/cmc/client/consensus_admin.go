@@ -0,0 +1,93 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"chainmaker.org/chainmaker-go/tools/cmc/util"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// the consensus is operated by the admin RPC of the node connected, see ConsensusAdmin in rpcserver
+const (
+	adminMethodConsensusAdmin        = "ConsensusAdmin"
+	consensusAdminTransferLeadership = "TRANSFER_LEADERSHIP"
+	consensusAdminNodeIdParam        = "node_id"
+)
+
+func consensusAdminCMD() *cobra.Command {
+	consensusCmd := &cobra.Command{
+		Use:   "consensus",
+		Short: "consensus admin command",
+		Long:  "consensus admin command",
+	}
+	consensusCmd.AddCommand(transferLeadershipCMD())
+	return consensusCmd
+}
+
+func transferLeadershipCMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "transfer-leadership",
+		Short: "transfer the consensus leadership",
+		Long: "transfer the consensus leadership to the voter specified by --node-id, if it is not specified, " +
+			"the node connected must be the leader and transfers the leadership to the most up-to-date voter. " +
+			"The sender must be an admin of the org which the node belongs to",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return transferLeadership()
+		},
+	}
+
+	attachFlags(cmd, []string{
+		flagUserSignKeyFilePath, flagUserSignCrtFilePath,
+		flagSdkConfPath, flagOrgId, flagChainId, flagUserTlsCrtFilePath, flagUserTlsKeyFilePath,
+		flagNodeId,
+	})
+
+	cmd.MarkFlagRequired(flagSdkConfPath)
+	cmd.MarkFlagRequired(flagChainId)
+
+	return cmd
+}
+
+func transferLeadership() error {
+	client, err := util.CreateChainClient(sdkConfPath, chainId, orgId, userTlsCrtFilePath, userTlsKeyFilePath,
+		userSignCrtFilePath, userSignKeyFilePath)
+	if err != nil {
+		return fmt.Errorf("create user client failed, %s", err.Error())
+	}
+	defer client.Stop()
+	var params []*common.KeyValuePair
+	if nodeId != "" {
+		params = append(params, &common.KeyValuePair{Key: consensusAdminNodeIdParam, Value: []byte(nodeId)})
+	}
+	resp, err := util.CallAdmin(client, &util.AdminRequest{
+		SdkConfPath:     sdkConfPath,
+		ChainId:         chainId,
+		OrgId:           orgId,
+		UserTlsCrtPath:  userTlsCrtFilePath,
+		UserTlsKeyPath:  userTlsKeyFilePath,
+		UserSignCrtPath: userSignCrtFilePath,
+		UserSignKeyPath: userSignKeyFilePath,
+		Method:          adminMethodConsensusAdmin,
+		Operation:       consensusAdminTransferLeadership,
+		Parameters:      params,
+		Timeout:         time.Duration(DEFAULT_TIMEOUT) * time.Millisecond,
+	})
+	if err != nil {
+		return fmt.Errorf("transfer leadership failed, %s", err.Error())
+	}
+	if resp.Code != common.TxStatusCode_SUCCESS {
+		return fmt.Errorf("transfer leadership failed, %s", resp.Message)
+	}
+	fmt.Printf("%s\n", resp.ContractResult.Result)
+	return nil
+}
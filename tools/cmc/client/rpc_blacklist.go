@@ -0,0 +1,103 @@
+/*
+Copyright (C) BABEC. All rights reserved.
+Copyright (C) THL A29 Limited, a Tencent company. All rights reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"chainmaker.org/chainmaker-go/tools/cmc/util"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// the rpc blacklist is managed by the admin RPC of the node connected, see RpcBlackList in rpcserver
+const (
+	adminMethodRpcBlackList = "RpcBlackList"
+	rpcBlackListGet         = "GET_BLACKLIST"
+	rpcBlackListSet         = "SET_BLACKLIST"
+	rpcBlackListParamConfig = "blacklist"
+)
+
+func rpcBlackListCMD() *cobra.Command {
+	blackListCmd := &cobra.Command{
+		Use:   "blacklist",
+		Short: "rpc blacklist command",
+		Long:  "rpc blacklist command",
+	}
+	blackListCmd.AddCommand(rpcBlackListMethodCMD("get", "get the rpc blacklist in use by the node",
+		rpcBlackListGet))
+	setCmd := rpcBlackListMethodCMD("set", "replace the rpc blacklist of the node with the one in the file, "+
+		"it is not written back to chainmaker.yml of the node", rpcBlackListSet)
+	attachFlags(setCmd, []string{flagBlackListPath})
+	setCmd.MarkFlagRequired(flagBlackListPath)
+	blackListCmd.AddCommand(setCmd)
+	return blackListCmd
+}
+
+func rpcBlackListMethodCMD(use, short, method string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Long:  short + ", the sender must be an admin of the org which the node belongs to",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return rpcBlackList(method)
+		},
+	}
+
+	attachFlags(cmd, []string{
+		flagUserSignKeyFilePath, flagUserSignCrtFilePath,
+		flagSdkConfPath, flagOrgId, flagChainId, flagUserTlsCrtFilePath, flagUserTlsKeyFilePath,
+	})
+
+	cmd.MarkFlagRequired(flagSdkConfPath)
+	cmd.MarkFlagRequired(flagChainId)
+
+	return cmd
+}
+
+func rpcBlackList(method string) error {
+	var params []*common.KeyValuePair
+	if method == rpcBlackListSet {
+		config, err := ioutil.ReadFile(blackListPath)
+		if err != nil {
+			return fmt.Errorf("read blacklist file failed, %s", err.Error())
+		}
+		params = append(params, &common.KeyValuePair{Key: rpcBlackListParamConfig, Value: config})
+	}
+
+	client, err := util.CreateChainClient(sdkConfPath, chainId, orgId, userTlsCrtFilePath, userTlsKeyFilePath,
+		userSignCrtFilePath, userSignKeyFilePath)
+	if err != nil {
+		return fmt.Errorf("create user client failed, %s", err.Error())
+	}
+	defer client.Stop()
+	resp, err := util.CallAdmin(client, &util.AdminRequest{
+		SdkConfPath:     sdkConfPath,
+		ChainId:         chainId,
+		OrgId:           orgId,
+		UserTlsCrtPath:  userTlsCrtFilePath,
+		UserTlsKeyPath:  userTlsKeyFilePath,
+		UserSignCrtPath: userSignCrtFilePath,
+		UserSignKeyPath: userSignKeyFilePath,
+		Method:          adminMethodRpcBlackList,
+		Operation:       method,
+		Parameters:      params,
+		Timeout:         time.Duration(DEFAULT_TIMEOUT) * time.Millisecond,
+	})
+	if err != nil {
+		return fmt.Errorf("%s failed, %s", method, err.Error())
+	}
+	if resp.Code != common.TxStatusCode_SUCCESS {
+		return fmt.Errorf("%s failed, %s", method, resp.Message)
+	}
+	fmt.Printf("%s\n", resp.ContractResult.Result)
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright (C) BABEC. All rights reserved.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"chainmaker.org/chainmaker/common/v2/ca"
+	"chainmaker.org/chainmaker/common/v2/crypto"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	sdk "chainmaker.org/chainmaker/sdk-go/v2"
+	sdkutils "chainmaker.org/chainmaker/sdk-go/v2/utils"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+// adminServiceName the gRPC service of the node which manages the node, see AdminServiceName in rpcserver
+const adminServiceName = "api.AdminNode"
+
+// AdminRequest the request of the admin RPCs of the node, it is signed by the sign key of the user,
+// which must be an admin of the org which the node belongs to.
+type AdminRequest struct {
+	// SdkConfPath the sdk config which the node address and the tls settings are read from, the first node is used
+	SdkConfPath string
+	// ChainId, OrgId, UserTlsCrtPath, UserTlsKeyPath, UserSignCrtPath, UserSignKeyPath overwrite the sdk config
+	// if not empty
+	ChainId         string
+	OrgId           string
+	UserTlsCrtPath  string
+	UserTlsKeyPath  string
+	UserSignCrtPath string
+	UserSignKeyPath string
+	// Method the name of the gRPC method, e.g. ChainLifecycle
+	Method string
+	// Operation the operation of the method, e.g. PAUSE_CHAIN
+	Operation  string
+	Parameters []*common.KeyValuePair
+	Timeout    time.Duration
+}
+
+// CallAdmin sends the admin request to the node, the auth type and hash type are those of the client.
+// The request has a random tx id and the current timestamp, so it can not be replayed to the node.
+func CallAdmin(client *sdk.ChainClient, req *AdminRequest) (*common.TxResponse, error) {
+	conf := viper.New()
+	conf.SetConfigFile(req.SdkConfPath)
+	if err := conf.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read sdk config failed, %s", err)
+	}
+	signKeyPath := firstNotEmpty(req.UserSignKeyPath, conf.GetString("chain_client.user_sign_key_file_path"),
+		req.UserTlsKeyPath, conf.GetString("chain_client.user_key_file_path"))
+	signCrtPath := firstNotEmpty(req.UserSignCrtPath, conf.GetString("chain_client.user_sign_crt_file_path"),
+		req.UserTlsCrtPath, conf.GetString("chain_client.user_crt_file_path"))
+	chainId := firstNotEmpty(req.ChainId, conf.GetString("chain_client.chain_id"))
+	orgId := firstNotEmpty(req.OrgId, conf.GetString("chain_client.org_id"))
+	if signKeyPath == "" {
+		return nil, errors.New("the sign key of the user is required")
+	}
+
+	payload := sdkutils.NewPayload(
+		sdkutils.WithChainId(chainId),
+		sdkutils.WithTxType(common.TxType_QUERY_CONTRACT),
+		sdkutils.WithTxId(sdkutils.GetRandTxId()),
+		sdkutils.WithTimestamp(time.Now().Unix()),
+		sdkutils.WithContractName(req.Method),
+		sdkutils.WithMethod(req.Operation),
+		sdkutils.WithParameters(req.Parameters),
+	)
+	var sender *common.EndorsementEntry
+	var err error
+	if client.GetAuthType() == sdk.PermissionedWithCert {
+		sender, err = sdkutils.MakeEndorserWithPath(signKeyPath, signCrtPath, payload)
+	} else {
+		sender, err = sdkutils.MakePkEndorserWithPath(signKeyPath, crypto.HashAlgoMap[client.GetHashType()],
+			orgId, payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sign the admin request failed, %s", err)
+	}
+
+	conn, err := dialFirstNode(conf, req)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), req.Timeout)
+	defer cancel()
+	resp := &common.TxResponse{}
+	err = conn.Invoke(ctx, "/"+adminServiceName+"/"+req.Method,
+		&common.TxRequest{Payload: payload, Sender: sender}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func dialFirstNode(conf *viper.Viper, req *AdminRequest) (*grpc.ClientConn, error) {
+	var nodes []map[string]interface{}
+	if err := conf.UnmarshalKey("chain_client.nodes", &nodes); err != nil || len(nodes) == 0 {
+		return nil, fmt.Errorf("no node in the sdk config, %v", err)
+	}
+	node := viper.New()
+	if err := node.MergeConfigMap(nodes[0]); err != nil {
+		return nil, err
+	}
+	if !node.GetBool("enable_tls") {
+		return grpc.Dial(node.GetString("node_addr"), grpc.WithInsecure())
+	}
+	tlsClient := ca.CAClient{
+		ServerName: node.GetString("tls_host_name"),
+		CaPaths:    node.GetStringSlice("trust_root_paths"),
+		CertFile:   firstNotEmpty(req.UserTlsCrtPath, conf.GetString("chain_client.user_crt_file_path")),
+		KeyFile:    firstNotEmpty(req.UserTlsKeyPath, conf.GetString("chain_client.user_key_file_path")),
+	}
+	c, err := tlsClient.GetCredentialsByCA()
+	if err != nil {
+		return nil, fmt.Errorf("get tls credentials failed, %s", err)
+	}
+	return grpc.Dial(node.GetString("node_addr"), grpc.WithTransportCredentials(*c))
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}